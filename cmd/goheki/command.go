@@ -0,0 +1,233 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/catalog"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/content_filter"
+	"github.com/maguro-alternative/goheki/pkg/db"
+	"github.com/maguro-alternative/goheki/pkg/migrate"
+)
+
+const usage = `usage:
+  goheki [serve]            マイグレーションを適用してからサーバーを起動する
+  goheki export [flags]     カタログを書き出す
+  goheki import [flags] <file.json|file.zip|dir|->
+                            カタログを読み込む
+  goheki migrate up         適用していないマイグレーションを全て適用する
+  goheki migrate down [-n N]
+                            最後に適用したマイグレーションからN個 (既定は1) を取り消す
+  goheki migrate status     マイグレーションの適用状況を表示する`
+
+// runCommand はサーバーを起動せずにサブコマンドを実行する
+// マイグレーションは適用しないため、export、importの前にmigrate upを実行する
+func runCommand(ctx context.Context, driver db.Driver, migrator *migrate.Migrator, args []string) error {
+	switch args[0] {
+	case "export":
+		return runExport(ctx, driver, args[1:])
+	case "import":
+		return runImport(ctx, driver, args[1:])
+	case "migrate":
+		return runMigrate(ctx, migrator, args[1:])
+	}
+	return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+}
+
+// runExport はカタログをJSONまたはCSVで書き出す
+// CSVは-oが.zipで終わる場合と省略した場合はzip、それ以外はディレクトリに書き出す
+func runExport(ctx context.Context, driver db.Driver, args []string) error {
+	fset := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fset.String("format", "json", "json or csv")
+	out := fset.String("o", "", "output file or directory (default: stdout)")
+	var policy contentfilter.Policy
+	fset.BoolVar(&policy.HideNsfw, "hide-nsfw", false, "exclude nsfw links and images")
+	fset.BoolVar(&policy.HideDarkness, "hide-darkness", false, "exclude darkness links and images")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("-format must be json or csv")
+	}
+	doc, err := catalog.Export(ctx, driver, policy)
+	if err != nil {
+		return err
+	}
+
+	if *format == "csv" && *out != "" && !strings.HasSuffix(*out, ".zip") {
+		if err := os.MkdirAll(*out, 0o755); err != nil {
+			return err
+		}
+		for _, f := range catalog.EncodeCSV(doc) {
+			if err := writeFile(filepath.Join(*out, f.Name), f.Write); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	write := func(w io.Writer) error {
+		if *format == "csv" {
+			return catalog.WriteZip(w, catalog.EncodeCSV(doc))
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	}
+	if *out == "" {
+		return write(os.Stdout)
+	}
+	return writeFile(*out, write)
+}
+
+// writeFile はファイルを作成してwriteで書き込む
+func writeFile(name string, write func(w io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// runImport はカタログを読み込み、結果をJSONで出力する
+func runImport(ctx context.Context, driver db.Driver, args []string) error {
+	fset := flag.NewFlagSet("import", flag.ContinueOnError)
+	onConflict := fset.String("on-conflict", string(catalog.OnConflictError), "error, skip or update")
+	dryRun := fset.Bool("dry-run", false, "report the result without writing")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if fset.NArg() != 1 {
+		return errors.New(usage)
+	}
+	mode, err := catalog.ParseOnConflict(*onConflict)
+	if err != nil {
+		return fmt.Errorf("-on-conflict: %w", err)
+	}
+	doc, err := readDocument(fset.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	report, err := catalog.Import(ctx, driver, doc, catalog.Options{OnConflict: mode, DryRun: *dryRun})
+	var conflict *catalog.ConflictError
+	if errors.As(err, &conflict) {
+		report = conflict.Report
+	} else if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if encErr := enc.Encode(report); encErr != nil {
+		return encErr
+	}
+	return err
+}
+
+// readDocument はJSONのファイル、CSVのzipまたはディレクトリから文書を読み込む
+// nameが-の場合は標準入力からJSONを読み込む
+func readDocument(name string) (*catalog.Document, error) {
+	if name == "-" {
+		return decodeJSON(os.Stdin)
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	var files map[string][][]string
+	switch {
+	case info.IsDir():
+		files, err = catalog.ReadCSV(os.DirFS(name))
+	case strings.HasSuffix(name, ".zip"):
+		var zr *zip.ReadCloser
+		zr, err = zip.OpenReader(name)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		files, err = catalog.ReadCSV(zr)
+	default:
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return decodeJSON(f)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return catalog.DecodeCSV(files)
+}
+
+func decodeJSON(r io.Reader) (*catalog.Document, error) {
+	var doc catalog.Document
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// runMigrate はマイグレーションを適用、取り消し、または適用状況を表示する
+func runMigrate(ctx context.Context, migrator *migrate.Migrator, args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied  %04d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+		return err
+	case "down":
+		fset := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		n := fset.Int("n", 1, "number of migrations to revert")
+		if err := fset.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *n < 1 {
+			return errors.New("-n must be at least 1")
+		}
+		reverted, err := migrator.Down(ctx, *n)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			if s.Missing {
+				appliedAt += " (file missing)"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return w.Flush()
+	}
+	return fmt.Errorf("unknown migrate command %q\n%s", args[0], usage)
+}
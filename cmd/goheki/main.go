@@ -6,116 +6,137 @@ import (
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/article"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/middleware"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/server"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
 
-	"github.com/maguro-alternative/goheki/internal/app/goheki/api/bwh"
-	"github.com/maguro-alternative/goheki/internal/app/goheki/api/entry"
-	"github.com/maguro-alternative/goheki/internal/app/goheki/api/entry_tag"
-	"github.com/maguro-alternative/goheki/internal/app/goheki/api/haircolor"
-	"github.com/maguro-alternative/goheki/internal/app/goheki/api/hairlength"
-	"github.com/maguro-alternative/goheki/internal/app/goheki/api/hairstyle"
-	"github.com/maguro-alternative/goheki/internal/app/goheki/api/heki_radar_chart"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/content_filter"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/api/link"
-	"github.com/maguro-alternative/goheki/internal/app/goheki/api/personality"
-	"github.com/maguro-alternative/goheki/internal/app/goheki/api/source"
-	"github.com/maguro-alternative/goheki/internal/app/goheki/api/tag"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/media"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/migrations"
+	"github.com/maguro-alternative/goheki/pkg/migrate"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/unfurl"
 
-	_ "embed"
 	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/justinas/alice"
 )
 
-//go:embed schema.sql
-var schema string // schema.sqlの内容をschemaに代入
-
 func main() {
-	ctx := context.Background()
+	// SIGINT、SIGTERMを受け取るとキャンセルされる
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	// load env
 	env, err := envconfig.NewEnv()
 	if err != nil {
 		log.Fatal(err)
 	}
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := db.NewDBV1(ctx, env.DatabaseType, env.DatabaseURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	migrator, err := migrate.New(indexDB, migrations.FS)
+	if err != nil {
+		cleanup()
+		log.Fatal(err)
+	}
+	// サーバーの起動以外のサブコマンドはマイグレーションを適用せずに実行する
+	// マイグレーションはmigrateで明示的に適用または取り消す
+	if len(os.Args) > 1 && os.Args[1] != "serve" {
+		err := runCommand(ctx, indexDB, migrator, os.Args[1:])
+		cleanup()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// 適用していないマイグレーションを適用してから起動する
+	applied, err := migrator.Up(ctx)
 	if err != nil {
+		cleanup()
 		log.Fatal(err)
 	}
-	// テーブルの作成
-	if _, err := indexDB.ExecContext(ctx, schema); err != nil {
+	for _, m := range applied {
+		log.Printf("migrated %04d_%s", m.Version, m.Name)
+	}
+
+	// リンクを隠す既定の設定が正しいか起動時に確認する
+	if _, err := contentfilter.Default(env); err != nil {
+		cleanup()
 		log.Fatal(err)
 	}
 
-	defer cleanup()
+	imageCfg, err := media.NewConfig(env)
+	if err != nil {
+		cleanup()
+		log.Fatal(err)
+	}
+	imageStorage := media.NewLocalStorage(imageCfg.Dir, imageCfg.BaseURL)
+
 	var indexService = service.NewIndexService(
 		indexDB,
 		cookie.Store,
 		env,
 	)
+	indexService.Images = media.NewUploader(imageStorage, imageCfg.MaxBytes)
 
 	// register routes
 	mux := http.NewServeMux()
-	middleChain := alice.New(middleware.CORS)
+	middleChain := alice.New(middleware.RequestID, middleware.CORS)
 	mux.Handle("/", middleChain.Then(article.NewIndexHandler(indexService)))
+	// 外部のURLで配信する場合はこのサーバーでは配信しない
+	if strings.HasPrefix(imageCfg.BaseURL, "/") {
+		imagePath := strings.TrimSuffix(imageCfg.BaseURL, "/") + "/"
+		mux.Handle(imagePath, middleChain.Then(http.StripPrefix(imagePath, imageStorage)))
+	}
 
-	mux.Handle("/api/bwh/create", middleChain.Append(middleware.BasicAuth).Then(bwh.NewCreateHandler(indexService)))
-	mux.Handle("/api/bwh/read", middleChain.Append(middleware.BasicAuth).Then(bwh.NewReadHandler(indexService)))
-	mux.Handle("/api/bwh/update", middleChain.Append(middleware.BasicAuth).Then(bwh.NewUpdateHandler(indexService)))
-	mux.Handle("/api/bwh/delete", middleChain.Append(middleware.BasicAuth).Then(bwh.NewDeleteHandler(indexService)))
-
-	mux.Handle("/api/entry/create", middleChain.Append(middleware.BasicAuth).Then(entry.NewCreateHandler(indexService)))
-	mux.Handle("/api/entry/read", middleChain.Append(middleware.BasicAuth).Then(entry.NewReadHandler(indexService)))
-	mux.Handle("/api/entry/update", middleChain.Append(middleware.BasicAuth).Then(entry.NewUpdateHandler(indexService)))
-	mux.Handle("/api/entry/delete", middleChain.Append(middleware.BasicAuth).Then(entry.NewDeleteHandler(indexService)))
-
-	mux.Handle("/api/tag/create", middleChain.Append(middleware.BasicAuth).Then(tag.NewCreateHandler(indexService)))
-	mux.Handle("/api/tag/read", middleChain.Append(middleware.BasicAuth).Then(tag.NewReadHandler(indexService)))
-	mux.Handle("/api/tag/update", middleChain.Append(middleware.BasicAuth).Then(tag.NewUpdateHandler(indexService)))
-	mux.Handle("/api/tag/delete", middleChain.Append(middleware.BasicAuth).Then(tag.NewDeleteHandler(indexService)))
-
-	mux.Handle("/api/entry_tag/create", middleChain.Append(middleware.BasicAuth).Then(entry_tag.NewCreateHandler(indexService)))
-	mux.Handle("/api/entry_tag/read", middleChain.Append(middleware.BasicAuth).Then(entry_tag.NewReadHandler(indexService)))
-	mux.Handle("/api/entry_tag/update", middleChain.Append(middleware.BasicAuth).Then(entry_tag.NewUpdateHandler(indexService)))
-	mux.Handle("/api/entry_tag/delete", middleChain.Append(middleware.BasicAuth).Then(entry_tag.NewDeleteHandler(indexService)))
-
-	mux.Handle("/api/haircolor/create", middleChain.Append(middleware.BasicAuth).Then(haircolor.NewCreateHandler(indexService)))
-	mux.Handle("/api/haircolor/read", middleChain.Append(middleware.BasicAuth).Then(haircolor.NewReadHandler(indexService)))
-	mux.Handle("/api/haircolor/update", middleChain.Append(middleware.BasicAuth).Then(haircolor.NewUpdateHandler(indexService)))
-	mux.Handle("/api/haircolor/delete", middleChain.Append(middleware.BasicAuth).Then(haircolor.NewDeleteHandler(indexService)))
-
-	mux.Handle("/api/hairlength/create", middleChain.Append(middleware.BasicAuth).Then(hairlength.NewCreateHandler(indexService)))
-	mux.Handle("/api/hairlength/read", middleChain.Append(middleware.BasicAuth).Then(hairlength.NewReadHandler(indexService)))
-	mux.Handle("/api/hairlength/update", middleChain.Append(middleware.BasicAuth).Then(hairlength.NewUpdateHandler(indexService)))
-	mux.Handle("/api/hairlength/delete", middleChain.Append(middleware.BasicAuth).Then(hairlength.NewDeleteHandler(indexService)))
-
-	mux.Handle("/api/hairstyle/create", middleChain.Append(middleware.BasicAuth).Then(hairstyle.NewCreateHandler(indexService)))
-	mux.Handle("/api/hairstyle/read", middleChain.Append(middleware.BasicAuth).Then(hairstyle.NewReadHandler(indexService)))
-	mux.Handle("/api/hairstyle/update", middleChain.Append(middleware.BasicAuth).Then(hairstyle.NewUpdateHandler(indexService)))
-	mux.Handle("/api/hairstyle/delete", middleChain.Append(middleware.BasicAuth).Then(hairstyle.NewDeleteHandler(indexService)))
-
-	mux.Handle("/api/heki_radar_chart/create", middleChain.Append(middleware.BasicAuth).Then(hekiradarchart.NewCreateHandler(indexService)))
-	mux.Handle("/api/heki_radar_chart/read", middleChain.Append(middleware.BasicAuth).Then(hekiradarchart.NewReadHandler(indexService)))
-	mux.Handle("/api/heki_radar_chart/update", middleChain.Append(middleware.BasicAuth).Then(hekiradarchart.NewUpdateHandler(indexService)))
-	mux.Handle("/api/heki_radar_chart/delete", middleChain.Append(middleware.BasicAuth).Then(hekiradarchart.NewDeleteHandler(indexService)))
-
-	mux.Handle("/api/link/create", middleChain.Append(middleware.BasicAuth).Then(link.NewCreateHandler(indexService)))
-	mux.Handle("/api/link/read", middleChain.Append(middleware.BasicAuth).Then(link.NewReadHandler(indexService)))
-	mux.Handle("/api/link/update", middleChain.Append(middleware.BasicAuth).Then(link.NewUpdateHandler(indexService)))
-	mux.Handle("/api/link/delete", middleChain.Append(middleware.BasicAuth).Then(link.NewDeleteHandler(indexService)))
-
-	mux.Handle("/api/personality/create", middleChain.Append(middleware.BasicAuth).Then(personality.NewCreateHandler(indexService)))
-	mux.Handle("/api/personality/read", middleChain.Append(middleware.BasicAuth).Then(personality.NewReadHandler(indexService)))
-	mux.Handle("/api/personality/update", middleChain.Append(middleware.BasicAuth).Then(personality.NewUpdateHandler(indexService)))
-	mux.Handle("/api/personality/delete", middleChain.Append(middleware.BasicAuth).Then(personality.NewDeleteHandler(indexService)))
-
-	mux.Handle("/api/source/create", middleChain.Append(middleware.BasicAuth).Then(source.NewCreateHandler(indexService)))
-	mux.Handle("/api/source/read", middleChain.Append(middleware.BasicAuth).Then(source.NewReadHandler(indexService)))
-	mux.Handle("/api/source/update", middleChain.Append(middleware.BasicAuth).Then(source.NewUpdateHandler(indexService)))
-	mux.Handle("/api/source/delete", middleChain.Append(middleware.BasicAuth).Then(source.NewDeleteHandler(indexService)))
+	apiRouter := router.New(mux, middleChain.Append(middleware.BasicAuth))
+	apiRouter.Mount(api.Routes(indexService)...)
+	for _, route := range apiRouter.Routes() {
+		if route.Deprecated {
+			log.Printf("mounted %-28s %s (deprecated)", route.Path, strings.Join(route.Methods, ","))
+			continue
+		}
+		log.Printf("mounted %-28s %s", route.Path, strings.Join(route.Methods, ","))
+	}
 
-	log.Print("Server listening on port http://localhost:8080")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		log.Fatal("ListenAndServe:", err)
+	cfg, err := server.NewConfig(env)
+	if err != nil {
+		cleanup()
+		log.Fatal(err)
+	}
+	srv := server.New(*cfg, mux)
+	// リンク先の情報を定期的に取得し直す
+	refreshCfg, err := link.NewRefreshConfig(env)
+	if err != nil {
+		cleanup()
+		log.Fatal(err)
+	}
+	srv.Go("link-unfurl", link.NewRefresher(indexDB, unfurl.NewHTTPFetcher(), *refreshCfg).Run)
+	// リンク切れを定期的に確認する
+	checkCfg, err := link.NewCheckConfig(env)
+	if err != nil {
+		cleanup()
+		log.Fatal(err)
+	}
+	srv.Go("link-check", link.NewChecker(indexDB, unfurl.NewClient(checkCfg.Timeout), *checkCfg).Run)
+	// サーバー停止後にDBとの接続を閉じる
+	srv.OnShutdown("db", func(ctx context.Context) error {
+		cleanup()
+		return nil
+	})
+
+	log.Printf("Server listening on port http://localhost%s", cfg.Addr)
+	if err := srv.Run(ctx); err != nil {
+		log.Fatal("server:", err)
 	}
-}
\ No newline at end of file
+	log.Println("server stopped")
+}
@@ -2,6 +2,7 @@ package envconfig
 
 import (
 	"context"
+	"io/fs"
 	"os"
 	"time"
 
@@ -17,8 +18,10 @@ const retryLimit = 3
 const retryInterval = 1 // 1秒
 
 type Env struct {
-	TOKEN            string
-	DatabaseType     string
+	TOKEN string
+	// DBの種類 (postgres または sqlite3)
+	DatabaseType string
+	// DBの接続先 (SQLiteの場合はファイルのパスまたは:memory:)
 	DatabaseURL      string
 	DatabaseName     string
 	DatabaseUser     string
@@ -26,13 +29,32 @@ type Env struct {
 	DatabaseHost     string
 	DatabasePort     string
 	ServerPort       string
-	SessionsSecret   string
-	DiscordClientID  string
-	DiscordSecret    string
-	FrontUrl         string
-	ServerUrl        string
-	SessionsName     string
-	CookieDomain     string
+	// サーバーのタイムアウト (例: 15s)
+	ServerReadTimeout     string
+	ServerWriteTimeout    string
+	ServerIdleTimeout     string
+	ServerShutdownTimeout string
+	// リンク先の情報を取得し直す間隔と、情報が古くなるまでの時間 (例: 1h)
+	LinkUnfurlInterval   string
+	LinkUnfurlStaleAfter string
+	// リンク切れを確認する間隔と1件のタイムアウト、同時に確認する数
+	LinkCheckInterval    string
+	LinkCheckTimeout     string
+	LinkCheckConcurrency string
+	SessionsSecret       string
+	DiscordClientID      string
+	DiscordSecret        string
+	FrontUrl             string
+	ServerUrl            string
+	SessionsName         string
+	CookieDomain         string
+	// nsfwとdarknessのリンクを既定で隠すか (例: true)
+	ContentHideNsfw     string
+	ContentHideDarkness string
+	// アップロードした画像の保存先と配信するURL、最大のファイルサイズ (byte)
+	ImageDir      string
+	ImageBaseURL  string
+	ImageMaxBytes string
 }
 
 func NewEnv() (*Env, error) {
@@ -40,7 +62,7 @@ func NewEnv() (*Env, error) {
 		operation := func() error {
 			err := godotenv.Load(".env")
 			// .envファイルがない場合は無視する
-			if err != nil && err.Error() != "open .env: no such file or directory" {
+			if errors.Is(err, fs.ErrNotExist) {
 				return nil
 			}
 			return errors.WithStack(err)
@@ -52,23 +74,47 @@ func NewEnv() (*Env, error) {
 		return nil, err
 	}
 
+	// 指定しない場合はPGURLのPostgresに接続する
+	databaseType := os.Getenv("DATABASE_TYPE")
+	if databaseType == "" {
+		databaseType = "postgres"
+	}
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		databaseURL = os.Getenv("PGURL")
+	}
+
 	return &Env{
-		TOKEN:            os.Getenv("D_TOKEN"),
-		DatabaseType:     "postgresql",
-		DatabaseURL:      os.Getenv("PGURL"),
-		DatabaseName:     os.Getenv("PGDATABASE"),
-		DatabaseUser:     os.Getenv("PGUSER"),
-		DatabasePassword: os.Getenv("PGPASSWORD"),
-		DatabaseHost:     os.Getenv("PGHOST"),
-		DatabasePort:     os.Getenv("PGPORT"),
-		ServerPort:       os.Getenv("PORT"),
-		SessionsSecret:   os.Getenv("SESSIONS_SECRET"),
-		DiscordClientID:  os.Getenv("DISCORD_CLIENT_ID"),
-		DiscordSecret:    os.Getenv("DISCORD_CLIENT_SECRET"),
-		FrontUrl:         os.Getenv("FRONT_URL"),
-		ServerUrl:        os.Getenv("SERVER_URL"),
-		SessionsName:     os.Getenv("SESSIONS_NAME"),
-		CookieDomain:     os.Getenv("COOKIE_DOMAIN"),
+		TOKEN:                 os.Getenv("D_TOKEN"),
+		DatabaseType:          databaseType,
+		DatabaseURL:           databaseURL,
+		DatabaseName:          os.Getenv("PGDATABASE"),
+		DatabaseUser:          os.Getenv("PGUSER"),
+		DatabasePassword:      os.Getenv("PGPASSWORD"),
+		DatabaseHost:          os.Getenv("PGHOST"),
+		DatabasePort:          os.Getenv("PGPORT"),
+		ServerPort:            os.Getenv("PORT"),
+		ServerReadTimeout:     os.Getenv("SERVER_READ_TIMEOUT"),
+		ServerWriteTimeout:    os.Getenv("SERVER_WRITE_TIMEOUT"),
+		ServerIdleTimeout:     os.Getenv("SERVER_IDLE_TIMEOUT"),
+		ServerShutdownTimeout: os.Getenv("SERVER_SHUTDOWN_TIMEOUT"),
+		LinkUnfurlInterval:    os.Getenv("LINK_UNFURL_INTERVAL"),
+		LinkUnfurlStaleAfter:  os.Getenv("LINK_UNFURL_STALE_AFTER"),
+		LinkCheckInterval:     os.Getenv("LINK_CHECK_INTERVAL"),
+		LinkCheckTimeout:      os.Getenv("LINK_CHECK_TIMEOUT"),
+		LinkCheckConcurrency:  os.Getenv("LINK_CHECK_CONCURRENCY"),
+		SessionsSecret:        os.Getenv("SESSIONS_SECRET"),
+		DiscordClientID:       os.Getenv("DISCORD_CLIENT_ID"),
+		DiscordSecret:         os.Getenv("DISCORD_CLIENT_SECRET"),
+		FrontUrl:              os.Getenv("FRONT_URL"),
+		ServerUrl:             os.Getenv("SERVER_URL"),
+		SessionsName:          os.Getenv("SESSIONS_NAME"),
+		CookieDomain:          os.Getenv("COOKIE_DOMAIN"),
+		ContentHideNsfw:       os.Getenv("CONTENT_HIDE_NSFW"),
+		ContentHideDarkness:   os.Getenv("CONTENT_HIDE_DARKNESS"),
+		ImageDir:              os.Getenv("IMAGE_DIR"),
+		ImageBaseURL:          os.Getenv("IMAGE_BASE_URL"),
+		ImageMaxBytes:         os.Getenv("IMAGE_MAX_BYTES"),
 	}, nil
 }
 
@@ -87,4 +133,4 @@ func retryOperation(ctx context.Context, operation func() error) error {
 		//slog.WarnContext(ctx, fmt.Sprintf("%v retrying in %v...", err, duration))
 	})
 	return errors.WithStack(err)
-}
\ No newline at end of file
+}
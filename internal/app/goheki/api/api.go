@@ -0,0 +1,65 @@
+package api
+
+import (
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/bwh"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/catalog"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/content_filter"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/entry"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/entry_image"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/entry_tag"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/eyecolor"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/eyecolor_type"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/facet"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/haircolor"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/haircolor_type"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/hairlength"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/hairlength_type"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/hairstyle"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/hairstyle_type"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/heki_radar_chart"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/link"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/personality"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/personality_type"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/profile"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/search"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/source"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/stats"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/tag"
+)
+
+// Routes はAPIの全リソースのルーティングの定義を返す
+// 新しいリソースを追加した場合はここに登録する
+func Routes(svc *service.IndexService) []router.Resource {
+	return []router.Resource{
+		source.Routes(svc),
+		entry.Routes(svc),
+		entryimage.Routes(svc),
+		tag.Routes(svc),
+		entry_tag.Routes(svc),
+		bwh.Routes(svc),
+		hekiradarchart.AxisRoutes(svc),
+		hekiradarchart.Routes(svc),
+		eyecolor.Routes(svc),
+		eyecolortype.Routes(svc),
+		haircolor.Routes(svc),
+		haircolortype.Routes(svc),
+		hairlength.Routes(svc),
+		hairlengthtype.Routes(svc),
+		hairstyle.Routes(svc),
+		hairstyletype.Routes(svc),
+		personality.Routes(svc),
+		personalitytype.Routes(svc),
+		link.Routes(svc),
+		link.BrokenRoutes(svc),
+		profile.Routes(svc),
+		search.Routes(svc),
+		facet.Routes(svc),
+		stats.Routes(svc),
+		contentfilter.Routes(svc),
+		catalog.ExportRoutes(svc),
+		catalog.ImportRoutes(svc),
+	}
+}
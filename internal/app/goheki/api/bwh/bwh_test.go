@@ -11,11 +11,10 @@ import (
 	"time"
 
 	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
 
-	"github.com/maguro-alternative/goheki/pkg/db"
-
 	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
 
 	"github.com/stretchr/testify/assert"
@@ -29,7 +28,7 @@ func TestCreateBEHHandler(t *testing.T) {
 	takaneHeight := int64(169)
 	takaneWeight := int64(49)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -144,6 +143,51 @@ func TestCreateBEHHandler(t *testing.T) {
 		assert.Equal(t, 0, count)
 	})
 
+	t.Run("bwh登録失敗(値の範囲が正しくない)", func(t *testing.T) {
+		underbust := int64(95)
+		weight := int64(0)
+		h := NewCreateHandler(indexService)
+		bJson, err := json.Marshal(BWHsJson{[]BWH{
+			{EntryID: f.Entrys[0].ID, Bust: 92, Waist: 20, Hip: 84, Underbust: &underbust, Weight: &weight},
+		}})
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/api/bwh/create", bytes.NewBuffer(bJson))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		var res problem.Problem
+		err = json.Unmarshal(w.Body.Bytes(), &res)
+		assert.NoError(t, err)
+		assert.Equal(t, []problem.FieldError{
+			{Field: "bwhs[0].underbust", Message: "must be less than bust (92)"},
+			{Field: "bwhs[0].waist", Message: "must be no less than 30"},
+			{Field: "bwhs[0].weight", Message: "cannot be blank"},
+		}, res.Errors)
+	})
+
+	t.Run("bwh登録失敗(アンダーバストが上限を超える)", func(t *testing.T) {
+		underbust := int64(250)
+		h := NewCreateHandler(indexService)
+		bJson, err := json.Marshal(BWHsJson{[]BWH{
+			{EntryID: f.Entrys[0].ID, Bust: 92, Waist: 58, Hip: 84, Underbust: &underbust},
+		}})
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/api/bwh/create", bytes.NewBuffer(bJson))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		var res problem.Problem
+		err = json.Unmarshal(w.Body.Bytes(), &res)
+		assert.NoError(t, err)
+		assert.Equal(t, []problem.FieldError{
+			{Field: "bwhs[0].underbust", Message: "must be no greater than 200"},
+		}, res.Errors)
+	})
+
 	t.Run("bwh登録", func(t *testing.T) {
 		h := NewCreateHandler(indexService)
 		bJson, err := json.Marshal(&bwhsJson)
@@ -160,7 +204,12 @@ func TestCreateBEHHandler(t *testing.T) {
 		var dbResult []BWH
 		err = json.Unmarshal(w.Body.Bytes(), &res)
 		assert.NoError(t, err)
-		assert.Equal(t, bwhs, res.BWHs)
+		assert.Equal(t, bwhs, stored(res.BWHs))
+		// 登録した値から計算した値も返す
+		if assert.Len(t, res.BWHs, 2) {
+			assert.Equal(t, 0.667, *res.BWHs[0].WaistHipRatio)
+			assert.Equal(t, 17.2, *res.BWHs[1].BMI)
+		}
 		err = tx.SelectContext(ctx, &dbResult, "SELECT * FROM bwh")
 		assert.NoError(t, err)
 		assert.Equal(t, bwhsJson.BWHs, dbResult)
@@ -175,7 +224,7 @@ func TestReadBEHHandler(t *testing.T) {
 	takaneHeight := int64(169)
 	takaneWeight := int64(49)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -229,21 +278,37 @@ func TestReadBEHHandler(t *testing.T) {
 	)
 
 	// テストデータの準備
+	// 計算する値はアンダーバストがないためウエスト+12cmから推定する
 	bwhs := []BWH{
 		{
-			EntryID: f.Entrys[0].ID,
-			Bust:    92,
-			Waist:   56,
-			Hip:     84,
-			Height:  &yumiHeight,
+			EntryID:          f.Entrys[0].ID,
+			Bust:             92,
+			Waist:            56,
+			Hip:              84,
+			Height:           &yumiHeight,
+			WaistHipRatio:    float(0.667),
+			CupSize:          str("G"),
+			CupSizeEstimated: true,
+			BustInch:         float(36.2),
+			WaistInch:        float(22),
+			HipInch:          float(33.1),
+			HeightInch:       float(65.7),
 		},
 		{
-			EntryID: f.Entrys[1].ID,
-			Bust:    90,
-			Waist:   60,
-			Hip:     92,
-			Height:  &takaneHeight,
-			Weight:  &takaneWeight,
+			EntryID:          f.Entrys[1].ID,
+			Bust:             90,
+			Waist:            60,
+			Hip:              92,
+			Height:           &takaneHeight,
+			Weight:           &takaneWeight,
+			WaistHipRatio:    float(0.652),
+			BMI:              float(17.2),
+			CupSize:          str("D"),
+			CupSizeEstimated: true,
+			BustInch:         float(35.4),
+			WaistInch:        float(23.6),
+			HipInch:          float(36.2),
+			HeightInch:       float(66.5),
 		},
 	}
 	var indexService = service.NewIndexService(
@@ -302,6 +367,64 @@ func TestReadBEHHandler(t *testing.T) {
 		assert.Equal(t, bwhs, res.BWHs)
 	})
 
+	t.Run("bwh計算した値で絞り込み", func(t *testing.T) {
+		h := NewReadHandler(indexService)
+		req := httptest.NewRequest(http.MethodGet, "/api/bwhs?cup_size=D&bmi[lt]=20", nil)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var res BWHsJson
+		err = json.Unmarshal(w.Body.Bytes(), &res)
+		assert.NoError(t, err)
+		assert.Equal(t, bwhs[1:], res.BWHs)
+	})
+
+	t.Run("bwh計算した値で並べ替え", func(t *testing.T) {
+		h := NewReadHandler(indexService)
+		req := httptest.NewRequest(http.MethodGet, "/api/bwhs?sort=waist_hip_ratio", nil)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var res BWHsJson
+		err = json.Unmarshal(w.Body.Bytes(), &res)
+		assert.NoError(t, err)
+		assert.Equal(t, []BWH{bwhs[1], bwhs[0]}, res.BWHs)
+	})
+
+	t.Run("bwhのNULLを含む値で並べ替えてページング", func(t *testing.T) {
+		h := NewReadHandler(indexService)
+		// 昇順ではNULLを最後、降順ではNULLを最初に返す
+		for url, want := range map[string][]BWH{
+			"/api/bwhs?sort=bmi&limit=1":        {bwhs[1], bwhs[0]},
+			"/api/bwhs?sort=-bmi&limit=1":       {bwhs[0], bwhs[1]},
+			"/api/bwhs?sort=underbust&limit=1":  {bwhs[0], bwhs[1]},
+			"/api/bwhs?sort=-underbust&limit=1": {bwhs[1], bwhs[0]},
+		} {
+			var actual []BWH
+			for next := url; next != ""; {
+				req := httptest.NewRequest(http.MethodGet, next, nil)
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, req)
+				if !assert.Equal(t, http.StatusOK, w.Code, next) {
+					break
+				}
+				var res struct {
+					BWHs []BWH  `json:"bwhs"`
+					Next string `json:"next"`
+				}
+				err = json.Unmarshal(w.Body.Bytes(), &res)
+				assert.NoError(t, err)
+				actual = append(actual, res.BWHs...)
+				next = res.Next
+			}
+			assert.Equal(t, want, actual, url)
+		}
+	})
+
 	t.Run("bwh1件取得(存在しない)", func(t *testing.T) {
 		h := NewReadHandler(indexService)
 		req := httptest.NewRequest(http.MethodGet, "/api/bwh/read?entry_id=0", nil)
@@ -344,7 +467,7 @@ func TestReadBEHHandler(t *testing.T) {
 		h.ServeHTTP(w, req)
 
 		// tx.RollbackCtx(ctx)
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 	})
 
 	t.Run("bwh2件取得(内1件は形式が正しくない)", func(t *testing.T) {
@@ -356,7 +479,7 @@ func TestReadBEHHandler(t *testing.T) {
 		h.ServeHTTP(w, req)
 
 		// tx.RollbackCtx(ctx)
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 	})
 }
 
@@ -368,7 +491,7 @@ func TestUpdateBEHHandler(t *testing.T) {
 	takaneHeight := int64(169)
 	takaneWeight := int64(49)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -505,7 +628,12 @@ func TestUpdateBEHHandler(t *testing.T) {
 		var actual []BWH
 		err = json.Unmarshal(w.Body.Bytes(), &res)
 		assert.NoError(t, err)
-		assert.Equal(t, updateBWHsJson, res)
+		assert.Equal(t, updateBWHsJson.BWHs, stored(res.BWHs))
+		// 更新後の値から計算し直す
+		if assert.Len(t, res.BWHs, 2) {
+			assert.Equal(t, "H", *res.BWHs[0].CupSize)
+			assert.Equal(t, "E", *res.BWHs[1].CupSize)
+		}
 
 		err = tx.SelectContext(ctx, &actual, "SELECT * FROM bwh")
 		assert.NoError(t, err)
@@ -521,7 +649,7 @@ func TestDeleteBEHHandler(t *testing.T) {
 	takaneHeight := int64(169)
 	takaneWeight := int64(49)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -635,3 +763,28 @@ func TestDeleteBEHHandler(t *testing.T) {
 		assert.Equal(t, 1, count)
 	})
 }
+
+func float(f float64) *float64 {
+	return &f
+}
+
+func str(s string) *string {
+	return &s
+}
+
+// stored は読み込み時に計算する値を除いた、テーブルに保存する値を返す
+func stored(bwhs []BWH) []BWH {
+	result := make([]BWH, len(bwhs))
+	for i, b := range bwhs {
+		result[i] = BWH{
+			EntryID:   b.EntryID,
+			Bust:      b.Bust,
+			Waist:     b.Waist,
+			Hip:       b.Hip,
+			Underbust: b.Underbust,
+			Height:    b.Height,
+			Weight:    b.Weight,
+		}
+	}
+	return result
+}
@@ -1,24 +1,60 @@
 package bwh
 
 import (
+	"fmt"
+
 	validation "github.com/go-ozzo/ozzo-validation"
 )
 
+// BWH は体型の値 (長さはcm、体重はkg)
 type BWH struct {
-	EntryID int64  `db:"entry_id" json:"entry_id"`
-	Bust    int64  `db:"bust" json:"bust"`
-	Waist   int64  `db:"waist" json:"waist"`
-	Hip     int64  `db:"hip" json:"hip"`
-	Height  *int64 `db:"height" json:"height"`
-	Weight  *int64 `db:"weight" json:"weight"`
+	EntryID   int64  `db:"entry_id" json:"entry_id"`
+	Bust      int64  `db:"bust" json:"bust"`
+	Waist     int64  `db:"waist" json:"waist"`
+	Hip       int64  `db:"hip" json:"hip"`
+	Underbust *int64 `db:"underbust" json:"underbust"`
+	Height    *int64 `db:"height" json:"height"`
+	Weight    *int64 `db:"weight" json:"weight"`
+
+	// 以下は読み込み時に計算する値で、書き込みでは無視する
+	// 計算に必要な値がない場合はnull
+	WaistHipRatio *float64 `db:"waist_hip_ratio" json:"waist_hip_ratio"`
+	BMI           *float64 `db:"bmi" json:"bmi"`
+	// アンダーバストがない場合はウエストから推定する
+	CupSize          *string `db:"cup_size" json:"cup_size"`
+	CupSizeEstimated bool    `db:"cup_size_estimated" json:"cup_size_estimated"`
+	// インチに換算した値
+	BustInch      *float64 `db:"bust_in" json:"bust_in"`
+	WaistInch     *float64 `db:"waist_in" json:"waist_in"`
+	HipInch       *float64 `db:"hip_in" json:"hip_in"`
+	UnderbustInch *float64 `db:"underbust_in" json:"underbust_in"`
+	HeightInch    *float64 `db:"height_in" json:"height_in"`
 }
 
+// 値として妥当な範囲
+const (
+	minGirth  = 30
+	maxGirth  = 200
+	minHeight = 30
+	maxHeight = 300
+	minWeight = 1
+	maxWeight = 500
+)
+
 func (b *BWH) Validate() error {
 	return validation.ValidateStruct(b,
 		validation.Field(&b.EntryID, validation.Required),
-		validation.Field(&b.Bust, validation.Required),
-		validation.Field(&b.Waist, validation.Required),
-		validation.Field(&b.Hip, validation.Required),
+		validation.Field(&b.Bust, validation.Required, validation.Min(int64(minGirth)), validation.Max(int64(maxGirth))),
+		validation.Field(&b.Waist, validation.Required, validation.Min(int64(minGirth)), validation.Max(int64(maxGirth))),
+		validation.Field(&b.Hip, validation.Required, validation.Min(int64(minGirth)), validation.Max(int64(maxGirth))),
+		validation.Field(&b.Underbust, validation.NilOrNotEmpty, validation.Min(int64(minGirth)), validation.Max(int64(maxGirth)), validation.By(func(interface{}) error {
+			if b.Underbust != nil && *b.Underbust >= b.Bust {
+				return fmt.Errorf("must be less than bust (%d)", b.Bust)
+			}
+			return nil
+		})),
+		validation.Field(&b.Height, validation.NilOrNotEmpty, validation.Min(int64(minHeight)), validation.Max(int64(maxHeight))),
+		validation.Field(&b.Weight, validation.NilOrNotEmpty, validation.Min(int64(minWeight)), validation.Max(int64(maxWeight))),
 	)
 }
 
@@ -0,0 +1,215 @@
+package catalog
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/content_filter"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// maxImportBytes は読み込むリクエストボディの上限
+const maxImportBytes = 64 << 20
+
+// zipContentType はCSVをまとめたzipのContent-Type
+const zipContentType = "application/zip"
+
+type ExportHandler struct {
+	svc *service.IndexService
+}
+
+func NewExportHandler(svc *service.IndexService) *ExportHandler {
+	return &ExportHandler{
+		svc: svc,
+	}
+}
+
+// ServeHTTP はカタログ全体を書き出す
+//
+//	GET /api/catalog/export?format=json
+//	GET /api/catalog/export?format=csv (CSVのファイルをまとめたzip)
+//
+// nsfwとdarknessのリンクと画像はリクエストの設定に従って除く
+func (h *ExportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// GET以外は受け付けない
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		problem.Validation(w, r, validation.Errors{"format": fmt.Errorf("must be json or csv")})
+		return
+	}
+	policy, err := contentfilter.FromRequest(r, h.svc)
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+	doc, err := Export(r.Context(), h.svc.DB, policy)
+	if err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+
+	filename := "goheki-catalog-" + doc.ExportedAt.Format("20060102T150405Z")
+	if format == "csv" {
+		// zipはヘッダーを書き込んだ後にエラーを返せないため、先にメモリ上で作る
+		var buf bytes.Buffer
+		if err := WriteZip(&buf, EncodeCSV(doc)); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", zipContentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename + ".zip"}))
+		if _, err := buf.WriteTo(w); err != nil {
+			log.Printf("write error: %v", err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename + ".json"}))
+	if err := json.NewEncoder(w).Encode(doc); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
+
+type ImportHandler struct {
+	svc *service.IndexService
+}
+
+func NewImportHandler(svc *service.IndexService) *ImportHandler {
+	return &ImportHandler{
+		svc: svc,
+	}
+}
+
+// ServeHTTP は書き出したカタログを読み込み、結果を返す
+//
+//	POST /api/catalog/import?on_conflict=error&dry_run=false
+//	(Content-Type: application/json または application/zip)
+//
+// on_conflictがerror (既定) で既存の行と異なる値がある場合は409を返し、何も書き込まない
+func (h *ImportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// POST以外は受け付けない
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	opts, err := parseImportQuery(r)
+	if err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	doc, err := decodeDocument(r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			problem.TooLarge(w, r, err)
+			return
+		}
+		problem.BadRequest(w, r, err)
+		return
+	}
+
+	report, err := Import(r.Context(), h.svc.DB, doc, opts)
+	var conflict *ConflictError
+	var verrs validation.Errors
+	switch {
+	case errors.As(err, &conflict):
+		problem.Conflict(w, r, err)
+		return
+	case errors.As(err, &verrs):
+		problem.Validation(w, r, verrs)
+		return
+	case err != nil:
+		problem.Database(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
+
+// parseImportQuery はクエリパラメータから読み込みの設定を読み込む
+func parseImportQuery(r *http.Request) (Options, error) {
+	query := r.URL.Query()
+	errs := validation.Errors{}
+	onConflict, err := ParseOnConflict(query.Get("on_conflict"))
+	if err != nil {
+		errs["on_conflict"] = err
+	}
+	var dryRun bool
+	if v := query.Get("dry_run"); v != "" {
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			errs["dry_run"] = fmt.Errorf("invalid boolean %q", v)
+		}
+	}
+	if len(errs) > 0 {
+		return Options{}, errs
+	}
+	return Options{OnConflict: onConflict, DryRun: dryRun}, nil
+}
+
+// decodeDocument はリクエストボディを文書として読み込む
+// Content-Typeがapplication/zipの場合はCSVをまとめたzipとして読み込む
+func decodeDocument(r *http.Request) (*Document, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != zipContentType {
+		var doc Document
+		if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+			return nil, err
+		}
+		return &doc, nil
+	}
+	// zipの読み込みにはio.ReaderAtが必要なため、全て読み込む
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, err
+	}
+	files, err := ReadCSV(zr)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeCSV(files)
+}
+
+// ExportRoutes は/api/catalog/exportのルーティングの定義を返す
+func ExportRoutes(svc *service.IndexService) router.Resource {
+	return router.Resource{
+		Name: "catalog/export",
+		Read: NewExportHandler(svc),
+	}
+}
+
+// ImportRoutes は/api/catalog/importのルーティングの定義を返す
+func ImportRoutes(svc *service.IndexService) router.Resource {
+	return router.Resource{
+		Name:   "catalog/import",
+		Create: NewImportHandler(svc),
+	}
+}
@@ -0,0 +1,476 @@
+package catalog
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/content_filter"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/stretchr/testify/assert"
+)
+
+// findEntry は作品名と名前が一致するentryを返す
+func findEntry(t *testing.T, doc *Document, source, name string) *Entry {
+	t.Helper()
+	for i := range doc.Entries {
+		if doc.Entries[i].Source == source && doc.Entries[i].Name == name {
+			return &doc.Entries[i]
+		}
+	}
+	t.Fatalf("entry %s/%s not found", source, name)
+	return nil
+}
+
+func TestExportHandler(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+	// ロールバック
+	defer tx.RollbackCtx(ctx)
+	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	takaneHeight := int64(169)
+	// データベースの準備
+	f := &fixtures.Fixture{DBv1: tx}
+	f.Build(t,
+		fixtures.NewHairColorType(ctx, func(s *fixtures.HairColorType) {
+			s.Color = "銀"
+		}),
+		fixtures.NewTag(ctx, func(s *fixtures.Tag) {
+			s.Name = "お姫様"
+		}),
+		fixtures.NewHekiRadarAxis(ctx, func(s *fixtures.HekiRadarAxis) {
+			s.Name = "ai"
+			s.DisplayOrder = 1
+		}),
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "アイドルマスター"
+			s.Url = "https://example.com/imas"
+			s.Type = "game"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "四条貴音"
+			s.Image = "https://example.com/takane.png"
+			s.Content = "お姫ちん"
+			s.CreatedAt = fixedTime
+		}).Connect(
+			fixtures.NewBWH(ctx, func(s *fixtures.BWH) {
+				s.Bust = 90
+				s.Waist = 60
+				s.Hip = 92
+				s.Height = &takaneHeight
+			}),
+			fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+				s.AxisID = f.HekiRadarAxes[0].ID
+				s.Score = 70
+			}),
+			fixtures.NewHairColor(ctx, func(s *fixtures.HairColor) {
+				s.ColorID = f.HairColorTypes[0].ID
+			}),
+			fixtures.NewEntryTag(ctx, func(s *fixtures.EntryTag) {
+				s.TagID = f.Tags[0].ID
+			}),
+			fixtures.NewLink(ctx, func(s *fixtures.Link) {
+				s.Type = "blog"
+				s.URL = "https://example.com/takane"
+			}),
+			fixtures.NewLink(ctx, func(s *fixtures.Link) {
+				s.Type = "funart"
+				s.URL = "https://example.com/takane-nsfw"
+				s.Nsfw = true
+			}),
+			fixtures.NewEntryImage(ctx, func(s *fixtures.EntryImage) {
+				s.URL = "https://example.com/takane-swimsuit.png"
+				s.Caption = "水着"
+				s.Position = 1
+				s.Nsfw = true
+			}),
+			fixtures.NewEntryImage(ctx, func(s *fixtures.EntryImage) {
+				s.URL = "https://example.com/takane.png"
+				s.Caption = "公式"
+				s.Primary = true
+			}),
+		)),
+	)
+
+	var indexService = service.NewIndexService(
+		tx,
+		cookie.Store,
+		env,
+	)
+
+	t.Run("全てのデータを書き出す", func(t *testing.T) {
+		h := NewExportHandler(indexService)
+		req := httptest.NewRequest(http.MethodGet, "/api/catalog/export", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+
+		var doc Document
+		err := json.Unmarshal(w.Body.Bytes(), &doc)
+		assert.NoError(t, err)
+		assert.Equal(t, Version, doc.Version)
+		assert.Contains(t, doc.Sources, Source{Name: "アイドルマスター", URL: "https://example.com/imas", Type: "game"})
+		assert.Contains(t, doc.Types["haircolor"], "銀")
+		assert.Contains(t, doc.Types["tag"], "お姫様")
+		assert.Contains(t, doc.RadarAxes, RadarAxis{Name: "ai", Min: 0, Max: 100, DisplayOrder: 1})
+
+		e := findEntry(t, &doc, "アイドルマスター", "四条貴音")
+		assert.Equal(t, "https://example.com/takane.png", e.Image)
+		assert.Equal(t, "お姫ちん", e.Content)
+		assert.Equal(t, []string{"銀"}, e.Attributes["haircolor"])
+		assert.Equal(t, []string{"お姫様"}, e.Attributes["tag"])
+		assert.Equal(t, int64(90), *e.BWH.Bust)
+		assert.Equal(t, int64(169), *e.BWH.Height)
+		assert.Nil(t, e.BWH.Weight)
+		assert.Equal(t, map[string]int64{"ai": 70}, e.Radar)
+		assert.Equal(t, []Link{
+			{Type: "blog", URL: "https://example.com/takane"},
+			{Type: "funart", URL: "https://example.com/takane-nsfw", Nsfw: true},
+		}, e.Links)
+		// 画像は表示順に並べる
+		assert.Len(t, e.Images, 2)
+		assert.Equal(t, "https://example.com/takane.png", e.Images[0].URL)
+		assert.Equal(t, true, e.Images[0].Primary)
+		assert.Equal(t, "https://example.com/takane-swimsuit.png", e.Images[1].URL)
+		assert.Equal(t, &Withheld{}, doc.Withheld)
+	})
+
+	t.Run("nsfwのリンクと画像を除いて書き出す", func(t *testing.T) {
+		h := NewExportHandler(indexService)
+		req := httptest.NewRequest(http.MethodGet, "/api/catalog/export?"+contentfilter.HideNsfwParam+"=true", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var doc Document
+		err := json.Unmarshal(w.Body.Bytes(), &doc)
+		assert.NoError(t, err)
+		e := findEntry(t, &doc, "アイドルマスター", "四条貴音")
+		assert.Equal(t, []Link{{Type: "blog", URL: "https://example.com/takane"}}, e.Links)
+		assert.Len(t, e.Images, 1)
+		assert.Equal(t, &Withheld{Links: 1, Images: 1}, doc.Withheld)
+	})
+
+	t.Run("CSVをzipで書き出す", func(t *testing.T) {
+		h := NewExportHandler(indexService)
+		req := httptest.NewRequest(http.MethodGet, "/api/catalog/export?format=csv", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+
+		zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		assert.NoError(t, err)
+		files, err := ReadCSV(zr)
+		assert.NoError(t, err)
+		doc, err := DecodeCSV(files)
+		assert.NoError(t, err)
+		e := findEntry(t, doc, "アイドルマスター", "四条貴音")
+		assert.Equal(t, []string{"お姫様"}, e.Attributes["tag"])
+		assert.Len(t, e.Links, 2)
+	})
+
+	t.Run("形式が正しくない", func(t *testing.T) {
+		h := NewExportHandler(indexService)
+		req := httptest.NewRequest(http.MethodGet, "/api/catalog/export?format=xml", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("書き出した文書を読み込んでも変わらない", func(t *testing.T) {
+		doc, err := Export(ctx, tx, contentfilter.Policy{})
+		assert.NoError(t, err)
+		report, err := Import(ctx, tx, doc, Options{})
+		assert.NoError(t, err)
+		assert.Empty(t, report.Created)
+		assert.Empty(t, report.Updated)
+		assert.Empty(t, report.Conflicts)
+		assert.Equal(t, len(doc.Entries), report.Unchanged["entry"])
+	})
+}
+
+func TestImportHandler(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+	// ロールバック
+	defer tx.RollbackCtx(ctx)
+	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	// データベースの準備
+	f := &fixtures.Fixture{DBv1: tx}
+	f.Build(t,
+		fixtures.NewHekiRadarAxis(ctx, func(s *fixtures.HekiRadarAxis) {
+			s.Name = "ai"
+		}),
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "閃乱カグラ"
+			s.Url = "https://example.com/kagura"
+			s.Type = "anime"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "雪泉"
+			s.Image = "https://example.com/yumi.png"
+			s.Content = "かわいい"
+			s.CreatedAt = fixedTime
+		}).Connect(
+			fixtures.NewLink(ctx, func(s *fixtures.Link) {
+				s.Type = "blog"
+				s.URL = "https://example.com/yumi"
+			}),
+		)),
+	)
+
+	var indexService = service.NewIndexService(
+		tx,
+		cookie.Store,
+		env,
+	)
+
+	// importDoc は既存のentryの値を変え、新しいentryを追加した文書を返す
+	importDoc := func() *Document {
+		return &Document{
+			Version: Version,
+			Sources: []Source{
+				{Name: "閃乱カグラ", URL: "https://example.com/kagura", Type: "anime"},
+				{Name: "アイドルマスター", URL: "https://example.com/imas", Type: "game"},
+			},
+			Types: map[string][]string{"haircolor": {"銀"}},
+			RadarAxes: []RadarAxis{
+				{Name: "ai", Min: 0, Max: 100},
+				{Name: "nu", Min: 0, Max: 10, DisplayOrder: 2},
+			},
+			Entries: []Entry{
+				{
+					Source:  "閃乱カグラ",
+					Name:    "雪泉",
+					Image:   "https://example.com/yumi.png",
+					Content: "とてもかわいい",
+					Links: []Link{
+						{Type: "blog", URL: "https://example.com/yumi"},
+						{Type: "twitter", URL: "https://example.com/yumi-twitter"},
+					},
+				},
+				{
+					Source:  "アイドルマスター",
+					Name:    "四条貴音",
+					Image:   "https://example.com/takane.png",
+					Content: "お姫ちん",
+					Attributes: map[string][]string{
+						"haircolor": {"銀"},
+						"tag":       {"お姫様"},
+					},
+					Radar: map[string]int64{"ai": 70, "nu": 8},
+					Images: []Image{
+						{URL: "https://example.com/takane-official.png", Primary: true},
+					},
+				},
+			},
+		}
+	}
+
+	// post は文書を読み込むリクエストを送る
+	post := func(t *testing.T, doc *Document, query string) *httptest.ResponseRecorder {
+		t.Helper()
+		h := NewImportHandler(indexService)
+		body, err := json.Marshal(doc)
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/api/catalog/import"+query, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	// count はテーブルの行数を返す
+	count := func(t *testing.T, table string) int64 {
+		t.Helper()
+		var n int64
+		err := tx.GetContext(ctx, &n, "SELECT COUNT(*) FROM "+table)
+		assert.NoError(t, err)
+		return n
+	}
+
+	t.Run("衝突がある場合は何も書き込まない", func(t *testing.T) {
+		w := post(t, importDoc(), "")
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		var p problem.Problem
+		err := json.Unmarshal(w.Body.Bytes(), &p)
+		assert.NoError(t, err)
+		assert.Equal(t, problem.CodeConflict, p.Code)
+		assert.Equal(t, []problem.FieldError{
+			{Field: "entries[0]", Message: "閃乱カグラ/雪泉: content: \"かわいい\" -> \"とてもかわいい\""},
+		}, p.Errors)
+
+		assert.Equal(t, int64(1), count(t, "source"))
+		assert.Equal(t, int64(1), count(t, "entry"))
+		assert.Equal(t, int64(1), count(t, "link"))
+	})
+
+	t.Run("dry_runでは結果だけを返す", func(t *testing.T) {
+		w := post(t, importDoc(), "?on_conflict=skip&dry_run=true")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var report Report
+		err := json.Unmarshal(w.Body.Bytes(), &report)
+		assert.NoError(t, err)
+		assert.Equal(t, true, report.DryRun)
+		assert.Equal(t, 1, report.Created["entry"])
+		assert.Equal(t, 1, report.Skipped["entry"])
+
+		assert.Equal(t, int64(1), count(t, "entry"))
+		assert.Equal(t, int64(1), count(t, "link"))
+	})
+
+	t.Run("on_conflictが正しくない", func(t *testing.T) {
+		w := post(t, importDoc(), "?on_conflict=overwrite")
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("存在しない作品を参照する", func(t *testing.T) {
+		doc := importDoc()
+		doc.Sources = doc.Sources[:1]
+		w := post(t, doc, "?on_conflict=skip")
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+		var p problem.Problem
+		err := json.Unmarshal(w.Body.Bytes(), &p)
+		assert.NoError(t, err)
+		assert.Equal(t, []problem.FieldError{
+			{Field: "entries[1].source", Message: "unknown source \"アイドルマスター\""},
+		}, p.Errors)
+		assert.Equal(t, int64(1), count(t, "entry"))
+	})
+
+	t.Run("軸の範囲外の値", func(t *testing.T) {
+		doc := importDoc()
+		doc.Entries[1].Radar["nu"] = 80
+		_, err := Import(ctx, tx, doc, Options{OnConflict: OnConflictSkip})
+
+		var verrs validation.Errors
+		assert.True(t, errors.As(err, &verrs))
+		assert.EqualError(t, verrs["entries[1].radar.nu"], "must be between 0 and 10")
+	})
+
+	t.Run("衝突を残して読み込む", func(t *testing.T) {
+		w := post(t, importDoc(), "?on_conflict=skip")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var report Report
+		err := json.Unmarshal(w.Body.Bytes(), &report)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int{
+			"source":           1,
+			"haircolor_type":   1,
+			"tag":              1,
+			"heki_radar_axis":  1,
+			"entry":            1,
+			"haircolor":        1,
+			"entry_tag":        1,
+			"heki_radar_score": 2,
+			"link":             1,
+			"entry_image":      1,
+		}, report.Created)
+		assert.Equal(t, 1, report.Skipped["entry"])
+		assert.Len(t, report.Conflicts, 1)
+
+		// 既存のentryの値は変えない
+		var content string
+		err = tx.GetContext(ctx, &content, "SELECT content FROM entry WHERE name = '雪泉'")
+		assert.NoError(t, err)
+		assert.Equal(t, "かわいい", content)
+
+		// 代表の画像のURLをentry.imageにも保存する
+		var image string
+		err = tx.GetContext(ctx, &image, "SELECT image FROM entry WHERE name = '四条貴音'")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/takane-official.png", image)
+
+		// 2回目は全て作成済み
+		report2, err := Import(ctx, tx, importDoc(), Options{OnConflict: OnConflictSkip})
+		assert.NoError(t, err)
+		assert.Empty(t, report2.Created)
+	})
+
+	t.Run("衝突を上書きする", func(t *testing.T) {
+		w := post(t, importDoc(), "?on_conflict=update")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var report Report
+		err := json.Unmarshal(w.Body.Bytes(), &report)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int{"entry": 1}, report.Updated)
+		assert.Empty(t, report.Conflicts)
+
+		var content string
+		err = tx.GetContext(ctx, &content, "SELECT content FROM entry WHERE name = '雪泉'")
+		assert.NoError(t, err)
+		assert.Equal(t, "とてもかわいい", content)
+	})
+
+	t.Run("CSVをzipで読み込む", func(t *testing.T) {
+		doc := importDoc()
+		doc.Entries[0].Content = "とてもかわいい"
+		doc.Entries = append(doc.Entries, Entry{
+			Source:  "閃乱カグラ",
+			Name:    "飛鳥",
+			Image:   "https://example.com/asuka.png",
+			Content: "忍",
+		})
+		var buf bytes.Buffer
+		assert.NoError(t, WriteZip(&buf, EncodeCSV(doc)))
+
+		h := NewImportHandler(indexService)
+		req := httptest.NewRequest(http.MethodPost, "/api/catalog/import", &buf)
+		req.Header.Set("Content-Type", "application/zip")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var report Report
+		err := json.Unmarshal(w.Body.Bytes(), &report)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int{"entry": 1}, report.Created)
+		assert.Empty(t, report.Conflicts)
+	})
+}
@@ -0,0 +1,474 @@
+package catalog
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/entry_filter"
+)
+
+// CSVでは文書をテーブルごとのファイルに分ける
+// entryに紐づく値は作品名 (source) と名前 (entry) の列でentryを参照する
+// nullの値は空文字で表すため、空文字とnullは区別しない
+const (
+	metaCSV            = "meta.csv"
+	sourcesCSV         = "sources.csv"
+	typesCSV           = "types.csv"
+	radarAxesCSV       = "radar_axes.csv"
+	entriesCSV         = "entries.csv"
+	entryAttributesCSV = "entry_attributes.csv"
+	bwhCSV             = "bwh.csv"
+	radarScoresCSV     = "radar_scores.csv"
+	linksCSV           = "links.csv"
+	entryImagesCSV     = "entry_images.csv"
+)
+
+var (
+	metaHeader            = []string{"version", "exported_at", "withheld_links", "withheld_images"}
+	sourcesHeader         = []string{"name", "url", "type"}
+	typesHeader           = []string{"attribute", "name"}
+	radarAxesHeader       = []string{"name", "min", "max", "display_order"}
+	entriesHeader         = []string{"source", "name", "image", "content", "created_at"}
+	entryAttributesHeader = []string{"source", "entry", "attribute", "name"}
+	bwhHeader             = []string{"source", "entry", "bust", "waist", "hip", "underbust", "height", "weight"}
+	radarScoresHeader     = []string{"source", "entry", "axis", "score"}
+	linksHeader           = []string{"source", "entry", "type", "url", "nsfw", "darkness"}
+	entryImagesHeader     = []string{"source", "entry", "url", "thumbnail_url", "webp_url", "thumbnail_webp_url", "width", "height", "caption", "position", "is_primary", "nsfw", "darkness"}
+)
+
+// CSVFile はCSVの1ファイル分
+// Recordsの先頭は列名
+type CSVFile struct {
+	Name    string
+	Records [][]string
+}
+
+// Write はCSVとして書き込む
+func (f *CSVFile) Write(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(f.Records); err != nil {
+		return fmt.Errorf("%s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// EncodeCSV は文書をCSVのファイルに変換する
+func EncodeCSV(doc *Document) []CSVFile {
+	meta := CSVFile{Name: metaCSV, Records: [][]string{metaHeader}}
+	var withheld Withheld
+	if doc.Withheld != nil {
+		withheld = *doc.Withheld
+	}
+	meta.Records = append(meta.Records, []string{
+		strconv.Itoa(doc.Version),
+		formatTime(&doc.ExportedAt),
+		strconv.FormatInt(withheld.Links, 10),
+		strconv.FormatInt(withheld.Images, 10),
+	})
+
+	sources := CSVFile{Name: sourcesCSV, Records: [][]string{sourcesHeader}}
+	for _, s := range doc.Sources {
+		sources.Records = append(sources.Records, []string{s.Name, s.URL, s.Type})
+	}
+
+	types := CSVFile{Name: typesCSV, Records: [][]string{typesHeader}}
+	for _, a := range entryfilter.Attributes {
+		for _, name := range doc.Types[a.Name] {
+			types.Records = append(types.Records, []string{a.Name, name})
+		}
+	}
+
+	axes := CSVFile{Name: radarAxesCSV, Records: [][]string{radarAxesHeader}}
+	for _, a := range doc.RadarAxes {
+		axes.Records = append(axes.Records, []string{a.Name, formatInt(a.Min), formatInt(a.Max), formatInt(a.DisplayOrder)})
+	}
+
+	entries := CSVFile{Name: entriesCSV, Records: [][]string{entriesHeader}}
+	attributes := CSVFile{Name: entryAttributesCSV, Records: [][]string{entryAttributesHeader}}
+	bwh := CSVFile{Name: bwhCSV, Records: [][]string{bwhHeader}}
+	scores := CSVFile{Name: radarScoresCSV, Records: [][]string{radarScoresHeader}}
+	links := CSVFile{Name: linksCSV, Records: [][]string{linksHeader}}
+	images := CSVFile{Name: entryImagesCSV, Records: [][]string{entryImagesHeader}}
+	for _, e := range doc.Entries {
+		entries.Records = append(entries.Records, []string{e.Source, e.Name, e.Image, e.Content, formatTime(e.CreatedAt)})
+		for _, a := range entryfilter.Attributes {
+			for _, name := range e.Attributes[a.Name] {
+				attributes.Records = append(attributes.Records, []string{e.Source, e.Name, a.Name, name})
+			}
+		}
+		if b := e.BWH; b != nil {
+			bwh.Records = append(bwh.Records, []string{
+				e.Source,
+				e.Name,
+				formatOptInt(b.Bust),
+				formatOptInt(b.Waist),
+				formatOptInt(b.Hip),
+				formatOptInt(b.Underbust),
+				formatOptInt(b.Height),
+				formatOptInt(b.Weight),
+			})
+		}
+		axisNames := make([]string, 0, len(e.Radar))
+		for name := range e.Radar {
+			axisNames = append(axisNames, name)
+		}
+		sort.Strings(axisNames)
+		for _, name := range axisNames {
+			scores.Records = append(scores.Records, []string{e.Source, e.Name, name, formatInt(e.Radar[name])})
+		}
+		for _, l := range e.Links {
+			links.Records = append(links.Records, []string{e.Source, e.Name, l.Type, l.URL, formatBool(l.Nsfw), formatBool(l.Darkness)})
+		}
+		for _, img := range e.Images {
+			images.Records = append(images.Records, []string{
+				e.Source,
+				e.Name,
+				img.URL,
+				formatOptString(img.ThumbnailURL),
+				formatOptString(img.WebPURL),
+				formatOptString(img.ThumbnailWebPURL),
+				formatOptInt(img.Width),
+				formatOptInt(img.Height),
+				img.Caption,
+				formatInt(img.Position),
+				formatBool(img.Primary),
+				formatBool(img.Nsfw),
+				formatBool(img.Darkness),
+			})
+		}
+	}
+	return []CSVFile{meta, sources, types, axes, entries, attributes, bwh, scores, links, images}
+}
+
+// DecodeCSV はファイル名ごとのCSVを文書に変換する
+// meta.csv以外のファイルはなくてもよい
+func DecodeCSV(files map[string][][]string) (*Document, error) {
+	doc := &Document{
+		Sources:   []Source{},
+		Types:     map[string][]string{},
+		RadarAxes: []RadarAxis{},
+		Entries:   []Entry{},
+	}
+	if _, ok := files[metaCSV]; !ok {
+		return nil, fmt.Errorf("%s is required", metaCSV)
+	}
+	metaRows := 0
+	err := eachRow(files, metaCSV, metaHeader, func(r *csvRow) {
+		metaRows++
+		doc.Version = int(r.int("version"))
+		if t := r.time("exported_at"); t != nil {
+			doc.ExportedAt = *t
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if metaRows != 1 {
+		return nil, fmt.Errorf("%s must have exactly one row", metaCSV)
+	}
+
+	err = eachRow(files, sourcesCSV, sourcesHeader, func(r *csvRow) {
+		doc.Sources = append(doc.Sources, Source{Name: r.str("name"), URL: r.str("url"), Type: r.str("type")})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = eachRow(files, typesCSV, typesHeader, func(r *csvRow) {
+		a := r.str("attribute")
+		doc.Types[a] = append(doc.Types[a], r.str("name"))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = eachRow(files, radarAxesCSV, radarAxesHeader, func(r *csvRow) {
+		doc.RadarAxes = append(doc.RadarAxes, RadarAxis{
+			Name:         r.str("name"),
+			Min:          r.int("min"),
+			Max:          r.int("max"),
+			DisplayOrder: r.int("display_order"),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := map[entryKey]int{}
+	err = eachRow(files, entriesCSV, entriesHeader, func(r *csvRow) {
+		e := Entry{
+			Source:     r.str("source"),
+			Name:       r.str("name"),
+			Image:      r.str("image"),
+			Content:    r.str("content"),
+			CreatedAt:  r.time("created_at"),
+			Attributes: map[string][]string{},
+			Radar:      map[string]int64{},
+			Links:      []Link{},
+			Images:     []Image{},
+		}
+		// 重複はDocument.Validateで確認するため、最初のentryに紐づける
+		if _, ok := byKey[e.key()]; !ok {
+			byKey[e.key()] = len(doc.Entries)
+		}
+		doc.Entries = append(doc.Entries, e)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// entry はsourceとentryの列で参照するentryを返す
+	entry := func(r *csvRow) *Entry {
+		k := entryKey{source: r.str("source"), name: r.str("entry")}
+		i, ok := byKey[k]
+		if !ok {
+			r.fail("entry", fmt.Errorf("unknown entry %q", k))
+			return &Entry{Attributes: map[string][]string{}, Radar: map[string]int64{}}
+		}
+		return &doc.Entries[i]
+	}
+
+	err = eachRow(files, entryAttributesCSV, entryAttributesHeader, func(r *csvRow) {
+		e := entry(r)
+		a := r.str("attribute")
+		e.Attributes[a] = append(e.Attributes[a], r.str("name"))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = eachRow(files, bwhCSV, bwhHeader, func(r *csvRow) {
+		e := entry(r)
+		if e.BWH != nil {
+			r.fail("entry", errors.New("duplicate bwh"))
+			return
+		}
+		e.BWH = &BWH{
+			Bust:      r.optInt("bust"),
+			Waist:     r.optInt("waist"),
+			Hip:       r.optInt("hip"),
+			Underbust: r.optInt("underbust"),
+			Height:    r.optInt("height"),
+			Weight:    r.optInt("weight"),
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = eachRow(files, radarScoresCSV, radarScoresHeader, func(r *csvRow) {
+		e := entry(r)
+		axis := r.str("axis")
+		if _, ok := e.Radar[axis]; ok {
+			r.fail("axis", fmt.Errorf("duplicate score for %q", axis))
+			return
+		}
+		e.Radar[axis] = r.int("score")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = eachRow(files, linksCSV, linksHeader, func(r *csvRow) {
+		e := entry(r)
+		e.Links = append(e.Links, Link{
+			Type:     r.str("type"),
+			URL:      r.str("url"),
+			Nsfw:     r.bool("nsfw"),
+			Darkness: r.bool("darkness"),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = eachRow(files, entryImagesCSV, entryImagesHeader, func(r *csvRow) {
+		e := entry(r)
+		e.Images = append(e.Images, Image{
+			URL:              r.str("url"),
+			ThumbnailURL:     r.optStr("thumbnail_url"),
+			WebPURL:          r.optStr("webp_url"),
+			ThumbnailWebPURL: r.optStr("thumbnail_webp_url"),
+			Width:            r.optInt("width"),
+			Height:           r.optInt("height"),
+			Caption:          r.str("caption"),
+			Position:         r.int("position"),
+			Primary:          r.bool("is_primary"),
+			Nsfw:             r.bool("nsfw"),
+			Darkness:         r.bool("darkness"),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// eachRow はファイルの列名を確認し、2行目以降を1行ずつfnに渡す
+// ファイルがない場合は何もしない
+// fnで値が読み込めなかった場合はファイル名と行番号を付けたエラーを返す
+func eachRow(files map[string][][]string, name string, header []string, fn func(r *csvRow)) error {
+	records, ok := files[name]
+	if !ok || len(records) == 0 {
+		return nil
+	}
+	index := make(map[string]int, len(records[0]))
+	for i, column := range records[0] {
+		index[column] = i
+	}
+	for _, column := range header {
+		if _, ok := index[column]; !ok {
+			return fmt.Errorf("%s: missing column %q", name, column)
+		}
+	}
+	for i, record := range records[1:] {
+		r := &csvRow{index: index, record: record}
+		fn(r)
+		if r.err != nil {
+			// 1行目は列名のため、データの行番号は2から始まる
+			return fmt.Errorf("%s line %d: %w", name, i+2, r.err)
+		}
+	}
+	return nil
+}
+
+// csvRow はCSVの1行
+// 最初に読み込めなかった列のエラーを保持する
+type csvRow struct {
+	index  map[string]int
+	record []string
+	err    error
+}
+
+func (r *csvRow) fail(column string, err error) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%s: %w", column, err)
+	}
+}
+
+func (r *csvRow) str(column string) string {
+	i := r.index[column]
+	if i >= len(r.record) {
+		return ""
+	}
+	return r.record[i]
+}
+
+func (r *csvRow) optStr(column string) *string {
+	s := r.str(column)
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func (r *csvRow) int(column string) int64 {
+	n, err := strconv.ParseInt(r.str(column), 10, 64)
+	if err != nil {
+		r.fail(column, err)
+	}
+	return n
+}
+
+func (r *csvRow) optInt(column string) *int64 {
+	if r.str(column) == "" {
+		return nil
+	}
+	n := r.int(column)
+	return &n
+}
+
+func (r *csvRow) bool(column string) bool {
+	b, err := strconv.ParseBool(r.str(column))
+	if err != nil {
+		r.fail(column, err)
+	}
+	return b
+}
+
+func (r *csvRow) time(column string) *time.Time {
+	s := r.str(column)
+	if s == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		r.fail(column, err)
+		return nil
+	}
+	return &t
+}
+
+func formatInt(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+func formatOptInt(n *int64) string {
+	if n == nil {
+		return ""
+	}
+	return formatInt(*n)
+}
+
+func formatOptString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func formatBool(b bool) string {
+	return strconv.FormatBool(b)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// WriteZip はCSVのファイルを1つのzipにまとめて書き込む
+func WriteZip(w io.Writer, files []CSVFile) error {
+	zw := zip.NewWriter(w)
+	for i := range files {
+		fw, err := zw.Create(files[i].Name)
+		if err != nil {
+			return err
+		}
+		if err := files[i].Write(fw); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// ReadCSV はfsysの直下にある.csvのファイルを全て読み込む
+// ディレクトリはos.DirFS、zipはzip.Readerで渡す
+func ReadCSV(fsys fs.FS) (map[string][][]string, error) {
+	names, err := fs.Glob(fsys, "*.csv")
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][][]string, len(names))
+	for _, name := range names {
+		f, err := fsys.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		records, err := csv.NewReader(f).ReadAll()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		files[path.Base(name)] = records
+	}
+	return files, nil
+}
@@ -0,0 +1,187 @@
+package catalog
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testDocument() *Document {
+	createdAt := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	height := int64(169)
+	bust := int64(90)
+	thumbnail := "https://example.com/takane-320.png"
+	width := int64(2048)
+	return &Document{
+		Version:    Version,
+		ExportedAt: time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC),
+		Sources: []Source{
+			{Name: "アイドルマスター", URL: "https://example.com/imas", Type: "game"},
+		},
+		Types: map[string][]string{
+			"haircolor": {"銀"},
+			"tag":       {"お姫様", "らぁめん"},
+		},
+		RadarAxes: []RadarAxis{
+			{Name: "ai", Min: 0, Max: 100, DisplayOrder: 1},
+		},
+		Entries: []Entry{
+			{
+				Source:    "アイドルマスター",
+				Name:      "四条貴音",
+				Image:     "https://example.com/takane.png",
+				Content:   "お姫ちん, \"とっぷしーくれっと\"",
+				CreatedAt: &createdAt,
+				Attributes: map[string][]string{
+					"haircolor": {"銀"},
+					"tag":       {"お姫様", "らぁめん"},
+				},
+				BWH:   &BWH{Bust: &bust, Height: &height},
+				Radar: map[string]int64{"ai": 70},
+				Links: []Link{
+					{Type: "blog", URL: "https://example.com/takane"},
+					{Type: "funart", URL: "https://example.com/takane-nsfw", Nsfw: true},
+				},
+				Images: []Image{
+					{
+						URL:          "https://example.com/takane.png",
+						ThumbnailURL: &thumbnail,
+						Width:        &width,
+						Caption:      "公式",
+						Primary:      true,
+					},
+				},
+			},
+			{
+				Source:     "アイドルマスター",
+				Name:       "我那覇響",
+				Image:      "https://example.com/hibiki.png",
+				Content:    "なんくるないさー",
+				Attributes: map[string][]string{},
+				Radar:      map[string]int64{},
+				Links:      []Link{},
+				Images:     []Image{},
+			},
+		},
+	}
+}
+
+// csvFiles はEncodeCSVの結果をDecodeCSVの引数に変換する
+func csvFiles(files []CSVFile) map[string][][]string {
+	m := make(map[string][][]string, len(files))
+	for _, f := range files {
+		m[f.Name] = f.Records
+	}
+	return m
+}
+
+func TestCSV(t *testing.T) {
+	t.Run("書き出した文書を読み込める", func(t *testing.T) {
+		doc := testDocument()
+		decoded, err := DecodeCSV(csvFiles(EncodeCSV(doc)))
+		assert.NoError(t, err)
+		assert.Equal(t, doc, decoded)
+		assert.NoError(t, decoded.Validate())
+	})
+
+	t.Run("zipで読み書きできる", func(t *testing.T) {
+		doc := testDocument()
+		var buf bytes.Buffer
+		assert.NoError(t, WriteZip(&buf, EncodeCSV(doc)))
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		assert.NoError(t, err)
+		files, err := ReadCSV(zr)
+		assert.NoError(t, err)
+		decoded, err := DecodeCSV(files)
+		assert.NoError(t, err)
+		assert.Equal(t, doc, decoded)
+	})
+
+	t.Run("書き出しで除いた数はmetaに含める", func(t *testing.T) {
+		doc := testDocument()
+		doc.Withheld = &Withheld{Links: 1, Images: 2}
+		files := csvFiles(EncodeCSV(doc))
+		assert.Equal(t, [][]string{
+			{"version", "exported_at", "withheld_links", "withheld_images"},
+			{"1", "2024-01-02T03:04:05Z", "1", "2"},
+		}, files[metaCSV])
+	})
+
+	t.Run("列の順序が異なっても読み込める", func(t *testing.T) {
+		files := csvFiles(EncodeCSV(testDocument()))
+		files[sourcesCSV] = [][]string{
+			{"type", "name", "url"},
+			{"game", "アイドルマスター", "https://example.com/imas"},
+		}
+		decoded, err := DecodeCSV(files)
+		assert.NoError(t, err)
+		assert.Equal(t, testDocument().Sources, decoded.Sources)
+	})
+
+	t.Run("metaがない場合はエラー", func(t *testing.T) {
+		files := csvFiles(EncodeCSV(testDocument()))
+		delete(files, metaCSV)
+		_, err := DecodeCSV(files)
+		assert.EqualError(t, err, "meta.csv is required")
+	})
+
+	t.Run("列が足りない場合はエラー", func(t *testing.T) {
+		files := csvFiles(EncodeCSV(testDocument()))
+		files[linksCSV] = [][]string{{"source", "entry", "type", "url"}}
+		_, err := DecodeCSV(files)
+		assert.EqualError(t, err, `links.csv: missing column "nsfw"`)
+	})
+
+	t.Run("存在しないentryを参照した場合はエラー", func(t *testing.T) {
+		files := csvFiles(EncodeCSV(testDocument()))
+		files[linksCSV] = append(files[linksCSV], []string{"アイドルマスター", "天海春香", "blog", "https://example.com/haruka", "false", "false"})
+		_, err := DecodeCSV(files)
+		assert.EqualError(t, err, `links.csv line 4: entry: unknown entry "アイドルマスター/天海春香"`)
+	})
+
+	t.Run("値が読み込めない場合は行番号を返す", func(t *testing.T) {
+		files := csvFiles(EncodeCSV(testDocument()))
+		files[radarScoresCSV][1][3] = "たくさん"
+		_, err := DecodeCSV(files)
+		assert.ErrorContains(t, err, "radar_scores.csv line 2: score: ")
+	})
+}
+
+func TestDocumentValidate(t *testing.T) {
+	t.Run("正しい文書", func(t *testing.T) {
+		assert.NoError(t, testDocument().Validate())
+	})
+
+	t.Run("対応していないバージョン", func(t *testing.T) {
+		doc := testDocument()
+		doc.Version = 2
+		assert.EqualError(t, doc.Validate(), "version: unsupported version 2 (supported: 1).")
+	})
+
+	t.Run("自然キーの重複", func(t *testing.T) {
+		doc := testDocument()
+		doc.Entries[1].Name = doc.Entries[0].Name
+		assert.EqualError(t, doc.Validate(), `entries: (1: duplicate "アイドルマスター/四条貴音".).`)
+	})
+
+	t.Run("tag以外の属性は1つまで", func(t *testing.T) {
+		doc := testDocument()
+		doc.Entries[0].Attributes["haircolor"] = []string{"銀", "黒"}
+		assert.EqualError(t, doc.Validate(), "entries: (0: (attributes: (haircolor: must have at most one value.).).).")
+	})
+
+	t.Run("存在しない属性", func(t *testing.T) {
+		doc := testDocument()
+		doc.Types["bloodtype"] = []string{"A"}
+		assert.EqualError(t, doc.Validate(), "types: (bloodtype: unknown attribute.).")
+	})
+
+	t.Run("代表の画像は1枚まで", func(t *testing.T) {
+		doc := testDocument()
+		doc.Entries[0].Images = append(doc.Entries[0].Images, Image{URL: "https://example.com/takane-2.png", Primary: true})
+		assert.EqualError(t, doc.Validate(), "entries: (0: (images: only one image can be primary.).).")
+	})
+}
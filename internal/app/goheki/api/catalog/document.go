@@ -0,0 +1,260 @@
+// Package catalog は全てのデータを1つの文書として書き出し、読み込む
+// 外部キーは連番のidではなく、作品名、タグ名、種類の名前などの自然キーで表す
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/entry_filter"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// Version は文書の形式のバージョン
+// 互換性のない変更をした場合に上げる
+const Version = 1
+
+// Document はカタログ全体
+type Document struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Sources    []Source  `json:"sources"`
+	// 属性の名前 (haircolor, tagなど) ごとの種類の名前
+	Types     map[string][]string `json:"types"`
+	RadarAxes []RadarAxis         `json:"radar_axes"`
+	Entries   []Entry             `json:"entries"`
+	// 書き出しで設定に応じて除いた数 (読み込みでは無視する)
+	Withheld *Withheld `json:"withheld,omitempty"`
+}
+
+type Source struct {
+	Name string `db:"name" json:"name"`
+	URL  string `db:"url" json:"url"`
+	Type string `db:"type" json:"type"`
+}
+
+func (s *Source) Validate() error {
+	return validation.ValidateStruct(s,
+		validation.Field(&s.Name, validation.Required),
+		validation.Field(&s.URL, validation.Required),
+		validation.Field(&s.Type, validation.Required),
+	)
+}
+
+type RadarAxis struct {
+	Name         string `db:"name" json:"name"`
+	Min          int64  `db:"min" json:"min"`
+	Max          int64  `db:"max" json:"max"`
+	DisplayOrder int64  `db:"display_order" json:"display_order"`
+}
+
+func (a *RadarAxis) Validate() error {
+	return validation.ValidateStruct(a,
+		validation.Field(&a.Name, validation.Required),
+		validation.Field(&a.Max, validation.By(func(interface{}) error {
+			if a.Max <= a.Min {
+				return fmt.Errorf("must be greater than min (%d)", a.Min)
+			}
+			return nil
+		})),
+	)
+}
+
+// Entry は1人分のentryと、entryに紐づく全ての値
+// 作品名と名前の組でentryを特定する
+type Entry struct {
+	Source    string     `json:"source"`
+	Name      string     `json:"name"`
+	Image     string     `json:"image"`
+	Content   string     `json:"content"`
+	CreatedAt *time.Time `json:"created_at"`
+	// 属性の名前ごとの種類の名前 (tag以外は1つまで)
+	Attributes map[string][]string `json:"attributes"`
+	BWH        *BWH                `json:"bwh"`
+	// 軸の名前ごとの値
+	Radar  map[string]int64 `json:"radar"`
+	Links  []Link           `json:"links"`
+	Images []Image          `json:"images"`
+}
+
+func (e *Entry) Validate() error {
+	return validation.ValidateStruct(e,
+		validation.Field(&e.Source, validation.Required),
+		validation.Field(&e.Name, validation.Required),
+		validation.Field(&e.Image, validation.Required),
+		validation.Field(&e.Content, validation.Required),
+		validation.Field(&e.Attributes, validation.By(func(interface{}) error {
+			return validateAttributes(e.Attributes, true)
+		})),
+		validation.Field(&e.Links, validation.By(func(interface{}) error {
+			return validateEach(len(e.Links), func(i int) error { return e.Links[i].Validate() }, func(i int) string { return e.Links[i].URL })
+		})),
+		validation.Field(&e.Images, validation.By(func(interface{}) error {
+			primaries := 0
+			for _, img := range e.Images {
+				if img.Primary {
+					primaries++
+				}
+			}
+			if primaries > 1 {
+				return errors.New("only one image can be primary")
+			}
+			return validateEach(len(e.Images), func(i int) error { return e.Images[i].Validate() }, func(i int) string { return e.Images[i].URL })
+		})),
+	)
+}
+
+// key はentryを特定する自然キーを返す
+func (e *Entry) key() entryKey {
+	return entryKey{source: e.Source, name: e.Name}
+}
+
+// entryKey は作品名と名前の組
+type entryKey struct {
+	source string
+	name   string
+}
+
+func (k entryKey) String() string {
+	return k.source + "/" + k.name
+}
+
+type BWH struct {
+	Bust      *int64 `db:"bust" json:"bust"`
+	Waist     *int64 `db:"waist" json:"waist"`
+	Hip       *int64 `db:"hip" json:"hip"`
+	Underbust *int64 `db:"underbust" json:"underbust"`
+	Height    *int64 `db:"height" json:"height"`
+	Weight    *int64 `db:"weight" json:"weight"`
+}
+
+// Link はentryのリンク
+// リンク先から取得した情報は読み込み後に取得し直すため含めない
+type Link struct {
+	Type     string `db:"type" json:"type"`
+	URL      string `db:"url" json:"url"`
+	Nsfw     bool   `db:"nsfw" json:"nsfw"`
+	Darkness bool   `db:"darkness" json:"darkness"`
+}
+
+func (l *Link) Validate() error {
+	return validation.ValidateStruct(l,
+		validation.Field(&l.Type, validation.Required),
+		validation.Field(&l.URL, validation.Required),
+	)
+}
+
+// Image はentryのギャラリーの画像
+type Image struct {
+	URL              string  `db:"url" json:"url"`
+	ThumbnailURL     *string `db:"thumbnail_url" json:"thumbnail_url"`
+	WebPURL          *string `db:"webp_url" json:"webp_url"`
+	ThumbnailWebPURL *string `db:"thumbnail_webp_url" json:"thumbnail_webp_url"`
+	Width            *int64  `db:"width" json:"width"`
+	Height           *int64  `db:"height" json:"height"`
+	Caption          string  `db:"caption" json:"caption"`
+	Position         int64   `db:"position" json:"position"`
+	Primary          bool    `db:"is_primary" json:"is_primary"`
+	Nsfw             bool    `db:"nsfw" json:"nsfw"`
+	Darkness         bool    `db:"darkness" json:"darkness"`
+}
+
+func (i *Image) Validate() error {
+	return validation.ValidateStruct(i,
+		validation.Field(&i.URL, validation.Required),
+		validation.Field(&i.Width, validation.Min(1)),
+		validation.Field(&i.Height, validation.Min(1)),
+		validation.Field(&i.Position, validation.Min(0)),
+	)
+}
+
+// Withheld は書き出しで除いたリンクと画像の数
+type Withheld struct {
+	Links  int64 `json:"links"`
+	Images int64 `json:"images"`
+}
+
+// attribute は名前に対応する属性を返す
+func attribute(name string) (entryfilter.Attribute, bool) {
+	for _, a := range entryfilter.Attributes {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return entryfilter.Attribute{}, false
+}
+
+// validateAttributes は属性の名前が正しく、種類の名前が空でないことを確認する
+// perEntryがtrueの場合はentry1人分の値として、tag以外の値が1つまでであることも確認する
+func validateAttributes(attrs map[string][]string, perEntry bool) error {
+	errs := validation.Errors{}
+	for name, values := range attrs {
+		a, ok := attribute(name)
+		if !ok {
+			errs[name] = errors.New("unknown attribute")
+			continue
+		}
+		if perEntry && !a.All && len(values) > 1 {
+			errs[name] = errors.New("must have at most one value")
+			continue
+		}
+		for _, v := range values {
+			if v == "" {
+				errs[name] = errors.New("must not contain an empty name")
+				break
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Validate は文書の形式と、自然キーが重複していないことを確認する
+func (d *Document) Validate() error {
+	return validation.ValidateStruct(d,
+		validation.Field(&d.Version, validation.By(func(interface{}) error {
+			if d.Version != Version {
+				return fmt.Errorf("unsupported version %d (supported: %d)", d.Version, Version)
+			}
+			return nil
+		})),
+		validation.Field(&d.Sources, validation.By(func(interface{}) error {
+			return validateEach(len(d.Sources), func(i int) error { return d.Sources[i].Validate() }, func(i int) string { return d.Sources[i].Name })
+		})),
+		validation.Field(&d.Types, validation.By(func(interface{}) error {
+			return validateAttributes(d.Types, false)
+		})),
+		validation.Field(&d.RadarAxes, validation.By(func(interface{}) error {
+			return validateEach(len(d.RadarAxes), func(i int) error { return d.RadarAxes[i].Validate() }, func(i int) string { return d.RadarAxes[i].Name })
+		})),
+		validation.Field(&d.Entries, validation.By(func(interface{}) error {
+			return validateEach(len(d.Entries), func(i int) error { return d.Entries[i].Validate() }, func(i int) string { return d.Entries[i].key().String() })
+		})),
+	)
+}
+
+// validateEach はn件を1件ずつ確認し、自然キーが重複していないことを確認する
+// エラーは添字をキーとして返し、重複した場合は2件目以降をエラーにする
+func validateEach(n int, validate func(i int) error, key func(i int) string) error {
+	seen := make(map[string]bool, n)
+	errs := validation.Errors{}
+	for i := 0; i < n; i++ {
+		if err := validate(i); err != nil {
+			errs[fmt.Sprint(i)] = err
+			continue
+		}
+		k := key(i)
+		if seen[k] {
+			errs[fmt.Sprint(i)] = fmt.Errorf("duplicate %q", k)
+		}
+		seen[k] = true
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/content_filter"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/entry_filter"
+	"github.com/maguro-alternative/goheki/pkg/db"
+)
+
+// Export はDBの全てのデータを文書として返す
+// policyで隠すリンクと画像は含めず、除いた数をWithheldで返す
+func Export(ctx context.Context, driver db.Driver, policy contentfilter.Policy) (*Document, error) {
+	doc := &Document{
+		Version:    Version,
+		ExportedAt: time.Now().UTC(),
+		Sources:    []Source{},
+		Types:      map[string][]string{},
+		RadarAxes:  []RadarAxis{},
+		Entries:    []Entry{},
+		Withheld:   &Withheld{},
+	}
+
+	var sources []struct {
+		Name string `db:"name"`
+		URL  string `db:"url"`
+		Type string `db:"type"`
+	}
+	if err := driver.SelectContext(ctx, &sources, "SELECT name, url, type FROM source ORDER BY id"); err != nil {
+		return nil, err
+	}
+	for _, s := range sources {
+		doc.Sources = append(doc.Sources, Source{Name: s.Name, URL: s.URL, Type: s.Type})
+	}
+
+	for _, a := range entryfilter.Attributes {
+		var names []string
+		query := fmt.Sprintf("SELECT %s FROM %s ORDER BY id", a.TypeColumn, a.TypeTable)
+		if err := driver.SelectContext(ctx, &names, query); err != nil {
+			return nil, err
+		}
+		// 同じ名前の種類は1つにまとめる
+		doc.Types[a.Name] = dedupe(names)
+	}
+
+	var axes []struct {
+		Name         string `db:"name"`
+		Min          int64  `db:"min"`
+		Max          int64  `db:"max"`
+		DisplayOrder int64  `db:"display_order"`
+	}
+	if err := driver.SelectContext(ctx, &axes, "SELECT name, min, max, display_order FROM heki_radar_axis ORDER BY display_order, id"); err != nil {
+		return nil, err
+	}
+	for _, a := range axes {
+		doc.RadarAxes = append(doc.RadarAxes, RadarAxis{Name: a.Name, Min: a.Min, Max: a.Max, DisplayOrder: a.DisplayOrder})
+	}
+
+	var entries []struct {
+		ID        int64      `db:"id"`
+		Source    string     `db:"source"`
+		Name      string     `db:"name"`
+		Image     string     `db:"image"`
+		Content   string     `db:"content"`
+		CreatedAt *time.Time `db:"created_at"`
+	}
+	err := driver.SelectContext(ctx, &entries, `SELECT
+			e.id,
+			s.name AS source,
+			e.name,
+			e.image,
+			e.content,
+			e.created_at
+		FROM entry e
+		JOIN source s ON s.id = e.source_id
+		ORDER BY e.id`)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]int, len(entries))
+	for i, e := range entries {
+		byID[e.ID] = i
+		doc.Entries = append(doc.Entries, Entry{
+			Source:     e.Source,
+			Name:       e.Name,
+			Image:      e.Image,
+			Content:    e.Content,
+			CreatedAt:  e.CreatedAt,
+			Attributes: map[string][]string{},
+			Radar:      map[string]int64{},
+			Links:      []Link{},
+			Images:     []Image{},
+		})
+	}
+	// entry_idに対応するentryを返す
+	entry := func(id int64) *Entry {
+		return &doc.Entries[byID[id]]
+	}
+
+	for _, a := range entryfilter.Attributes {
+		var values []struct {
+			EntryID int64  `db:"entry_id"`
+			Name    string `db:"name"`
+		}
+		query := fmt.Sprintf(`SELECT x.entry_id, t.%s AS name
+			FROM %s x
+			JOIN %s t ON t.id = x.%s
+			ORDER BY x.entry_id, t.id`, a.TypeColumn, a.Table, a.TypeTable, a.Column)
+		if err := driver.SelectContext(ctx, &values, query); err != nil {
+			return nil, err
+		}
+		for _, v := range values {
+			e := entry(v.EntryID)
+			e.Attributes[a.Name] = append(e.Attributes[a.Name], v.Name)
+		}
+	}
+
+	var bwhs []struct {
+		EntryID   int64  `db:"entry_id"`
+		Bust      *int64 `db:"bust"`
+		Waist     *int64 `db:"waist"`
+		Hip       *int64 `db:"hip"`
+		Underbust *int64 `db:"underbust"`
+		Height    *int64 `db:"height"`
+		Weight    *int64 `db:"weight"`
+	}
+	if err := driver.SelectContext(ctx, &bwhs, "SELECT entry_id, bust, waist, hip, underbust, height, weight FROM bwh"); err != nil {
+		return nil, err
+	}
+	for _, b := range bwhs {
+		entry(b.EntryID).BWH = &BWH{
+			Bust:      b.Bust,
+			Waist:     b.Waist,
+			Hip:       b.Hip,
+			Underbust: b.Underbust,
+			Height:    b.Height,
+			Weight:    b.Weight,
+		}
+	}
+
+	var scores []struct {
+		EntryID int64  `db:"entry_id"`
+		Axis    string `db:"axis"`
+		Score   int64  `db:"score"`
+	}
+	err = driver.SelectContext(ctx, &scores, `SELECT s.entry_id, a.name AS axis, s.score
+		FROM heki_radar_score s
+		JOIN heki_radar_axis a ON a.id = s.axis_id`)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range scores {
+		entry(s.EntryID).Radar[s.Axis] = s.Score
+	}
+
+	var links []struct {
+		EntryID int64 `db:"entry_id"`
+		Link
+	}
+	if err := driver.SelectContext(ctx, &links, "SELECT entry_id, type, url, nsfw, darkness FROM link ORDER BY id"); err != nil {
+		return nil, err
+	}
+	for _, l := range links {
+		if !policy.Allows(l.Nsfw, l.Darkness) {
+			doc.Withheld.Links++
+			continue
+		}
+		e := entry(l.EntryID)
+		e.Links = append(e.Links, l.Link)
+	}
+
+	var images []struct {
+		EntryID int64 `db:"entry_id"`
+		Image
+	}
+	err = driver.SelectContext(ctx, &images, `SELECT
+			entry_id,
+			url,
+			thumbnail_url,
+			webp_url,
+			thumbnail_webp_url,
+			width,
+			height,
+			caption,
+			position,
+			is_primary,
+			nsfw,
+			darkness
+		FROM entry_image
+		ORDER BY position, id`)
+	if err != nil {
+		return nil, err
+	}
+	for _, img := range images {
+		if !policy.Allows(img.Nsfw, img.Darkness) {
+			doc.Withheld.Images++
+			continue
+		}
+		e := entry(img.EntryID)
+		e.Images = append(e.Images, img.Image)
+	}
+	return doc, nil
+}
+
+// dedupe は順序を保って重複を除く
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	res := make([]string, 0, len(values))
+	for _, v := range values {
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		res = append(res, v)
+	}
+	return res
+}
@@ -0,0 +1,753 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/entry_filter"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/entry_image"
+	"github.com/maguro-alternative/goheki/pkg/db"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// OnConflict は既存の行と文書の値が異なる場合の扱い
+type OnConflict string
+
+const (
+	// OnConflictError は衝突を報告し、何も書き込まない
+	OnConflictError OnConflict = "error"
+	// OnConflictSkip は衝突を報告し、既存の値を残す
+	OnConflictSkip OnConflict = "skip"
+	// OnConflictUpdate は既存の値を文書の値で上書きする
+	OnConflictUpdate OnConflict = "update"
+)
+
+// ParseOnConflict は文字列をOnConflictに変換する
+// 空の場合はOnConflictErrorを返す
+func ParseOnConflict(s string) (OnConflict, error) {
+	switch OnConflict(s) {
+	case "":
+		return OnConflictError, nil
+	case OnConflictError, OnConflictSkip, OnConflictUpdate:
+		return OnConflict(s), nil
+	}
+	return "", fmt.Errorf("must be one of %s, %s, %s", OnConflictError, OnConflictSkip, OnConflictUpdate)
+}
+
+// Options は読み込みの設定
+type Options struct {
+	OnConflict OnConflict
+	// trueの場合は読み込んだ結果だけを返し、書き込みを取り消す
+	DryRun bool
+}
+
+// Report は読み込みの結果
+// 件数はテーブル名ごとに数える
+type Report struct {
+	DryRun     bool           `json:"dry_run"`
+	OnConflict OnConflict     `json:"on_conflict"`
+	Created    map[string]int `json:"created"`
+	Updated    map[string]int `json:"updated"`
+	Unchanged  map[string]int `json:"unchanged"`
+	Skipped    map[string]int `json:"skipped"`
+	Conflicts  []Conflict     `json:"conflicts"`
+}
+
+// Conflict は文書の値が既存の行と一致しない箇所
+type Conflict struct {
+	// 文書内の位置 (例: entries[3].links[0])
+	Path string `json:"path"`
+	// 自然キー
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+// ConflictError はOnConflictErrorで衝突があった場合のエラー
+// 書き込みは全て取り消している
+type ConflictError struct {
+	Report *Report
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%d conflicts with existing data", len(e.Report.Conflicts))
+}
+
+// Unwrap は衝突を位置ごとのエラーとして返す
+func (e *ConflictError) Unwrap() error {
+	errs := validation.Errors{}
+	for _, c := range e.Report.Conflicts {
+		errs[c.Path] = fmt.Errorf("%s: %s", c.Key, c.Message)
+	}
+	return errs
+}
+
+// errDryRun はDryRunで書き込みを取り消すためのエラー
+var errDryRun = errors.New("dry run")
+
+// Import は文書をDBに読み込む
+// 自然キーで既存の行と照合し、ない行は作成し、異なる行はOnConflictに従う
+// 文書にない既存の行は削除しない
+// 全体を1つのトランザクションで行い、エラーの場合は何も書き込まない
+func Import(ctx context.Context, driver db.Driver, doc *Document, opts Options) (*Report, error) {
+	if opts.OnConflict == "" {
+		opts.OnConflict = OnConflictError
+	}
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	report := &Report{
+		DryRun:     opts.DryRun,
+		OnConflict: opts.OnConflict,
+		Created:    map[string]int{},
+		Updated:    map[string]int{},
+		Unchanged:  map[string]int{},
+		Skipped:    map[string]int{},
+		Conflicts:  []Conflict{},
+	}
+	err := db.WithTx(ctx, driver, func(tx db.Driver) error {
+		im := &importer{
+			ctx:    ctx,
+			tx:     tx,
+			mode:   opts.OnConflict,
+			report: report,
+			errs:   validation.Errors{},
+		}
+		if err := im.run(doc); err != nil {
+			return err
+		}
+		if len(im.errs) > 0 {
+			return im.errs
+		}
+		if im.mode == OnConflictError && len(report.Conflicts) > 0 {
+			return &ConflictError{Report: report}
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		return report, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// importer は1回の読み込みの状態
+type importer struct {
+	ctx    context.Context
+	tx     db.Driver
+	mode   OnConflict
+	report *Report
+	// 文書が参照しているが存在しない作品や軸
+	errs validation.Errors
+
+	// 作品名ごとのid (同じ名前の行が複数ある場合は複数)
+	sources map[string][]int64
+	// 属性の名前ごとの、種類の名前ごとのid
+	types map[string]map[string]int64
+	// 軸の名前ごとの軸
+	axes map[string]*axisRow
+}
+
+type axisRow struct {
+	ID int64 `db:"id"`
+	RadarAxis
+}
+
+func (im *importer) run(doc *Document) error {
+	if err := im.importSources(doc.Sources); err != nil {
+		return err
+	}
+	if err := im.importTypes(doc.Types); err != nil {
+		return err
+	}
+	if err := im.importAxes(doc.RadarAxes); err != nil {
+		return err
+	}
+	return im.importEntries(doc.Entries)
+}
+
+// exec は?を置換文字としたクエリを実行する
+func (im *importer) exec(query string, args ...any) error {
+	// DBの種類に合わせて置換文字を変える
+	_, err := im.tx.ExecContext(im.ctx, im.tx.Rebind(query), args...)
+	return err
+}
+
+// get は?を置換文字としたクエリで1行を読み込む
+func (im *importer) get(dest any, query string, args ...any) error {
+	return im.tx.GetContext(im.ctx, dest, im.tx.Rebind(query), args...)
+}
+
+// selectAll は?を置換文字としたクエリで全ての行を読み込む
+func (im *importer) selectAll(dest any, query string, args ...any) error {
+	return im.tx.SelectContext(im.ctx, dest, im.tx.Rebind(query), args...)
+}
+
+// merge は既存の行と文書の値の差分から、行を更新するかを返す
+// 差分がある場合、OnConflictUpdate以外では衝突として報告する
+func (im *importer) merge(table, path, key, diff string) bool {
+	switch {
+	case diff == "":
+		im.report.Unchanged[table]++
+		return false
+	case im.mode == OnConflictUpdate:
+		im.report.Updated[table]++
+		return true
+	}
+	im.report.Conflicts = append(im.report.Conflicts, Conflict{Path: path, Key: key, Message: diff})
+	im.report.Skipped[table]++
+	return false
+}
+
+// ambiguous は自然キーが同じ行が複数あり、どの行に読み込むか決められないことを報告する
+// OnConflictに関わらず衝突として扱い、読み込まない
+func (im *importer) ambiguous(table, path, key string, n int) {
+	im.report.Conflicts = append(im.report.Conflicts, Conflict{
+		Path:    path,
+		Key:     key,
+		Message: fmt.Sprintf("%d rows in %s have the same key", n, table),
+	})
+	im.report.Skipped[table]++
+}
+
+// invalid は文書が存在しない値を参照していることを記録する
+func (im *importer) invalid(path string, err error) {
+	im.errs[path] = err
+}
+
+// change は既存の行と文書の値の組
+type change struct {
+	name     string
+	existing any
+	imported any
+}
+
+// diff は値が異なる組を文字列にまとめる
+// 全て一致する場合は空文字を返す
+func diff(changes ...change) string {
+	var diffs []string
+	for _, c := range changes {
+		if reflect.DeepEqual(c.existing, c.imported) {
+			continue
+		}
+		diffs = append(diffs, fmt.Sprintf("%s: %s -> %s", c.name, show(c.existing), show(c.imported)))
+	}
+	return strings.Join(diffs, ", ")
+}
+
+// show はポインタを外して値を文字列にする
+func show(v any) string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return "null"
+		}
+		rv = rv.Elem()
+	}
+	return fmt.Sprintf("%#v", rv.Interface())
+}
+
+func (im *importer) importSources(sources []Source) error {
+	var rows []struct {
+		ID int64 `db:"id"`
+		Source
+	}
+	if err := im.selectAll(&rows, "SELECT id, name, url, type FROM source ORDER BY id"); err != nil {
+		return err
+	}
+	im.sources = map[string][]int64{}
+	existing := map[string][]int{}
+	for i, row := range rows {
+		existing[row.Name] = append(existing[row.Name], i)
+		im.sources[row.Name] = append(im.sources[row.Name], row.ID)
+	}
+	for i, s := range sources {
+		path := fmt.Sprintf("sources[%d]", i)
+		matches := existing[s.Name]
+		switch len(matches) {
+		case 0:
+			var id int64
+			if err := im.get(&id, "INSERT INTO source (name, url, type) VALUES (?, ?, ?) RETURNING id", s.Name, s.URL, s.Type); err != nil {
+				return err
+			}
+			im.sources[s.Name] = []int64{id}
+			im.report.Created["source"]++
+		case 1:
+			row := rows[matches[0]]
+			d := diff(change{"url", row.URL, s.URL}, change{"type", row.Type, s.Type})
+			if im.merge("source", path, s.Name, d) {
+				if err := im.exec("UPDATE source SET url = ?, type = ? WHERE id = ?", s.URL, s.Type, row.ID); err != nil {
+					return err
+				}
+			}
+		default:
+			im.ambiguous("source", path, s.Name, len(matches))
+		}
+	}
+	return nil
+}
+
+// importTypes は属性の種類を読み込む
+// 種類は名前だけを持つため、ない名前を作成するだけで衝突はない
+func (im *importer) importTypes(types map[string][]string) error {
+	im.types = map[string]map[string]int64{}
+	for _, a := range entryfilter.Attributes {
+		var rows []struct {
+			ID   int64  `db:"id"`
+			Name string `db:"name"`
+		}
+		query := fmt.Sprintf("SELECT id, %s AS name FROM %s ORDER BY id", a.TypeColumn, a.TypeTable)
+		if err := im.selectAll(&rows, query); err != nil {
+			return err
+		}
+		// 同じ名前の種類が複数ある場合は最初の種類を使う
+		ids := map[string]int64{}
+		for _, row := range rows {
+			if _, ok := ids[row.Name]; !ok {
+				ids[row.Name] = row.ID
+			}
+		}
+		im.types[a.Name] = ids
+		for _, name := range types[a.Name] {
+			if _, err := im.typeID(a, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// typeID は種類の名前に対応するidを返す
+// ない場合は作成する
+func (im *importer) typeID(a entryfilter.Attribute, name string) (int64, error) {
+	if id, ok := im.types[a.Name][name]; ok {
+		im.report.Unchanged[a.TypeTable]++
+		return id, nil
+	}
+	var id int64
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (?) RETURNING id", a.TypeTable, a.TypeColumn)
+	if err := im.get(&id, query, name); err != nil {
+		return 0, err
+	}
+	im.types[a.Name][name] = id
+	im.report.Created[a.TypeTable]++
+	return id, nil
+}
+
+func (im *importer) importAxes(axes []RadarAxis) error {
+	var rows []*axisRow
+	if err := im.selectAll(&rows, "SELECT id, name, min, max, display_order FROM heki_radar_axis"); err != nil {
+		return err
+	}
+	im.axes = map[string]*axisRow{}
+	for _, row := range rows {
+		im.axes[row.Name] = row
+	}
+	for i, a := range axes {
+		path := fmt.Sprintf("radar_axes[%d]", i)
+		row, ok := im.axes[a.Name]
+		if !ok {
+			row = &axisRow{RadarAxis: a}
+			query := "INSERT INTO heki_radar_axis (name, min, max, display_order) VALUES (?, ?, ?, ?) RETURNING id"
+			if err := im.get(&row.ID, query, a.Name, a.Min, a.Max, a.DisplayOrder); err != nil {
+				return err
+			}
+			im.axes[a.Name] = row
+			im.report.Created["heki_radar_axis"]++
+			continue
+		}
+		d := diff(
+			change{"min", row.Min, a.Min},
+			change{"max", row.Max, a.Max},
+			change{"display_order", row.DisplayOrder, a.DisplayOrder},
+		)
+		if im.merge("heki_radar_axis", path, a.Name, d) {
+			query := "UPDATE heki_radar_axis SET min = ?, max = ?, display_order = ? WHERE id = ?"
+			if err := im.exec(query, a.Min, a.Max, a.DisplayOrder, row.ID); err != nil {
+				return err
+			}
+			row.Min, row.Max, row.DisplayOrder = a.Min, a.Max, a.DisplayOrder
+		}
+	}
+	return nil
+}
+
+func (im *importer) importEntries(entries []Entry) error {
+	var rows []struct {
+		ID       int64  `db:"id"`
+		SourceID int64  `db:"source_id"`
+		Name     string `db:"name"`
+		Image    string `db:"image"`
+		Content  string `db:"content"`
+	}
+	if err := im.selectAll(&rows, "SELECT id, source_id, name, image, content FROM entry ORDER BY id"); err != nil {
+		return err
+	}
+	type rowKey struct {
+		sourceID int64
+		name     string
+	}
+	existing := map[rowKey][]int{}
+	for i, row := range rows {
+		k := rowKey{row.SourceID, row.Name}
+		existing[k] = append(existing[k], i)
+	}
+	for i := range entries {
+		e := &entries[i]
+		path := fmt.Sprintf("entries[%d]", i)
+		key := e.key().String()
+		sourceIDs := im.sources[e.Source]
+		switch len(sourceIDs) {
+		case 0:
+			im.invalid(path+".source", fmt.Errorf("unknown source %q", e.Source))
+			continue
+		case 1:
+		default:
+			im.ambiguous("source", path+".source", e.Source, len(sourceIDs))
+			continue
+		}
+		sourceID := sourceIDs[0]
+		// 代表の画像がある場合はentry.imageを代表の画像のURLと同期するため、同じ値として扱う
+		image := e.Image
+		for _, img := range e.Images {
+			if img.Primary {
+				image = img.URL
+			}
+		}
+		var entryID int64
+		matches := existing[rowKey{sourceID, e.Name}]
+		switch len(matches) {
+		case 0:
+			// created_atがない場合はDBの既定値を使う
+			query := "INSERT INTO entry (source_id, name, image, content, created_at) VALUES (?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP)) RETURNING id"
+			if err := im.get(&entryID, query, sourceID, e.Name, image, e.Content, e.CreatedAt); err != nil {
+				return err
+			}
+			im.report.Created["entry"]++
+		case 1:
+			// created_atは読み込み元のDBによって異なるため比べない
+			row := rows[matches[0]]
+			entryID = row.ID
+			d := diff(change{"image", row.Image, image}, change{"content", row.Content, e.Content})
+			if im.merge("entry", path, key, d) {
+				if err := im.exec("UPDATE entry SET image = ?, content = ? WHERE id = ?", image, e.Content, entryID); err != nil {
+					return err
+				}
+			}
+		default:
+			// どのentryか決められないため、紐づく値も読み込まない
+			im.ambiguous("entry", path, key, len(matches))
+			continue
+		}
+		if err := im.importEntryValues(entryID, path, key, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importEntryValues はentryに紐づく値を読み込む
+func (im *importer) importEntryValues(entryID int64, path, key string, e *Entry) error {
+	for _, a := range entryfilter.Attributes {
+		values := e.Attributes[a.Name]
+		if len(values) == 0 {
+			continue
+		}
+		attrPath := path + ".attributes." + a.Name
+		var err error
+		if a.All {
+			err = im.importTags(a, entryID, values)
+		} else {
+			err = im.importAttribute(a, entryID, attrPath, key, values[0])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if e.BWH != nil {
+		if err := im.importBWH(entryID, path+".bwh", key, e.BWH); err != nil {
+			return err
+		}
+	}
+	if err := im.importRadar(entryID, path+".radar", key, e.Radar); err != nil {
+		return err
+	}
+	if err := im.importLinks(entryID, path+".links", key, e.Links); err != nil {
+		return err
+	}
+	return im.importImages(entryID, path+".images", key, e.Images)
+}
+
+// importAttribute はentryごとに1つの属性を読み込む
+func (im *importer) importAttribute(a entryfilter.Attribute, entryID int64, path, key, name string) error {
+	typeID, err := im.typeID(a, name)
+	if err != nil {
+		return err
+	}
+	var current []string
+	query := fmt.Sprintf(`SELECT t.%s
+		FROM %s x
+		JOIN %s t ON t.id = x.%s
+		WHERE x.entry_id = ?`, a.TypeColumn, a.Table, a.TypeTable, a.Column)
+	if err := im.selectAll(&current, query, entryID); err != nil {
+		return err
+	}
+	if len(current) == 0 {
+		query := fmt.Sprintf("INSERT INTO %s (entry_id, %s) VALUES (?, ?)", a.Table, a.Column)
+		if err := im.exec(query, entryID, typeID); err != nil {
+			return err
+		}
+		im.report.Created[a.Table]++
+		return nil
+	}
+	// 同じ名前の種類が複数ある場合はidが異なっても一致とする
+	if im.merge(a.Table, path, key, diff(change{a.Name, current[0], name})) {
+		query := fmt.Sprintf("UPDATE %s SET %s = ? WHERE entry_id = ?", a.Table, a.Column)
+		return im.exec(query, typeID, entryID)
+	}
+	return nil
+}
+
+// importTags はentryに複数付けられる属性を読み込む
+// 既にある値は残し、ない値だけを追加する
+func (im *importer) importTags(a entryfilter.Attribute, entryID int64, names []string) error {
+	var current []string
+	query := fmt.Sprintf(`SELECT t.%s
+		FROM %s x
+		JOIN %s t ON t.id = x.%s
+		WHERE x.entry_id = ?`, a.TypeColumn, a.Table, a.TypeTable, a.Column)
+	if err := im.selectAll(&current, query, entryID); err != nil {
+		return err
+	}
+	has := map[string]bool{}
+	for _, name := range current {
+		has[name] = true
+	}
+	for _, name := range names {
+		if has[name] {
+			im.report.Unchanged[a.Table]++
+			continue
+		}
+		typeID, err := im.typeID(a, name)
+		if err != nil {
+			return err
+		}
+		query := fmt.Sprintf("INSERT INTO %s (entry_id, %s) VALUES (?, ?)", a.Table, a.Column)
+		if err := im.exec(query, entryID, typeID); err != nil {
+			return err
+		}
+		has[name] = true
+		im.report.Created[a.Table]++
+	}
+	return nil
+}
+
+func (im *importer) importBWH(entryID int64, path, key string, b *BWH) error {
+	var rows []BWH
+	query := "SELECT bust, waist, hip, underbust, height, weight FROM bwh WHERE entry_id = ?"
+	if err := im.selectAll(&rows, query, entryID); err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		query := "INSERT INTO bwh (entry_id, bust, waist, hip, underbust, height, weight) VALUES (?, ?, ?, ?, ?, ?, ?)"
+		if err := im.exec(query, entryID, b.Bust, b.Waist, b.Hip, b.Underbust, b.Height, b.Weight); err != nil {
+			return err
+		}
+		im.report.Created["bwh"]++
+		return nil
+	}
+	current := rows[0]
+	d := diff(
+		change{"bust", current.Bust, b.Bust},
+		change{"waist", current.Waist, b.Waist},
+		change{"hip", current.Hip, b.Hip},
+		change{"underbust", current.Underbust, b.Underbust},
+		change{"height", current.Height, b.Height},
+		change{"weight", current.Weight, b.Weight},
+	)
+	if im.merge("bwh", path, key, d) {
+		query := "UPDATE bwh SET bust = ?, waist = ?, hip = ?, underbust = ?, height = ?, weight = ? WHERE entry_id = ?"
+		return im.exec(query, b.Bust, b.Waist, b.Hip, b.Underbust, b.Height, b.Weight, entryID)
+	}
+	return nil
+}
+
+// importRadar は軸ごとの値を読み込む
+// 軸は文書とDBのどちらかにある必要があり、値は軸の範囲内である必要がある
+func (im *importer) importRadar(entryID int64, path, key string, radar map[string]int64) error {
+	var rows []struct {
+		AxisID int64 `db:"axis_id"`
+		Score  int64 `db:"score"`
+	}
+	if err := im.selectAll(&rows, "SELECT axis_id, score FROM heki_radar_score WHERE entry_id = ?", entryID); err != nil {
+		return err
+	}
+	current := make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		current[row.AxisID] = row.Score
+	}
+	names := make([]string, 0, len(radar))
+	for name := range radar {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		score := radar[name]
+		scorePath := path + "." + name
+		axis, ok := im.axes[name]
+		if !ok {
+			im.invalid(scorePath, errors.New("unknown radar axis"))
+			continue
+		}
+		if score < axis.Min || score > axis.Max {
+			im.invalid(scorePath, fmt.Errorf("must be between %d and %d", axis.Min, axis.Max))
+			continue
+		}
+		existing, ok := current[axis.ID]
+		if !ok {
+			if err := im.exec("INSERT INTO heki_radar_score (entry_id, axis_id, score) VALUES (?, ?, ?)", entryID, axis.ID, score); err != nil {
+				return err
+			}
+			im.report.Created["heki_radar_score"]++
+			continue
+		}
+		if im.merge("heki_radar_score", scorePath, key, diff(change{"score", existing, score})) {
+			if err := im.exec("UPDATE heki_radar_score SET score = ? WHERE entry_id = ? AND axis_id = ?", score, entryID, axis.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// importLinks はリンクをURLで照合して読み込む
+func (im *importer) importLinks(entryID int64, path, key string, links []Link) error {
+	var rows []struct {
+		ID int64 `db:"id"`
+		Link
+	}
+	if err := im.selectAll(&rows, "SELECT id, type, url, nsfw, darkness FROM link WHERE entry_id = ? ORDER BY id", entryID); err != nil {
+		return err
+	}
+	existing := map[string][]int{}
+	for i, row := range rows {
+		existing[row.URL] = append(existing[row.URL], i)
+	}
+	for i, l := range links {
+		linkPath := fmt.Sprintf("%s[%d]", path, i)
+		linkKey := key + " " + l.URL
+		matches := existing[l.URL]
+		switch len(matches) {
+		case 0:
+			query := "INSERT INTO link (entry_id, type, url, nsfw, darkness) VALUES (?, ?, ?, ?, ?)"
+			if err := im.exec(query, entryID, l.Type, l.URL, l.Nsfw, l.Darkness); err != nil {
+				return err
+			}
+			im.report.Created["link"]++
+		case 1:
+			row := rows[matches[0]]
+			d := diff(
+				change{"type", row.Type, l.Type},
+				change{"nsfw", row.Nsfw, l.Nsfw},
+				change{"darkness", row.Darkness, l.Darkness},
+			)
+			if im.merge("link", linkPath, linkKey, d) {
+				query := "UPDATE link SET type = ?, nsfw = ?, darkness = ? WHERE id = ?"
+				if err := im.exec(query, l.Type, l.Nsfw, l.Darkness, row.ID); err != nil {
+					return err
+				}
+			}
+		default:
+			im.ambiguous("link", linkPath, linkKey, len(matches))
+		}
+	}
+	return nil
+}
+
+// importImages はギャラリーの画像をURLで照合して読み込む
+// 代表の画像はentryimage.Resourceを通してentry.imageと同期する
+func (im *importer) importImages(entryID int64, path, key string, images []Image) error {
+	var rows []entryimage.EntryImage
+	query := `SELECT
+			id,
+			entry_id,
+			url,
+			thumbnail_url,
+			webp_url,
+			thumbnail_webp_url,
+			width,
+			height,
+			caption,
+			position,
+			is_primary,
+			nsfw,
+			darkness
+		FROM entry_image
+		WHERE entry_id = ?
+		ORDER BY id`
+	if err := im.selectAll(&rows, query, entryID); err != nil {
+		return err
+	}
+	existing := map[string][]int{}
+	for i, row := range rows {
+		existing[row.URL] = append(existing[row.URL], i)
+	}
+	for i, img := range images {
+		imagePath := fmt.Sprintf("%s[%d]", path, i)
+		imageKey := key + " " + img.URL
+		item := entryimage.EntryImage{
+			EntryID:          entryID,
+			URL:              img.URL,
+			ThumbnailURL:     img.ThumbnailURL,
+			WebPURL:          img.WebPURL,
+			ThumbnailWebPURL: img.ThumbnailWebPURL,
+			Width:            img.Width,
+			Height:           img.Height,
+			Caption:          img.Caption,
+			Position:         img.Position,
+			Primary:          img.Primary,
+			Nsfw:             img.Nsfw,
+			Darkness:         img.Darkness,
+		}
+		matches := existing[img.URL]
+		switch len(matches) {
+		case 0:
+			if err := entryimage.Resource.Insert(im.ctx, im.tx, &item); err != nil {
+				return err
+			}
+			im.report.Created["entry_image"]++
+		case 1:
+			row := rows[matches[0]]
+			d := diff(
+				change{"thumbnail_url", row.ThumbnailURL, img.ThumbnailURL},
+				change{"webp_url", row.WebPURL, img.WebPURL},
+				change{"thumbnail_webp_url", row.ThumbnailWebPURL, img.ThumbnailWebPURL},
+				change{"width", row.Width, img.Width},
+				change{"height", row.Height, img.Height},
+				change{"caption", row.Caption, img.Caption},
+				change{"position", row.Position, img.Position},
+				change{"is_primary", row.Primary, img.Primary},
+				change{"nsfw", row.Nsfw, img.Nsfw},
+				change{"darkness", row.Darkness, img.Darkness},
+			)
+			if im.merge("entry_image", imagePath, imageKey, d) {
+				item.ID = row.ID
+				if err := entryimage.Resource.Update(im.ctx, im.tx, &item); err != nil {
+					return err
+				}
+			}
+		default:
+			im.ambiguous("entry_image", imagePath, imageKey, len(matches))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package contentfilter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/gorilla/sessions"
+)
+
+// errNoSession はセッションを使えない場合のエラー
+var errNoSession = errors.New("session store is not configured")
+
+type ReadHandler struct {
+	svc *service.IndexService
+}
+
+func NewReadHandler(svc *service.IndexService) *ReadHandler {
+	return &ReadHandler{
+		svc: svc,
+	}
+}
+
+// ServeHTTP はリクエストに適用される設定を返す
+//
+//	GET /api/content-filter?hide_nsfw=true
+func (h *ReadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// GET以外は受け付けない
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	p, err := FromRequest(r, h.svc)
+	var verrs validation.Errors
+	if errors.As(err, &verrs) {
+		problem.Validation(w, r, verrs)
+		return
+	} else if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+	writePolicy(w, p)
+}
+
+type UpdateHandler struct {
+	svc *service.IndexService
+}
+
+func NewUpdateHandler(svc *service.IndexService) *UpdateHandler {
+	return &UpdateHandler{
+		svc: svc,
+	}
+}
+
+// ServeHTTP は設定をセッションに保存する
+//
+//	PUT /api/content-filter {"hide_nsfw": true, "hide_darkness": false}
+//
+// 省略したキーは現在の設定を引き継ぐ
+func (h *UpdateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// PUT、PATCH以外は受け付けない
+	if r.Method != http.MethodPut && r.Method != http.MethodPatch {
+		w.Header().Set("Allow", http.MethodPatch+", "+http.MethodPut)
+		problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	session, err := loadSession(r, h.svc)
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+	// クエリパラメータは保存する設定に含めない
+	current := r.Clone(r.Context())
+	current.URL.RawQuery = ""
+	p, err := FromRequest(current, h.svc)
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+	// json読み込み
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		problem.BadRequest(w, r, err)
+		return
+	}
+	session.Values[HideNsfwParam] = p.HideNsfw
+	session.Values[HideDarknessParam] = p.HideDarkness
+	if err := session.Save(r, w); err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+	writePolicy(w, p)
+}
+
+type DeleteHandler struct {
+	svc *service.IndexService
+}
+
+func NewDeleteHandler(svc *service.IndexService) *DeleteHandler {
+	return &DeleteHandler{
+		svc: svc,
+	}
+}
+
+// ServeHTTP はセッションに保存した設定を消し、サーバーの既定の設定を返す
+//
+//	DELETE /api/content-filter
+func (h *DeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// DELETE以外は受け付けない
+	if r.Method != http.MethodDelete {
+		w.Header().Set("Allow", http.MethodDelete)
+		problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	session, err := loadSession(r, h.svc)
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+	// cookieを削除する
+	session.Options.MaxAge = -1
+	if err := session.Save(r, w); err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+	p, err := Default(h.svc.Env)
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+	writePolicy(w, p)
+}
+
+// loadSession は設定を保存するセッションを返す
+// 署名の鍵が変わった場合などで読めない場合は新しいセッションを返す
+func loadSession(r *http.Request, svc *service.IndexService) (*sessions.Session, error) {
+	if svc.CookieStore == nil {
+		return nil, errNoSession
+	}
+	s, err := svc.CookieStore.Get(r, SessionName)
+	if err != nil && s == nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func writePolicy(w http.ResponseWriter, p Policy) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&p); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
+
+// Routes は/api/content-filterのルーティングの定義を返す
+func Routes(svc *service.IndexService) router.Resource {
+	return router.Resource{
+		Name:   "content-filter",
+		Read:   NewReadHandler(svc),
+		Update: NewUpdateHandler(svc),
+		Delete: NewDeleteHandler(svc),
+	}
+}
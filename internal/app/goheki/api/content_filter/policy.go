@@ -0,0 +1,122 @@
+// Package contentfilter はnsfwとdarknessのリンクを隠すかを決める
+// linkの一覧、プロフィールなどリンクを返す全てのAPIで同じ設定を使う
+package contentfilter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// SessionName は設定を保存するセッションのcookie名
+const SessionName = "goheki_content_filter"
+
+// 設定のクエリパラメータ名とセッションのキー
+const (
+	HideNsfwParam     = "hide_nsfw"
+	HideDarknessParam = "hide_darkness"
+)
+
+// Policy はnsfwとdarknessのリンクを隠すかの設定
+// ゼロ値は全てのリンクを表示する
+type Policy struct {
+	HideNsfw     bool `json:"hide_nsfw"`
+	HideDarkness bool `json:"hide_darkness"`
+}
+
+// Allows はリンクを表示するかを返す
+func (p Policy) Allows(nsfw, darkness bool) bool {
+	return !(p.HideNsfw && nsfw) && !(p.HideDarkness && darkness)
+}
+
+// Exclude は隠すリンクの条件をSQLで返す
+// prefixは列名の接頭辞 (例: "l.")、隠すリンクがない場合は空文字を返す
+func (p Policy) Exclude(prefix string) string {
+	switch {
+	case p.HideNsfw && p.HideDarkness:
+		return prefix + "nsfw OR " + prefix + "darkness"
+	case p.HideNsfw:
+		return prefix + "nsfw"
+	case p.HideDarkness:
+		return prefix + "darkness"
+	}
+	return ""
+}
+
+// Default は環境変数で指定したサーバー全体の既定の設定を返す
+// 指定がない場合は互換性のため全てのリンクを表示する
+func Default(env *envconfig.Env) (Policy, error) {
+	var p Policy
+	if env == nil {
+		return p, nil
+	}
+	values := []struct {
+		name  string
+		value string
+		dest  *bool
+	}{
+		{"CONTENT_HIDE_NSFW", env.ContentHideNsfw, &p.HideNsfw},
+		{"CONTENT_HIDE_DARKNESS", env.ContentHideDarkness, &p.HideDarkness},
+	}
+	for _, v := range values {
+		if v.value == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(v.value)
+		if err != nil {
+			return Policy{}, fmt.Errorf("invalid %s: %w", v.name, err)
+		}
+		*v.dest = b
+	}
+	return p, nil
+}
+
+// FromRequest はクエリパラメータ、セッションに保存した設定、サーバーの既定の順に設定を決める
+// nsfwとdarknessはそれぞれ独立に決め、クエリパラメータの形式が正しくない場合はvalidation.Errorsを返す
+func FromRequest(r *http.Request, svc *service.IndexService) (Policy, error) {
+	p, err := Default(svc.Env)
+	if err != nil {
+		return Policy{}, err
+	}
+	values := []struct {
+		key  string
+		dest *bool
+	}{
+		{HideNsfwParam, &p.HideNsfw},
+		{HideDarknessParam, &p.HideDarkness},
+	}
+	// セッションが読めない場合 (署名の鍵が変わった場合など) は既定の設定を使う
+	if svc.CookieStore != nil {
+		if session, err := svc.CookieStore.Get(r, SessionName); err == nil {
+			for _, v := range values {
+				if b, ok := session.Values[v.key].(bool); ok {
+					*v.dest = b
+				}
+			}
+		}
+	}
+	errs := validation.Errors{}
+	query := r.URL.Query()
+	for _, v := range values {
+		raw := query.Get(v.key)
+		if raw == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			errs[v.key] = errors.New("must be true or false")
+			continue
+		}
+		*v.dest = b
+	}
+	if len(errs) > 0 {
+		return Policy{}, errs
+	}
+	return p, nil
+}
@@ -0,0 +1,127 @@
+package contentfilter
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+
+	"github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy(t *testing.T) {
+	t.Run("ゼロ値は全て表示する", func(t *testing.T) {
+		var p Policy
+		assert.True(t, p.Allows(true, true))
+		assert.Equal(t, "", p.Exclude("l."))
+	})
+
+	t.Run("nsfwとdarknessを隠す", func(t *testing.T) {
+		p := Policy{HideNsfw: true, HideDarkness: true}
+		assert.True(t, p.Allows(false, false))
+		assert.False(t, p.Allows(true, false))
+		assert.False(t, p.Allows(false, true))
+		assert.Equal(t, "l.nsfw OR l.darkness", p.Exclude("l."))
+	})
+
+	t.Run("darknessだけ隠す", func(t *testing.T) {
+		p := Policy{HideDarkness: true}
+		assert.True(t, p.Allows(true, false))
+		assert.False(t, p.Allows(false, true))
+		assert.Equal(t, "darkness", p.Exclude(""))
+	})
+}
+
+func TestDefault(t *testing.T) {
+	t.Run("指定がない場合は全て表示する", func(t *testing.T) {
+		p, err := Default(&envconfig.Env{})
+		assert.NoError(t, err)
+		assert.Equal(t, Policy{}, p)
+	})
+
+	t.Run("環境変数で隠す", func(t *testing.T) {
+		p, err := Default(&envconfig.Env{ContentHideNsfw: "true"})
+		assert.NoError(t, err)
+		assert.Equal(t, Policy{HideNsfw: true}, p)
+	})
+
+	t.Run("形式が正しくない", func(t *testing.T) {
+		_, err := Default(&envconfig.Env{ContentHideDarkness: "aaa"})
+		assert.Error(t, err)
+	})
+}
+
+func TestPreference(t *testing.T) {
+	svc := service.NewIndexService(
+		nil,
+		sessions.NewCookieStore([]byte("secret")),
+		&envconfig.Env{ContentHideNsfw: "true"},
+	)
+	read := func(t *testing.T, target string, cookies []*http.Cookie) (int, Policy) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		NewReadHandler(svc).ServeHTTP(w, req)
+		var p Policy
+		if w.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+		}
+		return w.Code, p
+	}
+
+	t.Run("サーバーの既定の設定", func(t *testing.T) {
+		code, p := read(t, "/api/content-filter", nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, Policy{HideNsfw: true}, p)
+	})
+
+	// セッションに保存する
+	req := httptest.NewRequest(http.MethodPut, "/api/content-filter", bytes.NewBufferString(`{"hide_darkness": true}`))
+	w := httptest.NewRecorder()
+	NewUpdateHandler(svc).ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	saved := w.Result().Cookies()
+
+	t.Run("省略したキーは現在の設定を引き継ぐ", func(t *testing.T) {
+		var p Policy
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+		assert.Equal(t, Policy{HideNsfw: true, HideDarkness: true}, p)
+	})
+
+	t.Run("セッションの設定", func(t *testing.T) {
+		code, p := read(t, "/api/content-filter", saved)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, Policy{HideNsfw: true, HideDarkness: true}, p)
+	})
+
+	t.Run("クエリパラメータはセッションより優先する", func(t *testing.T) {
+		code, p := read(t, "/api/content-filter?hide_nsfw=false", saved)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, Policy{HideDarkness: true}, p)
+	})
+
+	t.Run("クエリパラメータの形式が正しくない", func(t *testing.T) {
+		code, _ := read(t, "/api/content-filter?hide_darkness=aaa", saved)
+		assert.Equal(t, http.StatusUnprocessableEntity, code)
+	})
+
+	t.Run("セッションの設定を消す", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/api/content-filter", nil)
+		for _, c := range saved {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		NewDeleteHandler(svc).ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		cookies := w.Result().Cookies()
+		assert.Len(t, cookies, 1)
+		assert.True(t, cookies[0].MaxAge < 0)
+	})
+}
@@ -21,7 +21,6 @@ func (e *Entry) Validate() error {
 		validation.Field(&e.Name, validation.Required),
 		validation.Field(&e.Image, validation.Required),
 		validation.Field(&e.Content, validation.Required),
-		validation.Field(&e.CreatedAt, validation.Required),
 	)
 }
 
@@ -11,13 +11,13 @@ import (
 	"time"
 
 	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
 
-	"github.com/maguro-alternative/goheki/pkg/db"
-
 	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
 
+	"github.com/justinas/alice"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -26,7 +26,7 @@ func TestCreateEntryHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -131,6 +131,11 @@ func TestCreateEntryHandler(t *testing.T) {
 		err = json.NewDecoder(r.Body).Decode(&res)
 		assert.NoError(t, err)
 
+		// 採番されたidが返る
+		for i := range res.Entries {
+			assert.NotZero(t, res.Entries[i].ID)
+			entriesJson.Entries[i].ID = res.Entries[i].ID
+		}
 		assert.Equal(t, entriesJson, res)
 
 		err = tx.SelectContext(ctx, &actuals, "SELECT * FROM entry")
@@ -155,7 +160,7 @@ func TestReadEntryHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -435,7 +440,7 @@ func TestReadEntryHandler(t *testing.T) {
 		h.ServeHTTP(w, req)
 
 		res := w.Result()
-		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		assert.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
 	})
 
 	t.Run("entry2件取得(内1件は形式が正しくない)", func(t *testing.T) {
@@ -455,7 +460,7 @@ func TestReadEntryHandler(t *testing.T) {
 		h.ServeHTTP(w, req)
 
 		res := w.Result()
-		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		assert.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
 	})
 }
 
@@ -464,7 +469,7 @@ func TestUpdateEntryHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -546,7 +551,7 @@ func TestUpdateEntryHandler(t *testing.T) {
 
 		// 応答の検証
 		res := w.Result()
-		assert.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
 
 		var actuals []Entry
 		err = tx.SelectContext(ctx, &actuals, "SELECT * FROM entry")
@@ -594,6 +599,63 @@ func TestUpdateEntryHandler(t *testing.T) {
 		assert.Equal(t, updateEntriesJson.Entries[1].Image, actuals[1].Image)
 		assert.Equal(t, updateEntriesJson.Entries[1].Content, actuals[1].Content)
 	})
+
+	var indexService = service.NewIndexService(
+		tx,
+		cookie.Store,
+		env,
+	)
+	mux := http.NewServeMux()
+	router.New(mux, alice.New()).Handle(Routes(indexService))
+	request := func(method, path string, body any) *httptest.ResponseRecorder {
+		eJson, err := json.Marshal(body)
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBuffer(eJson)))
+		return w
+	}
+
+	t.Run("存在しないidのentryは更新しない", func(t *testing.T) {
+		path := fmt.Sprintf("/api/entries/%d", f.Entrys[1].ID+100)
+		w := request(http.MethodPut, path, EntriesJson{updateEntriesJson.Entries[:1]})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = request(http.MethodPatch, path, map[string]any{"entries": []any{map[string]any{"name": "雪泉"}}})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		var count int64
+		err := tx.GetContext(ctx, &count, "SELECT COUNT(*) FROM entry WHERE name = 'テストエントリ3'")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("PATCHは送ったフィールドだけ更新する", func(t *testing.T) {
+		w := request(http.MethodPatch, fmt.Sprintf("/api/entries/%d", f.Entrys[0].ID), map[string]any{
+			"entries": []any{map[string]any{"name": "雪泉"}},
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		expected := updateEntriesJson.Entries[0]
+		expected.Name = "雪泉"
+		var actual EntriesJson
+		err := json.Unmarshal(w.Body.Bytes(), &actual)
+		assert.NoError(t, err)
+		assert.Equal(t, EntriesJson{[]Entry{expected}}, actual)
+
+		// 一括の場合は本文のidで更新する
+		w = request(http.MethodPatch, "/api/entries", map[string]any{
+			"entries": []any{map[string]any{"id": f.Entrys[1].ID, "content": "お姫ちん"}},
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var actuals []Entry
+		err = tx.SelectContext(ctx, &actuals, "SELECT * FROM entry ORDER BY id")
+		assert.NoError(t, err)
+		assert.Equal(t, "雪泉", actuals[0].Name)
+		assert.Equal(t, expected.Image, actuals[0].Image)
+		assert.Equal(t, expected.Content, actuals[0].Content)
+		assert.Equal(t, updateEntriesJson.Entries[1].Name, actuals[1].Name)
+		assert.Equal(t, "お姫ちん", actuals[1].Content)
+	})
 }
 
 func TestDeleteEntryHandler(t *testing.T) {
@@ -601,7 +663,7 @@ func TestDeleteEntryHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -0,0 +1,204 @@
+package entry
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/entry_image"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/media"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// imageField はmultipartで画像を送るフィールド名
+const imageField = "image"
+
+// multipartOverhead は画像以外のmultipartの境界やヘッダーに許容するサイズ
+const multipartOverhead = 1 << 20
+
+// ImageJson は画像をアップロードした結果
+type ImageJson struct {
+	EntryID    int64                  `json:"entry_id"`
+	Image      *media.Image           `json:"image"`
+	EntryImage *entryimage.EntryImage `json:"entry_image"`
+}
+
+type ImageHandler struct {
+	svc *service.IndexService
+}
+
+func NewImageHandler(svc *service.IndexService) *ImageHandler {
+	return &ImageHandler{
+		svc: svc,
+	}
+}
+
+// ServeHTTP はmultipartで送られた画像を保存し、entryのギャラリーの最後に追加する
+//
+//	POST /api/entries/{id}/image?primary=true&caption=...&nsfw=false&darkness=false
+//	(Content-Type: multipart/form-data, フィールド名: image)
+//
+// primaryを省略した場合は代表の画像にし、entryのimageを保存した画像のURLにする
+// 画像でない場合は422、ファイルサイズが大きすぎる場合は413を返す
+func (h *ImageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// POST以外は受け付けない
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	ctx := r.Context()
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		problem.Validation(w, r, validation.Errors{"id": fmt.Errorf("invalid id %q", r.URL.Query().Get("id"))})
+		return
+	}
+	entryImage, err := parseImageQuery(r)
+	if err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+	if h.svc.Images == nil {
+		problem.Internal(w, r, errors.New("image storage is not configured"))
+		return
+	}
+	var exists int64
+	err = h.svc.DB.GetContext(ctx, &exists, h.svc.DB.Rebind("SELECT id FROM entry WHERE id = ?"), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		problem.NotFound(w, r, fmt.Errorf("entry %d not found", id))
+		return
+	} else if err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+
+	// 画像を読み込む前に、大きすぎるリクエストは打ち切る
+	r.Body = http.MaxBytesReader(w, r.Body, h.svc.Images.MaxBytes()+multipartOverhead)
+	part, err := imagePart(r)
+	if err != nil {
+		uploadError(w, r, err)
+		return
+	}
+	img, err := h.svc.Images.Upload(ctx, part)
+	if err != nil {
+		uploadError(w, r, err)
+		return
+	}
+
+	entryImage.EntryID = id
+	entryImage.URL = img.URL
+	entryImage.ThumbnailURL = &img.ThumbnailURL
+	entryImage.WebPURL = &img.WebPURL
+	entryImage.ThumbnailWebPURL = &img.ThumbnailWebPURL
+	width, height := int64(img.Width), int64(img.Height)
+	entryImage.Width = &width
+	entryImage.Height = &height
+	if err := entryimage.Append(ctx, h.svc.DB, entryImage); err != nil {
+		h.discard(ctx, img)
+		problem.Database(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ImageJson{EntryID: id, Image: img, EntryImage: entryImage}); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
+
+// discard はギャラリーに追加できなかった画像を保存先から削除する
+// 同じ内容の画像は同じURLになるため、他の画像やentryが参照している場合は削除しない
+func (h *ImageHandler) discard(ctx context.Context, img *media.Image) {
+	var refs []int64
+	query := h.svc.DB.Rebind("SELECT id FROM entry_image WHERE url = ? UNION ALL SELECT id FROM entry WHERE image = ?")
+	if err := h.svc.DB.SelectContext(ctx, &refs, query, img.URL, img.URL); err != nil {
+		log.Printf("image discard error: %v", err)
+		return
+	}
+	if len(refs) > 0 {
+		return
+	}
+	if err := h.svc.Images.Remove(ctx, img); err != nil {
+		log.Printf("image discard error: %v", err)
+	}
+}
+
+// parseImageQuery はクエリパラメータからギャラリーに追加する画像の設定を読み込む
+func parseImageQuery(r *http.Request) (*entryimage.EntryImage, error) {
+	query := r.URL.Query()
+	img := &entryimage.EntryImage{
+		Primary: true,
+		Caption: query.Get("caption"),
+	}
+	errs := validation.Errors{}
+	for _, b := range []struct {
+		name string
+		dest *bool
+	}{
+		{"primary", &img.Primary},
+		{"nsfw", &img.Nsfw},
+		{"darkness", &img.Darkness},
+	} {
+		v := query.Get(b.name)
+		if v == "" {
+			continue
+		}
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			errs[b.name] = fmt.Errorf("invalid boolean %q", v)
+			continue
+		}
+		*b.dest = parsed
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return img, nil
+}
+
+// imagePart はmultipartのボディから画像のフィールドを探す
+// 画像より前のフィールドは読み飛ばす
+func imagePart(r *http.Request) (io.Reader, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, validation.Errors{imageField: err}
+	}
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, validation.Errors{imageField: errors.New("cannot be blank")}
+		} else if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return nil, err
+			}
+			return nil, validation.Errors{imageField: err}
+		}
+		if part.FormName() == imageField {
+			return part, nil
+		}
+	}
+}
+
+// uploadError はアップロードのエラーの種類に応じたレスポンスを書き込む
+func uploadError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	var verrs validation.Errors
+	switch {
+	case errors.Is(err, media.ErrTooLarge), errors.As(err, &tooLarge):
+		problem.TooLarge(w, r, err)
+	case errors.Is(err, media.ErrUnsupported):
+		problem.Validation(w, r, validation.Errors{imageField: err})
+	case errors.As(err, &verrs):
+		problem.Validation(w, r, verrs)
+	default:
+		problem.Internal(w, r, err)
+	}
+}
@@ -0,0 +1,310 @@
+package entry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/entry_image"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/media"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newImageRequest はfieldにdataを入れたmultipartのリクエストを返す
+func newImageRequest(t *testing.T, id string, field string, data []byte) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	assert.NoError(t, mw.WriteField("caption", "雪泉"))
+	fw, err := mw.CreateFormFile(field, "image.jpg")
+	assert.NoError(t, err)
+	_, err = fw.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, mw.Close())
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/entries/%s/image?id=%s", id, id), &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestImageEntryHandler(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+	// ロールバック
+	defer tx.RollbackCtx(ctx)
+	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	// データベースの準備
+	f := &fixtures.Fixture{DBv1: tx}
+	f.Build(t,
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "閃乱カグラ"
+			s.Url = "https://example.com/image1.png"
+			s.Type = "anime"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "雪泉"
+			s.Image = "https://example.com/image1.png"
+			s.Content = "かわいい"
+			s.CreatedAt = fixedTime
+		})),
+	)
+
+	var indexService = service.NewIndexService(
+		tx,
+		cookie.Store,
+		env,
+	)
+	dir := t.TempDir()
+	indexService.Images = media.NewUploader(media.NewLocalStorage(dir, "/images/"), 1<<20)
+	h := NewImageHandler(indexService)
+
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for x := 0; x < 400; x++ {
+		img.Set(x, 100, color.RGBA{0xe4, 0x00, 0x7f, 0xff})
+	}
+	var jpg bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&jpg, img, nil))
+	id := fmt.Sprint(f.Entrys[0].ID)
+
+	t.Run("画像をアップロード", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, newImageRequest(t, id, "image", jpg.Bytes()))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var res ImageJson
+		err := json.Unmarshal(w.Body.Bytes(), &res)
+		assert.NoError(t, err)
+		assert.Equal(t, f.Entrys[0].ID, res.EntryID)
+		assert.True(t, strings.HasPrefix(res.Image.URL, "/images/"))
+		assert.Equal(t, 400, res.Image.Width)
+		assert.True(t, strings.HasSuffix(res.Image.ThumbnailURL, "/thumb.jpg"))
+		assert.True(t, strings.HasSuffix(res.Image.WebPURL, "/large.webp"))
+
+		var actual string
+		err = tx.GetContext(ctx, &actual, "SELECT image FROM entry WHERE id = $1", f.Entrys[0].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, res.Image.URL, actual)
+
+		// ギャラリーに代表の画像として追加する
+		assert.Equal(t, res.Image.URL, res.EntryImage.URL)
+		assert.Equal(t, true, res.EntryImage.Primary)
+		assert.Equal(t, int64(0), res.EntryImage.Position)
+	})
+
+	t.Run("代表にしない画像をアップロード", func(t *testing.T) {
+		// 内容が異なる画像にする
+		img.Set(0, 0, color.White)
+		var other bytes.Buffer
+		assert.NoError(t, jpeg.Encode(&other, img, nil))
+		req := newImageRequest(t, id, "image", other.Bytes())
+		req.URL.RawQuery += "&primary=false&nsfw=true&caption=" + url.QueryEscape("水着")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var res ImageJson
+		err := json.Unmarshal(w.Body.Bytes(), &res)
+		assert.NoError(t, err)
+		assert.Equal(t, false, res.EntryImage.Primary)
+		assert.Equal(t, true, res.EntryImage.Nsfw)
+		assert.Equal(t, "水着", res.EntryImage.Caption)
+		assert.Equal(t, int64(1), res.EntryImage.Position)
+
+		// entry.imageは変更しない
+		var actual string
+		err = tx.GetContext(ctx, &actual, "SELECT image FROM entry WHERE id = $1", f.Entrys[0].ID)
+		assert.NoError(t, err)
+		assert.NotEqual(t, res.Image.URL, actual)
+	})
+
+	t.Run("ギャラリーに追加できない場合は保存した画像を削除する", func(t *testing.T) {
+		// 同じentryに同じ説明文の画像を追加できないようにする
+		_, err := tx.ExecContext(ctx, "CREATE UNIQUE INDEX entry_image_caption_test ON entry_image (entry_id) WHERE caption = '重複'")
+		assert.NoError(t, err)
+		_, err = tx.ExecContext(ctx, tx.Rebind("INSERT INTO entry_image (entry_id, url, caption) VALUES (?, ?, '重複')"), f.Entrys[0].ID, "https://example.com/image1.png")
+		assert.NoError(t, err)
+		defer tx.ExecContext(ctx, "DROP INDEX entry_image_caption_test")
+
+		upload := func(data []byte) string {
+			req := newImageRequest(t, id, "image", data)
+			req.URL.RawQuery += "&caption=" + url.QueryEscape("重複")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			sum := sha256.Sum256(data)
+			return filepath.Join(dir, hex.EncodeToString(sum[:]), "large.jpg")
+		}
+
+		// 他の画像が参照している画像は削除しない
+		_, err = os.Stat(upload(jpg.Bytes()))
+		assert.NoError(t, err)
+
+		img.Set(1, 1, color.White)
+		var other bytes.Buffer
+		assert.NoError(t, jpeg.Encode(&other, img, nil))
+		_, err = os.Stat(upload(other.Bytes()))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("primaryの形式が正しくない", func(t *testing.T) {
+		req := newImageRequest(t, id, "image", jpg.Bytes())
+		req.URL.RawQuery += "&primary=aaa"
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("画像でない", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, newImageRequest(t, id, "image", []byte("<html></html>")))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("画像がない", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, newImageRequest(t, id, "file", jpg.Bytes()))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("ファイルサイズが大きすぎる", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, newImageRequest(t, id, "image", bytes.Repeat([]byte{0xff}, 2<<20)))
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("存在しないentry", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, newImageRequest(t, "0", "image", jpg.Bytes()))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("idの形式が正しくない", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, newImageRequest(t, "aaa", "image", jpg.Bytes()))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+}
+
+func TestDeletePrimaryEntryImage(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+	// ロールバック
+	defer tx.RollbackCtx(ctx)
+	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	// データベースの準備
+	f := &fixtures.Fixture{DBv1: tx}
+	f.Build(t,
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "閃乱カグラ"
+			s.Url = "https://example.com/image1.png"
+			s.Type = "anime"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "雪泉"
+			s.Image = "https://example.com/image1.png"
+			s.Content = "かわいい"
+			s.CreatedAt = fixedTime
+		}).Connect(
+			fixtures.NewEntryImage(ctx, func(s *fixtures.EntryImage) {
+				s.URL = "https://example.com/image1.png"
+				s.Primary = true
+			}),
+			fixtures.NewEntryImage(ctx, func(s *fixtures.EntryImage) {
+				s.URL = "https://example.com/swimsuit.png"
+				s.Position = 1
+				s.Nsfw = true
+			}),
+			fixtures.NewEntryImage(ctx, func(s *fixtures.EntryImage) {
+				s.URL = "https://example.com/uniform.png"
+				s.Position = 2
+			}),
+		)),
+	)
+
+	var indexService = service.NewIndexService(
+		tx,
+		cookie.Store,
+		env,
+	)
+
+	// entryを取得してそのまま更新する
+	roundTrip := func(t *testing.T) Entry {
+		w := httptest.NewRecorder()
+		NewReadHandler(indexService).ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/entry/read?id=%d", f.Entrys[0].ID), nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		var actual EntriesJson
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+		assert.Len(t, actual.Entries, 1)
+
+		eJson, err := json.Marshal(&actual)
+		assert.NoError(t, err)
+		w = httptest.NewRecorder()
+		NewUpdateHandler(indexService).ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/entry/update", bytes.NewBuffer(eJson)))
+		assert.Equal(t, http.StatusOK, w.Code)
+		return actual.Entries[0]
+	}
+	deleteImage := func(t *testing.T, id int64) {
+		dJson, err := json.Marshal(map[string][]int64{"ids": {id}})
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		entryimage.NewDeleteHandler(indexService).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/entry_images", bytes.NewBuffer(dJson)))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	t.Run("代表の画像を削除した後もentryを更新できる", func(t *testing.T) {
+		deleteImage(t, f.EntryImages[0].ID)
+
+		// nsfwの画像は使わず、表示順で次の画像にする
+		actual := roundTrip(t)
+		assert.Equal(t, "https://example.com/uniform.png", actual.Image)
+	})
+
+	t.Run("除外しない画像がなくなってもentryを更新できる", func(t *testing.T) {
+		// entry.imageの画像を削除しても、nsfwの画像にはしない
+		deleteImage(t, f.EntryImages[2].ID)
+
+		actual := roundTrip(t)
+		assert.Equal(t, "https://example.com/uniform.png", actual.Image)
+	})
+}
@@ -0,0 +1,238 @@
+// Package entryfilter は属性の組み合わせでentryを絞り込む条件を組み立てる
+// /api/facetsや/api/statsなど、entryを集計するAPIで同じ条件を使う
+package entryfilter
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/maguro-alternative/goheki/pkg/db"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// Attribute は種類のidで絞り込む属性
+type Attribute struct {
+	// クエリパラメータ名とレスポンスのキー
+	Name string
+	// entryと種類を紐づけるテーブルと種類のidの列
+	Table  string
+	Column string
+	// 種類のテーブルと名前の列
+	TypeTable  string
+	TypeColumn string
+	// trueの場合は指定した全ての値を持つものに絞り込む (タグ)
+	// falseの場合はいずれかの値を持つものに絞り込む
+	All bool
+}
+
+// Attributes は絞り込みと集計の対象の属性
+var Attributes = []Attribute{
+	{Name: "haircolor", Table: "haircolor", Column: "color_id", TypeTable: "haircolor_type", TypeColumn: "color"},
+	{Name: "hairlength", Table: "hairlength", Column: "hairlength_type_id", TypeTable: "hairlength_type", TypeColumn: "length"},
+	{Name: "hairstyle", Table: "hairstyle", Column: "style_id", TypeTable: "hairstyle_type", TypeColumn: "style"},
+	{Name: "eyecolor", Table: "eyecolor", Column: "color_id", TypeTable: "eyecolor_type", TypeColumn: "color"},
+	{Name: "personality", Table: "personality", Column: "type_id", TypeTable: "personality_type", TypeColumn: "type"},
+	{Name: "tag", Table: "entry_tag", Column: "tag_id", TypeTable: "tag", TypeColumn: "name", All: true},
+}
+
+// SourceType は作品の種類で絞り込むクエリパラメータ名
+const SourceType = "source_type"
+
+// From は絞り込みの対象となるentry(e)と作品(s)
+const From = "FROM entry e JOIN source s ON s.id = e.source_id"
+
+// RangeColumn は範囲で絞り込む数値の列
+type RangeColumn struct {
+	// クエリパラメータ名とレスポンスのキー
+	Name string
+	// 値を持つテーブルと列
+	Table  string
+	Column string
+	// レーダーチャートの軸の場合は軸のid
+	AxisID int64
+}
+
+// Scope は列の値を持つ行を絞り込む条件を返す
+// レーダーチャートの軸の場合は軸のidで絞り込む
+func (c RangeColumn) Scope() (string, []any) {
+	if c.Table != RadarTable {
+		return "", nil
+	}
+	return " AND axis_id = ?", []any{c.AxisID}
+}
+
+// RadarTable はレーダーチャートの軸ごとの値のテーブル
+const RadarTable = "heki_radar_score"
+
+// BWHColumns はbwhの数値の列
+var BWHColumns = []RangeColumn{
+	{Name: "bust", Table: "bwh", Column: "bust"},
+	{Name: "waist", Table: "bwh", Column: "waist"},
+	{Name: "hip", Table: "bwh", Column: "hip"},
+	{Name: "height", Table: "bwh", Column: "height"},
+	{Name: "weight", Table: "bwh", Column: "weight"},
+}
+
+// Axis はレーダーチャートの軸
+type Axis struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+// LoadAxes は絞り込みに使うレーダーチャートの軸を表示順に返す
+func LoadAxes(ctx context.Context, driver db.Driver) ([]Axis, error) {
+	var axes []Axis
+	if err := driver.SelectContext(ctx, &axes, "SELECT id, name FROM heki_radar_axis ORDER BY display_order, id"); err != nil {
+		return nil, err
+	}
+	return axes, nil
+}
+
+// RangeColumns はbwhの列とレーダーチャートの軸を返す
+// 軸の名前がbwhの列と同じ場合はbwhの列を優先する
+func RangeColumns(axes []Axis) []RangeColumn {
+	columns := append([]RangeColumn(nil), BWHColumns...)
+	for _, axis := range axes {
+		if _, ok := FindRangeColumn(BWHColumns, axis.Name); ok {
+			continue
+		}
+		columns = append(columns, RangeColumn{Name: axis.Name, Table: RadarTable, Column: "score", AxisID: axis.ID})
+	}
+	return columns
+}
+
+var rangeOperators = map[string]string{
+	"eq":  "=",
+	"gt":  ">",
+	"gte": ">=",
+	"lt":  "<",
+	"lte": "<=",
+}
+
+// rangeParam は column[op] 形式のクエリパラメータ
+// 軸の名前には日本語を使えるため、列名は[]以外の文字とする
+var rangeParam = regexp.MustCompile(`^([^\[\]]+)\[(\w+)\]$`)
+
+// Condition はentryを絞り込む条件
+// Keyは条件を指定した属性で、その属性の件数を数える際は条件から除く
+type Condition struct {
+	Key   string
+	Query string
+	Args  []any
+}
+
+// Parse はクエリパラメータから絞り込みの条件を作成する
+//
+//	?haircolor=1&haircolor=2 → 髪色が1または2
+//	?tag=1&tag=2             → タグ1と2の両方を持つ
+//	?source_type=game        → 作品の種類がgame
+//	?ai[gte]=80              → レーダーチャートの軸aiの値が80以上
+func Parse(query url.Values, columns []RangeColumn) ([]Condition, error) {
+	errs := validation.Errors{}
+	var conditions []Condition
+	for _, attr := range Attributes {
+		values := query[attr.Name]
+		if len(values) == 0 {
+			continue
+		}
+		ids, err := parseInts(values)
+		if err != nil {
+			errs[attr.Name] = err
+			continue
+		}
+		sub := fmt.Sprintf("e.id IN (SELECT entry_id FROM %s WHERE %s IN (?))", attr.Table, attr.Column)
+		if !attr.All {
+			conditions = append(conditions, Condition{Key: attr.Name, Query: sub, Args: []any{ids}})
+			continue
+		}
+		for _, id := range ids {
+			conditions = append(conditions, Condition{Key: attr.Name, Query: sub, Args: []any{[]int64{id}}})
+		}
+	}
+	if values := query[SourceType]; len(values) > 0 {
+		conditions = append(conditions, Condition{Key: SourceType, Query: "s.type IN (?)", Args: []any{values}})
+	}
+
+	params := make([]string, 0, len(query))
+	for param := range query {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+	for _, param := range params {
+		m := rangeParam.FindStringSubmatch(param)
+		if m == nil {
+			continue
+		}
+		column, ok := FindRangeColumn(columns, m[1])
+		if !ok {
+			errs[param] = fmt.Errorf("unknown column %q", m[1])
+			continue
+		}
+		op, ok := rangeOperators[m[2]]
+		if !ok {
+			errs[param] = fmt.Errorf("unknown operator %q", m[2])
+			continue
+		}
+		n, err := strconv.ParseInt(query.Get(param), 10, 64)
+		if err != nil {
+			errs[param] = fmt.Errorf("invalid number %q", query.Get(param))
+			continue
+		}
+		scope, args := column.Scope()
+		conditions = append(conditions, Condition{
+			Key:   column.Name,
+			Query: fmt.Sprintf("e.id IN (SELECT entry_id FROM %s WHERE %s %s ?%s)", column.Table, column.Column, op, scope),
+			Args:  append([]any{n}, args...),
+		})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return conditions, nil
+}
+
+// FindRangeColumn は名前が一致する列を返す
+func FindRangeColumn(columns []RangeColumn, name string) (RangeColumn, bool) {
+	for _, column := range columns {
+		if column.Name == name {
+			return column, true
+		}
+	}
+	return RangeColumn{}, false
+}
+
+func parseInts(values []string) ([]int64, error) {
+	ids := make([]int64, len(values))
+	for i, v := range values {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q", v)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// Where はexceptを除く条件をANDでつないだWHERE句と引数を返す
+// 条件がない場合は空文字を返す
+func Where(conditions []Condition, except string) (string, []any) {
+	var clauses []string
+	var args []any
+	for _, c := range conditions {
+		if except != "" && c.Key == except {
+			continue
+		}
+		clauses = append(clauses, c.Query)
+		args = append(args, c.Args...)
+	}
+	if len(clauses) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(clauses, " AND "), args
+}
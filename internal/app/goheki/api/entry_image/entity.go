@@ -0,0 +1,48 @@
+package entryimage
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// EntryImage はentryのギャラリーの画像1枚
+// アップロードした画像の場合は縮小した画像とWebPのURLも持つ
+type EntryImage struct {
+	ID               int64   `db:"id" json:"id"`
+	EntryID          int64   `db:"entry_id" json:"entry_id"`
+	URL              string  `db:"url" json:"url"`
+	ThumbnailURL     *string `db:"thumbnail_url" json:"thumbnail_url"`
+	WebPURL          *string `db:"webp_url" json:"webp_url"`
+	ThumbnailWebPURL *string `db:"thumbnail_webp_url" json:"thumbnail_webp_url"`
+	Width            *int64  `db:"width" json:"width"`
+	Height           *int64  `db:"height" json:"height"`
+	Caption          string  `db:"caption" json:"caption"`
+	// ギャラリーでの表示順 (昇順)
+	Position int64 `db:"position" json:"position"`
+	// entryの代表の画像か (entryごとに1枚)
+	Primary  bool `db:"is_primary" json:"is_primary"`
+	Nsfw     bool `db:"nsfw" json:"nsfw"`
+	Darkness bool `db:"darkness" json:"darkness"`
+}
+
+func (e *EntryImage) Validate() error {
+	return validation.ValidateStruct(e,
+		validation.Field(&e.EntryID, validation.Required),
+		validation.Field(&e.URL, validation.Required),
+		validation.Field(&e.Width, validation.Min(1)),
+		validation.Field(&e.Height, validation.Min(1)),
+		validation.Field(&e.Position, validation.Min(0)),
+		validation.Field(&e.Primary, validation.In(false, true)),
+		validation.Field(&e.Nsfw, validation.In(false, true)),
+		validation.Field(&e.Darkness, validation.In(false, true)),
+	)
+}
+
+type EntryImagesJson struct {
+	EntryImages []EntryImage `json:"entry_images"`
+}
+
+func (e *EntryImagesJson) Validate() error {
+	return validation.ValidateStruct(e,
+		validation.Field(&e.EntryImages, validation.Required),
+	)
+}
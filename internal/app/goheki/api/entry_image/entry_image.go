@@ -0,0 +1,145 @@
+package entryimage
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/content_filter"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/resource"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/pkg/db"
+)
+
+// Resource はentry_imageテーブルのCRUDの定義
+var Resource = &resource.Resource[EntryImage]{
+	Table:   "entry_image",
+	Key:     "id",
+	ListKey: "entry_images",
+	Columns: []string{
+		"entry_id",
+		"url",
+		"thumbnail_url",
+		"webp_url",
+		"thumbnail_webp_url",
+		"width",
+		"height",
+		"caption",
+		"position",
+		"is_primary",
+		"nsfw",
+		"darkness",
+	},
+	Defaults: []string{
+		"caption",
+		"position",
+		"is_primary",
+		"nsfw",
+		"darkness",
+	},
+	Validate: (*EntryImage).Validate,
+	// nsfwとdarknessの画像はリンクと同じ設定で除外する
+	Withhold: func(r *http.Request, svc *service.IndexService) (string, []any, error) {
+		policy, err := contentfilter.FromRequest(r, svc)
+		if err != nil {
+			return "", nil, err
+		}
+		return policy.Exclude(""), nil, nil
+	},
+	AfterWrite:   syncPrimary,
+	BeforeDelete: releaseImage,
+}
+
+// syncPrimary はimgが代表の画像の場合に、同じentryの他の画像を代表から外す
+// 互換性のため、代表の画像のURLをentry.imageにも保存する
+func syncPrimary(ctx context.Context, driver db.Driver, img *EntryImage) error {
+	if img.Primary {
+		// DBの種類に合わせて置換文字を変える
+		query := driver.Rebind("UPDATE entry_image SET is_primary = FALSE WHERE entry_id = ? AND id <> ? AND is_primary")
+		if _, err := driver.ExecContext(ctx, query, img.EntryID, img.ID); err != nil {
+			return err
+		}
+	}
+	// entry.imageはリンクと同じ設定で除外されないため、nsfwとdarknessの画像は保存しない
+	// 代表の画像がないか除外する画像の場合は、ギャラリーの画像を指しているentry.imageを表示順で次の画像に変える
+	// 除外しない画像がない場合は、entry.imageは必須のため元の値のままにする
+	query := driver.Rebind(`UPDATE entry SET image = COALESCE(
+			(SELECT url FROM entry_image WHERE entry_id = ? AND is_primary AND NOT nsfw AND NOT darkness),
+			CASE WHEN image IN (SELECT url FROM entry_image WHERE entry_id = ?) THEN (
+				SELECT url FROM entry_image WHERE entry_id = ? AND NOT nsfw AND NOT darkness ORDER BY position, id LIMIT 1
+			) END,
+			image
+		) WHERE id = ?`)
+	_, err := driver.ExecContext(ctx, query, img.EntryID, img.EntryID, img.EntryID, img.EntryID)
+	return err
+}
+
+// releaseImage は削除する画像を指しているentry.imageを、削除しない画像のうち表示順で次の画像に変える
+// nsfwとdarknessの画像は使わず、該当する画像がない場合は元の値のままにする
+func releaseImage(ctx context.Context, driver db.Driver, ids []int64) error {
+	query, args, err := db.In(`UPDATE entry SET image = COALESCE(
+			(
+				SELECT url FROM entry_image
+				WHERE entry_id = entry.id AND NOT nsfw AND NOT darkness AND id NOT IN (?)
+				ORDER BY position, id LIMIT 1
+			),
+			image
+		) WHERE image IN (
+			SELECT url FROM entry_image WHERE entry_id = entry.id AND id IN (?)
+		)`, ids, ids)
+	if err != nil {
+		return err
+	}
+	// DBの種類に合わせて置換文字を変える
+	_, err = driver.ExecContext(ctx, driver.Rebind(query), args...)
+	return err
+}
+
+// Append はimgをentryのギャラリーの最後に追加する
+// 代表の画像の場合はentry.imageも更新する
+func Append(ctx context.Context, driver db.Driver, img *EntryImage) error {
+	return db.WithTx(ctx, driver, func(tx db.Driver) error {
+		query := tx.Rebind("SELECT COALESCE(MAX(position) + 1, 0) FROM entry_image WHERE entry_id = ?")
+		if err := tx.GetContext(ctx, &img.Position, query, img.EntryID); err != nil {
+			return err
+		}
+		return Resource.Insert(ctx, tx, img)
+	})
+}
+
+type CreateHandler = resource.CreateHandler[EntryImage]
+
+func NewCreateHandler(svc *service.IndexService) *CreateHandler {
+	return resource.NewCreateHandler(svc, Resource)
+}
+
+type ReadHandler = resource.ReadHandler[EntryImage]
+
+func NewReadHandler(svc *service.IndexService) *ReadHandler {
+	return resource.NewReadHandler(svc, Resource)
+}
+
+type UpdateHandler = resource.UpdateHandler[EntryImage]
+
+func NewUpdateHandler(svc *service.IndexService) *UpdateHandler {
+	return resource.NewUpdateHandler(svc, Resource)
+}
+
+type DeleteHandler = resource.DeleteHandler[EntryImage]
+
+func NewDeleteHandler(svc *service.IndexService) *DeleteHandler {
+	return resource.NewDeleteHandler(svc, Resource)
+}
+
+// Routes は/api/entry_imagesのルーティングの定義を返す
+// 代表の画像を削除した場合や代表から外した場合はentry.imageを表示順で次の画像に変える
+func Routes(svc *service.IndexService) router.Resource {
+	return router.Resource{
+		Name:   "entry_images",
+		Key:    Resource.Key,
+		Create: NewCreateHandler(svc),
+		Read:   NewReadHandler(svc),
+		Update: NewUpdateHandler(svc),
+		Delete: NewDeleteHandler(svc),
+	}
+}
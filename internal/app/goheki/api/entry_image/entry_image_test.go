@@ -0,0 +1,453 @@
+package entryimage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateEntryImageHandler(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+	// ロールバック
+	defer tx.RollbackCtx(ctx)
+	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	// データベースの準備
+	f := &fixtures.Fixture{DBv1: tx}
+	f.Build(t,
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "閃乱カグラ"
+			s.Url = "https://example.com/image1.png"
+			s.Type = "anime"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "雪泉"
+			s.Image = "https://example.com/image1.png"
+			s.Content = "かわいい"
+			s.CreatedAt = fixedTime
+		}).Connect(fixtures.NewEntryImage(ctx, func(s *fixtures.EntryImage) {
+			s.URL = "https://example.com/image1.png"
+			s.Primary = true
+		}))),
+	)
+
+	var indexService = service.NewIndexService(
+		tx,
+		cookie.Store,
+		env,
+	)
+
+	t.Run("entry_image作成失敗", func(t *testing.T) {
+		h := NewCreateHandler(indexService)
+		eJson, err := json.Marshal(EntryImagesJson{[]EntryImage{{EntryID: f.Entrys[0].ID}}})
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/api/entry_images", bytes.NewBuffer(eJson))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+		var count int64
+		err = tx.GetContext(ctx, &count, "SELECT COUNT(*) FROM entry_image")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("entry_image作成", func(t *testing.T) {
+		h := NewCreateHandler(indexService)
+		eJson, err := json.Marshal(EntryImagesJson{[]EntryImage{
+			{
+				EntryID:  f.Entrys[0].ID,
+				URL:      "https://example.com/yumi-swimsuit.png",
+				Caption:  "水着",
+				Position: 1,
+				Nsfw:     true,
+			},
+			{
+				EntryID:  f.Entrys[0].ID,
+				URL:      "https://example.com/yumi-uniform.png",
+				Caption:  "制服",
+				Position: 2,
+				Primary:  true,
+			},
+		}})
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/api/entry_images", bytes.NewBuffer(eJson))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var res EntryImagesJson
+		err = json.Unmarshal(w.Body.Bytes(), &res)
+		assert.NoError(t, err)
+		assert.Len(t, res.EntryImages, 2)
+		assert.NotZero(t, res.EntryImages[0].ID)
+		assert.Equal(t, "水着", res.EntryImages[0].Caption)
+		assert.Equal(t, true, res.EntryImages[0].Nsfw)
+		assert.Equal(t, false, res.EntryImages[0].Primary)
+		assert.Equal(t, true, res.EntryImages[1].Primary)
+
+		// 代表の画像は1枚だけになる
+		var primaries []int64
+		err = tx.SelectContext(ctx, &primaries, "SELECT id FROM entry_image WHERE is_primary")
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{res.EntryImages[1].ID}, primaries)
+
+		// 代表の画像のURLをentry.imageにも保存する
+		var image string
+		err = tx.GetContext(ctx, &image, "SELECT image FROM entry WHERE id = $1", f.Entrys[0].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/yumi-uniform.png", image)
+	})
+}
+
+func TestReadEntryImageHandler(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+	// ロールバック
+	defer tx.RollbackCtx(ctx)
+	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	// データベースの準備
+	f := &fixtures.Fixture{DBv1: tx}
+	f.Build(t,
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "アイドルマスター"
+			s.Url = "https://example.com/image2.png"
+			s.Type = "game"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "四条貴音"
+			s.Image = "https://example.com/image2.png"
+			s.Content = "お姫ちん"
+			s.CreatedAt = fixedTime
+		}).Connect(
+			fixtures.NewEntryImage(ctx, func(s *fixtures.EntryImage) {
+				s.URL = "https://example.com/takane-darkness.png"
+				s.Position = 2
+				s.Darkness = true
+			}),
+			fixtures.NewEntryImage(ctx, func(s *fixtures.EntryImage) {
+				s.URL = "https://example.com/image2.png"
+				s.Position = 0
+				s.Primary = true
+			}),
+			fixtures.NewEntryImage(ctx, func(s *fixtures.EntryImage) {
+				s.URL = "https://example.com/takane-stage.png"
+				s.Position = 1
+			}),
+		)),
+	)
+
+	var indexService = service.NewIndexService(
+		tx,
+		cookie.Store,
+		env,
+	)
+	h := NewReadHandler(indexService)
+
+	t.Run("entry_imageを表示順に取得", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/entry_images?entry_id[eq]=%d&sort=position", f.Entrys[0].ID), nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var res EntryImagesJson
+		err := json.Unmarshal(w.Body.Bytes(), &res)
+		assert.NoError(t, err)
+		assert.Len(t, res.EntryImages, 3)
+		assert.Equal(t, f.EntryImages[1].ID, res.EntryImages[0].ID)
+		assert.Equal(t, f.EntryImages[2].ID, res.EntryImages[1].ID)
+		assert.Equal(t, f.EntryImages[0].ID, res.EntryImages[2].ID)
+	})
+
+	t.Run("darknessのentry_imageを隠す", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/entry_images?hide_darkness=true", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var res struct {
+			EntryImages []EntryImage `json:"entry_images"`
+			Withheld    int64        `json:"withheld"`
+		}
+		err := json.Unmarshal(w.Body.Bytes(), &res)
+		assert.NoError(t, err)
+		assert.Len(t, res.EntryImages, 2)
+		assert.Equal(t, int64(1), res.Withheld)
+	})
+}
+
+func TestUpdateEntryImageHandler(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+	// ロールバック
+	defer tx.RollbackCtx(ctx)
+	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	// データベースの準備
+	f := &fixtures.Fixture{DBv1: tx}
+	f.Build(t,
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "アイドルマスター"
+			s.Url = "https://example.com/image2.png"
+			s.Type = "game"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "四条貴音"
+			s.Image = "https://example.com/image2.png"
+			s.Content = "お姫ちん"
+			s.CreatedAt = fixedTime
+		}).Connect(
+			fixtures.NewEntryImage(ctx, func(s *fixtures.EntryImage) {
+				s.URL = "https://example.com/image2.png"
+				s.Primary = true
+			}),
+			fixtures.NewEntryImage(ctx, func(s *fixtures.EntryImage) {
+				s.URL = "https://example.com/takane-stage.png"
+				s.Position = 1
+			}),
+		)),
+	)
+
+	var indexService = service.NewIndexService(
+		tx,
+		cookie.Store,
+		env,
+	)
+	h := NewUpdateHandler(indexService)
+
+	t.Run("代表の画像を変更", func(t *testing.T) {
+		eJson, err := json.Marshal(EntryImagesJson{[]EntryImage{
+			{
+				ID:       f.EntryImages[1].ID,
+				EntryID:  f.Entrys[0].ID,
+				URL:      "https://example.com/takane-stage.png",
+				Caption:  "ステージ衣装",
+				Position: 1,
+				Primary:  true,
+			},
+		}})
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPut, "/api/entry_images", bytes.NewBuffer(eJson))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var actual []EntryImage
+		err = tx.SelectContext(ctx, &actual, "SELECT * FROM entry_image ORDER BY position")
+		assert.NoError(t, err)
+		assert.Len(t, actual, 2)
+		assert.Equal(t, false, actual[0].Primary)
+		assert.Equal(t, true, actual[1].Primary)
+		assert.Equal(t, "ステージ衣装", actual[1].Caption)
+
+		var image string
+		err = tx.GetContext(ctx, &image, "SELECT image FROM entry WHERE id = $1", f.Entrys[0].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/takane-stage.png", image)
+	})
+
+	t.Run("存在しないentry_imageは代表にしない", func(t *testing.T) {
+		eJson, err := json.Marshal(EntryImagesJson{[]EntryImage{
+			{
+				ID:      f.EntryImages[1].ID + 100,
+				EntryID: f.Entrys[0].ID,
+				URL:     "https://example.com/unknown.png",
+				Primary: true,
+			},
+		}})
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPut, "/api/entry_images", bytes.NewBuffer(eJson))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var image string
+		err = tx.GetContext(ctx, &image, "SELECT image FROM entry WHERE id = $1", f.Entrys[0].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/takane-stage.png", image)
+	})
+
+	t.Run("nsfwの代表の画像はentry.imageに保存しない", func(t *testing.T) {
+		eJson, err := json.Marshal(EntryImagesJson{[]EntryImage{
+			{
+				ID:       f.EntryImages[1].ID,
+				EntryID:  f.Entrys[0].ID,
+				URL:      "https://example.com/takane-stage.png",
+				Caption:  "ステージ衣装",
+				Position: 1,
+				Primary:  true,
+				Nsfw:     true,
+			},
+		}})
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPut, "/api/entry_images", bytes.NewBuffer(eJson))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// 表示順で次の除外しない画像にする
+		var image string
+		err = tx.GetContext(ctx, &image, "SELECT image FROM entry WHERE id = $1", f.Entrys[0].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/image2.png", image)
+	})
+
+	t.Run("代表から外した場合は表示順で次の画像にする", func(t *testing.T) {
+		eJson, err := json.Marshal(EntryImagesJson{[]EntryImage{
+			{
+				ID:       f.EntryImages[0].ID,
+				EntryID:  f.Entrys[0].ID,
+				URL:      "https://example.com/image2.png",
+				Position: 2,
+			},
+			{
+				ID:       f.EntryImages[1].ID,
+				EntryID:  f.Entrys[0].ID,
+				URL:      "https://example.com/takane-stage.png",
+				Caption:  "ステージ衣装",
+				Position: 1,
+			},
+		}})
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPut, "/api/entry_images", bytes.NewBuffer(eJson))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var image string
+		err = tx.GetContext(ctx, &image, "SELECT image FROM entry WHERE id = $1", f.Entrys[0].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/takane-stage.png", image)
+	})
+
+	t.Run("除外しない画像がない場合はentry.imageを変更しない", func(t *testing.T) {
+		eJson, err := json.Marshal(EntryImagesJson{[]EntryImage{
+			{
+				ID:       f.EntryImages[0].ID,
+				EntryID:  f.Entrys[0].ID,
+				URL:      "https://example.com/image2.png",
+				Position: 2,
+				Darkness: true,
+			},
+			{
+				ID:       f.EntryImages[1].ID,
+				EntryID:  f.Entrys[0].ID,
+				URL:      "https://example.com/takane-stage.png",
+				Caption:  "ステージ衣装",
+				Position: 1,
+				Nsfw:     true,
+			},
+		}})
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPut, "/api/entry_images", bytes.NewBuffer(eJson))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var image string
+		err = tx.GetContext(ctx, &image, "SELECT image FROM entry WHERE id = $1", f.Entrys[0].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/takane-stage.png", image)
+	})
+}
+
+func TestDeleteEntryImageHandler(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+	// ロールバック
+	defer tx.RollbackCtx(ctx)
+	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	// データベースの準備
+	f := &fixtures.Fixture{DBv1: tx}
+	f.Build(t,
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "閃乱カグラ"
+			s.Url = "https://example.com/image1.png"
+			s.Type = "anime"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "雪泉"
+			s.Image = "https://example.com/image1.png"
+			s.Content = "かわいい"
+			s.CreatedAt = fixedTime
+		}).Connect(fixtures.NewEntryImage(ctx, func(s *fixtures.EntryImage) {
+			s.URL = "https://example.com/image1.png"
+			s.Primary = true
+		}))),
+	)
+
+	var indexService = service.NewIndexService(
+		tx,
+		cookie.Store,
+		env,
+	)
+
+	t.Run("entry_image削除", func(t *testing.T) {
+		h := NewDeleteHandler(indexService)
+		dJson, err := json.Marshal(map[string][]int64{"ids": {f.EntryImages[0].ID}})
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodDelete, "/api/entry_images", bytes.NewBuffer(dJson))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var count int64
+		err = tx.GetContext(ctx, &count, "SELECT COUNT(*) FROM entry_image")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), count)
+
+		// 他の画像がない場合はentry.imageを変更しない
+		var image string
+		err = tx.GetContext(ctx, &image, "SELECT image FROM entry WHERE id = $1", f.Entrys[0].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/image1.png", image)
+	})
+}
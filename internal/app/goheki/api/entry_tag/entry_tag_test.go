@@ -14,8 +14,6 @@ import (
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
 
-	"github.com/maguro-alternative/goheki/pkg/db"
-
 	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
 
 	"github.com/stretchr/testify/assert"
@@ -26,7 +24,7 @@ func TestCreateEntryTagHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -152,7 +150,7 @@ func TestReadEntryTagHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -397,7 +395,7 @@ func TestReadEntryTagHandler(t *testing.T) {
 
 		// 応答の検証
 		r := w.Result()
-		assert.Equal(t, http.StatusInternalServerError, r.StatusCode)
+		assert.Equal(t, http.StatusUnprocessableEntity, r.StatusCode)
 	})
 
 	t.Run("entry_tad2件取得(内1件は形式が正しくない)", func(t *testing.T) {
@@ -423,7 +421,7 @@ func TestReadEntryTagHandler(t *testing.T) {
 
 		// 応答の検証
 		r := w.Result()
-		assert.Equal(t, http.StatusInternalServerError, r.StatusCode)
+		assert.Equal(t, http.StatusUnprocessableEntity, r.StatusCode)
 	})
 }
 
@@ -432,7 +430,7 @@ func TestUpdateEntryHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -574,7 +572,7 @@ func TestDeleteEntryTagHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
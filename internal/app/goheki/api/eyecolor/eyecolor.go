@@ -1,320 +1,55 @@
 package eyecolor
 
 import (
-	"fmt"
-	"log"
-	"io"
-
+	"github.com/maguro-alternative/goheki/internal/app/goheki/resource"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
-	"github.com/maguro-alternative/goheki/pkg/db"
-
-	"encoding/json"
-	"net/http"
 )
 
-type CreateHandler struct {
-	svc *service.IndexService
+// Resource はeyecolorテーブルのCRUDの定義
+var Resource = &resource.Resource[EyeColor]{
+	Table:   "eyecolor",
+	Key:     "entry_id",
+	ListKey: "eyecolors",
+	Columns: []string{
+		"entry_id",
+		"color_id",
+	},
+	Validate: (*EyeColor).Validate,
 }
 
-func NewCreateHandler(svc *service.IndexService) *CreateHandler {
-	return &CreateHandler{
-		svc: svc,
-	}
-}
+type CreateHandler = resource.CreateHandler[EyeColor]
 
-func (h *CreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// POST以外は受け付けない
-	if r.Method != http.MethodPost {
-		return
-	}
-	var eyeColorsJson EyeColorsJson
-	query := `
-		INSERT INTO eyecolor (
-			entry_id,
-			color_id
-		) VALUES (
-			:entry_id,
-			:color_id
-		)
-	`
-	// json読み込み
-	jsonBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		log.Println(fmt.Sprintf("read error: %v", err))
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	}
-	err = json.Unmarshal(jsonBytes, &eyeColorsJson)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json decode error: %v body:%v", err, r.Body))
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		return
-	}
-	// jsonバリデーション
-	err = eyeColorsJson.Validate()
-	if err != nil {
-		log.Printf(fmt.Sprintf("validation error: %v", err))
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		return
-	}
-	for _, eyeColor := range eyeColorsJson.EyeColors {
-		// jsonバリデーション
-		err = eyeColor.Validate()
-		if err != nil {
-			log.Printf(fmt.Sprintf("validation error: %v", err))
-			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-			return
-		}
-		_, err = h.svc.DB.NamedExecContext(r.Context(), query, eyeColor)
-		if err != nil {
-			log.Printf(fmt.Sprintf("db error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	}
-	// json返却
-	err = json.NewEncoder(w).Encode(&eyeColorsJson)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json encode error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+func NewCreateHandler(svc *service.IndexService) *CreateHandler {
+	return resource.NewCreateHandler(svc, Resource)
 }
 
-type ReadHandler struct {
-	svc *service.IndexService
-}
+type ReadHandler = resource.ReadHandler[EyeColor]
 
 func NewReadHandler(svc *service.IndexService) *ReadHandler {
-	return &ReadHandler{
-		svc: svc,
-	}
-}
-
-func (h *ReadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// GET以外は受け付けない
-	if r.Method != http.MethodGet {
-		return
-	}
-	var eyeColorsJson EyeColorsJson
-	query := `
-		SELECT
-			entry_id,
-			color_id
-		FROM
-			eyecolor
-		WHERE
-			entry_id IN (?)
-	`
-	queryIDs, ok := r.URL.Query()["entry_id"]
-	if !ok {
-		query = `
-			SELECT
-				entry_id,
-				color_id
-			FROM
-				eyecolor
-		`
-		err := h.svc.DB.SelectContext(r.Context(), &eyeColorsJson.EyeColors, query)
-		if err != nil {
-			log.Printf(fmt.Sprintf("db error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		// json返却
-		err = json.NewEncoder(w).Encode(&eyeColorsJson)
-		if err != nil {
-			log.Printf(fmt.Sprintf("json encode error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		return
-	} else if len(queryIDs) == 1 {
-		query = `
-			SELECT
-				entry_id,
-				color_id
-			FROM
-				eyecolor
-			WHERE
-				entry_id = $1
-		`
-		err := h.svc.DB.SelectContext(r.Context(), &eyeColorsJson.EyeColors, query, queryIDs[0])
-		if err != nil {
-			log.Printf(fmt.Sprintf("db error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		// json返却
-		err = json.NewEncoder(w).Encode(&eyeColorsJson)
-		if err != nil {
-			log.Printf(fmt.Sprintf("json encode error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		return
-	}
-	// entry_idの数だけ置換文字を作成
-	query, args, err := db.In(query, queryIDs)
-	if err != nil {
-		log.Printf(fmt.Sprintf("db error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
-	// Postgresの場合は置換文字を$1, $2, ...とする必要がある
-	query = db.Rebind(len(queryIDs), query)
-	err = h.svc.DB.SelectContext(r.Context(), &eyeColorsJson.EyeColors, query, args...)
-	if err != nil {
-		log.Printf(fmt.Sprintf("db error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
-	// json返却
-	err = json.NewEncoder(w).Encode(&eyeColorsJson)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json encode error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	return resource.NewReadHandler(svc, Resource)
 }
 
-type UpdateHandler struct {
-	svc *service.IndexService
-}
+type UpdateHandler = resource.UpdateHandler[EyeColor]
 
 func NewUpdateHandler(svc *service.IndexService) *UpdateHandler {
-	return &UpdateHandler{
-		svc: svc,
-	}
+	return resource.NewUpdateHandler(svc, Resource)
 }
 
-func (h *UpdateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPut {
-		return
-	}
-	var eyeColorsJson EyeColorsJson
-	query := `
-		UPDATE
-			eyecolor
-		SET
-			color_id = :color_id
-		WHERE
-			entry_id = :entry_id
-	`
-	jsonBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		log.Println(fmt.Sprintf("read error: %v", err))
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	}
-	err = json.Unmarshal(jsonBytes, &eyeColorsJson)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json decode error: %v body:%v", err, r.Body))
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		return
-	}
-	// jsonバリデーション
-	err = eyeColorsJson.Validate()
-	if err != nil {
-		log.Printf(fmt.Sprintf("validation error: %v", err))
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		return
-	}
-	for _, eyeColor := range eyeColorsJson.EyeColors {
-		// jsonバリデーション
-		err = eyeColor.Validate()
-		if err != nil {
-			log.Printf(fmt.Sprintf("validation error: %v", err))
-			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-			return
-		}
-		_, err = h.svc.DB.NamedExecContext(r.Context(), query, eyeColor)
-		if err != nil {
-			log.Printf(fmt.Sprintf("db error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	}
-	// json返却
-	err = json.NewEncoder(w).Encode(&eyeColorsJson)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json encode error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-}
-
-type DeleteHandler struct {
-	svc *service.IndexService
-}
+type DeleteHandler = resource.DeleteHandler[EyeColor]
 
 func NewDeleteHandler(svc *service.IndexService) *DeleteHandler {
-	return &DeleteHandler{
-		svc: svc,
-	}
-}
-
-func (h *DeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// DELETE以外は受け付けない
-	if r.Method != http.MethodDelete {
-		return
-	}
-	var delIDs IDs
-	query := `
-		DELETE FROM
-			eyecolor
-		WHERE
-			entry_id IN (?)
-	`
-	jsonBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		log.Println(fmt.Sprintf("read error: %v", err))
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	}
-	err = json.Unmarshal(jsonBytes, &delIDs)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json decode error: %v body:%v", err, r.Body))
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		return
-	}
-	// jsonバリデーション
-	err = delIDs.Validate()
-	if err != nil {
-		log.Printf(fmt.Sprintf("validation error: %v", err))
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		return
-	}
-	if len(delIDs.IDs) == 0 {
-		return
-	} else if len(delIDs.IDs) == 1 {
-		query = `
-			DELETE FROM
-				eyecolor
-			WHERE
-				entry_id = $1
-		`
-		_, err = h.svc.DB.ExecContext(r.Context(), query, delIDs.IDs[0])
-		if err != nil {
-			log.Printf(fmt.Sprintf("db error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		err = json.NewEncoder(w).Encode(&delIDs)
-		if err != nil {
-			log.Printf(fmt.Sprintf("json encode error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		return
-	}
-	// entry_idの数だけ置換文字を作成
-	query, args, err := db.In(query, delIDs.IDs)
-	if err != nil {
-		log.Printf(fmt.Sprintf("db error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
-	// Postgresの場合は置換文字を$1, $2, ...とする必要がある
-	query = db.Rebind(len(delIDs.IDs), query)
-	_, err = h.svc.DB.ExecContext(r.Context(), query, args...)
-	if err != nil {
-		log.Printf(fmt.Sprintf("db error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
-	// json返却
-	err = json.NewEncoder(w).Encode(&delIDs)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json encode error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	return resource.NewDeleteHandler(svc, Resource)
+}
+
+// Routes は/api/eyecolorsのルーティングの定義を返す
+func Routes(svc *service.IndexService) router.Resource {
+	return router.Resource{
+		Name:   "eyecolors",
+		Key:    Resource.Key,
+		Create: NewCreateHandler(svc),
+		Read:   NewReadHandler(svc),
+		Update: NewUpdateHandler(svc),
+		Delete: NewDeleteHandler(svc),
 	}
 }
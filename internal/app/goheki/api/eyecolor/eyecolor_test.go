@@ -14,8 +14,6 @@ import (
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
 
-	"github.com/maguro-alternative/goheki/pkg/db"
-
 	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
 
 	"github.com/stretchr/testify/assert"
@@ -26,7 +24,7 @@ func TestCreateEyeColorHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -84,7 +82,7 @@ func TestCreateEyeColorHandler(t *testing.T) {
 		handler := NewCreateHandler(indexService)
 		handler.ServeHTTP(rr, req)
 		// レスポンスの検証
-		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
 
 		var actual []EyeColor
 		err = tx.SelectContext(ctx, &actual, "SELECT * FROM eyecolor")
@@ -132,7 +130,7 @@ func TestReadEyeColorHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -265,7 +263,7 @@ func TestReadEyeColorHandler(t *testing.T) {
 		handler := NewReadHandler(indexService)
 		handler.ServeHTTP(rr, req)
 		// レスポンスの検証
-		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
 	})
 
 	t.Run("eyecolor2件取得(内1件は形式が正しくない)", func(t *testing.T) {
@@ -277,7 +275,7 @@ func TestReadEyeColorHandler(t *testing.T) {
 		handler := NewReadHandler(indexService)
 		handler.ServeHTTP(rr, req)
 		// レスポンスの検証
-		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
 	})
 }
 
@@ -286,7 +284,7 @@ func TestUpdateEyeColorHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -406,7 +404,7 @@ func TestDeleteEyeColorHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
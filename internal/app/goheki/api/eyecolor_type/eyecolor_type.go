@@ -1,327 +1,54 @@
 package eyecolortype
 
 import (
-	"fmt"
-	"log"
-	"io"
-
+	"github.com/maguro-alternative/goheki/internal/app/goheki/resource"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
-	"github.com/maguro-alternative/goheki/pkg/db"
-
-	"encoding/json"
-	"net/http"
 )
 
-type CreateHandler struct {
-	svc *service.IndexService
+// Resource はeyecolor_typeテーブルのCRUDの定義
+var Resource = &resource.Resource[EyeColorType]{
+	Table:   "eyecolor_type",
+	Key:     "id",
+	ListKey: "eyecolor_types",
+	Columns: []string{
+		"color",
+	},
+	Validate: (*EyeColorType).Validate,
 }
 
-func NewCreateHandler(svc *service.IndexService) *CreateHandler {
-	return &CreateHandler{
-		svc: svc,
-	}
-}
+type CreateHandler = resource.CreateHandler[EyeColorType]
 
-func (h *CreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// POST以外は受け付けない
-	if r.Method != http.MethodPost {
-		return
-	}
-	var eyeColorTypesJson EyeColorTypesJson
-	query := `
-		INSERT INTO eyecolor_type (
-			color
-		) VALUES (
-			:color
-		)
-	`
-	jsonBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		log.Println(fmt.Sprintf("read error: %v", err))
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	}
-	err = json.Unmarshal(jsonBytes, &eyeColorTypesJson)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json decode error: %v body:%v", err, r.Body))
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		return
-	}
-	// jsonバリデーション
-	err = eyeColorTypesJson.Validate()
-	if err != nil {
-		log.Printf(fmt.Sprintf("validation error: %v", err))
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		return
-	}
-	for _, eyeColorType := range eyeColorTypesJson.EyeColorTypes {
-		// jsonバリデーション
-		err = eyeColorType.Validate()
-		if err != nil {
-			log.Printf(fmt.Sprintf("validation error: %v", err))
-			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-			return
-		}
-		_, err = h.svc.DB.NamedExecContext(r.Context(), query, eyeColorType)
-		if err != nil {
-			log.Printf(fmt.Sprintf("db error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	}
-	// jsonを返す
-	err = json.NewEncoder(w).Encode(&eyeColorTypesJson)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json encode error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+func NewCreateHandler(svc *service.IndexService) *CreateHandler {
+	return resource.NewCreateHandler(svc, Resource)
 }
 
-type ReadHandler struct {
-	svc *service.IndexService
-}
+type ReadHandler = resource.ReadHandler[EyeColorType]
 
 func NewReadHandler(svc *service.IndexService) *ReadHandler {
-	return &ReadHandler{
-		svc: svc,
-	}
-}
-
-func (h *ReadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// GET以外は受け付けない
-	if r.Method != http.MethodGet {
-		return
-	}
-	var eyeColorTypesJson EyeColorTypesJson
-	query := `
-		SELECT
-			id,
-			color
-		FROM
-			eyecolor_type
-		WHERE
-			id IN (?)
-	`
-	queryIDs, ok := r.URL.Query()["id"]
-	if !ok {
-		query = `
-			SELECT
-				id,
-				color
-			FROM
-				eyecolor_type
-		`
-		err := h.svc.DB.SelectContext(r.Context(), &eyeColorTypesJson.EyeColorTypes, query)
-		if err != nil {
-			log.Printf(fmt.Sprintf("db error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		// jsonを返す
-		err = json.NewEncoder(w).Encode(&eyeColorTypesJson)
-		if err != nil {
-			log.Printf(fmt.Sprintf("json encode error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		return
-	} else if len(queryIDs) == 1 {
-		query = `
-			SELECT
-				id,
-				color
-			FROM
-				eyecolor_type
-			WHERE
-				id = $1
-		`
-		err := h.svc.DB.SelectContext(r.Context(), &eyeColorTypesJson.EyeColorTypes, query, queryIDs[0])
-		if err != nil {
-			log.Printf(fmt.Sprintf("db error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		// jsonを返す
-		err = json.NewEncoder(w).Encode(&eyeColorTypesJson)
-		if err != nil {
-			log.Printf(fmt.Sprintf("json encode error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		return
-	}
-	// idの数だけ置換文字を作成
-	query, args, err := db.In(query, queryIDs)
-	if err != nil {
-		log.Printf(fmt.Sprintf("db error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	// Postgresの場合は置換文字を$1, $2, ...とする必要がある
-	query = db.Rebind(len(queryIDs), query)
-	err = h.svc.DB.SelectContext(r.Context(), &eyeColorTypesJson.EyeColorTypes, query, args...)
-	if err != nil {
-		log.Printf(fmt.Sprintf("db error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	// jsonを返す
-	err = json.NewEncoder(w).Encode(&eyeColorTypesJson)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json encode error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+	return resource.NewReadHandler(svc, Resource)
 }
 
-type UpdateHandler struct {
-	svc *service.IndexService
-}
+type UpdateHandler = resource.UpdateHandler[EyeColorType]
 
 func NewUpdateHandler(svc *service.IndexService) *UpdateHandler {
-	return &UpdateHandler{
-		svc: svc,
-	}
+	return resource.NewUpdateHandler(svc, Resource)
 }
 
-func (h *UpdateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// PUT以外は受け付けない
-	if r.Method != http.MethodPut {
-		return
-	}
-	var eyeColorTypesJson EyeColorTypesJson
-	query := `
-		UPDATE
-			eyecolor_type
-		SET
-			color = :color
-		WHERE
-			id = :id
-	`
-	jsonBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		log.Println(fmt.Sprintf("read error: %v", err))
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	}
-	err = json.Unmarshal(jsonBytes, &eyeColorTypesJson)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json decode error: %v body:%v", err, r.Body))
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		return
-	}
-	// jsonバリデーション
-	err = eyeColorTypesJson.Validate()
-	if err != nil {
-		log.Printf(fmt.Sprintf("validation error: %v", err))
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		return
-	}
-	for _, eyeColorType := range eyeColorTypesJson.EyeColorTypes {
-		err = eyeColorType.Validate()
-		if err != nil {
-			log.Printf(fmt.Sprintf("validation error: %v", err))
-			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-			return
-		}
-		_, err = h.svc.DB.NamedExecContext(r.Context(), query, eyeColorType)
-		if err != nil {
-			log.Printf(fmt.Sprintf("db error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	}
-	// jsonを返す
-	err = json.NewEncoder(w).Encode(&eyeColorTypesJson)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json encode error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-}
-
-type DeleteHandler struct {
-	svc *service.IndexService
-}
+type DeleteHandler = resource.DeleteHandler[EyeColorType]
 
 func NewDeleteHandler(svc *service.IndexService) *DeleteHandler {
-	return &DeleteHandler{
-		svc: svc,
-	}
-}
-
-func (h *DeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// DELETE以外は受け付けない
-	if r.Method != http.MethodDelete {
-		return
-	}
-	var delIDs IDs
-	query := `
-		DELETE FROM
-			eyecolor_type
-		WHERE
-			id IN (?)
-	`
-	jsonBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		log.Println(fmt.Sprintf("read error: %v", err))
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	}
-	err = json.Unmarshal(jsonBytes, &delIDs)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json decode error: %v body:%v", err, r.Body))
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		return
-	}
-	// jsonバリデーション
-	err = delIDs.Validate()
-	if err != nil {
-		log.Printf(fmt.Sprintf("validation error: %v", err))
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		return
-	}
-	if len(delIDs.IDs) == 0 {
-		return
-	} else if len(delIDs.IDs) == 1 {
-		query = `
-			DELETE FROM
-				eyecolor_type
-			WHERE
-				id = $1
-		`
-		_, err = h.svc.DB.ExecContext(r.Context(), query, delIDs.IDs[0])
-		if err != nil {
-			log.Printf(fmt.Sprintf("db error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		// jsonを返す
-		err = json.NewEncoder(w).Encode(&delIDs)
-		if err != nil {
-			log.Printf(fmt.Sprintf("json encode error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		return
-	}
-	// idの数だけ置換文字を作成
-	query, args, err := db.In(query, delIDs.IDs)
-	if err != nil {
-		log.Printf(fmt.Sprintf("db error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	// Postgresの場合は置換文字を$1, $2, ...とする必要がある
-	query = db.Rebind(len(delIDs.IDs), query)
-	_, err = h.svc.DB.ExecContext(r.Context(), query, args...)
-	if err != nil {
-		log.Printf(fmt.Sprintf("db error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	// jsonを返す
-	err = json.NewEncoder(w).Encode(&delIDs)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json encode error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	return resource.NewDeleteHandler(svc, Resource)
+}
+
+// Routes は/api/eyecolor_typesのルーティングの定義を返す
+func Routes(svc *service.IndexService) router.Resource {
+	return router.Resource{
+		Name:   "eyecolor_types",
+		Key:    Resource.Key,
+		Create: NewCreateHandler(svc),
+		Read:   NewReadHandler(svc),
+		Update: NewUpdateHandler(svc),
+		Delete: NewDeleteHandler(svc),
 	}
 }
@@ -13,8 +13,6 @@ import (
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
 
-	"github.com/maguro-alternative/goheki/pkg/db"
-
 	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
 
 	"github.com/stretchr/testify/assert"
@@ -25,7 +23,7 @@ func TestCreateEyeColorTypeHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -92,6 +90,11 @@ func TestCreateEyeColorTypeHandler(t *testing.T) {
 		err = json.NewDecoder(w.Body).Decode(&res)
 		assert.NoError(t, err)
 		// レスポンスの検証
+		// 採番されたidが返る
+		for i := range res.EyeColorTypes {
+			assert.NotZero(t, res.EyeColorTypes[i].ID)
+			eyeColorTypesJson.EyeColorTypes[i].ID = res.EyeColorTypes[i].ID
+		}
 		assert.Equal(t, eyeColorTypesJson, res)
 
 		var actualEyeColorTypes []EyeColorType
@@ -107,7 +110,7 @@ func TestReadEyeColorTypeHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -237,7 +240,7 @@ func TestReadEyeColorTypeHandler(t *testing.T) {
 		h := NewReadHandler(indexService)
 		h.ServeHTTP(w, req)
 		// レスポンスの検証
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 	})
 
 	t.Run("eyecolor_type2件取得(内1件は形式が正しくない)", func(t *testing.T) {
@@ -250,7 +253,7 @@ func TestReadEyeColorTypeHandler(t *testing.T) {
 		h := NewReadHandler(indexService)
 		h.ServeHTTP(w, req)
 		// レスポンスの検証
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 	})
 }
 
@@ -259,7 +262,7 @@ func TestUpdateEyeColorTypeHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -355,7 +358,7 @@ func TestDeleteEyeColorTypeHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
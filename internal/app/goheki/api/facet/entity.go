@@ -0,0 +1,38 @@
+package facet
+
+// Entry は絞り込んだentry
+type Entry struct {
+	ID         int64  `db:"id" json:"id"`
+	Name       string `db:"name" json:"name"`
+	Image      string `db:"image" json:"image"`
+	SourceID   int64  `db:"source_id" json:"source_id"`
+	SourceType string `db:"source_type" json:"source_type"`
+}
+
+// Count は属性の値ごとの件数
+type Count struct {
+	ID    int64  `db:"id" json:"id"`
+	Name  string `db:"name" json:"name"`
+	Count int64  `db:"count" json:"count"`
+}
+
+// ValueCount は作品の種類ごとの件数
+type ValueCount struct {
+	Value string `db:"value" json:"value"`
+	Count int64  `db:"count" json:"count"`
+}
+
+// Range は数値の列の最小値と最大値
+// 値を持つentryがない場合はnull
+type Range struct {
+	Min *int64 `db:"min" json:"min"`
+	Max *int64 `db:"max" json:"max"`
+}
+
+type FacetsJson struct {
+	Total      int64              `json:"total"`
+	Entries    []Entry            `json:"entries"`
+	Facets     map[string][]Count `json:"facets"`
+	SourceType []ValueCount       `json:"source_type"`
+	Ranges     map[string]Range   `json:"ranges"`
+}
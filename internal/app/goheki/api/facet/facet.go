@@ -0,0 +1,172 @@
+package facet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/entry_filter"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/pkg/db"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+const (
+	// DefaultLimit はlimitを省略した場合に返すentryの件数
+	DefaultLimit = 100
+	// MaxLimit はlimitに指定できる最大の件数
+	MaxLimit = 1000
+)
+
+type ReadHandler struct {
+	svc *service.IndexService
+}
+
+func NewReadHandler(svc *service.IndexService) *ReadHandler {
+	return &ReadHandler{
+		svc: svc,
+	}
+}
+
+// ServeHTTP は属性の組み合わせでentryを絞り込み、属性の値ごとの件数と合わせて返す
+//
+//	GET /api/facets?haircolor=1&hairlength=2&eyecolor=3&tag=4&ai[gte]=80
+//
+// 属性の値ごとの件数は、その属性以外の条件で絞り込んだ件数を数える
+// (髪色を1つ選んでも他の髪色の件数が0にならない)
+// タグは全てのタグを持つものに絞り込むため、全ての条件で絞り込んだ件数を数える
+func (h *ReadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// GET以外は受け付けない
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	query := r.URL.Query()
+	axes, err := entryfilter.LoadAxes(r.Context(), h.svc.DB)
+	if err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+	columns := entryfilter.RangeColumns(axes)
+	conditions, err := entryfilter.Parse(query, columns)
+	if err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+	limit := DefaultLimit
+	if v := query.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			problem.Validation(w, r, validation.Errors{"limit": fmt.Errorf("must be between 1 and %d", MaxLimit)})
+			return
+		}
+	}
+	result, err := h.facets(r.Context(), conditions, columns, limit)
+	if err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
+
+func (h *ReadHandler) facets(ctx context.Context, conditions []entryfilter.Condition, columns []entryfilter.RangeColumn, limit int) (*FacetsJson, error) {
+	result := &FacetsJson{
+		Entries: []Entry{},
+		Facets:  make(map[string][]Count, len(entryfilter.Attributes)),
+		Ranges:  make(map[string]Range, len(columns)),
+	}
+	w, args := entryfilter.Where(conditions, "")
+	if err := h.get(ctx, &result.Total, "SELECT COUNT(*) "+entryfilter.From+w, args); err != nil {
+		return nil, err
+	}
+	err := h.selectIn(ctx, &result.Entries, `SELECT
+			e.id,
+			e.name,
+			e.image,
+			e.source_id,
+			s.type AS source_type
+		`+entryfilter.From+w+" ORDER BY e.id LIMIT ?", append(args, limit))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, attr := range entryfilter.Attributes {
+		except := attr.Name
+		if attr.All {
+			except = ""
+		}
+		w, args := entryfilter.Where(conditions, except)
+		// 件数が0の値も返すため、種類のテーブルを基準に数える
+		counts := []Count{}
+		err := h.selectIn(ctx, &counts, fmt.Sprintf(`SELECT
+				t.id,
+				t.%[1]s AS name,
+				COUNT(a.entry_id) AS count
+			FROM %[2]s t
+			LEFT JOIN %[3]s a ON a.%[4]s = t.id AND a.entry_id IN (SELECT e.id %[5]s%[6]s)
+			GROUP BY t.id, t.%[1]s
+			ORDER BY t.id`, attr.TypeColumn, attr.TypeTable, attr.Table, attr.Column, entryfilter.From, w), args)
+		if err != nil {
+			return nil, err
+		}
+		result.Facets[attr.Name] = counts
+	}
+
+	w, args = entryfilter.Where(conditions, entryfilter.SourceType)
+	result.SourceType = []ValueCount{}
+	err = h.selectIn(ctx, &result.SourceType, "SELECT s.type AS value, COUNT(*) AS count "+entryfilter.From+w+" GROUP BY s.type ORDER BY s.type", args)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, column := range columns {
+		w, args := entryfilter.Where(conditions, column.Name)
+		scope, scopeArgs := column.Scope()
+		var rng Range
+		err := h.get(ctx, &rng, fmt.Sprintf(
+			"SELECT MIN(%[1]s) AS min, MAX(%[1]s) AS max FROM %[2]s WHERE entry_id IN (SELECT e.id %[3]s%[4]s)%[5]s",
+			column.Column, column.Table, entryfilter.From, w, scope,
+		), append(args, scopeArgs...))
+		if err != nil {
+			return nil, err
+		}
+		result.Ranges[column.Name] = rng
+	}
+	return result, nil
+}
+
+// get はIN句を展開して1行取得する
+func (h *ReadHandler) get(ctx context.Context, dest any, query string, args []any) error {
+	query, args, err := db.In(query, args...)
+	if err != nil {
+		return err
+	}
+	// DBの種類に合わせて置換文字を変える
+	return h.svc.DB.GetContext(ctx, dest, h.svc.DB.Rebind(query), args...)
+}
+
+// selectIn はIN句を展開して取得する
+func (h *ReadHandler) selectIn(ctx context.Context, dest any, query string, args []any) error {
+	query, args, err := db.In(query, args...)
+	if err != nil {
+		return err
+	}
+	return h.svc.DB.SelectContext(ctx, dest, h.svc.DB.Rebind(query), args...)
+}
+
+// Routes は/api/facetsのルーティングの定義を返す
+func Routes(svc *service.IndexService) router.Resource {
+	return router.Resource{
+		Name: "facets",
+		Read: NewReadHandler(svc),
+	}
+}
@@ -0,0 +1,242 @@
+package facet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
+
+	"github.com/justinas/alice"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadFacetHandler(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+
+	// トランザクションのロールバック
+	defer tx.RollbackCtx(ctx)
+
+	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	// データベースの準備
+	f := &fixtures.Fixture{DBv1: tx}
+	f.Build(t,
+		fixtures.NewHairColorType(ctx, func(s *fixtures.HairColorType) {
+			s.Color = "黒"
+		}),
+		fixtures.NewHairColorType(ctx, func(s *fixtures.HairColorType) {
+			s.Color = "銀"
+		}),
+		fixtures.NewEyeColorType(ctx, func(s *fixtures.EyeColorType) {
+			s.Color = "赤"
+		}),
+		fixtures.NewEyeColorType(ctx, func(s *fixtures.EyeColorType) {
+			s.Color = "紫"
+		}),
+		fixtures.NewTag(ctx, func(s *fixtures.Tag) {
+			s.Name = "眼鏡"
+		}),
+		fixtures.NewHekiRadarAxis(ctx, func(s *fixtures.HekiRadarAxis) {
+			s.Name = "ai"
+		}),
+		fixtures.NewHekiRadarAxis(ctx, func(s *fixtures.HekiRadarAxis) {
+			s.Name = "nu"
+		}),
+		fixtures.NewTag(ctx, func(s *fixtures.Tag) {
+			s.Name = "お姫様"
+		}),
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "アイドルマスター"
+			s.Url = "https://example.com/image2.png"
+			s.Type = "game"
+		}).Connect(
+			fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+				s.Name = "秋月律子"
+				s.Image = "https://example.com/image3.png"
+				s.Content = "プロデューサー"
+				s.CreatedAt = fixedTime
+			}).Connect(
+				fixtures.NewHairColor(ctx, func(s *fixtures.HairColor) {
+					s.ColorID = f.HairColorTypes[0].ID
+				}),
+				fixtures.NewEyeColor(ctx, func(s *fixtures.EyeColor) {
+					s.ColorID = f.EyeColorTypes[0].ID
+				}),
+				fixtures.NewEntryTag(ctx, func(s *fixtures.EntryTag) {
+					s.TagID = f.Tags[0].ID
+				}),
+				fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+					s.AxisID = f.HekiRadarAxes[0].ID
+					s.Score = 90
+				}),
+				fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+					s.AxisID = f.HekiRadarAxes[1].ID
+					s.Score = 10
+				}),
+			),
+			fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+				s.Name = "四条貴音"
+				s.Image = "https://example.com/image2.png"
+				s.Content = "お姫ちん"
+				s.CreatedAt = fixedTime
+			}).Connect(
+				fixtures.NewHairColor(ctx, func(s *fixtures.HairColor) {
+					s.ColorID = f.HairColorTypes[1].ID
+				}),
+				fixtures.NewEyeColor(ctx, func(s *fixtures.EyeColor) {
+					s.ColorID = f.EyeColorTypes[1].ID
+				}),
+				fixtures.NewEntryTag(ctx, func(s *fixtures.EntryTag) {
+					s.TagID = f.Tags[0].ID
+				}),
+				fixtures.NewEntryTag(ctx, func(s *fixtures.EntryTag) {
+					s.TagID = f.Tags[1].ID
+				}),
+				fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+					s.AxisID = f.HekiRadarAxes[0].ID
+					s.Score = 80
+				}),
+				fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+					s.AxisID = f.HekiRadarAxes[1].ID
+					s.Score = 70
+				}),
+			),
+		),
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "閃乱カグラ"
+			s.Url = "https://example.com/image1.png"
+			s.Type = "anime"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "焔"
+			s.Image = "https://example.com/image1.png"
+			s.Content = "かっこいい"
+			s.CreatedAt = fixedTime
+		}).Connect(
+			fixtures.NewHairColor(ctx, func(s *fixtures.HairColor) {
+				s.ColorID = f.HairColorTypes[0].ID
+			}),
+			fixtures.NewEyeColor(ctx, func(s *fixtures.EyeColor) {
+				s.ColorID = f.EyeColorTypes[0].ID
+			}),
+			fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+				s.AxisID = f.HekiRadarAxes[0].ID
+				s.Score = 50
+			}),
+			fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+				s.AxisID = f.HekiRadarAxes[1].ID
+				s.Score = 50
+			}),
+		)),
+	)
+
+	var indexService = service.NewIndexService(
+		tx,
+		cookie.Store,
+		env,
+	)
+	mux := http.NewServeMux()
+	router.New(mux, alice.New()).Handle(Routes(indexService))
+
+	read := func(t *testing.T, query string) FacetsJson {
+		req := httptest.NewRequest(http.MethodGet, "/api/facets"+query, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var actual FacetsJson
+		err := json.NewDecoder(w.Body).Decode(&actual)
+		assert.NoError(t, err)
+		return actual
+	}
+	ids := func(entries []Entry) []int64 {
+		result := []int64{}
+		for _, e := range entries {
+			result = append(result, e.ID)
+		}
+		return result
+	}
+	int64p := func(n int64) *int64 { return &n }
+
+	t.Run("条件なし", func(t *testing.T) {
+		actual := read(t, "")
+
+		assert.Equal(t, int64(3), actual.Total)
+		assert.Equal(t, []int64{f.Entrys[0].ID, f.Entrys[1].ID, f.Entrys[2].ID}, ids(actual.Entries))
+		assert.Equal(t, []Count{
+			{ID: f.HairColorTypes[0].ID, Name: "黒", Count: 2},
+			{ID: f.HairColorTypes[1].ID, Name: "銀", Count: 1},
+		}, actual.Facets["haircolor"])
+		assert.Equal(t, []ValueCount{
+			{Value: "anime", Count: 1},
+			{Value: "game", Count: 2},
+		}, actual.SourceType)
+		assert.Equal(t, Range{Min: int64p(50), Max: int64p(90)}, actual.Ranges["ai"])
+		assert.Equal(t, Range{}, actual.Ranges["bust"])
+	})
+
+	t.Run("属性の組み合わせで絞り込む", func(t *testing.T) {
+		actual := read(t, "?haircolor="+itoa(f.HairColorTypes[0].ID)+"&eyecolor="+itoa(f.EyeColorTypes[0].ID)+"&tag="+itoa(f.Tags[0].ID)+"&ai[gte]=80")
+
+		assert.Equal(t, int64(1), actual.Total)
+		assert.Equal(t, []int64{f.Entrys[0].ID}, ids(actual.Entries))
+		// 髪色の件数は髪色以外の条件で数える
+		assert.Equal(t, []Count{
+			{ID: f.HairColorTypes[0].ID, Name: "黒", Count: 1},
+			{ID: f.HairColorTypes[1].ID, Name: "銀", Count: 0},
+		}, actual.Facets["haircolor"])
+		// タグの件数は全ての条件で数える
+		assert.Equal(t, []Count{
+			{ID: f.Tags[0].ID, Name: "眼鏡", Count: 1},
+			{ID: f.Tags[1].ID, Name: "お姫様", Count: 0},
+		}, actual.Facets["tag"])
+		// aiの範囲はai以外の条件で求める
+		assert.Equal(t, Range{Min: int64p(90), Max: int64p(90)}, actual.Ranges["ai"])
+		assert.Equal(t, []ValueCount{
+			{Value: "game", Count: 1},
+		}, actual.SourceType)
+	})
+
+	t.Run("同じ属性の値はいずれかに一致", func(t *testing.T) {
+		actual := read(t, "?haircolor="+itoa(f.HairColorTypes[0].ID)+"&haircolor="+itoa(f.HairColorTypes[1].ID)+"&source_type=game")
+
+		assert.Equal(t, int64(2), actual.Total)
+		assert.Equal(t, []int64{f.Entrys[0].ID, f.Entrys[1].ID}, ids(actual.Entries))
+	})
+
+	t.Run("タグは全てに一致", func(t *testing.T) {
+		actual := read(t, "?tag="+itoa(f.Tags[0].ID)+"&tag="+itoa(f.Tags[1].ID)+"&limit=1")
+
+		assert.Equal(t, int64(1), actual.Total)
+		assert.Equal(t, []int64{f.Entrys[1].ID}, ids(actual.Entries))
+	})
+
+	t.Run("条件の形式が正しくない", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/facets?haircolor=aaa&bust[foo]=1&nose[gte]=1", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+}
+
+func itoa(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
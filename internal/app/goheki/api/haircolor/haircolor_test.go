@@ -14,7 +14,6 @@ import (
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
 
-	"github.com/maguro-alternative/goheki/pkg/db"
 
 	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
 
@@ -26,7 +25,7 @@ func TestCreateHairColorHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -97,7 +96,7 @@ func TestCreateHairColorHandler(t *testing.T) {
 		handler.ServeHTTP(w, req)
 
 		// レスポンスの検証
-		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 
 		// データベースの検証
 		var hairColors []HairColor
@@ -142,7 +141,7 @@ func TestReadHairColorHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -307,7 +306,7 @@ func TestReadHairColorHandler(t *testing.T) {
 		// トランザクションのロールバック
 		// tx.RollbackCtx(ctx)
 		// レスポンスの検証
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 	})
 
 	t.Run("haircolor2件取得(形式が正しくない)", func(t *testing.T) {
@@ -322,7 +321,7 @@ func TestReadHairColorHandler(t *testing.T) {
 		// トランザクションのロールバック
 		// tx.RollbackCtx(ctx)
 		// レスポンスの検証
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 	})
 }
 
@@ -331,7 +330,7 @@ func TestUpdateHairColorHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -448,7 +447,7 @@ func TestDeleteHairColorHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
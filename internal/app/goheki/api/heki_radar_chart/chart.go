@@ -0,0 +1,336 @@
+package hekiradarchart
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/pkg/db"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// listKey はリクエストおよびレスポンスのjsonのキー
+const listKey = "heki_radar_charts"
+
+// writeMode は値の書き込み方
+type writeMode int
+
+const (
+	// 値を追加する (既に値がある軸はエラー)
+	modeInsert writeMode = iota
+	// entryの値を全て置き換える (指定しなかった軸の値は削除する)
+	modeReplace
+	// 指定した軸の値だけを置き換える
+	modeMerge
+)
+
+type CreateHandler struct {
+	svc *service.IndexService
+}
+
+func NewCreateHandler(svc *service.IndexService) *CreateHandler {
+	return &CreateHandler{
+		svc: svc,
+	}
+}
+
+func (h *CreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// POST以外は受け付けない
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	var chartsJson HekiRadarChartsJson
+	// json読み込み
+	if err := json.NewDecoder(r.Body).Decode(&chartsJson); err != nil {
+		problem.BadRequest(w, r, err)
+		return
+	}
+	write(w, r, h.svc.DB, chartsJson.HekiRadarCharts, modeInsert)
+}
+
+type ReadHandler struct {
+	svc *service.IndexService
+}
+
+func NewReadHandler(svc *service.IndexService) *ReadHandler {
+	return &ReadHandler{
+		svc: svc,
+	}
+}
+
+func (h *ReadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// GET以外は受け付けない
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	// クエリパラメータにentry_idがある場合は絞り込む
+	var ids []int64
+	if values, ok := r.URL.Query()["entry_id"]; ok {
+		ids = make([]int64, len(values))
+		for i, v := range values {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				problem.Validation(w, r, validation.Errors{"entry_id": fmt.Errorf("invalid id %q", v)})
+				return
+			}
+			ids[i] = id
+		}
+	}
+	charts, err := readCharts(r.Context(), h.svc.DB, ids)
+	if err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+	encode(w, charts)
+}
+
+type UpdateHandler struct {
+	svc *service.IndexService
+}
+
+func NewUpdateHandler(svc *service.IndexService) *UpdateHandler {
+	return &UpdateHandler{
+		svc: svc,
+	}
+}
+
+// ServeHTTP はentryの値を更新する
+// PUTは指定しなかった軸の値を削除し、PATCHは指定した軸の値だけを更新する
+func (h *UpdateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mode := modeReplace
+	switch r.Method {
+	case http.MethodPut:
+	case http.MethodPatch:
+		mode = modeMerge
+	default:
+		// PUT、PATCH以外は受け付けない
+		w.Header().Set("Allow", http.MethodPatch+", "+http.MethodPut)
+		problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	var chartsJson HekiRadarChartsJson
+	// json読み込み
+	if err := json.NewDecoder(r.Body).Decode(&chartsJson); err != nil {
+		problem.BadRequest(w, r, err)
+		return
+	}
+	charts := chartsJson.HekiRadarCharts
+	// /api/heki_radar_charts/{entry_id}の場合はパスのidで1件を更新する
+	if pathID := router.PathID(r); pathID != "" {
+		if len(charts) != 1 {
+			problem.Validation(w, r, validation.Errors{
+				listKey: fmt.Errorf("exactly one item is required for /%s", pathID),
+			})
+			return
+		}
+		charts[0].EntryID, _ = strconv.ParseInt(pathID, 10, 64)
+	}
+	write(w, r, h.svc.DB, charts, mode)
+}
+
+type DeleteHandler struct {
+	svc *service.IndexService
+}
+
+func NewDeleteHandler(svc *service.IndexService) *DeleteHandler {
+	return &DeleteHandler{
+		svc: svc,
+	}
+}
+
+// ServeHTTP は指定したentryの全ての軸の値を削除する
+func (h *DeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// DELETE以外は受け付けない
+	if r.Method != http.MethodDelete {
+		w.Header().Set("Allow", http.MethodDelete)
+		problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	var delIDs IDs
+	// json読み込み
+	if err := json.NewDecoder(r.Body).Decode(&delIDs); err != nil {
+		problem.BadRequest(w, r, err)
+		return
+	}
+	// jsonバリデーション
+	if err := delIDs.Validate(); err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+	// idの数だけ置換文字を作成
+	query, args, err := db.In("DELETE FROM heki_radar_score WHERE entry_id IN (?)", delIDs.IDs)
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+	// DBの種類に合わせて置換文字を変える
+	query = h.svc.DB.Rebind(query)
+	if _, err := h.svc.DB.ExecContext(r.Context(), query, args...); err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+	// json返却
+	if err := json.NewEncoder(w).Encode(&delIDs); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
+
+// write は軸の範囲を確認し、トランザクション内で全てのentryの値を書き込む
+// レスポンスには書き込んだ後のentryの全ての値を返す
+func write(w http.ResponseWriter, r *http.Request, driver db.Driver, charts []HekiRadarChart, mode writeMode) {
+	ctx := r.Context()
+	if len(charts) == 0 {
+		problem.Validation(w, r, validation.Errors{listKey: errors.New("cannot be blank")})
+		return
+	}
+	axes, err := loadAxes(ctx, driver)
+	if err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+	errs := validation.Errors{}
+	for i := range charts {
+		charts[i].axes = axes
+		if err := charts[i].Validate(); err != nil {
+			errs[itemField(i)] = err
+		}
+	}
+	if len(errs) > 0 {
+		problem.Validation(w, r, errs)
+		return
+	}
+	ids := make([]int64, len(charts))
+	err = db.WithTx(ctx, driver, func(tx db.Driver) error {
+		for i := range charts {
+			if err := writeScores(ctx, tx, &charts[i], mode); err != nil {
+				return validation.Errors{itemField(i): err}
+			}
+			ids[i] = charts[i].EntryID
+		}
+		return nil
+	})
+	if err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+	written, err := readCharts(ctx, driver, ids)
+	if err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+	encode(w, written)
+}
+
+func itemField(i int) string {
+	return fmt.Sprintf("%s[%d]", listKey, i)
+}
+
+func encode(w http.ResponseWriter, charts []HekiRadarChart) {
+	if err := json.NewEncoder(w).Encode(&HekiRadarChartsJson{HekiRadarCharts: charts}); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
+
+// loadAxes は軸の定義を名前ごとに返す
+func loadAxes(ctx context.Context, driver db.Driver) (map[string]Axis, error) {
+	var axes []Axis
+	if err := driver.SelectContext(ctx, &axes, "SELECT id, name, min, max, display_order FROM heki_radar_axis"); err != nil {
+		return nil, err
+	}
+	byName := make(map[string]Axis, len(axes))
+	for _, axis := range axes {
+		byName[axis.Name] = axis
+	}
+	return byName, nil
+}
+
+// writeScores はentryの値をmodeに従って書き込む
+// chartは検証済みで、全ての軸が定義済みであること
+func writeScores(ctx context.Context, tx db.Driver, chart *HekiRadarChart, mode writeMode) error {
+	names := make([]string, 0, len(chart.Scores))
+	axisIDs := make([]int64, 0, len(chart.Scores))
+	for name := range chart.Scores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		axisIDs = append(axisIDs, chart.axes[name].ID)
+	}
+
+	switch mode {
+	case modeReplace:
+		if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM heki_radar_score WHERE entry_id = ?"), chart.EntryID); err != nil {
+			return err
+		}
+	case modeMerge:
+		query, args, err := db.In("DELETE FROM heki_radar_score WHERE entry_id = ? AND axis_id IN (?)", chart.EntryID, axisIDs)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+			return err
+		}
+	}
+	for i, name := range names {
+		_, err := tx.ExecContext(ctx,
+			tx.Rebind("INSERT INTO heki_radar_score (entry_id, axis_id, score) VALUES (?, ?, ?)"),
+			chart.EntryID, axisIDs[i], chart.Scores[name],
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readCharts はentryごとの値をentry_idの順に返す
+// idsがnilの場合は全てのentryの値を返す
+func readCharts(ctx context.Context, driver db.Driver, ids []int64) ([]HekiRadarChart, error) {
+	query := `SELECT
+			s.entry_id,
+			a.name,
+			s.score
+		FROM heki_radar_score s
+		JOIN heki_radar_axis a ON a.id = s.axis_id`
+	var args []any
+	if ids != nil {
+		if len(ids) == 0 {
+			return []HekiRadarChart{}, nil
+		}
+		var err error
+		query, args, err = db.In(query+" WHERE s.entry_id IN (?)", ids)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var rows []struct {
+		EntryID int64  `db:"entry_id"`
+		Name    string `db:"name"`
+		Score   int64  `db:"score"`
+	}
+	query = driver.Rebind(query + " ORDER BY s.entry_id, a.display_order, a.id")
+	if err := driver.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	charts := []HekiRadarChart{}
+	for _, row := range rows {
+		if n := len(charts); n == 0 || charts[n-1].EntryID != row.EntryID {
+			charts = append(charts, HekiRadarChart{EntryID: row.EntryID, Scores: map[string]int64{}})
+		}
+		charts[len(charts)-1].Scores[row.Name] = row.Score
+	}
+	return charts, nil
+}
@@ -1,23 +1,80 @@
 package hekiradarchart
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+
 	validation "github.com/go-ozzo/ozzo-validation"
 )
 
+// Axis はレーダーチャートの軸
+type Axis struct {
+	ID           int64  `db:"id" json:"id"`
+	Name         string `db:"name" json:"name"`
+	Min          int64  `db:"min" json:"min"`
+	Max          int64  `db:"max" json:"max"`
+	DisplayOrder int64  `db:"display_order" json:"display_order"`
+}
+
+func (a *Axis) Validate() error {
+	return validation.ValidateStruct(a,
+		validation.Field(&a.Name, validation.Required),
+		validation.Field(&a.Max, validation.By(func(interface{}) error {
+			if a.Max <= a.Min {
+				return fmt.Errorf("must be greater than min (%d)", a.Min)
+			}
+			return nil
+		})),
+	)
+}
+
+type AxesJson struct {
+	Axes []Axis `json:"heki_radar_axes"`
+}
+
+// HekiRadarChart はentryの軸ごとの値
+// Scoresのキーは軸の名前
 type HekiRadarChart struct {
-	EntryID int64 `db:"entry_id"`
-	AI      int64 `db:"ai"`
-	NU      int64 `db:"nu"`
+	EntryID int64            `json:"entry_id"`
+	Scores  map[string]int64 `json:"scores"`
+
+	// Validateで値の範囲を確認するための軸の定義
+	axes map[string]Axis
 }
 
+// Validate は全ての値が定義済みの軸の範囲内であることを確認する
+// 軸の定義はハンドラがDBから読み込んで設定する
 func (h *HekiRadarChart) Validate() error {
 	return validation.ValidateStruct(h,
 		validation.Field(&h.EntryID, validation.Required),
-		validation.Field(&h.AI, validation.Required),
-		validation.Field(&h.NU, validation.Required),
+		validation.Field(&h.Scores, validation.Required, validation.By(h.validateScores)),
 	)
 }
 
+func (h *HekiRadarChart) validateScores(interface{}) error {
+	errs := validation.Errors{}
+	names := make([]string, 0, len(h.Scores))
+	for name := range h.Scores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		axis, ok := h.axes[name]
+		if !ok {
+			errs[name] = errors.New("unknown axis")
+			continue
+		}
+		if score := h.Scores[name]; score < axis.Min || score > axis.Max {
+			errs[name] = fmt.Errorf("must be between %d and %d", axis.Min, axis.Max)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
 type HekiRadarChartsJson struct {
 	HekiRadarCharts []HekiRadarChart `json:"heki_radar_charts"`
 }
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -12,33 +11,122 @@ import (
 	"time"
 
 	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
 
-	"github.com/maguro-alternative/goheki/pkg/db"
-
 	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// score は軸ごとの値の行
+type score struct {
+	EntryID int64 `db:"entry_id"`
+	AxisID  int64 `db:"axis_id"`
+	Score   int64 `db:"score"`
+}
+
+func TestCreateHekiRadarAxisHandler(t *testing.T) {
+	// setup
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+
+	// ロールバック
+	defer tx.RollbackCtx(ctx)
+
+	var indexService = service.NewIndexService(
+		tx,
+		cookie.Store,
+		env,
+	)
+
+	t.Run("heki_radar_axis登録", func(t *testing.T) {
+		// リクエストの作成
+		b, err := json.Marshal(AxesJson{[]Axis{
+			{Name: "萌え", Min: 0, Max: 10, DisplayOrder: 1},
+			{Name: "尊さ", Min: -5, Max: 5, DisplayOrder: 2},
+		}})
+		assert.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/api/heki_radar_axes", bytes.NewBuffer(b))
+		assert.NoError(t, err)
+		// レスポンスの作成
+		w := httptest.NewRecorder()
+		handler := NewCreateAxisHandler(indexService)
+		handler.ServeHTTP(w, req)
+		// レスポンスの検証
+		assert.Equal(t, http.StatusOK, w.Code)
+		var res AxesJson
+		err = json.Unmarshal(w.Body.Bytes(), &res)
+		assert.NoError(t, err)
+		if assert.Len(t, res.Axes, 2) {
+			assert.NotZero(t, res.Axes[0].ID)
+			assert.Equal(t, "萌え", res.Axes[0].Name)
+			assert.Equal(t, int64(-5), res.Axes[1].Min)
+		}
+	})
+
+	t.Run("heki_radar_axis登録失敗(範囲が正しくない)", func(t *testing.T) {
+		// リクエストの作成
+		b, err := json.Marshal(AxesJson{[]Axis{
+			{Name: "萌え", Min: 10, Max: 10},
+		}})
+		assert.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/api/heki_radar_axes", bytes.NewBuffer(b))
+		assert.NoError(t, err)
+		// レスポンスの作成
+		w := httptest.NewRecorder()
+		handler := NewCreateAxisHandler(indexService)
+		handler.ServeHTTP(w, req)
+		// レスポンスの検証
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		var res problem.Problem
+		err = json.Unmarshal(w.Body.Bytes(), &res)
+		assert.NoError(t, err)
+		assert.Equal(t, []problem.FieldError{
+			{Field: "heki_radar_axes[0].max", Message: "must be greater than min (10)"},
+		}, res.Errors)
+	})
+}
+
 func TestCreateHekiRadarChartHandler(t *testing.T) {
 	// setup
 	ctx := context.Background()
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
 	tx, err := indexDB.BeginTxx(ctx, nil)
 	assert.NoError(t, err)
 
+	// ロールバック
+	defer tx.RollbackCtx(ctx)
+
 	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
 	// データベースの準備
 	f := &fixtures.Fixture{DBv1: tx}
 	f.Build(t,
+		fixtures.NewHekiRadarAxis(ctx, func(s *fixtures.HekiRadarAxis) {
+			s.Name = "ai"
+			s.Min = 0
+			s.Max = 100
+		}),
+		fixtures.NewHekiRadarAxis(ctx, func(s *fixtures.HekiRadarAxis) {
+			s.Name = "nu"
+			s.Min = 1
+			s.Max = 5
+		}),
 		fixtures.NewSource(ctx, func(s *fixtures.Source) {
 			s.Name = "閃乱カグラ"
 			s.Url = "https://example.com/image1.png"
@@ -61,26 +149,48 @@ func TestCreateHekiRadarChartHandler(t *testing.T) {
 		})),
 	)
 
-	// テストデータの作成
-	charts := HekiRadarChartsJson{[]HekiRadarChart{
-		{
-			EntryID: f.Entrys[0].ID,
-			AI:      1,
-			NU:      2,
-		},
-		{
-			EntryID: f.Entrys[1].ID,
-			AI:      3,
-			NU:      4,
-		},
-	}}
-
 	var indexService = service.NewIndexService(
 		tx,
 		cookie.Store,
 		env,
 	)
+
+	t.Run("heki_rader_chart登録失敗(範囲外の値)", func(t *testing.T) {
+		// リクエストの作成
+		b, err := json.Marshal(HekiRadarChartsJson{[]HekiRadarChart{
+			{EntryID: f.Entrys[0].ID, Scores: map[string]int64{"ai": 80, "nu": 3}},
+			{EntryID: f.Entrys[1].ID, Scores: map[string]int64{"ai": 101, "nu": 0, "萌え": 1}},
+		}})
+		assert.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/api/heki_radar_chart/create", bytes.NewBuffer(b))
+		assert.NoError(t, err)
+		// レスポンスの作成
+		w := httptest.NewRecorder()
+		handler := NewCreateHandler(indexService)
+		handler.ServeHTTP(w, req)
+		// レスポンスの検証
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		var res problem.Problem
+		err = json.Unmarshal(w.Body.Bytes(), &res)
+		assert.NoError(t, err)
+		assert.Equal(t, []problem.FieldError{
+			{Field: "heki_radar_charts[1].scores.ai", Message: "must be between 0 and 100"},
+			{Field: "heki_radar_charts[1].scores.nu", Message: "must be between 1 and 5"},
+			{Field: "heki_radar_charts[1].scores.萌え", Message: "unknown axis"},
+		}, res.Errors)
+
+		// 1件も登録しない
+		var actuals []score
+		err = tx.SelectContext(ctx, &actuals, "SELECT * FROM heki_radar_score")
+		assert.NoError(t, err)
+		assert.Len(t, actuals, 0)
+	})
+
 	t.Run("heki_rader_chart登録", func(t *testing.T) {
+		charts := HekiRadarChartsJson{[]HekiRadarChart{
+			{EntryID: f.Entrys[0].ID, Scores: map[string]int64{"ai": 1, "nu": 2}},
+			{EntryID: f.Entrys[1].ID, Scores: map[string]int64{"ai": 3}},
+		}}
 		// リクエストの作成
 		b, err := json.Marshal(charts)
 		assert.NoError(t, err)
@@ -90,10 +200,8 @@ func TestCreateHekiRadarChartHandler(t *testing.T) {
 		w := httptest.NewRecorder()
 		handler := NewCreateHandler(indexService)
 		handler.ServeHTTP(w, req)
-		tx.RollbackCtx(ctx)
 		// レスポンスの検証
 		assert.Equal(t, http.StatusOK, w.Code)
-		// レスポンスの検証
 		var res HekiRadarChartsJson
 		err = json.Unmarshal(w.Body.Bytes(), &res)
 		assert.NoError(t, err)
@@ -107,7 +215,7 @@ func TestReadHekiRadarChartHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -121,6 +229,12 @@ func TestReadHekiRadarChartHandler(t *testing.T) {
 	// データベースの準備
 	f := &fixtures.Fixture{DBv1: tx}
 	f.Build(t,
+		fixtures.NewHekiRadarAxis(ctx, func(s *fixtures.HekiRadarAxis) {
+			s.Name = "ai"
+		}),
+		fixtures.NewHekiRadarAxis(ctx, func(s *fixtures.HekiRadarAxis) {
+			s.Name = "nu"
+		}),
 		fixtures.NewSource(ctx, func(s *fixtures.Source) {
 			s.Name = "閃乱カグラ"
 			s.Url = "https://example.com/image1.png"
@@ -130,10 +244,16 @@ func TestReadHekiRadarChartHandler(t *testing.T) {
 			s.Image = "https://example.com/image1.png"
 			s.Content = "かわいい"
 			s.CreatedAt = fixedTime
-		}).Connect(fixtures.NewHekiRadarChart(ctx, func(s *fixtures.HekiRadarChart) {
-			s.AI = 100
-			s.NU = 70
-		}))),
+		}).Connect(
+			fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+				s.AxisID = f.HekiRadarAxes[0].ID
+				s.Score = 100
+			}),
+			fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+				s.AxisID = f.HekiRadarAxes[1].ID
+				s.Score = 70
+			}),
+		)),
 		fixtures.NewSource(ctx, func(s *fixtures.Source) {
 			s.Name = "アイドルマスター"
 			s.Url = "https://example.com/image2.png"
@@ -143,23 +263,23 @@ func TestReadHekiRadarChartHandler(t *testing.T) {
 			s.Image = "https://example.com/image2.png"
 			s.Content = "お姫ちん"
 			s.CreatedAt = fixedTime
-		}).Connect(fixtures.NewHekiRadarChart(ctx, func(s *fixtures.HekiRadarChart) {
-			s.AI = 70
-			s.NU = 60
-		}))),
+		}).Connect(
+			fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+				s.AxisID = f.HekiRadarAxes[0].ID
+				s.Score = 70
+			}),
+		)),
 	)
 
 	// テストデータの作成
 	charts := []HekiRadarChart{
 		{
 			EntryID: f.Entrys[0].ID,
-			AI:      f.HekiRadarCharts[0].AI,
-			NU:      f.HekiRadarCharts[0].NU,
+			Scores:  map[string]int64{"ai": 100, "nu": 70},
 		},
 		{
 			EntryID: f.Entrys[1].ID,
-			AI:      f.HekiRadarCharts[1].AI,
-			NU:      f.HekiRadarCharts[1].NU,
+			Scores:  map[string]int64{"ai": 70},
 		},
 	}
 
@@ -176,10 +296,8 @@ func TestReadHekiRadarChartHandler(t *testing.T) {
 		w := httptest.NewRecorder()
 		handler := NewReadHandler(indexService)
 		handler.ServeHTTP(w, req)
-		// tx.RollbackCtx(ctx)
 		// レスポンスの検証
 		assert.Equal(t, http.StatusOK, w.Code)
-		// レスポンスの検証
 		var res HekiRadarChartsJson
 		err = json.Unmarshal(w.Body.Bytes(), &res)
 		assert.NoError(t, err)
@@ -188,38 +306,18 @@ func TestReadHekiRadarChartHandler(t *testing.T) {
 
 	t.Run("heki_rader_chart1件取得", func(t *testing.T) {
 		// リクエストの作成
-		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/heki_radar_chart/read?entry_id=%d", f.Entrys[0].ID), nil)
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/heki_radar_chart/read?entry_id=%d", f.Entrys[1].ID), nil)
 		assert.NoError(t, err)
 		// レスポンスの作成
 		w := httptest.NewRecorder()
 		handler := NewReadHandler(indexService)
 		handler.ServeHTTP(w, req)
-		// tx.RollbackCtx(ctx)
 		// レスポンスの検証
 		assert.Equal(t, http.StatusOK, w.Code)
-		// レスポンスの検証
 		var res HekiRadarChartsJson
 		err = json.Unmarshal(w.Body.Bytes(), &res)
 		assert.NoError(t, err)
-		assert.Equal(t, charts[0], res.HekiRadarCharts[0])
-	})
-
-	t.Run("heki_rader_chart2件取得", func(t *testing.T) {
-		// リクエストの作成
-		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/heki_radar_chart/read?entry=%d&entry_id%d", f.Entrys[0].ID, f.Entrys[1].ID), nil)
-		assert.NoError(t, err)
-		// レスポンスの作成
-		w := httptest.NewRecorder()
-		handler := NewReadHandler(indexService)
-		handler.ServeHTTP(w, req)
-		// tx.RollbackCtx(ctx)
-		// レスポンスの検証
-		assert.Equal(t, http.StatusOK, w.Code)
-		// レスポンスの検証
-		var res HekiRadarChartsJson
-		err = json.Unmarshal(w.Body.Bytes(), &res)
-		assert.NoError(t, err)
-		assert.Equal(t, charts, res.HekiRadarCharts)
+		assert.Equal(t, charts[1:], res.HekiRadarCharts)
 	})
 
 	t.Run("heki_rader_chart1件取得(存在しない)", func(t *testing.T) {
@@ -230,34 +328,14 @@ func TestReadHekiRadarChartHandler(t *testing.T) {
 		w := httptest.NewRecorder()
 		handler := NewReadHandler(indexService)
 		handler.ServeHTTP(w, req)
-		// tx.RollbackCtx(ctx)
 		// レスポンスの検証
 		assert.Equal(t, http.StatusOK, w.Code)
-		// レスポンスの検証
 		var res HekiRadarChartsJson
 		err = json.Unmarshal(w.Body.Bytes(), &res)
 		assert.NoError(t, err)
 		assert.Len(t, res.HekiRadarCharts, 0)
 	})
 
-	t.Run("heki_rader_chart2件取得(内1件は存在しない)", func(t *testing.T) {
-		// リクエストの作成
-		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/heki_radar_chart/read?entry_id=%d&entry_id=%d", f.Entrys[0].ID, 0), nil)
-		assert.NoError(t, err)
-		// レスポンスの作成
-		w := httptest.NewRecorder()
-		handler := NewReadHandler(indexService)
-		handler.ServeHTTP(w, req)
-		// tx.RollbackCtx(ctx)
-		// レスポンスの検証
-		assert.Equal(t, http.StatusOK, w.Code)
-		// レスポンスの検証
-		var res HekiRadarChartsJson
-		err = json.Unmarshal(w.Body.Bytes(), &res)
-		assert.NoError(t, err)
-		assert.Equal(t, charts[0], res.HekiRadarCharts[0])
-	})
-
 	t.Run("heki_rader_chart1件取得(形式が正しくない)", func(t *testing.T) {
 		// リクエストの作成
 		req, err := http.NewRequest(http.MethodGet, "/api/heki_radar_chart/read?entry_id=aaa", nil)
@@ -266,22 +344,8 @@ func TestReadHekiRadarChartHandler(t *testing.T) {
 		w := httptest.NewRecorder()
 		handler := NewReadHandler(indexService)
 		handler.ServeHTTP(w, req)
-		// tx.RollbackCtx(ctx)
-		// レスポンスの検証
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
-	})
-
-	t.Run("heki_rader_chart2件取得(内1件形式が正しくない)", func(t *testing.T) {
-		// リクエストの作成
-		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/heki_radar_chart/read?entry_id=%d&entry_id=bbb", f.HekiRadarCharts[0].EntryID), nil)
-		assert.NoError(t, err)
-		// レスポンスの作成
-		w := httptest.NewRecorder()
-		handler := NewReadHandler(indexService)
-		handler.ServeHTTP(w, req)
-		// tx.RollbackCtx(ctx)
 		// レスポンスの検証
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 	})
 }
 
@@ -291,7 +355,7 @@ func TestUpdateHekiRadarChartHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -305,6 +369,12 @@ func TestUpdateHekiRadarChartHandler(t *testing.T) {
 	// データベースの準備
 	f := &fixtures.Fixture{DBv1: tx}
 	f.Build(t,
+		fixtures.NewHekiRadarAxis(ctx, func(s *fixtures.HekiRadarAxis) {
+			s.Name = "ai"
+		}),
+		fixtures.NewHekiRadarAxis(ctx, func(s *fixtures.HekiRadarAxis) {
+			s.Name = "nu"
+		}),
 		fixtures.NewSource(ctx, func(s *fixtures.Source) {
 			s.Name = "閃乱カグラ"
 			s.Url = "https://example.com/image1.png"
@@ -314,96 +384,82 @@ func TestUpdateHekiRadarChartHandler(t *testing.T) {
 			s.Image = "https://example.com/image1.png"
 			s.Content = "かわいい"
 			s.CreatedAt = fixedTime
-		}).Connect(fixtures.NewHekiRadarChart(ctx, func(s *fixtures.HekiRadarChart) {
-			s.AI = 100
-			s.NU = 70
-		}))),
-		fixtures.NewSource(ctx, func(s *fixtures.Source) {
-			s.Name = "アイドルマスター"
-			s.Url = "https://example.com/image2.png"
-			s.Type = "game"
-		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
-			s.Name = "四条貴音"
-			s.Image = "https://example.com/image2.png"
-			s.Content = "お姫ちん"
-			s.CreatedAt = fixedTime
-		}).Connect(fixtures.NewHekiRadarChart(ctx, func(s *fixtures.HekiRadarChart) {
-			s.AI = 70
-			s.NU = 60
-		}))),
+		}).Connect(
+			fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+				s.AxisID = f.HekiRadarAxes[0].ID
+				s.Score = 100
+			}),
+			fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+				s.AxisID = f.HekiRadarAxes[1].ID
+				s.Score = 70
+			}),
+		)),
 	)
 
-	// テストデータの作成
-	updateCharts := HekiRadarChartsJson{[]HekiRadarChart{
-		{
-			EntryID: f.Entrys[0].ID,
-			AI:      100,
-			NU:      90,
-		},
-		{
-			EntryID: f.Entrys[1].ID,
-			AI:      80,
-			NU:      70,
-		},
-	}}
-
 	var indexService = service.NewIndexService(
 		tx,
 		cookie.Store,
 		env,
 	)
 
-	t.Run("heki_rader_chart更新失敗", func(t *testing.T) {
-		// リクエストの作成
-		b, err := json.Marshal(HekiRadarChartsJson{[]HekiRadarChart{}})
+	update := func(t *testing.T, method string, charts HekiRadarChartsJson) *httptest.ResponseRecorder {
+		b, err := json.Marshal(charts)
 		assert.NoError(t, err)
-		req, err := http.NewRequest(http.MethodPut, "/api/heki_radar_chart/update", bytes.NewBuffer(b))
+		req, err := http.NewRequest(method, "/api/heki_radar_charts", bytes.NewBuffer(b))
 		assert.NoError(t, err)
-		// レスポンスの作成
 		w := httptest.NewRecorder()
 		handler := NewUpdateHandler(indexService)
 		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("heki_rader_chart更新失敗", func(t *testing.T) {
+		w := update(t, http.MethodPut, HekiRadarChartsJson{[]HekiRadarChart{}})
 
 		// レスポンスの検証
 		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 
-		var actuals []HekiRadarChart
-		err = tx.SelectContext(ctx, &actuals, "SELECT * FROM heki_radar_chart")
+		var actuals []score
+		err = tx.SelectContext(ctx, &actuals, "SELECT * FROM heki_radar_score")
 		assert.NoError(t, err)
 		assert.Len(t, actuals, 2)
-		assert.Equal(t, f.HekiRadarCharts[0].AI, actuals[0].AI)
-		assert.Equal(t, f.HekiRadarCharts[0].NU, actuals[0].NU)
-		assert.Equal(t, f.HekiRadarCharts[1].AI, actuals[1].AI)
-		assert.Equal(t, f.HekiRadarCharts[1].NU, actuals[1].NU)
 	})
 
-	t.Run("heki_rader_chart更新", func(t *testing.T) {
-		// リクエストの作成
-		b, err := json.Marshal(updateCharts)
-		assert.NoError(t, err)
-		req, err := http.NewRequest(http.MethodPut, "/api/heki_radar_chart/update", bytes.NewBuffer(b))
-		assert.NoError(t, err)
-		// レスポンスの作成
-		w := httptest.NewRecorder()
-		handler := NewUpdateHandler(indexService)
-		handler.ServeHTTP(w, req)
+	t.Run("heki_rader_chart更新(PATCHは指定した軸だけ更新)", func(t *testing.T) {
+		w := update(t, http.MethodPatch, HekiRadarChartsJson{[]HekiRadarChart{
+			{EntryID: f.Entrys[0].ID, Scores: map[string]int64{"nu": 10}},
+		}})
 
 		// レスポンスの検証
 		assert.Equal(t, http.StatusOK, w.Code)
+		var res HekiRadarChartsJson
+		err = json.Unmarshal(w.Body.Bytes(), &res)
+		assert.NoError(t, err)
+		assert.Equal(t, HekiRadarChartsJson{[]HekiRadarChart{
+			{EntryID: f.Entrys[0].ID, Scores: map[string]int64{"ai": 100, "nu": 10}},
+		}}, res)
+	})
+
+	t.Run("heki_rader_chart更新(PUTは全ての軸を置き換える)", func(t *testing.T) {
+		w := update(t, http.MethodPut, HekiRadarChartsJson{[]HekiRadarChart{
+			{EntryID: f.Entrys[0].ID, Scores: map[string]int64{"ai": 50}},
+		}})
+
 		// レスポンスの検証
+		assert.Equal(t, http.StatusOK, w.Code)
 		var res HekiRadarChartsJson
 		err = json.Unmarshal(w.Body.Bytes(), &res)
 		assert.NoError(t, err)
-		assert.Equal(t, updateCharts, res)
+		assert.Equal(t, HekiRadarChartsJson{[]HekiRadarChart{
+			{EntryID: f.Entrys[0].ID, Scores: map[string]int64{"ai": 50}},
+		}}, res)
 
-		var actuals []HekiRadarChart
-		err = tx.SelectContext(ctx, &actuals, "SELECT * FROM heki_radar_chart")
+		var actuals []score
+		err = tx.SelectContext(ctx, &actuals, "SELECT * FROM heki_radar_score")
 		assert.NoError(t, err)
-		assert.Len(t, actuals, 2)
-		assert.Equal(t, updateCharts.HekiRadarCharts[0].AI, actuals[0].AI)
-		assert.Equal(t, updateCharts.HekiRadarCharts[0].NU, actuals[0].NU)
-		assert.Equal(t, updateCharts.HekiRadarCharts[1].AI, actuals[1].AI)
-		assert.Equal(t, updateCharts.HekiRadarCharts[1].NU, actuals[1].NU)
+		assert.Equal(t, []score{
+			{EntryID: f.Entrys[0].ID, AxisID: f.HekiRadarAxes[0].ID, Score: 50},
+		}, actuals)
 	})
 }
 
@@ -413,7 +469,7 @@ func TestDeleteHekiRadarChartHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -427,6 +483,9 @@ func TestDeleteHekiRadarChartHandler(t *testing.T) {
 	// データベースの準備
 	f := &fixtures.Fixture{DBv1: tx}
 	f.Build(t,
+		fixtures.NewHekiRadarAxis(ctx, func(s *fixtures.HekiRadarAxis) {
+			s.Name = "ai"
+		}),
 		fixtures.NewSource(ctx, func(s *fixtures.Source) {
 			s.Name = "閃乱カグラ"
 			s.Url = "https://example.com/image1.png"
@@ -436,9 +495,9 @@ func TestDeleteHekiRadarChartHandler(t *testing.T) {
 			s.Image = "https://example.com/image1.png"
 			s.Content = "かわいい"
 			s.CreatedAt = fixedTime
-		}).Connect(fixtures.NewHekiRadarChart(ctx, func(s *fixtures.HekiRadarChart) {
-			s.AI = 100
-			s.NU = 70
+		}).Connect(fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+			s.AxisID = f.HekiRadarAxes[0].ID
+			s.Score = 100
 		}))),
 		fixtures.NewSource(ctx, func(s *fixtures.Source) {
 			s.Name = "アイドルマスター"
@@ -449,9 +508,9 @@ func TestDeleteHekiRadarChartHandler(t *testing.T) {
 			s.Image = "https://example.com/image2.png"
 			s.Content = "お姫ちん"
 			s.CreatedAt = fixedTime
-		}).Connect(fixtures.NewHekiRadarChart(ctx, func(s *fixtures.HekiRadarChart) {
-			s.AI = 70
-			s.NU = 60
+		}).Connect(fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+			s.AxisID = f.HekiRadarAxes[0].ID
+			s.Score = 70
 		}))),
 	)
 
@@ -477,15 +536,14 @@ func TestDeleteHekiRadarChartHandler(t *testing.T) {
 		// レスポンスの検証
 		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 
-		var actuals []HekiRadarChart
-		err = tx.SelectContext(ctx, &actuals, "SELECT * FROM heki_radar_chart")
+		var actuals []score
+		err = tx.SelectContext(ctx, &actuals, "SELECT * FROM heki_radar_score")
 		assert.NoError(t, err)
 		assert.Len(t, actuals, 2)
 	})
 
 	t.Run("heki_rader_chart削除", func(t *testing.T) {
 		deleteIDs.IDs = append(deleteIDs.IDs, f.Entrys[0].ID)
-		deleteIDs.IDs = append(deleteIDs.IDs, f.Entrys[1].ID)
 		// リクエストの作成
 		b, err := json.Marshal(deleteIDs)
 		assert.NoError(t, err)
@@ -498,10 +556,16 @@ func TestDeleteHekiRadarChartHandler(t *testing.T) {
 
 		// レスポンスの検証
 		assert.Equal(t, http.StatusOK, w.Code)
-
 		var res IDs
 		err = json.Unmarshal(w.Body.Bytes(), &res)
 		assert.NoError(t, err)
 		assert.Equal(t, deleteIDs.IDs, res.IDs)
+
+		var actuals []score
+		err = tx.SelectContext(ctx, &actuals, "SELECT * FROM heki_radar_score")
+		assert.NoError(t, err)
+		assert.Equal(t, []score{
+			{EntryID: f.Entrys[1].ID, AxisID: f.HekiRadarAxes[0].ID, Score: 70},
+		}, actuals)
 	})
 }
@@ -0,0 +1,375 @@
+package link
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/content_filter"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/pkg/db"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// checkUserAgent はリンク切れの確認で送るUser-Agent
+const checkUserAgent = "goheki-linkcheck/1.0 (+https://github.com/maguro-alternative/goheki)"
+
+// CheckConfig はリンク切れを定期的に確認する設定
+type CheckConfig struct {
+	// 全てのlinkを確認する間隔 (0の場合は定期的に確認しない)
+	Interval time.Duration
+	// 1件の確認にかける最大の時間
+	Timeout time.Duration
+	// 同時に確認するlinkの数
+	Concurrency int
+}
+
+// NewCheckConfig は環境変数からリンク切れを確認する設定を生成する
+// 指定されていない値は既定値を使う
+func NewCheckConfig(env *envconfig.Env) (*CheckConfig, error) {
+	cfg := &CheckConfig{
+		Interval:    6 * time.Hour,
+		Timeout:     10 * time.Second,
+		Concurrency: 4,
+	}
+	durations := []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"LINK_CHECK_INTERVAL", env.LinkCheckInterval, &cfg.Interval},
+		{"LINK_CHECK_TIMEOUT", env.LinkCheckTimeout, &cfg.Timeout},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", d.name, err)
+		}
+		*d.dest = v
+	}
+	if v := env.LinkCheckConcurrency; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid LINK_CHECK_CONCURRENCY: %q", v)
+		}
+		cfg.Concurrency = n
+	}
+	return cfg, nil
+}
+
+// checkResult は1件の確認の結果
+type checkResult struct {
+	id int64
+	// 応答がなかった場合はnil
+	status *int64
+	err    error
+}
+
+// failed は確認に失敗したかを返す
+// 接続できなかった場合と、400以上のステータスコードを返した場合を失敗とする
+func (r checkResult) failed() bool {
+	return r.err != nil || *r.status >= http.StatusBadRequest
+}
+
+// Checker は全てのlinkのURLに接続し、ステータスコードと連続で失敗した回数を記録する
+type Checker struct {
+	driver db.Driver
+	client *http.Client
+	cfg    CheckConfig
+	now    func() time.Time
+}
+
+// NewChecker はclientでlinkを確認するCheckerを返す
+// 本番ではプライベートなアドレスに接続しないunfurl.NewClientを使う
+func NewChecker(driver db.Driver, client *http.Client, cfg CheckConfig) *Checker {
+	return &Checker{
+		driver: driver,
+		client: client,
+		cfg:    cfg,
+		now:    time.Now,
+	}
+}
+
+// Run はctxがキャンセルされるまでInterval毎にCheckを実行する
+// server.Server.Goでワーカーとして起動する
+func (c *Checker) Run(ctx context.Context) error {
+	if c.cfg.Interval <= 0 {
+		return nil
+	}
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		n, err := c.Check(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			log.Printf("link check: %v", err)
+		} else {
+			log.Printf("link check: checked %d links", n)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check は全てのlinkを最大Concurrency件ずつ同時に確認し、確認した件数を返す
+// 前回の確認が古いものから順に確認する
+func (c *Checker) Check(ctx context.Context) (int, error) {
+	var targets []struct {
+		ID  int64  `db:"id"`
+		URL string `db:"url"`
+	}
+	err := c.driver.SelectContext(ctx, &targets, "SELECT id, url FROM link ORDER BY checked_at NULLS FIRST, id")
+	if err != nil {
+		return 0, err
+	}
+
+	concurrency := c.cfg.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	jobs := make(chan int)
+	// 中断した場合にワーカーが止まらないよう、全件分の結果を受け取れるようにする
+	results := make(chan checkResult, len(targets))
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			for j := range jobs {
+				status, err := c.check(ctx, targets[j].URL)
+				results <- checkResult{id: targets[j].ID, status: status, err: err}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for i := range targets {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// DBへの書き込みはトランザクションでも使えるよう、このgoroutineだけで行う
+	checked := 0
+	var storeErr error
+	for checked < len(targets) {
+		var r checkResult
+		select {
+		case r = <-results:
+		case <-ctx.Done():
+			return checked, ctx.Err()
+		}
+		checked++
+		if storeErr != nil {
+			continue
+		}
+		// シャットダウンで中断した場合は確認の失敗として保存しない
+		if ctx.Err() != nil {
+			return checked, ctx.Err()
+		}
+		storeErr = c.store(ctx, r)
+	}
+	return checked, storeErr
+}
+
+// check はURLにHEADで接続し、ステータスコードを返す
+// HEADに対応していないサーバーがあるため、HEADが失敗のステータスコードを返した場合はGETで確認し直す
+func (c *Checker) check(ctx context.Context, rawURL string) (*int64, error) {
+	status, err := c.request(ctx, http.MethodHead, rawURL)
+	if err != nil || *status < http.StatusBadRequest {
+		return status, err
+	}
+	return c.request(ctx, http.MethodGet, rawURL)
+}
+
+// request はリダイレクトをたどった後のステータスコードを返す
+func (c *Checker) request(ctx context.Context, method, rawURL string) (*int64, error) {
+	if c.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", checkUserAgent)
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	// 接続を再利用できるよう少しだけ読み捨てる
+	_, _ = io.CopyN(io.Discard, res.Body, 4096)
+	status := int64(res.StatusCode)
+	return &status, nil
+}
+
+// store は確認の結果を保存する
+// 失敗した場合は連続で失敗した回数を増やし、成功した場合は0に戻す
+func (c *Checker) store(ctx context.Context, r checkResult) error {
+	failures := "0"
+	if r.failed() {
+		failures = "consecutive_failures + 1"
+	}
+	var checkErr *string
+	if r.err != nil {
+		checkErr = nullable(r.err.Error())
+	}
+	query := fmt.Sprintf(`UPDATE link SET
+			status_code = ?,
+			checked_at = ?,
+			check_error = ?,
+			consecutive_failures = %s
+		WHERE id = ?`, failures)
+	// DBの種類に合わせて置換文字を変える
+	_, err := c.driver.ExecContext(ctx, c.driver.Rebind(query), r.status, c.now().UTC(), checkErr, r.id)
+	return err
+}
+
+// BrokenLink はリンク切れのlink
+type BrokenLink struct {
+	ID                  int64      `db:"id" json:"id"`
+	Type                string     `db:"type" json:"type"`
+	URL                 string     `db:"url" json:"url"`
+	StatusCode          *int64     `db:"status_code" json:"status_code"`
+	CheckedAt           *time.Time `db:"checked_at" json:"checked_at"`
+	CheckError          *string    `db:"check_error" json:"check_error"`
+	ConsecutiveFailures int64      `db:"consecutive_failures" json:"consecutive_failures"`
+}
+
+// BrokenEntry はentryごとのリンク切れのlink
+type BrokenEntry struct {
+	EntryID int64        `json:"entry_id"`
+	Name    string       `json:"name"`
+	Links   []BrokenLink `json:"links"`
+}
+
+type BrokenLinksJson struct {
+	Entries []BrokenEntry `json:"entries"`
+	// 設定に応じて隠したリンク切れのlinkの数
+	Withheld int64 `json:"withheld"`
+}
+
+type BrokenHandler struct {
+	svc *service.IndexService
+}
+
+func NewBrokenHandler(svc *service.IndexService) *BrokenHandler {
+	return &BrokenHandler{
+		svc: svc,
+	}
+}
+
+// ServeHTTP はリンク切れのlinkをentryごとに返す
+//
+//	GET /api/links/broken?min_failures=3&entry_id=1&hide_nsfw=true
+//
+// 連続でmin_failures回(既定は1回)以上確認に失敗したlinkをリンク切れとする
+// nsfwとdarknessのリンクはcontentfilterの設定に応じて隠し、隠した数をwithheldで返す
+func (h *BrokenHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// GET以外は受け付けない
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	query := r.URL.Query()
+	errs := validation.Errors{}
+	minFailures := int64(1)
+	if v := query.Get("min_failures"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			errs["min_failures"] = errors.New("must be a positive integer")
+		}
+		minFailures = n
+	}
+	where := "WHERE l.consecutive_failures >= ?"
+	args := []any{minFailures}
+	if v := query.Get("entry_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			errs["entry_id"] = fmt.Errorf("invalid id %q", v)
+		}
+		where += " AND l.entry_id = ?"
+		args = append(args, id)
+	}
+	// nsfwとdarknessのリンクは設定に応じて隠す
+	policy, err := contentfilter.FromRequest(r, h.svc)
+	var verrs validation.Errors
+	if errors.As(err, &verrs) {
+		for k, v := range verrs {
+			errs[k] = v
+		}
+	} else if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+	if len(errs) > 0 {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	res := BrokenLinksJson{Entries: []BrokenEntry{}}
+	if exclude := policy.Exclude("l."); exclude != "" {
+		q := "SELECT COUNT(*) FROM link l " + where + " AND (" + exclude + ")"
+		if err := h.svc.DB.GetContext(r.Context(), &res.Withheld, h.svc.DB.Rebind(q), args...); err != nil {
+			problem.Database(w, r, err)
+			return
+		}
+		where += " AND NOT (" + exclude + ")"
+	}
+	var rows []struct {
+		EntryID int64  `db:"entry_id"`
+		Name    string `db:"name"`
+		BrokenLink
+	}
+	q := `SELECT
+			l.entry_id,
+			e.name,
+			l.id,
+			l.type,
+			l.url,
+			l.status_code,
+			l.checked_at,
+			l.check_error,
+			l.consecutive_failures
+		FROM link l
+		JOIN entry e ON e.id = l.entry_id
+		` + where + `
+		ORDER BY l.entry_id, l.id`
+	// DBの種類に合わせて置換文字を変える
+	if err := h.svc.DB.SelectContext(r.Context(), &rows, h.svc.DB.Rebind(q), args...); err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+
+	for _, row := range rows {
+		if n := len(res.Entries); n == 0 || res.Entries[n-1].EntryID != row.EntryID {
+			res.Entries = append(res.Entries, BrokenEntry{EntryID: row.EntryID, Name: row.Name})
+		}
+		last := &res.Entries[len(res.Entries)-1]
+		last.Links = append(last.Links, row.BrokenLink)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
@@ -0,0 +1,257 @@
+package link
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newCheckServer はリンク先の代わりに決まったステータスコードを返すサーバーを起動する
+func newCheckServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/head-not-allowed", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	// タイムアウトするまで応答しない
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestChecker(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+	// ロールバック
+	defer tx.RollbackCtx(ctx)
+	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	srv := newCheckServer(t)
+	// データベースの準備
+	f := &fixtures.Fixture{DBv1: tx}
+	f.Build(t,
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "閃乱カグラ"
+			s.Url = "https://example.com/image1.png"
+			s.Type = "anime"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "雪泉"
+			s.Image = "https://example.com/image1.png"
+			s.Content = "かわいい"
+			s.CreatedAt = fixedTime
+		}).Connect(fixtures.NewLink(ctx, func(l *fixtures.Link) {
+			// 前回までは失敗していた
+			l.URL = srv.URL + "/ok"
+			l.ConsecutiveFailures = 2
+		}), fixtures.NewLink(ctx, func(l *fixtures.Link) {
+			l.URL = srv.URL + "/head-not-allowed"
+		}), fixtures.NewLink(ctx, func(l *fixtures.Link) {
+			l.URL = srv.URL + "/moved"
+		}), fixtures.NewLink(ctx, func(l *fixtures.Link) {
+			l.URL = srv.URL + "/gone"
+			l.ConsecutiveFailures = 2
+		}), fixtures.NewLink(ctx, func(l *fixtures.Link) {
+			l.URL = srv.URL + "/slow"
+		}))),
+	)
+
+	now := fixedTime.Add(24 * time.Hour)
+	c := NewChecker(tx, srv.Client(), CheckConfig{
+		Interval:    time.Hour,
+		Timeout:     100 * time.Millisecond,
+		Concurrency: 2,
+	})
+	c.now = func() time.Time { return now }
+
+	n, err := c.Check(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+
+	var actual []Link
+	err = tx.SelectContext(ctx, &actual, "SELECT * FROM link ORDER BY id")
+	assert.NoError(t, err)
+	assert.Len(t, actual, 5)
+	for _, l := range actual {
+		assert.True(t, now.Equal(*l.CheckedAt), l.URL)
+	}
+
+	t.Run("成功した場合は連続で失敗した回数を0に戻す", func(t *testing.T) {
+		assert.Equal(t, int64(http.StatusOK), *actual[0].StatusCode)
+		assert.Equal(t, int64(0), actual[0].ConsecutiveFailures)
+		assert.Nil(t, actual[0].CheckError)
+	})
+
+	t.Run("HEADに対応していない場合はGETで確認する", func(t *testing.T) {
+		assert.Equal(t, int64(http.StatusOK), *actual[1].StatusCode)
+		assert.Equal(t, int64(0), actual[1].ConsecutiveFailures)
+	})
+
+	t.Run("リダイレクト後のステータスコード", func(t *testing.T) {
+		assert.Equal(t, int64(http.StatusOK), *actual[2].StatusCode)
+	})
+
+	t.Run("失敗した場合は連続で失敗した回数を増やす", func(t *testing.T) {
+		assert.Equal(t, int64(http.StatusNotFound), *actual[3].StatusCode)
+		assert.Equal(t, int64(3), actual[3].ConsecutiveFailures)
+		assert.Nil(t, actual[3].CheckError)
+	})
+
+	t.Run("タイムアウト", func(t *testing.T) {
+		assert.Nil(t, actual[4].StatusCode)
+		assert.Equal(t, int64(1), actual[4].ConsecutiveFailures)
+		assert.NotNil(t, actual[4].CheckError)
+	})
+}
+
+func TestBrokenHandler(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+	// ロールバック
+	defer tx.RollbackCtx(ctx)
+	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	notFound := int64(http.StatusNotFound)
+	ok := int64(http.StatusOK)
+	// データベースの準備
+	f := &fixtures.Fixture{DBv1: tx}
+	f.Build(t,
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "閃乱カグラ"
+			s.Url = "https://example.com/image1.png"
+			s.Type = "anime"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "雪泉"
+			s.Image = "https://example.com/image1.png"
+			s.Content = "かわいい"
+			s.CreatedAt = fixedTime
+		}).Connect(fixtures.NewLink(ctx, func(l *fixtures.Link) {
+			l.Type = "funart"
+			l.URL = "https://example.com/gone"
+			l.StatusCode = &notFound
+			l.CheckedAt = &fixedTime
+			l.ConsecutiveFailures = 3
+		}), fixtures.NewLink(ctx, func(l *fixtures.Link) {
+			l.Type = "official"
+			l.URL = "https://example.com/ok"
+			l.StatusCode = &ok
+			l.CheckedAt = &fixedTime
+		}))),
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "アイドルマスター"
+			s.Url = "https://example.com/image2.png"
+			s.Type = "game"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "四条貴音"
+			s.Image = "https://example.com/image2.png"
+			s.Content = "お姫ちん"
+			s.CreatedAt = fixedTime
+		}).Connect(fixtures.NewLink(ctx, func(l *fixtures.Link) {
+			l.Type = "original"
+			l.URL = "https://example.com/flaky"
+			l.Darkness = true
+			l.StatusCode = &notFound
+			l.CheckedAt = &fixedTime
+			l.ConsecutiveFailures = 1
+		}))),
+	)
+
+	var indexService = service.NewIndexService(
+		tx,
+		cookie.Store,
+		env,
+	)
+	h := NewBrokenHandler(indexService)
+
+	read := func(t *testing.T, query string) BrokenLinksJson {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/links/broken"+query, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		var res BrokenLinksJson
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return res
+	}
+
+	t.Run("entryごとのリンク切れ", func(t *testing.T) {
+		res := read(t, "")
+		assert.Len(t, res.Entries, 2)
+		assert.Equal(t, f.Entrys[0].ID, res.Entries[0].EntryID)
+		assert.Equal(t, "雪泉", res.Entries[0].Name)
+		assert.Len(t, res.Entries[0].Links, 1)
+		link := res.Entries[0].Links[0]
+		assert.Equal(t, f.Links[0].ID, link.ID)
+		assert.Equal(t, "https://example.com/gone", link.URL)
+		assert.Equal(t, &notFound, link.StatusCode)
+		assert.Equal(t, int64(3), link.ConsecutiveFailures)
+		assert.Equal(t, "四条貴音", res.Entries[1].Name)
+	})
+
+	t.Run("連続で失敗した回数で絞り込む", func(t *testing.T) {
+		res := read(t, "?min_failures=3")
+		assert.Len(t, res.Entries, 1)
+		assert.Equal(t, "雪泉", res.Entries[0].Name)
+	})
+
+	t.Run("entryで絞り込む", func(t *testing.T) {
+		res := read(t, fmt.Sprintf("?entry_id=%d", f.Entrys[1].ID))
+		assert.Len(t, res.Entries, 1)
+		assert.Equal(t, "四条貴音", res.Entries[0].Name)
+	})
+
+	t.Run("darknessのlinkを隠す", func(t *testing.T) {
+		res := read(t, "?hide_darkness=true")
+		assert.Len(t, res.Entries, 1)
+		assert.Equal(t, "雪泉", res.Entries[0].Name)
+		assert.Equal(t, int64(1), res.Withheld)
+	})
+
+	t.Run("リンク切れがない", func(t *testing.T) {
+		res := read(t, "?min_failures=10")
+		assert.Empty(t, res.Entries)
+	})
+
+	t.Run("形式が正しくない", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/links/broken?min_failures=0&entry_id=aaa", nil))
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+}
@@ -1,6 +1,8 @@
 package link
 
 import (
+	"time"
+
 	validation "github.com/go-ozzo/ozzo-validation"
 )
 
@@ -11,6 +13,27 @@ type Link struct {
 	URL      string `db:"url"`
 	Nsfw     bool   `db:"nsfw"`
 	Darkness bool   `db:"darkness"`
+
+	// 以下はリンク先から取得した情報で、書き込みでは無視する
+	// 取得していない、またはリンク先にない場合はnull
+	Title       *string    `db:"title"`
+	Description *string    `db:"description"`
+	Thumbnail   *string    `db:"thumbnail"`
+	SiteName    *string    `db:"site_name"`
+	UnfurledAt  *time.Time `db:"unfurled_at"`
+	// 前回の取得に失敗した場合のエラー
+	UnfurlError *string `db:"unfurl_error"`
+	// 情報を取得した時点のURL (URLが変更された場合は取得し直す)
+	UnfurledURL *string `db:"unfurled_url" json:"-"`
+
+	// 以下はリンク切れの確認の結果で、書き込みでは無視する
+	// 確認していない場合はnull
+	StatusCode *int64     `db:"status_code"`
+	CheckedAt  *time.Time `db:"checked_at"`
+	// 接続できなかった場合のエラー
+	CheckError *string `db:"check_error"`
+	// 連続で確認に失敗した回数 (成功すると0に戻す)
+	ConsecutiveFailures int64 `db:"consecutive_failures"`
 }
 
 func (l *Link) Validate() error {
@@ -1,318 +1,104 @@
 package link
 
 import (
-	"fmt"
-	"log"
+	"net/http"
 
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/content_filter"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/resource"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
-	"github.com/maguro-alternative/goheki/pkg/db"
-
-	"encoding/json"
-	"net/http"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/unfurl"
 )
 
-type CreateHandler struct {
-	svc *service.IndexService
-}
-
-func NewCreateHandler(svc *service.IndexService) *CreateHandler {
-	return &CreateHandler{
-		svc: svc,
-	}
-}
-
-func (h *CreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// POST以外は受け付けない
-	if r.Method != http.MethodPost {
-		return
-	}
-	var linksJson LinksJson
-	query := `
-		INSERT INTO link (
-			entry_id,
-			type,
-			url,
-			nsfw,
-			darkness
-		) VALUES (
-			:entry_id,
-			:type,
-			:url,
-			:nsfw,
-			:darkness
-		)
-	`
-	// リクエストボディをJSONに変換
-	err := json.NewDecoder(r.Body).Decode(&linksJson)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json decode error: %v body:%v", err, r.Body))
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-	// JSONのバリデーション
-	err = linksJson.Validate()
-	if err != nil {
-		log.Printf(fmt.Sprintf("validation error: %v", err))
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		return
-	}
-	for _, link := range linksJson.Links {
-		// リンクのバリデーション
-		err = link.Validate()
+// Resource はlinkテーブルのCRUDの定義
+var Resource = &resource.Resource[Link]{
+	Table:   "link",
+	Key:     "id",
+	ListKey: "links",
+	Columns: []string{
+		"entry_id",
+		"type",
+		"url",
+		"nsfw",
+		"darkness",
+	},
+	// 互換性のため連番のidは返さない
+	ReadColumns: []string{
+		"entry_id",
+		"type",
+		"url",
+		"nsfw",
+		"darkness",
+		"title",
+		"description",
+		"thumbnail",
+		"site_name",
+		"unfurled_at",
+		"unfurl_error",
+		"status_code",
+		"checked_at",
+		"check_error",
+		"consecutive_failures",
+	},
+	Defaults: []string{
+		"nsfw",
+		"darkness",
+	},
+	Validate: (*Link).Validate,
+	// nsfwとdarknessのリンクは設定に応じて除外する
+	Withhold: func(r *http.Request, svc *service.IndexService) (string, []any, error) {
+		policy, err := contentfilter.FromRequest(r, svc)
 		if err != nil {
-			log.Printf(fmt.Sprintf("validation error: %v", err))
-			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-			return
+			return "", nil, err
 		}
-		_, err = h.svc.DB.NamedExecContext(r.Context(), query, link)
-		if err != nil {
-			log.Printf(fmt.Sprintf("db error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	}
-	// レスポンスボディをJSONに変換
-	err = json.NewEncoder(w).Encode(&linksJson)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json encode error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+		return policy.Exclude(""), nil, nil
+	},
 }
 
-type ReadHandler struct {
-	svc *service.IndexService
-}
+type CreateHandler = resource.CreateHandler[Link]
 
-func NewReadHandler(svc *service.IndexService) *ReadHandler {
-	return &ReadHandler{
-		svc: svc,
-	}
+func NewCreateHandler(svc *service.IndexService) *CreateHandler {
+	return resource.NewCreateHandler(svc, Resource)
 }
 
-func (h *ReadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// GET以外は受け付けない
-	if r.Method != http.MethodGet {
-		return
-	}
-	var linksJson LinksJson
-	query := `
-		SELECT
-			entry_id,
-			type,
-			url,
-			nsfw,
-			darkness
-		FROM
-			link
-		WHERE
-			id IN (?)
-	`
-	queryIDs, ok := r.URL.Query()["id"]
-	if !ok {
-		query = `
-			SELECT
-				entry_id,
-				type,
-				url,
-				nsfw,
-				darkness
-			FROM
-				link
-		`
-		err := h.svc.DB.SelectContext(r.Context(), &linksJson.Links, query)
-		if err != nil {
-			log.Printf(fmt.Sprintf("db error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		// レスポンスボディをJSONに変換
-		err = json.NewEncoder(w).Encode(&linksJson)
-		if err != nil {
-			log.Printf(fmt.Sprintf("json encode error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		return
-	} else if len(queryIDs) == 1 {
-		query = `
-			SELECT
-				entry_id,
-				type,
-				url,
-				nsfw,
-				darkness
-			FROM
-				link
-			WHERE
-				id = $1
-		`
-		err := h.svc.DB.SelectContext(r.Context(), &linksJson.Links, query, queryIDs[0])
-		if err != nil {
-			log.Printf(fmt.Sprintf("select error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		// レスポンスボディをJSONに変換
-		err = json.NewEncoder(w).Encode(&linksJson)
-		if err != nil {
-			log.Printf(fmt.Sprintf("json encode error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		return
-	}
-	// idの数だけ置換文字を作成
-	query, args, err := db.In(query, queryIDs)
-	if err != nil {
-		log.Printf(fmt.Sprintf("in query error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
-	// Postgresの場合は置換文字を$1, $2, ...とする必要がある
-	query = db.Rebind(len(queryIDs), query)
-	err = h.svc.DB.SelectContext(r.Context(), &linksJson.Links, query, args...)
-	if err != nil {
-		log.Printf(fmt.Sprintf("select error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
-	// レスポンスボディをJSONに変換
-	err = json.NewEncoder(w).Encode(&linksJson)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json encode error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
-}
+type ReadHandler = resource.ReadHandler[Link]
 
-type UpdateHandler struct {
-	svc *service.IndexService
+func NewReadHandler(svc *service.IndexService) *ReadHandler {
+	return resource.NewReadHandler(svc, Resource)
 }
 
+type UpdateHandler = resource.UpdateHandler[Link]
+
 func NewUpdateHandler(svc *service.IndexService) *UpdateHandler {
-	return &UpdateHandler{
-		svc: svc,
-	}
+	return resource.NewUpdateHandler(svc, Resource)
 }
 
-func (h *UpdateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// PUT以外は受け付けない
-	if r.Method != http.MethodPut {
-		return
-	}
-	var linksJson LinksJson
-	query := `
-		UPDATE
-			link
-		SET
-			entry_id = :entry_id,
-			type = :type,
-			url = :url,
-			nsfw = :nsfw,
-			darkness = :darkness
-		WHERE
-			id = :id
-	`
-	// リクエストボディをJSONに変換
-	err := json.NewDecoder(r.Body).Decode(&linksJson)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json decode error: %v body:%v", err, r.Body))
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	}
-	// JSONのバリデーション
-	err = linksJson.Validate()
-	if err != nil {
-		log.Printf(fmt.Sprintf("validation error: %v", err))
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-	}
-	for _, link := range linksJson.Links {
-		// リンクのバリデーション
-		err = link.Validate()
-		if err != nil {
-			log.Printf(fmt.Sprintf("validation error: %v", err))
-			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		}
-		_, err = h.svc.DB.NamedExecContext(r.Context(), query, link)
-		if err != nil {
-			log.Printf(fmt.Sprintf("update error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-	}
-	// レスポンスボディをJSONに変換
-	err = json.NewEncoder(w).Encode(&linksJson)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json encode error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
-}
+type DeleteHandler = resource.DeleteHandler[Link]
 
-type DeleteHandler struct {
-	svc *service.IndexService
+func NewDeleteHandler(svc *service.IndexService) *DeleteHandler {
+	return resource.NewDeleteHandler(svc, Resource)
 }
 
-func NewDeleteHandler(svc *service.IndexService) *DeleteHandler {
-	return &DeleteHandler{
-		svc: svc,
+// Routes は/api/linksのルーティングの定義を返す
+func Routes(svc *service.IndexService) router.Resource {
+	return router.Resource{
+		Name:       "links",
+		LegacyName: "link",
+		Key:        Resource.Key,
+		Create:     NewCreateHandler(svc),
+		Read:       NewReadHandler(svc),
+		Update:     NewUpdateHandler(svc),
+		Delete:     NewDeleteHandler(svc),
+		Actions: map[string]http.Handler{
+			"unfurl": NewUnfurlHandler(svc, unfurl.NewHTTPFetcher()),
+		},
 	}
 }
 
-func (h *DeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// DELETE以外は受け付けない
-	if r.Method != http.MethodDelete {
-		return
-	}
-	var delIDs IDs
-	query := `
-		DELETE FROM
-			link
-		WHERE
-			id IN (?)
-	`
-	// リクエストボディをJSONに変換
-	err := json.NewDecoder(r.Body).Decode(&delIDs)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json decode error: %v body:%v", err, r.Body))
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	}
-	// JSONのバリデーション
-	err = delIDs.Validate()
-	if err != nil {
-		log.Printf(fmt.Sprintf("validation error: %v", err))
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-	}
-	if len(delIDs.IDs) == 0 {
-		return
-	} else if len(delIDs.IDs) == 1 {
-		query = `
-			DELETE FROM
-				link
-			WHERE
-				id = $1
-		`
-		_, err = h.svc.DB.ExecContext(r.Context(), query, delIDs.IDs[0])
-		if err != nil {
-			log.Printf(fmt.Sprintf("delete error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		// レスポンスボディをJSONに変換
-		err = json.NewEncoder(w).Encode(&delIDs)
-		if err != nil {
-			log.Printf(fmt.Sprintf("json encode error: %v", err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		return
-	}
-	// idの数だけ置換文字を作成
-	query, args, err := db.In(query, delIDs.IDs)
-	if err != nil {
-		log.Printf(fmt.Sprintf("in query error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
-	// Postgresの場合は置換文字を$1, $2, ...とする必要がある
-	query = db.Rebind(len(delIDs.IDs), query)
-	_, err = h.svc.DB.ExecContext(r.Context(), query, args...)
-	if err != nil {
-		log.Printf(fmt.Sprintf("delete error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
-	// レスポンスボディをJSONに変換
-	err = json.NewEncoder(w).Encode(&delIDs)
-	if err != nil {
-		log.Printf(fmt.Sprintf("json encode error: %v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// BrokenRoutes は/api/links/brokenのルーティングの定義を返す
+func BrokenRoutes(svc *service.IndexService) router.Resource {
+	return router.Resource{
+		Name: "links/broken",
+		Read: NewBrokenHandler(svc),
 	}
 }
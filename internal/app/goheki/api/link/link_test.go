@@ -14,7 +14,6 @@ import (
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
 
-	"github.com/maguro-alternative/goheki/pkg/db"
 
 	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
 
@@ -26,7 +25,7 @@ func TestCreateLinkHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -155,7 +154,7 @@ func TestReadLinkHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -291,6 +290,43 @@ func TestReadLinkHandler(t *testing.T) {
 		assert.Equal(t, links, res.Links)
 	})
 
+	t.Run("darknessのlinkを隠す", func(t *testing.T) {
+		// テストの実行
+		h := NewReadHandler(indexService)
+		// リクエストを作成
+		req, err := http.NewRequest(http.MethodGet, "/api/link/read?hide_darkness=true", nil)
+		assert.NoError(t, err)
+		// レスポンスを作成
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// レスポンスの検証
+		var res struct {
+			Links    []Link `json:"links"`
+			Withheld int64  `json:"withheld"`
+		}
+		err = json.Unmarshal(w.Body.Bytes(), &res)
+		assert.NoError(t, err)
+
+		assert.Equal(t, links[:1], res.Links)
+		assert.Equal(t, int64(1), res.Withheld)
+	})
+
+	t.Run("hide_nsfwの形式が正しくない", func(t *testing.T) {
+		// テストの実行
+		h := NewReadHandler(indexService)
+		// リクエストを作成
+		req, err := http.NewRequest(http.MethodGet, "/api/link/read?hide_nsfw=aaa", nil)
+		assert.NoError(t, err)
+		// レスポンスを作成
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
 	t.Run("link1件取得(存在しない)", func(t *testing.T) {
 		// テストの実行
 		h := NewReadHandler(indexService)
@@ -348,7 +384,7 @@ func TestReadLinkHandler(t *testing.T) {
 
 		// tx.RollbackCtx(ctx)
 
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 	})
 
 	t.Run("link2件取得(内1件は形式が正しくない)", func(t *testing.T) {
@@ -363,7 +399,7 @@ func TestReadLinkHandler(t *testing.T) {
 
 		// tx.RollbackCtx(ctx)
 
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 	})
 }
 
@@ -372,7 +408,7 @@ func TestUpdateLinkHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -502,7 +538,7 @@ func TestDeleteLinkHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -0,0 +1,255 @@
+package link
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/unfurl"
+	"github.com/maguro-alternative/goheki/pkg/db"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// target は情報を取得するlink
+type target struct {
+	ID          int64   `db:"id"`
+	URL         string  `db:"url"`
+	UnfurledURL *string `db:"unfurled_url"`
+}
+
+// store は取得したリンク先の情報を保存する
+// 取得に失敗した場合はエラーを保存し、URLが変わっていなければ前回の情報を残す
+// 取得中にURLが変更された場合は保存しない
+func store(ctx context.Context, driver db.Driver, t target, meta *unfurl.Metadata, fetchErr error, now time.Time) error {
+	var query string
+	var args []any
+	switch {
+	case fetchErr == nil:
+		query = `UPDATE link SET
+				title = ?,
+				description = ?,
+				thumbnail = ?,
+				site_name = ?,
+				unfurled_url = ?,
+				unfurled_at = ?,
+				unfurl_error = NULL
+			WHERE id = ? AND url = ?`
+		args = []any{nullable(meta.Title), nullable(meta.Description), nullable(meta.Thumbnail), nullable(meta.SiteName), t.URL, now, t.ID, t.URL}
+	case t.UnfurledURL != nil && *t.UnfurledURL == t.URL:
+		query = "UPDATE link SET unfurled_at = ?, unfurl_error = ? WHERE id = ? AND url = ?"
+		args = []any{now, fetchErr.Error(), t.ID, t.URL}
+	default:
+		// 前のURLの情報は残さない
+		query = `UPDATE link SET
+				title = NULL,
+				description = NULL,
+				thumbnail = NULL,
+				site_name = NULL,
+				unfurled_url = ?,
+				unfurled_at = ?,
+				unfurl_error = ?
+			WHERE id = ? AND url = ?`
+		args = []any{t.URL, now, fetchErr.Error(), t.ID, t.URL}
+	}
+	// DBの種類に合わせて置換文字を変える
+	_, err := driver.ExecContext(ctx, driver.Rebind(query), args...)
+	return err
+}
+
+// nullable は空文字をnullとして保存する
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+type UnfurlHandler struct {
+	svc     *service.IndexService
+	fetcher unfurl.Fetcher
+	now     func() time.Time
+}
+
+func NewUnfurlHandler(svc *service.IndexService, fetcher unfurl.Fetcher) *UnfurlHandler {
+	return &UnfurlHandler{
+		svc:     svc,
+		fetcher: fetcher,
+		now:     time.Now,
+	}
+}
+
+// ServeHTTP はリンク先の情報を取得し直し、更新したlinkを返す
+//
+//	POST /api/links/{id}/unfurl
+//
+// 取得に失敗した場合はエラーを保存して502を返す
+func (h *UnfurlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// POST以外は受け付けない
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	ctx := r.Context()
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		problem.Validation(w, r, validation.Errors{"id": fmt.Errorf("invalid id %q", r.URL.Query().Get("id"))})
+		return
+	}
+	var t target
+	err = h.svc.DB.GetContext(ctx, &t, h.svc.DB.Rebind("SELECT id, url, unfurled_url FROM link WHERE id = ?"), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		problem.NotFound(w, r, fmt.Errorf("link %d not found", id))
+		return
+	} else if err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+
+	meta, fetchErr := h.fetcher.Fetch(ctx, t.URL)
+	if err := store(ctx, h.svc.DB, t, meta, fetchErr, h.now().UTC()); err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+	if fetchErr != nil {
+		problem.BadGateway(w, r, fetchErr)
+		return
+	}
+
+	var link Link
+	query := fmt.Sprintf("SELECT %s FROM link WHERE id = ?", strings.Join(Resource.ReadColumns, ", "))
+	if err := h.svc.DB.GetContext(ctx, &link, h.svc.DB.Rebind(query), id); err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(LinksJson{Links: []Link{link}}); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
+
+// RefreshConfig はリンク先の情報を定期的に取得し直す設定
+type RefreshConfig struct {
+	// 取得し直すかを確認する間隔 (0の場合は定期的に取得しない)
+	Interval time.Duration
+	// 前回の取得からこの時間が経過したlinkを取得し直す
+	StaleAfter time.Duration
+	// 1回に取得する最大のlinkの数
+	BatchSize int
+}
+
+// NewRefreshConfig は環境変数からリンク先の情報を取得し直す設定を生成する
+// 指定されていない値は既定値を使う
+func NewRefreshConfig(env *envconfig.Env) (*RefreshConfig, error) {
+	cfg := &RefreshConfig{
+		Interval:   time.Hour,
+		StaleAfter: 7 * 24 * time.Hour,
+		BatchSize:  50,
+	}
+	durations := []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"LINK_UNFURL_INTERVAL", env.LinkUnfurlInterval, &cfg.Interval},
+		{"LINK_UNFURL_STALE_AFTER", env.LinkUnfurlStaleAfter, &cfg.StaleAfter},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", d.name, err)
+		}
+		*d.dest = v
+	}
+	return cfg, nil
+}
+
+// Refresher は未取得、URLが変更された、または古くなったlinkの情報を定期的に取得する
+type Refresher struct {
+	driver  db.Driver
+	fetcher unfurl.Fetcher
+	cfg     RefreshConfig
+	now     func() time.Time
+}
+
+func NewRefresher(driver db.Driver, fetcher unfurl.Fetcher, cfg RefreshConfig) *Refresher {
+	return &Refresher{
+		driver:  driver,
+		fetcher: fetcher,
+		cfg:     cfg,
+		now:     time.Now,
+	}
+}
+
+// Run はctxがキャンセルされるまでInterval毎にRefreshを実行する
+// server.Server.Goでワーカーとして起動する
+func (rf *Refresher) Run(ctx context.Context) error {
+	if rf.cfg.Interval <= 0 {
+		return nil
+	}
+	ticker := time.NewTicker(rf.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		n, err := rf.Refresh(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			log.Printf("link unfurl: %v", err)
+		} else if n > 0 {
+			log.Printf("link unfurl: refreshed %d links", n)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh は取得し直す必要のあるlinkを最大BatchSize件取得し、取得を試みた件数を返す
+// 未取得のものを優先し、次に前回の取得が古いものから順に取得する
+// 個々のlinkの取得の失敗はlinkに保存し、エラーとしては返さない
+func (rf *Refresher) Refresh(ctx context.Context) (int, error) {
+	now := rf.now().UTC()
+	var targets []target
+	err := rf.driver.SelectContext(ctx, &targets, rf.driver.Rebind(`SELECT
+			id,
+			url,
+			unfurled_url
+		FROM link
+		WHERE unfurled_url IS NULL OR unfurled_url <> url OR unfurled_at < ?
+		ORDER BY unfurled_at NULLS FIRST, id
+		LIMIT ?`), now.Add(-rf.cfg.StaleAfter), rf.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i, t := range targets {
+		if ctx.Err() != nil {
+			return i, ctx.Err()
+		}
+		meta, fetchErr := rf.fetcher.Fetch(ctx, t.URL)
+		// シャットダウンで中断した場合は取得の失敗として保存しない
+		if ctx.Err() != nil {
+			return i, ctx.Err()
+		}
+		if err := store(ctx, rf.driver, t, meta, fetchErr, rf.now().UTC()); err != nil {
+			return i, err
+		}
+	}
+	return len(targets), nil
+}
@@ -0,0 +1,245 @@
+package link
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/unfurl"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newUnfurlServer はリンク先の代わりにOpenGraphを返すサーバーを起動する
+// 登録されていないパスは404を返す
+func newUnfurlServer(t *testing.T) (*httptest.Server, unfurl.Fetcher) {
+	pages := map[string]string{
+		"/yumi": `<html><head>
+<meta property="og:title" content="雪泉">
+<meta property="og:description" content="閃乱カグラ">
+<meta property="og:image" content="/yumi.png">
+<meta property="og:site_name" content="pixiv">
+</head></html>`,
+		"/takane": `<html><head><title>四条貴音</title></head></html>`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	// 既定のFetcherはループバックのアドレスに接続しないため、テスト用のClientを使う
+	return srv, &unfurl.HTTPFetcher{Client: srv.Client()}
+}
+
+func str(s string) *string {
+	return &s
+}
+
+func TestUnfurlLinkHandler(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+	// ロールバック
+	defer tx.RollbackCtx(ctx)
+	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	srv, fetcher := newUnfurlServer(t)
+	// データベースの準備
+	f := &fixtures.Fixture{DBv1: tx}
+	f.Build(t,
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "閃乱カグラ"
+			s.Url = "https://example.com/image1.png"
+			s.Type = "anime"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "雪泉"
+			s.Image = "https://example.com/image1.png"
+			s.Content = "かわいい"
+			s.CreatedAt = fixedTime
+		}).Connect(fixtures.NewLink(ctx, func(l *fixtures.Link) {
+			l.Type = "funart"
+			l.URL = srv.URL + "/yumi"
+		}), fixtures.NewLink(ctx, func(l *fixtures.Link) {
+			// 前回は取得できたが、リンク切れになったリンク
+			l.Type = "official"
+			l.URL = srv.URL + "/gone"
+			l.Title = str("前回のタイトル")
+			l.UnfurledURL = str(srv.URL + "/gone")
+			l.UnfurledAt = &fixedTime
+		}))),
+	)
+
+	var indexService = service.NewIndexService(
+		tx,
+		cookie.Store,
+		env,
+	)
+	now := fixedTime.Add(24 * time.Hour)
+	h := NewUnfurlHandler(indexService, fetcher)
+	h.now = func() time.Time { return now }
+
+	t.Run("リンク先の情報を取得", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/links/%d/unfurl?id=%d", f.Links[0].ID, f.Links[0].ID), nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var res LinksJson
+		err := json.Unmarshal(w.Body.Bytes(), &res)
+		assert.NoError(t, err)
+		assert.Len(t, res.Links, 1)
+		link := res.Links[0]
+		assert.Equal(t, f.Links[0].URL, link.URL)
+		assert.Equal(t, str("雪泉"), link.Title)
+		assert.Equal(t, str("閃乱カグラ"), link.Description)
+		assert.Equal(t, str(srv.URL+"/yumi.png"), link.Thumbnail)
+		assert.Equal(t, str("pixiv"), link.SiteName)
+		assert.Nil(t, link.UnfurlError)
+		assert.True(t, now.Equal(*link.UnfurledAt))
+	})
+
+	t.Run("取得に失敗した場合は前回の情報を残す", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/links/%d/unfurl?id=%d", f.Links[1].ID, f.Links[1].ID), nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+
+		var actual Link
+		err := tx.GetContext(ctx, &actual, "SELECT * FROM link WHERE id = $1", f.Links[1].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, str("前回のタイトル"), actual.Title)
+		assert.Contains(t, *actual.UnfurlError, "404")
+		assert.True(t, now.Equal(*actual.UnfurledAt))
+	})
+
+	t.Run("存在しないlink", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/links/0/unfurl?id=0", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("idの形式が正しくない", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/links/aaa/unfurl?id=aaa", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+}
+
+func TestRefresher(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+	// ロールバック
+	defer tx.RollbackCtx(ctx)
+	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	now := fixedTime.Add(30 * 24 * time.Hour)
+	recent := now.Add(-time.Hour)
+	stale := now.Add(-8 * 24 * time.Hour)
+	srv, fetcher := newUnfurlServer(t)
+	// データベースの準備
+	f := &fixtures.Fixture{DBv1: tx}
+	f.Build(t,
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "アイドルマスター"
+			s.Url = "https://example.com/image2.png"
+			s.Type = "game"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "四条貴音"
+			s.Image = "https://example.com/image2.png"
+			s.Content = "お姫ちん"
+			s.CreatedAt = fixedTime
+		}).Connect(fixtures.NewLink(ctx, func(l *fixtures.Link) {
+			// 未取得
+			l.URL = srv.URL + "/yumi"
+		}), fixtures.NewLink(ctx, func(l *fixtures.Link) {
+			// 最近取得した
+			l.URL = srv.URL + "/takane"
+			l.Title = str("取得済み")
+			l.UnfurledURL = str(srv.URL + "/takane")
+			l.UnfurledAt = &recent
+		}), fixtures.NewLink(ctx, func(l *fixtures.Link) {
+			// 前回の取得から時間が経過した
+			l.URL = srv.URL + "/takane"
+			l.Title = str("古いタイトル")
+			l.UnfurledURL = str(srv.URL + "/takane")
+			l.UnfurledAt = &stale
+		}), fixtures.NewLink(ctx, func(l *fixtures.Link) {
+			// 取得後にURLが変更され、変更後のURLはリンク切れ
+			l.URL = srv.URL + "/gone"
+			l.Title = str("変更前のタイトル")
+			l.UnfurledURL = str("https://example.com/old")
+			l.UnfurledAt = &recent
+		}))),
+	)
+
+	rf := NewRefresher(tx, fetcher, RefreshConfig{
+		Interval:   time.Hour,
+		StaleAfter: 7 * 24 * time.Hour,
+		BatchSize:  10,
+	})
+	rf.now = func() time.Time { return now }
+
+	t.Run("未取得、古い、URLが変更されたlinkを取得", func(t *testing.T) {
+		n, err := rf.Refresh(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, n)
+
+		var actual []Link
+		err = tx.SelectContext(ctx, &actual, "SELECT * FROM link ORDER BY id")
+		assert.NoError(t, err)
+		assert.Len(t, actual, 4)
+
+		assert.Equal(t, str("雪泉"), actual[0].Title)
+		assert.True(t, now.Equal(*actual[0].UnfurledAt))
+
+		assert.Equal(t, str("取得済み"), actual[1].Title)
+		assert.True(t, recent.Equal(*actual[1].UnfurledAt))
+
+		assert.Equal(t, str("四条貴音"), actual[2].Title)
+		assert.True(t, now.Equal(*actual[2].UnfurledAt))
+
+		// 変更前のURLの情報は消す
+		assert.Nil(t, actual[3].Title)
+		assert.Equal(t, str(srv.URL+"/gone"), actual[3].UnfurledURL)
+		assert.Contains(t, *actual[3].UnfurlError, "404")
+	})
+
+	t.Run("取得し直すlinkがない", func(t *testing.T) {
+		n, err := rf.Refresh(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+	})
+}
@@ -13,7 +13,6 @@ import (
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
 
-	"github.com/maguro-alternative/goheki/pkg/db"
 
 	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
 
@@ -25,7 +24,7 @@ func TestCreatePersonalityTypeHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -86,6 +85,11 @@ func TestCreatePersonalityTypeHandler(t *testing.T) {
 		err = json.Unmarshal(w.Body.Bytes(), &res)
 		assert.NoError(t, err)
 		// レスポンスの検証
+		// 採番されたidが返る
+		for i := range res.PersonalityTypes {
+			assert.NotZero(t, res.PersonalityTypes[i].ID)
+			personalityType.PersonalityTypes[i].ID = res.PersonalityTypes[i].ID
+		}
 		assert.Equal(t, personalityType, res)
 
 		var actuals []PersonalityType
@@ -102,7 +106,7 @@ func TestReadPersonalityTypeHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -233,7 +237,7 @@ func TestReadPersonalityTypeHandler(t *testing.T) {
 		handler := NewReadHandler(indexService)
 		handler.ServeHTTP(w, req)
 		// レスポンスの検証
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 	})
 
 	t.Run("personality_type2件取得(内1件不正なID)", func(t *testing.T) {
@@ -245,7 +249,7 @@ func TestReadPersonalityTypeHandler(t *testing.T) {
 		handler := NewReadHandler(indexService)
 		handler.ServeHTTP(w, req)
 		// レスポンスの検証
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 	})
 }
 
@@ -254,7 +258,7 @@ func TestUpdatePersonalityTypeHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -347,7 +351,7 @@ func TestDeletePersonalityTypeHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
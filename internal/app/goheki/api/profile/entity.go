@@ -0,0 +1,83 @@
+package profile
+
+import (
+	"time"
+)
+
+// Profile はentryに紐づく属性をまとめた1人分のプロフィール
+// 種類のidは名前に解決して返す
+type Profile struct {
+	ID          int64        `db:"id" json:"id"`
+	Name        string       `db:"name" json:"name"`
+	Image       string       `db:"image" json:"image"`
+	Content     string       `db:"content" json:"content"`
+	CreatedAt   time.Time    `db:"created_at" json:"created_at"`
+	Source      Source       `db:"source" json:"source"`
+	BWH         *BWH         `json:"bwh"`
+	RadarChart  []RadarScore `json:"heki_radar_chart"`
+	HairColor   *Type        `json:"haircolor"`
+	HairLength  *Type        `json:"hairlength"`
+	HairStyle   *Type        `json:"hairstyle"`
+	EyeColor    *Type        `json:"eyecolor"`
+	Personality *Type        `json:"personality"`
+	Tags        []Type       `json:"tags"`
+	Links       []Link       `json:"links"`
+	// 設定に応じて隠したリンクの数
+	WithheldLinks int64 `json:"withheld_links"`
+	// ギャラリーの画像 (表示順)
+	Images []Image `json:"images"`
+	// 設定に応じて隠した画像の数
+	WithheldImages int64 `json:"withheld_images"`
+}
+
+type Source struct {
+	ID   int64  `db:"id" json:"id"`
+	Name string `db:"name" json:"name"`
+	Url  string `db:"url" json:"url"`
+	Type string `db:"type" json:"type"`
+}
+
+type BWH struct {
+	Bust   *int64 `db:"bust" json:"bust"`
+	Waist  *int64 `db:"waist" json:"waist"`
+	Hip    *int64 `db:"hip" json:"hip"`
+	Height *int64 `db:"height" json:"height"`
+	Weight *int64 `db:"weight" json:"weight"`
+}
+
+// RadarScore はレーダーチャートの軸ごとの値と軸の範囲
+type RadarScore struct {
+	Axis  string `db:"axis" json:"axis"`
+	Score int64  `db:"score" json:"score"`
+	Min   int64  `db:"min" json:"min"`
+	Max   int64  `db:"max" json:"max"`
+}
+
+// Type は種類のidと名前 (髪色、髪型、タグなど)
+type Type struct {
+	ID   int64  `db:"id" json:"id"`
+	Name string `db:"name" json:"name"`
+}
+
+type Link struct {
+	ID       int64  `db:"id" json:"id"`
+	Type     string `db:"type" json:"type"`
+	URL      string `db:"url" json:"url"`
+	Nsfw     bool   `db:"nsfw" json:"nsfw"`
+	Darkness bool   `db:"darkness" json:"darkness"`
+}
+
+type Image struct {
+	ID           int64   `db:"id" json:"id"`
+	URL          string  `db:"url" json:"url"`
+	ThumbnailURL *string `db:"thumbnail_url" json:"thumbnail_url"`
+	WebPURL      *string `db:"webp_url" json:"webp_url"`
+	Caption      string  `db:"caption" json:"caption"`
+	Primary      bool    `db:"is_primary" json:"is_primary"`
+	Nsfw         bool    `db:"nsfw" json:"nsfw"`
+	Darkness     bool    `db:"darkness" json:"darkness"`
+}
+
+type ProfilesJson struct {
+	Profiles []Profile `json:"profiles"`
+}
@@ -0,0 +1,223 @@
+package profile
+
+import (
+	"context"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/content_filter"
+	"github.com/maguro-alternative/goheki/pkg/db"
+)
+
+// Loader はentryのidの一覧からプロフィールを組み立てる
+// 属性ごとにIN句で1回ずつ取得するため、idの数によらずクエリの回数は一定になる
+type Loader struct {
+	db     db.Driver
+	policy contentfilter.Policy
+}
+
+func NewLoader(driver db.Driver) *Loader {
+	return &Loader{db: driver}
+}
+
+// WithPolicy はpolicyで隠すリンクと画像を除いて組み立てるLoaderを返す
+// 除いた数はProfile.WithheldLinksとProfile.WithheldImagesで返す
+func (l *Loader) WithPolicy(policy contentfilter.Policy) *Loader {
+	return &Loader{db: l.db, policy: policy}
+}
+
+// TypeQuery は種類の名前に解決する属性の取得方法
+// 類似度の計算など、プロフィールの一部だけを組み立てる場合にも同じ定義を使う
+type TypeQuery struct {
+	// entry_id、id、nameを返すSELECT文 (WHERE句とORDER BY句は含めない)
+	Select string
+	// 1人に複数の値がある場合の並び順
+	OrderBy string
+	Set     func(p *Profile, t Type)
+}
+
+// All は全てのentryの属性を取得するクエリを返す
+func (q TypeQuery) All() string {
+	return q.Select + q.OrderBy
+}
+
+// In はentry_idをIN句で絞り込んで取得するクエリを返す
+func (q TypeQuery) In() string {
+	return q.Select + " WHERE entry_id IN (?)" + q.OrderBy
+}
+
+// TypeQueries は1人に1つの属性と、複数のタグを種類の名前に解決するクエリ
+var TypeQueries = []TypeQuery{
+	{
+		Select: "SELECT h.entry_id, t.id, t.color AS name FROM haircolor h JOIN haircolor_type t ON t.id = h.color_id",
+		Set:    func(p *Profile, t Type) { p.HairColor = &t },
+	},
+	{
+		Select: "SELECT h.entry_id, t.id, t.length AS name FROM hairlength h JOIN hairlength_type t ON t.id = h.hairlength_type_id",
+		Set:    func(p *Profile, t Type) { p.HairLength = &t },
+	},
+	{
+		Select: "SELECT h.entry_id, t.id, t.style AS name FROM hairstyle h JOIN hairstyle_type t ON t.id = h.style_id",
+		Set:    func(p *Profile, t Type) { p.HairStyle = &t },
+	},
+	{
+		Select: "SELECT e.entry_id, t.id, t.color AS name FROM eyecolor e JOIN eyecolor_type t ON t.id = e.color_id",
+		Set:    func(p *Profile, t Type) { p.EyeColor = &t },
+	},
+	{
+		Select: "SELECT p.entry_id, t.id, t.type AS name FROM personality p JOIN personality_type t ON t.id = p.type_id",
+		Set:    func(p *Profile, t Type) { p.Personality = &t },
+	},
+	{
+		Select:  "SELECT et.entry_id, t.id, t.name FROM entry_tag et JOIN tag t ON t.id = et.tag_id",
+		OrderBy: " ORDER BY et.id",
+		Set:     func(p *Profile, t Type) { p.Tags = append(p.Tags, t) },
+	},
+}
+
+// Load はidsの順にプロフィールを返す
+// 存在しないidは結果に含めない
+func (l *Loader) Load(ctx context.Context, ids []int64) ([]Profile, error) {
+	if len(ids) == 0 {
+		return []Profile{}, nil
+	}
+	var entries []Profile
+	err := l.selectIn(ctx, &entries, `SELECT
+			e.id,
+			e.name,
+			e.image,
+			e.content,
+			e.created_at,
+			s.id AS "source.id",
+			s.name AS "source.name",
+			s.url AS "source.url",
+			s.type AS "source.type"
+		FROM entry e
+		JOIN source s ON s.id = e.source_id
+		WHERE e.id IN (?)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*Profile, len(entries))
+	for i := range entries {
+		entries[i].RadarChart = []RadarScore{}
+		entries[i].Tags = []Type{}
+		entries[i].Links = []Link{}
+		entries[i].Images = []Image{}
+		byID[entries[i].ID] = &entries[i]
+	}
+	if len(byID) == 0 {
+		return []Profile{}, nil
+	}
+	found := make([]int64, 0, len(byID))
+	for id := range byID {
+		found = append(found, id)
+	}
+
+	var bwhs []struct {
+		EntryID int64 `db:"entry_id"`
+		BWH
+	}
+	if err := l.selectIn(ctx, &bwhs, "SELECT entry_id, bust, waist, hip, height, weight FROM bwh WHERE entry_id IN (?)", found); err != nil {
+		return nil, err
+	}
+	for i := range bwhs {
+		byID[bwhs[i].EntryID].BWH = &bwhs[i].BWH
+	}
+
+	var scores []struct {
+		EntryID int64 `db:"entry_id"`
+		RadarScore
+	}
+	err = l.selectIn(ctx, &scores, `SELECT
+			s.entry_id,
+			a.name AS axis,
+			s.score,
+			a.min,
+			a.max
+		FROM heki_radar_score s
+		JOIN heki_radar_axis a ON a.id = s.axis_id
+		WHERE s.entry_id IN (?)
+		ORDER BY a.display_order, a.id`, found)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range scores {
+		p := byID[s.EntryID]
+		p.RadarChart = append(p.RadarChart, s.RadarScore)
+	}
+
+	for _, tq := range TypeQueries {
+		var types []struct {
+			EntryID int64 `db:"entry_id"`
+			Type
+		}
+		if err := l.selectIn(ctx, &types, tq.In(), found); err != nil {
+			return nil, err
+		}
+		for _, t := range types {
+			tq.Set(byID[t.EntryID], t.Type)
+		}
+	}
+
+	var links []struct {
+		EntryID int64 `db:"entry_id"`
+		Link
+	}
+	if err := l.selectIn(ctx, &links, "SELECT entry_id, id, type, url, nsfw, darkness FROM link WHERE entry_id IN (?) ORDER BY id", found); err != nil {
+		return nil, err
+	}
+	for _, link := range links {
+		p := byID[link.EntryID]
+		if !l.policy.Allows(link.Nsfw, link.Darkness) {
+			p.WithheldLinks++
+			continue
+		}
+		p.Links = append(p.Links, link.Link)
+	}
+
+	var images []struct {
+		EntryID int64 `db:"entry_id"`
+		Image
+	}
+	err = l.selectIn(ctx, &images, `SELECT
+			entry_id,
+			id,
+			url,
+			thumbnail_url,
+			webp_url,
+			caption,
+			is_primary,
+			nsfw,
+			darkness
+		FROM entry_image
+		WHERE entry_id IN (?)
+		ORDER BY position, id`, found)
+	if err != nil {
+		return nil, err
+	}
+	for _, image := range images {
+		p := byID[image.EntryID]
+		if !l.policy.Allows(image.Nsfw, image.Darkness) {
+			p.WithheldImages++
+			continue
+		}
+		p.Images = append(p.Images, image.Image)
+	}
+
+	profiles := make([]Profile, 0, len(byID))
+	for _, id := range ids {
+		if p, ok := byID[id]; ok {
+			profiles = append(profiles, *p)
+		}
+	}
+	return profiles, nil
+}
+
+// selectIn はidsをIN句に展開して取得する
+func (l *Loader) selectIn(ctx context.Context, dest any, query string, ids []int64) error {
+	query, args, err := db.In(query, ids)
+	if err != nil {
+		return err
+	}
+	// DBの種類に合わせて置換文字を変える
+	return l.db.SelectContext(ctx, dest, l.db.Rebind(query), args...)
+}
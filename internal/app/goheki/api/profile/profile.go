@@ -0,0 +1,106 @@
+package profile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/content_filter"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// MaxIDs は1回のリクエストで取得できるプロフィールの最大数
+const MaxIDs = 100
+
+type ReadHandler struct {
+	svc *service.IndexService
+}
+
+func NewReadHandler(svc *service.IndexService) *ReadHandler {
+	return &ReadHandler{
+		svc: svc,
+	}
+}
+
+// ServeHTTP はプロフィールを返す
+//
+//	GET /api/entries/{id}/profile, GET /api/profiles/{id} → 1人分のプロフィール
+//	GET /api/profiles?id=1&id=2                           → {"profiles": [...]}
+func (h *ReadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// GET以外は受け付けない
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	ids, err := parseIDs(r.URL.Query()["id"])
+	if err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+	// nsfwとdarknessのリンクは設定に応じて隠す
+	policy, err := contentfilter.FromRequest(r, h.svc)
+	var verrs validation.Errors
+	if errors.As(err, &verrs) {
+		problem.Validation(w, r, verrs)
+		return
+	} else if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+	profiles, err := NewLoader(h.svc.DB).WithPolicy(policy).Load(r.Context(), ids)
+	if err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+	// /api/entries/{id}/profileの場合は1人分をそのまま返す
+	if router.PathID(r) != "" {
+		if len(profiles) == 0 {
+			problem.NotFound(w, r, fmt.Errorf("entry %d not found", ids[0]))
+			return
+		}
+		if err := json.NewEncoder(w).Encode(&profiles[0]); err != nil {
+			log.Printf("json encode error: %v", err)
+		}
+		return
+	}
+	if err := json.NewEncoder(w).Encode(&ProfilesJson{Profiles: profiles}); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
+
+// parseIDs はクエリパラメータのidを数値に変換する
+func parseIDs(values []string) ([]int64, error) {
+	if len(values) == 0 {
+		return nil, validation.Errors{"id": errors.New("cannot be blank")}
+	}
+	if len(values) > MaxIDs {
+		return nil, validation.Errors{"id": fmt.Errorf("at most %d ids are allowed", MaxIDs)}
+	}
+	ids := make([]int64, len(values))
+	for i, v := range values {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, validation.Errors{"id": fmt.Errorf("invalid id %q", v)}
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// Routes は/api/profilesのルーティングの定義を返す
+// /api/entries/{id}/profileはentry.Routesで登録する
+func Routes(svc *service.IndexService) router.Resource {
+	return router.Resource{
+		Name: "profiles",
+		Key:  "id",
+		Read: NewReadHandler(svc),
+	}
+}
@@ -0,0 +1,294 @@
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
+
+	"github.com/justinas/alice"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadProfileHandler(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+
+	// トランザクションのロールバック
+	defer tx.RollbackCtx(ctx)
+
+	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	takaneHeight := int64(169)
+	// データベースの準備
+	f := &fixtures.Fixture{DBv1: tx}
+	f.Build(t,
+		fixtures.NewHairColorType(ctx, func(s *fixtures.HairColorType) {
+			s.Color = "銀"
+		}),
+		fixtures.NewHairLengthType(ctx, func(s *fixtures.HairLengthType) {
+			s.Length = "ロング"
+		}),
+		fixtures.NewHairStyleType(ctx, func(s *fixtures.HairStyleType) {
+			s.Style = "ストレート"
+		}),
+		fixtures.NewEyeColorType(ctx, func(s *fixtures.EyeColorType) {
+			s.Color = "紫"
+		}),
+		fixtures.NewPersonalityType(ctx, func(s *fixtures.PersonalityType) {
+			s.Type = "大和撫子"
+		}),
+		fixtures.NewTag(ctx, func(s *fixtures.Tag) {
+			s.Name = "お姫様"
+		}),
+		fixtures.NewHekiRadarAxis(ctx, func(s *fixtures.HekiRadarAxis) {
+			s.Name = "nu"
+			s.DisplayOrder = 2
+		}),
+		fixtures.NewHekiRadarAxis(ctx, func(s *fixtures.HekiRadarAxis) {
+			s.Name = "ai"
+			s.DisplayOrder = 1
+		}),
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "アイドルマスター"
+			s.Url = "https://example.com/image2.png"
+			s.Type = "game"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "四条貴音"
+			s.Image = "https://example.com/image2.png"
+			s.Content = "お姫ちん"
+			s.CreatedAt = fixedTime
+		}).Connect(
+			fixtures.NewBWH(ctx, func(s *fixtures.BWH) {
+				s.Bust = 90
+				s.Waist = 60
+				s.Hip = 92
+				s.Height = &takaneHeight
+			}),
+			fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+				s.AxisID = f.HekiRadarAxes[0].ID
+				s.Score = 70
+			}),
+			fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+				s.AxisID = f.HekiRadarAxes[1].ID
+				s.Score = 80
+			}),
+			fixtures.NewHairColor(ctx, func(s *fixtures.HairColor) {
+				s.ColorID = f.HairColorTypes[0].ID
+			}),
+			fixtures.NewHairLength(ctx, func(s *fixtures.HairLength) {
+				s.HairLengthTypeID = f.HairLengthTypes[0].ID
+			}),
+			fixtures.NewHairStyle(ctx, func(s *fixtures.HairStyle) {
+				s.StyleID = f.HairStyleTypes[0].ID
+			}),
+			fixtures.NewEyeColor(ctx, func(s *fixtures.EyeColor) {
+				s.ColorID = f.EyeColorTypes[0].ID
+			}),
+			fixtures.NewPersonality(ctx, func(s *fixtures.Personality) {
+				s.TypeID = f.PersonalityTypes[0].ID
+			}),
+			fixtures.NewEntryTag(ctx, func(s *fixtures.EntryTag) {
+				s.TagID = f.Tags[0].ID
+			}),
+			fixtures.NewLink(ctx, func(s *fixtures.Link) {
+				s.Type = "blog"
+				s.URL = "https://example.com/takane"
+			}),
+			fixtures.NewLink(ctx, func(s *fixtures.Link) {
+				s.Type = "funart"
+				s.URL = "https://example.com/takane-nsfw"
+				s.Nsfw = true
+			}),
+			fixtures.NewEntryImage(ctx, func(s *fixtures.EntryImage) {
+				s.URL = "https://example.com/takane-swimsuit.png"
+				s.Caption = "水着"
+				s.Position = 1
+				s.Nsfw = true
+			}),
+			fixtures.NewEntryImage(ctx, func(s *fixtures.EntryImage) {
+				s.URL = "https://example.com/image2.png"
+				s.Caption = "公式"
+				s.Primary = true
+			}),
+		)),
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "閃乱カグラ"
+			s.Url = "https://example.com/image1.png"
+			s.Type = "anime"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "雪泉"
+			s.Image = "https://example.com/image1.png"
+			s.Content = "かわいい"
+			s.CreatedAt = fixedTime
+		})),
+	)
+
+	var indexService = service.NewIndexService(
+		tx,
+		cookie.Store,
+		env,
+	)
+	mux := http.NewServeMux()
+	router.New(mux, alice.New()).Handle(Routes(indexService))
+
+	// 1人目は全ての属性を持つ
+	bust, waist, hip := int64(90), int64(60), int64(92)
+	takane := Profile{
+		ID:        f.Entrys[0].ID,
+		Name:      "四条貴音",
+		Image:     "https://example.com/image2.png",
+		Content:   "お姫ちん",
+		CreatedAt: fixedTime,
+		Source: Source{
+			ID:   f.Sources[0].ID,
+			Name: "アイドルマスター",
+			Url:  "https://example.com/image2.png",
+			Type: "game",
+		},
+		BWH: &BWH{
+			Bust:   &bust,
+			Waist:  &waist,
+			Hip:    &hip,
+			Height: &takaneHeight,
+		},
+		// 軸の表示順に並べる
+		RadarChart: []RadarScore{
+			{Axis: "ai", Score: 80, Min: 0, Max: 100},
+			{Axis: "nu", Score: 70, Min: 0, Max: 100},
+		},
+		HairColor:   &Type{ID: f.HairColorTypes[0].ID, Name: "銀"},
+		HairLength:  &Type{ID: f.HairLengthTypes[0].ID, Name: "ロング"},
+		HairStyle:   &Type{ID: f.HairStyleTypes[0].ID, Name: "ストレート"},
+		EyeColor:    &Type{ID: f.EyeColorTypes[0].ID, Name: "紫"},
+		Personality: &Type{ID: f.PersonalityTypes[0].ID, Name: "大和撫子"},
+		Tags:        []Type{{ID: f.Tags[0].ID, Name: "お姫様"}},
+		Links: []Link{
+			{
+				ID:   f.Links[0].ID,
+				Type: "blog",
+				URL:  "https://example.com/takane",
+			},
+			{
+				ID:   f.Links[1].ID,
+				Type: "funart",
+				URL:  "https://example.com/takane-nsfw",
+				Nsfw: true,
+			},
+		},
+		// 表示順に並べる
+		Images: []Image{
+			{
+				ID:      f.EntryImages[1].ID,
+				URL:     "https://example.com/image2.png",
+				Caption: "公式",
+				Primary: true,
+			},
+			{
+				ID:      f.EntryImages[0].ID,
+				URL:     "https://example.com/takane-swimsuit.png",
+				Caption: "水着",
+				Nsfw:    true,
+			},
+		},
+	}
+	// 2人目は属性を持たない
+	yumi := Profile{
+		ID:        f.Entrys[1].ID,
+		Name:      "雪泉",
+		Image:     "https://example.com/image1.png",
+		Content:   "かわいい",
+		CreatedAt: fixedTime,
+		Source: Source{
+			ID:   f.Sources[1].ID,
+			Name: "閃乱カグラ",
+			Url:  "https://example.com/image1.png",
+			Type: "anime",
+		},
+		RadarChart: []RadarScore{},
+		Tags:       []Type{},
+		Links:      []Link{},
+		Images:     []Image{},
+	}
+
+	t.Run("profile1件取得", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/profiles/%d", f.Entrys[0].ID), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var actual Profile
+		err := json.NewDecoder(w.Body).Decode(&actual)
+		assert.NoError(t, err)
+		assert.Equal(t, takane, actual)
+	})
+
+	t.Run("profile複数取得", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/profiles?id=%d&id=%d", f.Entrys[1].ID, f.Entrys[0].ID), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var actual ProfilesJson
+		err := json.NewDecoder(w.Body).Decode(&actual)
+		assert.NoError(t, err)
+		// 指定したidの順に返す
+		assert.Equal(t, ProfilesJson{Profiles: []Profile{yumi, takane}}, actual)
+	})
+
+	t.Run("nsfwのリンクと画像を隠す", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/profiles/%d?hide_nsfw=true", f.Entrys[0].ID), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var actual Profile
+		err := json.NewDecoder(w.Body).Decode(&actual)
+		assert.NoError(t, err)
+		assert.Equal(t, takane.Links[:1], actual.Links)
+		assert.Equal(t, int64(1), actual.WithheldLinks)
+		assert.Equal(t, takane.Images[:1], actual.Images)
+		assert.Equal(t, int64(1), actual.WithheldImages)
+	})
+
+	t.Run("hide_nsfwの形式が正しくない", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/profiles/%d?hide_nsfw=aaa", f.Entrys[0].ID), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("profile存在しないid", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/profiles/%d", f.Entrys[1].ID+100), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("profileのidが正しくない", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/profiles?id=aaa", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+}
@@ -0,0 +1,205 @@
+package radar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/pkg/db"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// MaxCompare はcompareで重ねて描画できるentryの最大の数
+const MaxCompare = 4
+
+// hexColor は#を省略できる3桁または6桁の16進数の色
+var hexColor = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+type ReadHandler struct {
+	svc *service.IndexService
+}
+
+func NewReadHandler(svc *service.IndexService) *ReadHandler {
+	return &ReadHandler{
+		svc: svc,
+	}
+}
+
+// ServeHTTP はentryのレーダーチャートをSVGで返す
+//
+//	GET /api/entries/{id}/radar.svg?size=400&labels=false&compare=2&compare=3&color=e4007f&color=00a0e9
+//
+// compareを指定した場合は指定したentryの値を重ねて描画する
+// 色は#を省略した16進数で指定し、backgroundはnoneで透明にする
+func (h *ReadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// GET以外は受け付けない
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	ids, opts, err := parseQuery(r.URL.Query())
+	if err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+	axes, series, err := h.load(r.Context(), ids)
+	if err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+	for i, id := range ids {
+		if series[i].Scores != nil {
+			continue
+		}
+		if i == 0 {
+			problem.NotFound(w, r, fmt.Errorf("entry %d not found", id))
+		} else {
+			problem.Validation(w, r, validation.Errors{"compare": fmt.Errorf("entry %d not found", id)})
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	if err := Render(w, axes, series, opts); err != nil {
+		log.Printf("svg render error: %v", err)
+	}
+}
+
+// load は全ての軸と、idsの順にentryの値を返す
+// 存在しないidの系列はScoresがnilになる
+func (h *ReadHandler) load(ctx context.Context, ids []int64) ([]Axis, []Series, error) {
+	var axes []Axis
+	if err := h.svc.DB.SelectContext(ctx, &axes, "SELECT name, min, max FROM heki_radar_axis ORDER BY display_order, id"); err != nil {
+		return nil, nil, err
+	}
+	var entries []struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+	if err := h.selectIn(ctx, &entries, "SELECT id, name FROM entry WHERE id IN (?)", ids); err != nil {
+		return nil, nil, err
+	}
+	var scores []struct {
+		EntryID int64  `db:"entry_id"`
+		Name    string `db:"name"`
+		Score   int64  `db:"score"`
+	}
+	err := h.selectIn(ctx, &scores, `SELECT
+			s.entry_id,
+			a.name,
+			s.score
+		FROM heki_radar_score s
+		JOIN heki_radar_axis a ON a.id = s.axis_id
+		WHERE s.entry_id IN (?)`, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byID := make(map[int64]*Series, len(ids))
+	for _, e := range entries {
+		byID[e.ID] = &Series{Name: e.Name, Scores: map[string]int64{}}
+	}
+	for _, s := range scores {
+		if series, ok := byID[s.EntryID]; ok {
+			series.Scores[s.Name] = s.Score
+		}
+	}
+	series := make([]Series, len(ids))
+	for i, id := range ids {
+		if s, ok := byID[id]; ok {
+			series[i] = *s
+		}
+	}
+	return axes, series, nil
+}
+
+// selectIn はIN句を展開して取得する
+func (h *ReadHandler) selectIn(ctx context.Context, dest any, query string, args ...any) error {
+	query, args, err := db.In(query, args...)
+	if err != nil {
+		return err
+	}
+	// DBの種類に合わせて置換文字を変える
+	return h.svc.DB.SelectContext(ctx, dest, h.svc.DB.Rebind(query), args...)
+}
+
+// parseQuery は描画するentryのid(先頭がパスのid)と描画の設定を検証する
+func parseQuery(query url.Values) ([]int64, Options, error) {
+	errs := validation.Errors{}
+	opts := DefaultOptions()
+
+	id, err := strconv.ParseInt(query.Get("id"), 10, 64)
+	if err != nil {
+		errs["id"] = fmt.Errorf("invalid id %q", query.Get("id"))
+	}
+	ids := []int64{id}
+	// compare=2&compare=3 と compare=2,3 のどちらも受け付ける
+	for _, value := range query["compare"] {
+		for _, v := range strings.Split(value, ",") {
+			cid, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				errs["compare"] = fmt.Errorf("invalid id %q", v)
+				continue
+			}
+			ids = append(ids, cid)
+		}
+	}
+	if len(ids)-1 > MaxCompare {
+		errs["compare"] = fmt.Errorf("must be at most %d entries", MaxCompare)
+	}
+
+	if v := query.Get("size"); v != "" {
+		opts.Size, err = strconv.Atoi(v)
+		if err != nil || opts.Size < MinSize || opts.Size > MaxSize {
+			errs["size"] = fmt.Errorf("must be between %d and %d", MinSize, MaxSize)
+		}
+	}
+	if v := query.Get("labels"); v != "" {
+		opts.Labels, err = strconv.ParseBool(v)
+		if err != nil {
+			errs["labels"] = errors.New("must be true or false")
+		}
+	}
+	if values, ok := query["color"]; ok {
+		opts.Colors = make([]string, len(values))
+		for i, v := range values {
+			opts.Colors[i] = parseColor(errs, "color", v)
+		}
+	}
+	if v := query.Get("background"); v == "none" {
+		opts.Background = ""
+	} else if v != "" {
+		opts.Background = parseColor(errs, "background", v)
+	}
+	if v := query.Get("grid_color"); v != "" {
+		opts.GridColor = parseColor(errs, "grid_color", v)
+	}
+	if v := query.Get("text_color"); v != "" {
+		opts.TextColor = parseColor(errs, "text_color", v)
+	}
+
+	if len(errs) > 0 {
+		return nil, Options{}, errs
+	}
+	return ids, opts, nil
+}
+
+// parseColor は16進数の色を#付きで返す
+// SVGの属性にそのまま書き込むため、形式が正しくない場合はerrsに追加する
+func parseColor(errs validation.Errors, key, v string) string {
+	if !hexColor.MatchString(v) {
+		errs[key] = fmt.Errorf("invalid color %q", v)
+		return ""
+	}
+	return "#" + strings.TrimPrefix(v, "#")
+}
@@ -0,0 +1,152 @@
+package radar
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
+
+	"github.com/justinas/alice"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadRadarHandler(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+
+	// トランザクションのロールバック
+	defer tx.RollbackCtx(ctx)
+
+	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	// データベースの準備
+	f := &fixtures.Fixture{DBv1: tx}
+	f.Build(t,
+		fixtures.NewHekiRadarAxis(ctx, func(s *fixtures.HekiRadarAxis) {
+			s.Name = "ai"
+			s.DisplayOrder = 1
+		}),
+		fixtures.NewHekiRadarAxis(ctx, func(s *fixtures.HekiRadarAxis) {
+			s.Name = "nu"
+			s.DisplayOrder = 2
+		}),
+		fixtures.NewHekiRadarAxis(ctx, func(s *fixtures.HekiRadarAxis) {
+			s.Name = "尊さ"
+			s.DisplayOrder = 3
+		}),
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "閃乱カグラ"
+			s.Url = "https://example.com/image1.png"
+			s.Type = "anime"
+		}).Connect(
+			fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+				s.Name = "雪泉"
+				s.Image = "https://example.com/image1.png"
+				s.Content = "かわいい"
+				s.CreatedAt = fixedTime
+			}).Connect(
+				fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+					s.AxisID = f.HekiRadarAxes[0].ID
+					s.Score = 100
+				}),
+				fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+					s.AxisID = f.HekiRadarAxes[1].ID
+					s.Score = 50
+				}),
+			),
+			fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+				s.Name = "焔"
+				s.Image = "https://example.com/image3.png"
+				s.Content = "かっこいい"
+				s.CreatedAt = fixedTime
+			}).Connect(
+				fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+					s.AxisID = f.HekiRadarAxes[2].ID
+					s.Score = 100
+				}),
+			),
+		),
+	)
+
+	var indexService = service.NewIndexService(
+		tx,
+		cookie.Store,
+		env,
+	)
+	mux := http.NewServeMux()
+	router.New(mux, alice.New()).Handle(router.Resource{
+		Name: "entries",
+		Key:  "id",
+		Subresources: map[string]http.Handler{
+			"radar.svg": NewReadHandler(indexService),
+		},
+	})
+
+	t.Run("レーダーチャートを取得", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/entries/%d/radar.svg", f.Entrys[0].ID), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/svg+xml", w.Header().Get("Content-Type"))
+		svg := w.Body.String()
+		assert.Contains(t, svg, `<title>雪泉</title>`)
+		// 軸の表示順に ai=100% nu=50% 尊さ=値なし
+		assert.Contains(t, svg, `<polygon points="200,72 255.43,232 200,200" fill="#e4007f"`)
+		assert.Contains(t, svg, `>尊さ</text>`)
+	})
+
+	t.Run("他のentryと比較", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/entries/%d/radar.svg?compare=%d&size=200&labels=false&color=111&color=222", f.Entrys[0].ID, f.Entrys[1].ID), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		svg := w.Body.String()
+		assert.Contains(t, svg, `width="200" height="200"`)
+		assert.Contains(t, svg, `<title>雪泉</title>`)
+		assert.Contains(t, svg, `<title>焔</title>
+<polygon points="100,100 100,100 22.06,145" fill="#222"`)
+		assert.NotContains(t, svg, "<text")
+	})
+
+	t.Run("存在しないentry", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/entries/%d/radar.svg", f.Entrys[1].ID+100), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("比較するentryが存在しない", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/entries/%d/radar.svg?compare=%d", f.Entrys[0].ID, f.Entrys[1].ID+100), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("設定の形式が正しくない", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/entries/%d/radar.svg?size=1&color=red", f.Entrys[0].ID), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+}
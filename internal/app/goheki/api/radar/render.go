@@ -0,0 +1,186 @@
+package radar
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultSize は画像の幅と高さの既定値(px)
+	DefaultSize = 400
+	// MinSize は指定できる最小の大きさ
+	MinSize = 100
+	// MaxSize は指定できる最大の大きさ
+	MaxSize = 2000
+	// gridLevels は目盛りの多角形の数
+	gridLevels = 4
+)
+
+// DefaultColors は系列ごとの色の既定値
+// 系列が色の数より多い場合は先頭から繰り返す
+var DefaultColors = []string{"#e4007f", "#00a0e9", "#f39800", "#009944", "#8f82bc"}
+
+// Axis は描画する軸と値の範囲
+type Axis struct {
+	Name string `db:"name"`
+	Min  int64  `db:"min"`
+	Max  int64  `db:"max"`
+}
+
+// Series は1人分の値
+// Scoresのキーは軸の名前で、値がない軸は最小値として描画する
+type Series struct {
+	Name   string
+	Scores map[string]int64
+}
+
+// Options は描画の設定
+type Options struct {
+	// 幅と高さ(px)
+	Size int
+	// 系列ごとの線と塗りの色
+	Colors []string
+	// 背景色 (空の場合は透明)
+	Background string
+	// 目盛りと軸の色
+	GridColor string
+	// 軸の名前と凡例の色
+	TextColor string
+	// 軸の名前と凡例を描画するか
+	Labels bool
+}
+
+// DefaultOptions は既定の描画の設定を返す
+func DefaultOptions() Options {
+	return Options{
+		Size:       DefaultSize,
+		Colors:     DefaultColors,
+		Background: "#ffffff",
+		GridColor:  "#cccccc",
+		TextColor:  "#333333",
+		Labels:     true,
+	}
+}
+
+// Render は軸の順に時計回りで、上を起点としたレーダーチャートをSVGで書き込む
+// 系列は先に指定したものほど手前に描画する
+func Render(w io.Writer, axes []Axis, series []Series, opts Options) error {
+	bw := bufio.NewWriter(w)
+	size := float64(opts.Size)
+	center := size / 2
+	// 軸の名前を描画する場合は周囲に余白を取る
+	radius := size * 0.45
+	if opts.Labels {
+		radius = size * 0.32
+	}
+
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="%[1]d" viewBox="0 0 %[1]d %[1]d">`, opts.Size)
+	bw.WriteString("\n")
+	if opts.Background != "" {
+		fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", opts.Background)
+	}
+
+	n := len(axes)
+	// point はi番目の軸上で中心からratioの位置の座標を返す
+	point := func(i int, ratio float64) (float64, float64) {
+		angle := -math.Pi/2 + 2*math.Pi*float64(i)/float64(n)
+		return center + radius*ratio*math.Cos(angle), center + radius*ratio*math.Sin(angle)
+	}
+	polygon := func(ratio func(i int) float64) string {
+		points := make([]string, n)
+		for i := range axes {
+			x, y := point(i, ratio(i))
+			points[i] = num(x) + "," + num(y)
+		}
+		return strings.Join(points, " ")
+	}
+
+	if n > 0 {
+		bw.WriteString(`<g class="grid" fill="none" stroke="` + opts.GridColor + `" stroke-width="1">` + "\n")
+		for level := 1; level <= gridLevels; level++ {
+			ratio := float64(level) / gridLevels
+			fmt.Fprintf(bw, `<polygon points="%s"/>`+"\n", polygon(func(int) float64 { return ratio }))
+		}
+		for i := range axes {
+			x, y := point(i, 1)
+			fmt.Fprintf(bw, `<line x1="%s" y1="%s" x2="%s" y2="%s"/>`+"\n", num(center), num(center), num(x), num(y))
+		}
+		bw.WriteString("</g>\n")
+	}
+
+	// 先に指定した系列が手前になるよう逆順に描画する
+	for s := len(series) - 1; s >= 0 && n > 0; s-- {
+		color := opts.Colors[s%len(opts.Colors)]
+		fmt.Fprintf(bw, `<g class="series"><title>%s</title>`+"\n", escape(series[s].Name))
+		fmt.Fprintf(bw, `<polygon points="%s" fill="%s" fill-opacity="0.25" stroke="%s" stroke-width="2"/>`+"\n",
+			polygon(func(i int) float64 { return ratio(axes[i], series[s].Scores) }), color, color)
+		bw.WriteString("</g>\n")
+	}
+
+	if opts.Labels {
+		fontSize := math.Max(10, size/30)
+		fmt.Fprintf(bw, `<g class="labels" fill="%s" font-family="sans-serif" font-size="%s">`+"\n", opts.TextColor, num(fontSize))
+		for i, axis := range axes {
+			x, y := point(i, 1.12)
+			fmt.Fprintf(bw, `<text x="%s" y="%s" text-anchor="%s" dominant-baseline="middle">%s</text>`+"\n",
+				num(x), num(y), anchor(x, center), escape(axis.Name))
+		}
+		// 複数の系列を比較する場合は凡例を描画する
+		if len(series) > 1 {
+			for s := range series {
+				y := fontSize * (1.5*float64(s) + 1)
+				fmt.Fprintf(bw, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`+"\n",
+					num(fontSize/2), num(y-fontSize/2), num(fontSize), num(fontSize), opts.Colors[s%len(opts.Colors)])
+				fmt.Fprintf(bw, `<text x="%s" y="%s" dominant-baseline="middle">%s</text>`+"\n",
+					num(fontSize*2), num(y), escape(series[s].Name))
+			}
+		}
+		bw.WriteString("</g>\n")
+	}
+	bw.WriteString("</svg>\n")
+	return bw.Flush()
+}
+
+// ratio は値を軸の範囲の中での割合(0〜1)に変換する
+func ratio(axis Axis, scores map[string]int64) float64 {
+	score, ok := scores[axis.Name]
+	if !ok || axis.Max <= axis.Min {
+		return 0
+	}
+	r := float64(score-axis.Min) / float64(axis.Max-axis.Min)
+	return math.Max(0, math.Min(1, r))
+}
+
+// anchor は中心からの位置に応じて文字の揃え方を返す
+func anchor(x, center float64) string {
+	switch {
+	case math.Abs(x-center) < 1:
+		return "middle"
+	case x < center:
+		return "end"
+	default:
+		return "start"
+	}
+}
+
+// num は座標を小数点以下2桁までの文字列にする
+func num(f float64) string {
+	f = math.Round(f*100) / 100
+	// -0を避ける
+	if f == 0 {
+		f = 0
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	// strings.Builderへの書き込みは失敗しない
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,133 @@
+package radar
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	axes := []Axis{
+		{Name: "ai", Min: 0, Max: 100},
+		{Name: "nu", Min: 0, Max: 100},
+		{Name: "<萌え>", Min: -10, Max: 10},
+		{Name: "尊さ", Min: 0, Max: 100},
+	}
+
+	t.Run("1人分を描画", func(t *testing.T) {
+		var b bytes.Buffer
+		err := Render(&b, axes, []Series{
+			{Name: "雪泉", Scores: map[string]int64{"ai": 100, "nu": 50, "<萌え>": 10}},
+		}, DefaultOptions())
+		assert.NoError(t, err)
+		svg := b.String()
+
+		// 整形式のXMLであること
+		assert.NoError(t, xml.Unmarshal(b.Bytes(), new(struct{})))
+		assert.Contains(t, svg, `width="400" height="400" viewBox="0 0 400 400"`)
+		assert.Contains(t, svg, `<rect width="100%" height="100%" fill="#ffffff"/>`)
+		// 上から時計回りに ai=100% nu=50% 萌え=100% 尊さ=値なし
+		assert.Contains(t, svg, `<polygon points="200,72 264,200 200,328 200,200" fill="#e4007f"`)
+		assert.Contains(t, svg, `<title>雪泉</title>`)
+		assert.Contains(t, svg, `&lt;萌え&gt;</text>`)
+		// 1人分の場合は凡例を描画しない
+		assert.Equal(t, 4, bytes.Count(b.Bytes(), []byte("<text")))
+	})
+
+	t.Run("複数人を重ねて描画", func(t *testing.T) {
+		opts := DefaultOptions()
+		opts.Colors = []string{"#111", "#222"}
+		var b bytes.Buffer
+		err := Render(&b, axes, []Series{
+			{Name: "雪泉", Scores: map[string]int64{"ai": 100}},
+			{Name: "焔", Scores: map[string]int64{"ai": 0}},
+			{Name: "四条貴音", Scores: map[string]int64{"ai": 50}},
+		}, opts)
+		assert.NoError(t, err)
+		svg := b.String()
+
+		assert.NoError(t, xml.Unmarshal(b.Bytes(), new(struct{})))
+		// 先に指定したものほど手前(後)に描画する
+		first := bytes.Index(b.Bytes(), []byte("<title>雪泉</title>"))
+		last := bytes.Index(b.Bytes(), []byte("<title>四条貴音</title>"))
+		assert.Greater(t, first, last)
+		// 色は先頭から繰り返す
+		assert.Contains(t, svg, `<title>四条貴音</title>
+<polygon points="200,136 200,200 200,200 200,200" fill="#111"`)
+		// 凡例
+		assert.Contains(t, svg, `>四条貴音</text>`)
+		assert.Equal(t, 7, bytes.Count(b.Bytes(), []byte("<text")))
+	})
+
+	t.Run("軸の名前を描画しない", func(t *testing.T) {
+		opts := DefaultOptions()
+		opts.Size = 200
+		opts.Labels = false
+		opts.Background = ""
+		var b bytes.Buffer
+		err := Render(&b, axes, []Series{
+			{Name: "雪泉", Scores: map[string]int64{"ai": 200}},
+		}, opts)
+		assert.NoError(t, err)
+		svg := b.String()
+
+		assert.NotContains(t, svg, "<text")
+		assert.NotContains(t, svg, "<rect")
+		// 範囲外の値は最大値として描画する
+		assert.Contains(t, svg, `<polygon points="100,10 100,100 100,100 100,100" fill="#e4007f"`)
+	})
+
+	t.Run("軸がない", func(t *testing.T) {
+		var b bytes.Buffer
+		err := Render(&b, nil, []Series{{Name: "雪泉"}}, DefaultOptions())
+		assert.NoError(t, err)
+
+		assert.NoError(t, xml.Unmarshal(b.Bytes(), new(struct{})))
+		assert.NotContains(t, b.String(), "<polygon")
+	})
+}
+
+func TestParseQuery(t *testing.T) {
+	t.Run("既定値", func(t *testing.T) {
+		ids, opts, err := parseQuery(map[string][]string{"id": {"1"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{1}, ids)
+		assert.Equal(t, DefaultOptions(), opts)
+	})
+
+	t.Run("設定を指定", func(t *testing.T) {
+		ids, opts, err := parseQuery(map[string][]string{
+			"id":         {"1"},
+			"compare":    {"2,3", "4"},
+			"size":       {"800"},
+			"labels":     {"false"},
+			"color":      {"FF0000", "#0f0"},
+			"background": {"none"},
+			"text_color": {"000"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{1, 2, 3, 4}, ids)
+		assert.Equal(t, Options{
+			Size:       800,
+			Colors:     []string{"#FF0000", "#0f0"},
+			Background: "",
+			GridColor:  "#cccccc",
+			TextColor:  "#000",
+			Labels:     false,
+		}, opts)
+	})
+
+	t.Run("形式が正しくない", func(t *testing.T) {
+		_, _, err := parseQuery(map[string][]string{
+			"id":         {"1"},
+			"compare":    {"1,2,3,4,5"},
+			"size":       {"10"},
+			"labels":     {"maybe"},
+			"color":      {`red" onload="alert(1)`},
+			"background": {"#12345"},
+		})
+		assert.EqualError(t, err, "background: invalid color \"#12345\"; color: invalid color \"red\\\" onload=\\\"alert(1)\"; compare: must be at most 4 entries; labels: must be true or false; size: must be between 100 and 2000.")
+	})
+}
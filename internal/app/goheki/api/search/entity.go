@@ -0,0 +1,35 @@
+package search
+
+import (
+	"github.com/maguro-alternative/goheki/internal/app/goheki/fulltext"
+)
+
+// Document は検索対象のentry
+type Document struct {
+	ID         int64  `db:"id"`
+	Name       string `db:"name"`
+	Image      string `db:"image"`
+	Content    string `db:"content"`
+	SourceName string `db:"source_name"`
+}
+
+type EntryTag struct {
+	EntryID int64  `db:"entry_id"`
+	Name    string `db:"name"`
+}
+
+// Result は検索結果の1件
+type Result struct {
+	ID         int64              `json:"id"`
+	Name       string             `json:"name"`
+	Image      string             `json:"image"`
+	SourceName string             `json:"source_name"`
+	Score      float64            `json:"score"`
+	Snippets   []fulltext.Snippet `json:"snippets"`
+}
+
+type ResultsJson struct {
+	Query   string   `json:"query"`
+	Total   int      `json:"total"`
+	Results []Result `json:"results"`
+}
@@ -0,0 +1,219 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/fulltext"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/pkg/db"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+const (
+	// DefaultLimit はlimitを省略した場合の件数
+	DefaultLimit = 20
+	// MaxLimit はlimitに指定できる最大の件数
+	MaxLimit = 100
+	// IndexTTL は作成した索引を使い続ける時間
+	// 登録や更新の直後はこの時間だけ古い索引で検索することがある
+	IndexTTL = time.Minute
+)
+
+// cachedIndex は作成した索引と、検索結果に含めるentry
+type cachedIndex struct {
+	index   *fulltext.Index
+	docs    map[int64]Document
+	expires time.Time
+}
+
+type ReadHandler struct {
+	svc *service.IndexService
+
+	mu    sync.Mutex
+	cache *cachedIndex
+	now   func() time.Time
+}
+
+func NewReadHandler(svc *service.IndexService) *ReadHandler {
+	return &ReadHandler{
+		svc: svc,
+		now: time.Now,
+	}
+}
+
+// ServeHTTP はentryの名前、説明文、作品名、タグ名を全文検索する
+//
+//	GET /api/search?q=雪泉&limit=20
+//
+// 検索語は空白で区切り、全ての検索語を含むものをスコアの高い順に返す
+func (h *ReadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// GET以外は受け付けない
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	limit, err := parseQuery(q, query.Get("limit"))
+	if err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+	idx, err := h.index(r.Context())
+	if err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+	hits, total := idx.index.Search(q, limit)
+	results := make([]Result, len(hits))
+	for i, hit := range hits {
+		d := idx.docs[hit.ID]
+		results[i] = Result{
+			ID:         d.ID,
+			Name:       d.Name,
+			Image:      d.Image,
+			SourceName: d.SourceName,
+			Score:      hit.Score,
+			Snippets:   hit.Snippets,
+		}
+	}
+	// スニペットの<mark>をエスケープしない
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(&ResultsJson{Query: q, Total: total, Results: results}); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
+
+// index は期限内の索引を返し、期限が切れている場合は作り直す
+// 索引の作成中に届いたリクエストは作成が終わるまで待つ
+func (h *ReadHandler) index(ctx context.Context) (*cachedIndex, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.cache != nil && h.now().Before(h.cache.expires) {
+		return h.cache, nil
+	}
+	docs, err := loadDocuments(ctx, h.svc.DB)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]Document, len(docs))
+	for _, d := range docs {
+		byID[d.ID] = d.Document
+	}
+	h.cache = &cachedIndex{
+		index:   fulltext.NewIndex(indexDocuments(docs)),
+		docs:    byID,
+		expires: h.now().Add(IndexTTL),
+	}
+	return h.cache, nil
+}
+
+// parseQuery は検索語とlimitを検証する
+func parseQuery(q, limit string) (int, error) {
+	errs := validation.Errors{}
+	if q == "" {
+		errs["q"] = errors.New("cannot be blank")
+	}
+	n := DefaultLimit
+	if limit != "" {
+		var err error
+		n, err = strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxLimit {
+			errs["limit"] = fmt.Errorf("must be between 1 and %d", MaxLimit)
+		}
+	}
+	if len(errs) > 0 {
+		return 0, errs
+	}
+	return n, nil
+}
+
+// document はentryと紐づくタグ名
+type document struct {
+	Document
+	tags []string
+}
+
+// loadDocuments は検索対象の全てのentryを取得する
+// 正規化した文字列の部分一致はSQLで表現できないため、取得したentryから索引を作成する
+func loadDocuments(ctx context.Context, driver db.Driver) ([]document, error) {
+	var entries []Document
+	err := driver.SelectContext(ctx, &entries, `
+		SELECT
+			e.id,
+			e.name,
+			e.image,
+			e.content,
+			s.name AS source_name
+		FROM entry e
+		JOIN source s ON s.id = e.source_id
+		ORDER BY e.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	var tags []EntryTag
+	err = driver.SelectContext(ctx, &tags, `
+		SELECT
+			et.entry_id,
+			t.name
+		FROM entry_tag et
+		JOIN tag t ON t.id = et.tag_id
+		ORDER BY et.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]document, len(entries))
+	byID := make(map[int64]*document, len(entries))
+	for i, e := range entries {
+		docs[i] = document{Document: e}
+		byID[e.ID] = &docs[i]
+	}
+	for _, tag := range tags {
+		if d, ok := byID[tag.EntryID]; ok {
+			d.tags = append(d.tags, tag.Name)
+		}
+	}
+	return docs, nil
+}
+
+// indexDocuments は検索対象の項目と重みを定義する
+// 名前に一致したものを最も上位にし、タグと作品名、説明文の順とする
+func indexDocuments(docs []document) []fulltext.Document {
+	result := make([]fulltext.Document, len(docs))
+	for i, d := range docs {
+		result[i] = fulltext.Document{
+			ID: d.ID,
+			Fields: []fulltext.Field{
+				{Name: "name", Text: d.Name, Weight: 4},
+				{Name: "tags", Text: strings.Join(d.tags, " "), Weight: 2},
+				{Name: "source", Text: d.SourceName, Weight: 2},
+				{Name: "content", Text: d.Content, Weight: 1},
+			},
+		}
+	}
+	return result
+}
+
+// Routes は/api/searchのルーティングの定義を返す
+func Routes(svc *service.IndexService) router.Resource {
+	return router.Resource{
+		Name: "search",
+		Read: NewReadHandler(svc),
+	}
+}
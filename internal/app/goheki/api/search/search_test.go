@@ -0,0 +1,168 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/fulltext"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
+
+	"github.com/justinas/alice"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchHandler(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+
+	// トランザクションのロールバック
+	defer tx.RollbackCtx(ctx)
+
+	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	// データベースの準備
+	f := &fixtures.Fixture{DBv1: tx}
+	f.Build(t,
+		fixtures.NewTag(ctx, func(s *fixtures.Tag) {
+			s.Name = "お姫様"
+		}),
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "アイドルマスター"
+			s.Url = "https://example.com/image2.png"
+			s.Type = "game"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "四条貴音"
+			s.Image = "https://example.com/image2.png"
+			s.Content = "お姫ちん。ラーメンが好き"
+			s.CreatedAt = fixedTime
+		}).Connect(
+			fixtures.NewEntryTag(ctx, func(s *fixtures.EntryTag) {
+				s.TagID = f.Tags[0].ID
+			}),
+		)),
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "閃乱カグラ"
+			s.Url = "https://example.com/image1.png"
+			s.Type = "anime"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "雪泉"
+			s.Image = "https://example.com/image1.png"
+			s.Content = "かわいい"
+			s.CreatedAt = fixedTime
+		})),
+	)
+
+	var indexService = service.NewIndexService(
+		tx,
+		cookie.Store,
+		env,
+	)
+	mux := http.NewServeMux()
+	router.New(mux, alice.New()).Handle(Routes(indexService))
+
+	search := func(query url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/search?"+query.Encode(), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("カタカナとひらがなを区別せずに検索", func(t *testing.T) {
+		w := search(url.Values{"q": {"らーめん"}})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var actual ResultsJson
+		err := json.NewDecoder(w.Body).Decode(&actual)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, actual.Total)
+		if assert.Len(t, actual.Results, 1) {
+			assert.Equal(t, f.Entrys[0].ID, actual.Results[0].ID)
+			assert.Equal(t, "四条貴音", actual.Results[0].Name)
+			assert.Equal(t, "アイドルマスター", actual.Results[0].SourceName)
+			assert.Equal(t, []fulltext.Snippet{
+				{Field: "content", Text: "お姫ちん。<mark>ラーメン</mark>が好き"},
+			}, actual.Results[0].Snippets)
+		}
+	})
+
+	t.Run("タグと作品名も検索対象", func(t *testing.T) {
+		w := search(url.Values{"q": {"お姫"}})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var actual ResultsJson
+		err := json.NewDecoder(w.Body).Decode(&actual)
+		assert.NoError(t, err)
+		if assert.Len(t, actual.Results, 1) {
+			assert.Equal(t, []fulltext.Snippet{
+				{Field: "tags", Text: "<mark>お姫</mark>様"},
+				{Field: "content", Text: "<mark>お姫</mark>ちん。ラーメンが好き"},
+			}, actual.Results[0].Snippets)
+		}
+
+		w = search(url.Values{"q": {"ｶｸﾞﾗ"}})
+		assert.Equal(t, http.StatusOK, w.Code)
+		err = json.NewDecoder(w.Body).Decode(&actual)
+		assert.NoError(t, err)
+		if assert.Len(t, actual.Results, 1) {
+			assert.Equal(t, f.Entrys[1].ID, actual.Results[0].ID)
+		}
+	})
+
+	t.Run("一致しない", func(t *testing.T) {
+		w := search(url.Values{"q": {"雪泉 ラーメン"}})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var actual ResultsJson
+		err := json.NewDecoder(w.Body).Decode(&actual)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, actual.Total)
+		assert.Empty(t, actual.Results)
+	})
+
+	t.Run("検索語がない", func(t *testing.T) {
+		w := search(url.Values{"q": {" "}, "limit": {"0"}})
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("作成した索引を期限まで使う", func(t *testing.T) {
+		handler := NewReadHandler(indexService)
+		total := func() int {
+			req := httptest.NewRequest(http.MethodGet, "/api/search?q=雪泉", nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+			var actual ResultsJson
+			err := json.NewDecoder(w.Body).Decode(&actual)
+			assert.NoError(t, err)
+			return actual.Total
+		}
+		assert.Equal(t, 1, total())
+
+		// 期限内は名前の変更を反映しない
+		_, err := tx.ExecContext(ctx, tx.Rebind("UPDATE entry SET name = ? WHERE id = ?"), "雪不帰", f.Entrys[1].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, total())
+
+		// 期限が切れた場合は索引を作り直す
+		handler.now = func() time.Time { return time.Now().Add(IndexTTL) }
+		assert.Equal(t, 0, total())
+	})
+}
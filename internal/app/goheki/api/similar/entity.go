@@ -0,0 +1,16 @@
+package similar
+
+// Result は似ているentryと一致した属性
+type Result struct {
+	ID      int64   `json:"id"`
+	Name    string  `json:"name"`
+	Image   string  `json:"image"`
+	Score   float64 `json:"score"`
+	Matches []Match `json:"matches"`
+}
+
+type SimilarJson struct {
+	EntryID int64    `json:"entry_id"`
+	Weights Weights  `json:"weights"`
+	Similar []Result `json:"similar"`
+}
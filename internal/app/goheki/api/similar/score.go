@@ -0,0 +1,199 @@
+package similar
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/profile"
+)
+
+// 類似度を計算する属性
+const (
+	AttrTags        = "tags"
+	AttrHairColor   = "haircolor"
+	AttrHairLength  = "hairlength"
+	AttrHairStyle   = "hairstyle"
+	AttrEyeColor    = "eyecolor"
+	AttrPersonality = "personality"
+	AttrSourceType  = "source_type"
+	AttrBWH         = "bwh"
+)
+
+// Weights は属性ごとの重み
+type Weights map[string]float64
+
+// DefaultWeights はリクエストで指定しなかった属性の重み
+// タグは好みを最もよく表すため重くする
+var DefaultWeights = Weights{
+	AttrTags:        3,
+	AttrHairColor:   2,
+	AttrHairLength:  1,
+	AttrHairStyle:   1,
+	AttrEyeColor:    2,
+	AttrPersonality: 2,
+	AttrSourceType:  1,
+	AttrBWH:         1,
+}
+
+// bwhScale は体型の差がこの値(cm)以上の場合に類似度を0とする
+const bwhScale = 15
+
+// Match は一致した属性とその根拠
+type Match struct {
+	Attribute  string   `json:"attribute"`
+	Similarity float64  `json:"similarity"`
+	Weight     float64  `json:"weight"`
+	Values     []string `json:"values"`
+}
+
+// Scored は類似度を計算したentry
+type Scored struct {
+	Profile profile.Profile
+	Score   float64
+	Matches []Match
+}
+
+// Rank はtargetとcandidatesの類似度を計算し、高い順に返す
+// target自身と類似度が0のものは含めない
+// 類似度は属性ごとの類似度(0〜1)の重み付き平均で、0〜1の値になる
+func Rank(target profile.Profile, candidates []profile.Profile, weights Weights) []Scored {
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	var result []Scored
+	for _, c := range candidates {
+		if c.ID == target.ID {
+			continue
+		}
+		matches := compare(target, c)
+		var score float64
+		for i := range matches {
+			matches[i].Weight = weights[matches[i].Attribute]
+			score += matches[i].Weight * matches[i].Similarity
+		}
+		if score == 0 || total == 0 {
+			continue
+		}
+		// 重みが0の属性は根拠に含めない
+		kept := matches[:0]
+		for _, m := range matches {
+			if m.Weight > 0 {
+				kept = append(kept, m)
+			}
+		}
+		result = append(result, Scored{
+			Profile: c,
+			Score:   round(score / total),
+			Matches: kept,
+		})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].Profile.ID < result[j].Profile.ID
+	})
+	return result
+}
+
+// compare は2人の属性を比較し、類似度が0より大きい属性を返す
+func compare(a, b profile.Profile) []Match {
+	var matches []Match
+	if m, ok := compareTags(a.Tags, b.Tags); ok {
+		matches = append(matches, m)
+	}
+	types := []struct {
+		attr string
+		a, b *profile.Type
+	}{
+		{AttrHairColor, a.HairColor, b.HairColor},
+		{AttrHairLength, a.HairLength, b.HairLength},
+		{AttrHairStyle, a.HairStyle, b.HairStyle},
+		{AttrEyeColor, a.EyeColor, b.EyeColor},
+		{AttrPersonality, a.Personality, b.Personality},
+	}
+	for _, t := range types {
+		if t.a != nil && t.b != nil && t.a.ID == t.b.ID {
+			matches = append(matches, Match{Attribute: t.attr, Similarity: 1, Values: []string{t.a.Name}})
+		}
+	}
+	if a.Source.Type != "" && a.Source.Type == b.Source.Type {
+		matches = append(matches, Match{Attribute: AttrSourceType, Similarity: 1, Values: []string{a.Source.Type}})
+	}
+	if m, ok := compareBWH(a.BWH, b.BWH); ok {
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// compareTags はタグのJaccard係数を類似度とする
+func compareTags(a, b []profile.Type) (Match, bool) {
+	names := make(map[int64]string, len(a))
+	for _, t := range a {
+		names[t.ID] = t.Name
+	}
+	union := len(names)
+	var shared []string
+	seen := map[int64]bool{}
+	for _, t := range b {
+		if seen[t.ID] {
+			continue
+		}
+		seen[t.ID] = true
+		if name, ok := names[t.ID]; ok {
+			shared = append(shared, name)
+			continue
+		}
+		union++
+	}
+	if len(shared) == 0 {
+		return Match{}, false
+	}
+	return Match{
+		Attribute:  AttrTags,
+		Similarity: round(float64(len(shared)) / float64(union)),
+		Values:     shared,
+	}, true
+}
+
+// compareBWH は両方が持つ寸法ごとに差から類似度を求め、平均する
+func compareBWH(a, b *profile.BWH) (Match, bool) {
+	if a == nil || b == nil {
+		return Match{}, false
+	}
+	sizes := []struct {
+		name string
+		a, b *int64
+	}{
+		{"bust", a.Bust, b.Bust},
+		{"waist", a.Waist, b.Waist},
+		{"hip", a.Hip, b.Hip},
+		{"height", a.Height, b.Height},
+	}
+	var sum float64
+	var n int
+	var values []string
+	for _, s := range sizes {
+		if s.a == nil || s.b == nil {
+			continue
+		}
+		n++
+		diff := math.Abs(float64(*s.a - *s.b))
+		sum += math.Max(0, 1-diff/bwhScale)
+		values = append(values, fmt.Sprintf("%s %d/%d", s.name, *s.a, *s.b))
+	}
+	if n == 0 || sum == 0 {
+		return Match{}, false
+	}
+	return Match{
+		Attribute:  AttrBWH,
+		Similarity: round(sum / float64(n)),
+		Values:     values,
+	}, true
+}
+
+func round(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
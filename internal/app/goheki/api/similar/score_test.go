@@ -0,0 +1,79 @@
+package similar
+
+import (
+	"testing"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/profile"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func int64p(n int64) *int64 { return &n }
+
+func TestRank(t *testing.T) {
+	black := &profile.Type{ID: 1, Name: "黒"}
+	silver := &profile.Type{ID: 2, Name: "銀"}
+	glasses := profile.Type{ID: 1, Name: "眼鏡"}
+	princess := profile.Type{ID: 2, Name: "お姫様"}
+	target := profile.Profile{
+		ID:        1,
+		Source:    profile.Source{Type: "anime"},
+		HairColor: black,
+		Tags:      []profile.Type{glasses, princess},
+		BWH:       &profile.BWH{Bust: int64p(90), Waist: int64p(60), Hip: int64p(90)},
+	}
+	candidates := []profile.Profile{
+		target,
+		// タグが1つ一致し、髪色と作品の種類が一致
+		{
+			ID:        2,
+			Source:    profile.Source{Type: "anime"},
+			HairColor: black,
+			Tags:      []profile.Type{glasses},
+		},
+		// 体型だけが近い
+		{
+			ID:        3,
+			Source:    profile.Source{Type: "game"},
+			HairColor: silver,
+			BWH:       &profile.BWH{Bust: int64p(84), Waist: int64p(60)},
+		},
+		// 何も一致しない
+		{
+			ID:        4,
+			Source:    profile.Source{Type: "game"},
+			HairColor: silver,
+		},
+	}
+
+	t.Run("類似度の高い順に一致した属性と合わせて返す", func(t *testing.T) {
+		ranked := Rank(target, candidates, DefaultWeights)
+
+		if assert.Len(t, ranked, 2) {
+			assert.Equal(t, int64(2), ranked[0].Profile.ID)
+			// (タグ3×0.5 + 髪色2 + 作品の種類1) / 重みの合計13
+			assert.Equal(t, 0.346, ranked[0].Score)
+			assert.Equal(t, []Match{
+				{Attribute: AttrTags, Similarity: 0.5, Weight: 3, Values: []string{"眼鏡"}},
+				{Attribute: AttrHairColor, Similarity: 1, Weight: 2, Values: []string{"黒"}},
+				{Attribute: AttrSourceType, Similarity: 1, Weight: 1, Values: []string{"anime"}},
+			}, ranked[0].Matches)
+
+			assert.Equal(t, int64(3), ranked[1].Profile.ID)
+			// bust 1-6/15=0.6, waist 1 の平均
+			assert.Equal(t, []Match{
+				{Attribute: AttrBWH, Similarity: 0.8, Weight: 1, Values: []string{"bust 90/84", "waist 60/60"}},
+			}, ranked[1].Matches)
+		}
+	})
+
+	t.Run("重みを変えると順位が変わる", func(t *testing.T) {
+		weights := Weights{AttrBWH: 10, AttrTags: 0, AttrHairColor: 0, AttrSourceType: 0}
+		ranked := Rank(target, candidates, weights)
+
+		if assert.Len(t, ranked, 1) {
+			assert.Equal(t, int64(3), ranked[0].Profile.ID)
+			assert.Equal(t, 0.8, ranked[0].Score)
+		}
+	})
+}
@@ -0,0 +1,200 @@
+package similar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/profile"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/pkg/db"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+const (
+	// DefaultLimit はlimitを省略した場合の件数
+	DefaultLimit = 10
+	// MaxLimit はlimitに指定できる最大の件数
+	MaxLimit = 100
+)
+
+// weightParam は weight[属性] 形式のクエリパラメータ
+var weightParam = regexp.MustCompile(`^weight\[(\w+)\]$`)
+
+type ReadHandler struct {
+	svc *service.IndexService
+}
+
+func NewReadHandler(svc *service.IndexService) *ReadHandler {
+	return &ReadHandler{
+		svc: svc,
+	}
+}
+
+// ServeHTTP は指定したentryに似ているentryを類似度の高い順に返す
+//
+//	GET /api/entries/{id}/similar?limit=10&weight[tags]=5&weight[bwh]=0
+//
+// 重みを指定しなかった属性はDefaultWeightsを使う
+func (h *ReadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// GET以外は受け付けない
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	query := r.URL.Query()
+	id, limit, weights, err := parseQuery(query)
+	if err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+
+	profiles, err := loadProfiles(r.Context(), h.svc.DB)
+	if err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+	var target *profile.Profile
+	for i := range profiles {
+		if profiles[i].ID == id {
+			target = &profiles[i]
+			break
+		}
+	}
+	if target == nil {
+		problem.NotFound(w, r, fmt.Errorf("entry %d not found", id))
+		return
+	}
+
+	ranked := Rank(*target, profiles, weights)
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	results := make([]Result, len(ranked))
+	for i, s := range ranked {
+		results[i] = Result{
+			ID:      s.Profile.ID,
+			Name:    s.Profile.Name,
+			Image:   s.Profile.Image,
+			Score:   s.Score,
+			Matches: s.Matches,
+		}
+	}
+	if err := json.NewEncoder(w).Encode(&SimilarJson{
+		EntryID: id,
+		Weights: weights,
+		Similar: results,
+	}); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
+
+// parseQuery はentryのid、limit、属性ごとの重みを検証する
+func parseQuery(query url.Values) (int64, int, Weights, error) {
+	errs := validation.Errors{}
+	id, err := strconv.ParseInt(query.Get("id"), 10, 64)
+	if err != nil {
+		errs["id"] = fmt.Errorf("invalid id %q", query.Get("id"))
+	}
+	limit := DefaultLimit
+	if v := query.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			errs["limit"] = fmt.Errorf("must be between 1 and %d", MaxLimit)
+		}
+	}
+	weights := make(Weights, len(DefaultWeights))
+	for attr, w := range DefaultWeights {
+		weights[attr] = w
+	}
+	for param, values := range query {
+		m := weightParam.FindStringSubmatch(param)
+		if m == nil {
+			continue
+		}
+		if _, ok := DefaultWeights[m[1]]; !ok {
+			errs[param] = fmt.Errorf("unknown attribute %q", m[1])
+			continue
+		}
+		w, err := strconv.ParseFloat(values[0], 64)
+		if err != nil || w < 0 || w > 100 {
+			errs[param] = errors.New("must be a number between 0 and 100")
+			continue
+		}
+		weights[m[1]] = w
+	}
+	if len(errs) == 0 {
+		var total float64
+		for _, w := range weights {
+			total += w
+		}
+		if total == 0 {
+			errs["weight"] = errors.New("at least one weight must be greater than 0")
+		}
+	}
+	if len(errs) > 0 {
+		return 0, 0, nil, errs
+	}
+	return id, limit, weights, nil
+}
+
+// loadProfiles は全てのentryについて類似度の計算に使う属性だけを取得する
+// idのIN句を使わないため、entryの数によらず置換文字の数の上限を超えない
+// リンクや画像、レーダーチャートは取得しない
+func loadProfiles(ctx context.Context, driver db.Driver) ([]profile.Profile, error) {
+	var profiles []profile.Profile
+	err := driver.SelectContext(ctx, &profiles, `SELECT
+			e.id,
+			e.name,
+			e.image,
+			s.type AS "source.type"
+		FROM entry e
+		JOIN source s ON s.id = e.source_id
+		ORDER BY e.id`)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*profile.Profile, len(profiles))
+	for i := range profiles {
+		byID[profiles[i].ID] = &profiles[i]
+	}
+
+	var bwhs []struct {
+		EntryID int64 `db:"entry_id"`
+		profile.BWH
+	}
+	if err := driver.SelectContext(ctx, &bwhs, "SELECT entry_id, bust, waist, hip, height FROM bwh"); err != nil {
+		return nil, err
+	}
+	for i := range bwhs {
+		if p, ok := byID[bwhs[i].EntryID]; ok {
+			p.BWH = &bwhs[i].BWH
+		}
+	}
+
+	// 属性はプロフィールと同じ定義で、全てのentryの分を取得する
+	for _, tq := range profile.TypeQueries {
+		var types []struct {
+			EntryID int64 `db:"entry_id"`
+			profile.Type
+		}
+		if err := driver.SelectContext(ctx, &types, tq.All()); err != nil {
+			return nil, err
+		}
+		for _, t := range types {
+			if p, ok := byID[t.EntryID]; ok {
+				tq.Set(p, t.Type)
+			}
+		}
+	}
+	return profiles, nil
+}
@@ -0,0 +1,165 @@
+package similar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
+
+	"github.com/justinas/alice"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadSimilarHandler(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+
+	// トランザクションのロールバック
+	defer tx.RollbackCtx(ctx)
+
+	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	// データベースの準備
+	f := &fixtures.Fixture{DBv1: tx}
+	f.Build(t,
+		fixtures.NewHairColorType(ctx, func(s *fixtures.HairColorType) {
+			s.Color = "黒"
+		}),
+		fixtures.NewTag(ctx, func(s *fixtures.Tag) {
+			s.Name = "巨乳"
+		}),
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "閃乱カグラ"
+			s.Url = "https://example.com/image1.png"
+			s.Type = "anime"
+		}).Connect(
+			fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+				s.Name = "雪泉"
+				s.Image = "https://example.com/image1.png"
+				s.Content = "かわいい"
+				s.CreatedAt = fixedTime
+			}).Connect(
+				fixtures.NewHairColor(ctx, func(s *fixtures.HairColor) {
+					s.ColorID = f.HairColorTypes[0].ID
+				}),
+				fixtures.NewEntryTag(ctx, func(s *fixtures.EntryTag) {
+					s.TagID = f.Tags[0].ID
+				}),
+			),
+			fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+				s.Name = "焔"
+				s.Image = "https://example.com/image3.png"
+				s.Content = "かっこいい"
+				s.CreatedAt = fixedTime
+			}).Connect(
+				fixtures.NewHairColor(ctx, func(s *fixtures.HairColor) {
+					s.ColorID = f.HairColorTypes[0].ID
+				}),
+				fixtures.NewEntryTag(ctx, func(s *fixtures.EntryTag) {
+					s.TagID = f.Tags[0].ID
+				}),
+			),
+		),
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "アイドルマスター"
+			s.Url = "https://example.com/image2.png"
+			s.Type = "game"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "四条貴音"
+			s.Image = "https://example.com/image2.png"
+			s.Content = "お姫ちん"
+			s.CreatedAt = fixedTime
+		}).Connect(
+			fixtures.NewHairColor(ctx, func(s *fixtures.HairColor) {
+				s.ColorID = f.HairColorTypes[0].ID
+			}),
+		)),
+	)
+
+	var indexService = service.NewIndexService(
+		tx,
+		cookie.Store,
+		env,
+	)
+	mux := http.NewServeMux()
+	router.New(mux, alice.New()).Handle(router.Resource{
+		Name: "entries",
+		Key:  "id",
+		Subresources: map[string]http.Handler{
+			"similar": NewReadHandler(indexService),
+		},
+	})
+
+	t.Run("似ているentryを類似度の高い順に取得", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/entries/%d/similar", f.Entrys[0].ID), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var actual SimilarJson
+		err := json.NewDecoder(w.Body).Decode(&actual)
+		assert.NoError(t, err)
+		assert.Equal(t, f.Entrys[0].ID, actual.EntryID)
+		assert.Equal(t, DefaultWeights, actual.Weights)
+		if assert.Len(t, actual.Similar, 2) {
+			assert.Equal(t, "焔", actual.Similar[0].Name)
+			assert.Equal(t, []Match{
+				{Attribute: AttrTags, Similarity: 1, Weight: 3, Values: []string{"巨乳"}},
+				{Attribute: AttrHairColor, Similarity: 1, Weight: 2, Values: []string{"黒"}},
+				{Attribute: AttrSourceType, Similarity: 1, Weight: 1, Values: []string{"anime"}},
+			}, actual.Similar[0].Matches)
+			assert.Equal(t, "四条貴音", actual.Similar[1].Name)
+		}
+	})
+
+	t.Run("重みを指定して取得", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/entries/%d/similar?limit=1&weight[tags]=0&weight[source_type]=0", f.Entrys[0].ID), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var actual SimilarJson
+		err := json.NewDecoder(w.Body).Decode(&actual)
+		assert.NoError(t, err)
+		// 髪色だけが一致するため同点の場合はidの順
+		if assert.Len(t, actual.Similar, 1) {
+			assert.Equal(t, "焔", actual.Similar[0].Name)
+			assert.Equal(t, []Match{
+				{Attribute: AttrHairColor, Similarity: 1, Weight: 2, Values: []string{"黒"}},
+			}, actual.Similar[0].Matches)
+		}
+	})
+
+	t.Run("存在しないentry", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/entries/%d/similar", f.Entrys[2].ID+100), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("重みの形式が正しくない", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/entries/%d/similar?weight[nose]=1&weight[tags]=-1", f.Entrys[0].ID), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+}
@@ -13,8 +13,6 @@ import (
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
 
-	"github.com/maguro-alternative/goheki/pkg/db"
-
 	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
 
 	"github.com/stretchr/testify/assert"
@@ -25,7 +23,7 @@ func TestCreateSourceHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -91,6 +89,11 @@ func TestCreateSourceHandler(t *testing.T) {
 		err = json.Unmarshal(w.Body.Bytes(), &actuals)
 		assert.NoError(t, err)
 
+		// 採番されたidが返る
+		for i := range actuals.Sources {
+			assert.NotZero(t, actuals.Sources[i].ID)
+			sources.Sources[i].ID = actuals.Sources[i].ID
+		}
 		assert.Equal(t, actuals, sources)
 
 		err = tx.SelectContext(ctx, &actual, "SELECT * FROM source")
@@ -111,7 +114,7 @@ func TestReadSourceHandler(t *testing.T) {
 		env, err := envconfig.NewEnv()
 		assert.NoError(t, err)
 		// データベースに接続
-		indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+		indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 		assert.NoError(t, err)
 		defer cleanup()
 		// トランザクションの開始
@@ -181,7 +184,7 @@ func TestReadSourceHandler(t *testing.T) {
 		env, err := envconfig.NewEnv()
 		assert.NoError(t, err)
 		// データベースに接続
-		indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+		indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 		assert.NoError(t, err)
 		defer cleanup()
 		// トランザクションの開始
@@ -248,7 +251,7 @@ func TestReadSourceHandler(t *testing.T) {
 		env, err := envconfig.NewEnv()
 		assert.NoError(t, err)
 		// データベースに接続
-		indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+		indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 		assert.NoError(t, err)
 		defer cleanup()
 		// トランザクションの開始
@@ -318,7 +321,7 @@ func TestReadSourceHandler(t *testing.T) {
 		env, err := envconfig.NewEnv()
 		assert.NoError(t, err)
 		// データベースに接続
-		indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+		indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 		assert.NoError(t, err)
 		defer cleanup()
 		// トランザクションの開始
@@ -369,7 +372,7 @@ func TestReadSourceHandler(t *testing.T) {
 		env, err := envconfig.NewEnv()
 		assert.NoError(t, err)
 		// データベースに接続
-		indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+		indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 		assert.NoError(t, err)
 		defer cleanup()
 		// トランザクションの開始
@@ -406,7 +409,7 @@ func TestReadSourceHandler(t *testing.T) {
 
 		tx.RollbackCtx(ctx)
 
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 	})
 }
 
@@ -415,7 +418,7 @@ func TestUpdateSourceHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -529,7 +532,7 @@ func TestDeleteSourceHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -0,0 +1,265 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/entry_filter"
+	"github.com/maguro-alternative/goheki/pkg/db"
+)
+
+// DefaultBins はヒストグラムの区間の数の既定値
+const DefaultBins = 10
+
+// Percentiles はDistributionで返すパーセンタイル
+var Percentiles = map[string]float64{
+	"p10": 0.10,
+	"p25": 0.25,
+	"p50": 0.50,
+	"p75": 0.75,
+	"p90": 0.90,
+}
+
+// tagAttribute はタグの属性名 (entryfilter.Attributesのタグと同じ)
+const tagAttribute = "tag"
+
+// measurements は分布を求めるbwhの数値の列
+var measurements = []string{"bust", "waist", "hip", "height", "weight"}
+
+// aggregator は絞り込んだentryをSQLで集計する
+// entryのidの一覧を取得せず、絞り込みの条件を副問い合わせとして各クエリに含める
+type aggregator struct {
+	driver db.Driver
+	// 絞り込んだentryのidを返す副問い合わせと引数
+	scope string
+	args  []any
+}
+
+// Aggregate はwhereで絞り込んだentryを集計する
+// whereとargsはentryfilter.Whereが返すWHERE句と引数
+// 件数の一覧は件数の多い順、同数の場合はidの順に並べる
+func Aggregate(ctx context.Context, driver db.Driver, where string, args []any, bins int) (*StatsJson, error) {
+	a := &aggregator{
+		driver: driver,
+		scope:  "SELECT e.id " + entryfilter.From + where,
+		args:   args,
+	}
+	result := &StatsJson{
+		Attributes:    make(map[string][]Count, len(entryfilter.Attributes)-1),
+		Sources:       []SourceCount{},
+		SourceTypes:   []ValueCount{},
+		Measurements:  make(map[string]Distribution, len(measurements)),
+		RadarAverages: map[string]map[string][]Average{},
+		Tags:          []TagFrequency{},
+	}
+	if err := a.get(ctx, &result.Total, "SELECT COUNT(*) "+entryfilter.From+where, args); err != nil {
+		return nil, err
+	}
+
+	for _, attr := range entryfilter.Attributes {
+		counts, err := a.counts(ctx, attr)
+		if err != nil {
+			return nil, err
+		}
+		if attr.Name != tagAttribute {
+			result.Attributes[attr.Name] = counts
+			continue
+		}
+		for _, c := range counts {
+			result.Tags = append(result.Tags, TagFrequency{
+				ID:    c.ID,
+				Name:  c.Name,
+				Count: c.Count,
+				Ratio: round(float64(c.Count) / float64(result.Total)),
+			})
+		}
+	}
+
+	err := a.selectIn(ctx, &result.Sources, `SELECT
+			s.id,
+			s.name,
+			s.type,
+			COUNT(*) AS count
+		`+entryfilter.From+where+`
+		GROUP BY s.id, s.name, s.type
+		ORDER BY count DESC, s.id`, args)
+	if err != nil {
+		return nil, err
+	}
+	err = a.selectIn(ctx, &result.SourceTypes, "SELECT s.type AS value, COUNT(*) AS count "+entryfilter.From+where+" GROUP BY s.type ORDER BY count DESC, s.type", args)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, column := range measurements {
+		d, err := a.distribution(ctx, column, bins)
+		if err != nil {
+			return nil, err
+		}
+		result.Measurements[column] = d
+	}
+
+	for _, attr := range entryfilter.Attributes {
+		if err := a.radarAverages(ctx, attr, result.RadarAverages); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// counts は属性の値ごとの件数を返す
+// 件数が0の値は含めない
+func (a *aggregator) counts(ctx context.Context, attr entryfilter.Attribute) ([]Count, error) {
+	counts := []Count{}
+	err := a.selectIn(ctx, &counts, fmt.Sprintf(`SELECT
+			t.id,
+			t.%[1]s AS name,
+			COUNT(*) AS count
+		FROM %[3]s a
+		JOIN %[2]s t ON t.id = a.%[4]s
+		WHERE a.entry_id IN (%[5]s)
+		GROUP BY t.id, t.%[1]s
+		ORDER BY count DESC, t.id`, attr.TypeColumn, attr.TypeTable, attr.Table, attr.Column, a.scope), a.args)
+	return counts, err
+}
+
+// radarAverages は軸ごとに、属性の値を持つentryの値の平均をaveragesに追加する
+// 軸の値を持たないentryは平均に含めない
+func (a *aggregator) radarAverages(ctx context.Context, attr entryfilter.Attribute, averages map[string]map[string][]Average) error {
+	var rows []struct {
+		Axis string `db:"axis"`
+		Average
+	}
+	err := a.selectIn(ctx, &rows, fmt.Sprintf(`SELECT
+			ax.name AS axis,
+			t.id,
+			t.%[1]s AS name,
+			COUNT(*) AS count,
+			AVG(rs.score) AS average
+		FROM heki_radar_score rs
+		JOIN heki_radar_axis ax ON ax.id = rs.axis_id
+		JOIN %[3]s a ON a.entry_id = rs.entry_id
+		JOIN %[2]s t ON t.id = a.%[4]s
+		WHERE rs.entry_id IN (%[5]s)
+		GROUP BY ax.name, t.id, t.%[1]s
+		ORDER BY t.id`, attr.TypeColumn, attr.TypeTable, attr.Table, attr.Column, a.scope), a.args)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if averages[row.Axis] == nil {
+			averages[row.Axis] = map[string][]Average{}
+		}
+		row.Average.Average = round(row.Average.Average)
+		averages[row.Axis][attr.Name] = append(averages[row.Axis][attr.Name], row.Average)
+	}
+	return nil
+}
+
+// distribution はbwhの列の値の件数、最小値、最大値、平均、パーセンタイルとヒストグラムを返す
+// パーセンタイルは隣り合う値を線形補間する (PostgreSQLのpercentile_contと同じ)
+// SQLiteにはpercentile_contがないため、補間に使う順位の値だけをROW_NUMBERで取得する
+func (a *aggregator) distribution(ctx context.Context, column string, bins int) (Distribution, error) {
+	d := Distribution{
+		Percentiles: map[string]float64{},
+		Histogram:   []Bucket{},
+	}
+	var summary struct {
+		Count int64    `db:"count"`
+		Min   *int64   `db:"min"`
+		Max   *int64   `db:"max"`
+		Mean  *float64 `db:"mean"`
+	}
+	err := a.get(ctx, &summary, fmt.Sprintf(
+		"SELECT COUNT(%[1]s) AS count, MIN(%[1]s) AS min, MAX(%[1]s) AS max, AVG(%[1]s) AS mean FROM bwh WHERE entry_id IN (%[2]s)",
+		column, a.scope,
+	), a.args)
+	if err != nil {
+		return d, err
+	}
+	d.Count = summary.Count
+	if d.Count == 0 {
+		return d, nil
+	}
+	min, max, mean := *summary.Min, *summary.Max, round(*summary.Mean)
+	d.Min, d.Max, d.Mean = &min, &max, &mean
+
+	// 値の順位(0始まり)とパーセンタイルの位置
+	positions := make(map[string]float64, len(Percentiles))
+	var ranks []int64
+	for name, p := range Percentiles {
+		pos := p * float64(d.Count-1)
+		positions[name] = pos
+		ranks = append(ranks, int64(math.Floor(pos)), int64(math.Ceil(pos)))
+	}
+	var ranked []struct {
+		N     int64 `db:"n"`
+		Value int64 `db:"value"`
+	}
+	err = a.selectIn(ctx, &ranked, fmt.Sprintf(`SELECT v.n, v.value FROM (
+			SELECT %[1]s AS value, ROW_NUMBER() OVER (ORDER BY %[1]s) - 1 AS n
+			FROM bwh
+			WHERE %[1]s IS NOT NULL AND entry_id IN (%[2]s)
+		) v
+		WHERE v.n IN (?)`, column, a.scope), append(append([]any{}, a.args...), ranks))
+	if err != nil {
+		return d, err
+	}
+	values := make(map[int64]int64, len(ranked))
+	for _, r := range ranked {
+		values[r.N] = r.Value
+	}
+	for name, pos := range positions {
+		lo, hi := values[int64(math.Floor(pos))], values[int64(math.Ceil(pos))]
+		d.Percentiles[name] = round(float64(lo) + float64(hi-lo)*(pos-math.Floor(pos)))
+	}
+
+	// 値は整数のため、区間の幅も整数にする
+	width := (max - min + int64(bins)) / int64(bins)
+	if width < 1 {
+		width = 1
+	}
+	for from := min; from <= max; from += width {
+		d.Histogram = append(d.Histogram, Bucket{From: from, To: from + width})
+	}
+	var buckets []struct {
+		Bucket int64 `db:"bucket"`
+		Count  int64 `db:"count"`
+	}
+	err = a.selectIn(ctx, &buckets, fmt.Sprintf(`SELECT (%[1]s - ?) / ? AS bucket, COUNT(*) AS count
+		FROM bwh
+		WHERE %[1]s IS NOT NULL AND entry_id IN (%[2]s)
+		GROUP BY bucket`, column, a.scope), append([]any{min, width}, a.args...))
+	if err != nil {
+		return d, err
+	}
+	for _, b := range buckets {
+		d.Histogram[b.Bucket].Count = b.Count
+	}
+	return d, nil
+}
+
+// get はIN句を展開して1行取得する
+func (a *aggregator) get(ctx context.Context, dest any, query string, args []any) error {
+	query, args, err := db.In(query, args...)
+	if err != nil {
+		return err
+	}
+	// DBの種類に合わせて置換文字を変える
+	return a.driver.GetContext(ctx, dest, a.driver.Rebind(query), args...)
+}
+
+// selectIn はIN句を展開して取得する
+func (a *aggregator) selectIn(ctx context.Context, dest any, query string, args []any) error {
+	query, args, err := db.In(query, args...)
+	if err != nil {
+		return err
+	}
+	return a.driver.SelectContext(ctx, dest, a.driver.Rebind(query), args...)
+}
+
+// round は小数点以下2桁に丸める
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -0,0 +1,219 @@
+package stats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ptr(v int64) *int64 {
+	return &v
+}
+
+func TestAggregate(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+
+	// トランザクションのロールバック
+	defer tx.RollbackCtx(ctx)
+
+	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	entry := func(name string) func(s *fixtures.Entry) {
+		return func(s *fixtures.Entry) {
+			s.Name = name
+			s.Image = "https://example.com/image1.png"
+			s.Content = name
+			s.CreatedAt = fixedTime
+		}
+	}
+	// データベースの準備
+	f := &fixtures.Fixture{DBv1: tx}
+	f.Build(t,
+		fixtures.NewHairColorType(ctx, func(s *fixtures.HairColorType) {
+			s.Color = "黒"
+		}),
+		fixtures.NewHairColorType(ctx, func(s *fixtures.HairColorType) {
+			s.Color = "銀"
+		}),
+		fixtures.NewTag(ctx, func(s *fixtures.Tag) {
+			s.Name = "巨乳"
+		}),
+		fixtures.NewTag(ctx, func(s *fixtures.Tag) {
+			s.Name = "お嬢様"
+		}),
+		fixtures.NewHekiRadarAxis(ctx, func(s *fixtures.HekiRadarAxis) {
+			s.Name = "ai"
+		}),
+		fixtures.NewHekiRadarAxis(ctx, func(s *fixtures.HekiRadarAxis) {
+			s.Name = "nu"
+		}),
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "閃乱カグラ"
+			s.Url = "https://example.com/image1.png"
+			s.Type = "anime"
+		}).Connect(
+			fixtures.NewEntry(ctx, entry("雪泉")).Connect(
+				fixtures.NewHairColor(ctx, func(s *fixtures.HairColor) {
+					s.ColorID = f.HairColorTypes[1].ID
+				}),
+				fixtures.NewBWH(ctx, func(s *fixtures.BWH) {
+					s.Bust = 86
+					s.Waist = 55
+					s.Hip = 84
+					s.Height = ptr(160)
+				}),
+				fixtures.NewEntryTag(ctx, func(s *fixtures.EntryTag) {
+					s.TagID = f.Tags[0].ID
+				}),
+				fixtures.NewEntryTag(ctx, func(s *fixtures.EntryTag) {
+					s.TagID = f.Tags[1].ID
+				}),
+				fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+					s.AxisID = f.HekiRadarAxes[0].ID
+					s.Score = 100
+				}),
+			),
+			fixtures.NewEntry(ctx, entry("焔")).Connect(
+				fixtures.NewHairColor(ctx, func(s *fixtures.HairColor) {
+					s.ColorID = f.HairColorTypes[0].ID
+				}),
+				fixtures.NewBWH(ctx, func(s *fixtures.BWH) {
+					s.Bust = 92
+					s.Waist = 57
+					s.Hip = 88
+				}),
+				fixtures.NewEntryTag(ctx, func(s *fixtures.EntryTag) {
+					s.TagID = f.Tags[0].ID
+				}),
+				fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+					s.AxisID = f.HekiRadarAxes[0].ID
+					s.Score = 60
+				}),
+			),
+		),
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "アイドルマスター"
+			s.Url = "https://example.com/image2.png"
+			s.Type = "game"
+		}).Connect(
+			fixtures.NewEntry(ctx, entry("四条貴音")).Connect(
+				fixtures.NewHairColor(ctx, func(s *fixtures.HairColor) {
+					s.ColorID = f.HairColorTypes[1].ID
+				}),
+				fixtures.NewBWH(ctx, func(s *fixtures.BWH) {
+					s.Bust = 90
+					s.Waist = 62
+					s.Hip = 92
+				}),
+				fixtures.NewEntryTag(ctx, func(s *fixtures.EntryTag) {
+					s.TagID = f.Tags[0].ID
+				}),
+				fixtures.NewEntryTag(ctx, func(s *fixtures.EntryTag) {
+					s.TagID = f.Tags[1].ID
+				}),
+				fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+					s.AxisID = f.HekiRadarAxes[0].ID
+					s.Score = 80
+				}),
+				fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+					s.AxisID = f.HekiRadarAxes[1].ID
+					s.Score = 10
+				}),
+			),
+			fixtures.NewEntry(ctx, entry("天海春香")),
+		),
+	)
+	black, silver := f.HairColorTypes[0].ID, f.HairColorTypes[1].ID
+	kyonyu, ojou := f.Tags[0].ID, f.Tags[1].ID
+
+	t.Run("全てのentryを集計", func(t *testing.T) {
+		actual, err := Aggregate(ctx, tx, "", nil, 3)
+		assert.NoError(t, err)
+
+		assert.Equal(t, int64(4), actual.Total)
+		assert.Equal(t, []Count{
+			{ID: silver, Name: "銀", Count: 2},
+			{ID: black, Name: "黒", Count: 1},
+		}, actual.Attributes["haircolor"])
+		assert.Equal(t, []Count{}, actual.Attributes["eyecolor"])
+		assert.Equal(t, []SourceCount{
+			{ID: f.Sources[0].ID, Name: "閃乱カグラ", Type: "anime", Count: 2},
+			{ID: f.Sources[1].ID, Name: "アイドルマスター", Type: "game", Count: 2},
+		}, actual.Sources)
+		assert.Equal(t, []ValueCount{
+			{Value: "anime", Count: 2},
+			{Value: "game", Count: 2},
+		}, actual.SourceTypes)
+		assert.Equal(t, []TagFrequency{
+			{ID: kyonyu, Name: "巨乳", Count: 3, Ratio: 0.75},
+			{ID: ojou, Name: "お嬢様", Count: 2, Ratio: 0.5},
+		}, actual.Tags)
+
+		mean := 89.33
+		assert.Equal(t, Distribution{
+			Count: 3,
+			Min:   ptr(86),
+			Max:   ptr(92),
+			Mean:  &mean,
+			Percentiles: map[string]float64{
+				"p10": 86.8,
+				"p25": 88,
+				"p50": 90,
+				"p75": 91,
+				"p90": 91.6,
+			},
+			// 86〜92の7つの値を幅3の区間に分ける
+			Histogram: []Bucket{
+				{From: 86, To: 89, Count: 1},
+				{From: 89, To: 92, Count: 1},
+				{From: 92, To: 95, Count: 1},
+			},
+		}, actual.Measurements["bust"])
+		// 値を持つentryがない
+		assert.Equal(t, Distribution{
+			Count:       0,
+			Percentiles: map[string]float64{},
+			Histogram:   []Bucket{},
+		}, actual.Measurements["weight"])
+		assert.Equal(t, int64(1), actual.Measurements["height"].Count)
+
+		assert.Equal(t, map[string][]Average{
+			"haircolor": {
+				{ID: black, Name: "黒", Count: 1, Average: 60},
+				{ID: silver, Name: "銀", Count: 2, Average: 90},
+			},
+			"tag": {
+				{ID: kyonyu, Name: "巨乳", Count: 3, Average: 80},
+				{ID: ojou, Name: "お嬢様", Count: 2, Average: 90},
+			},
+		}, actual.RadarAverages["ai"])
+		assert.Equal(t, []Average{
+			{ID: silver, Name: "銀", Count: 1, Average: 10},
+		}, actual.RadarAverages["nu"]["haircolor"])
+	})
+
+	t.Run("一致するentryがない", func(t *testing.T) {
+		actual, err := Aggregate(ctx, tx, " WHERE s.type = ?", []any{"book"}, DefaultBins)
+		assert.NoError(t, err)
+
+		assert.Equal(t, int64(0), actual.Total)
+		assert.Equal(t, []SourceCount{}, actual.Sources)
+		assert.Equal(t, []TagFrequency{}, actual.Tags)
+		assert.Empty(t, actual.RadarAverages)
+		assert.Len(t, actual.Measurements, len(measurements))
+	})
+}
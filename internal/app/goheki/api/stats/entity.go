@@ -0,0 +1,67 @@
+package stats
+
+// Count は属性の値ごとの件数
+type Count struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// SourceCount は作品ごとの件数
+type SourceCount struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Count int64  `json:"count"`
+}
+
+// ValueCount は作品の種類ごとの件数
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Bucket はヒストグラムの1区間 (From以上To未満) の件数
+type Bucket struct {
+	From  int64 `json:"from"`
+	To    int64 `json:"to"`
+	Count int64 `json:"count"`
+}
+
+// Distribution は数値の列の分布
+// 値を持つentryがない場合はCount以外がnullになる
+type Distribution struct {
+	Count       int64              `json:"count"`
+	Min         *int64             `json:"min"`
+	Max         *int64             `json:"max"`
+	Mean        *float64           `json:"mean"`
+	Percentiles map[string]float64 `json:"percentiles"`
+	Histogram   []Bucket           `json:"histogram"`
+}
+
+// Average は属性の値ごとのレーダーチャートの値の平均
+type Average struct {
+	ID      int64   `json:"id"`
+	Name    string  `json:"name"`
+	Count   int64   `json:"count"`
+	Average float64 `json:"average"`
+}
+
+// TagFrequency はタグの件数と、絞り込んだentryのうちタグを持つものの割合
+type TagFrequency struct {
+	ID    int64   `json:"id"`
+	Name  string  `json:"name"`
+	Count int64   `json:"count"`
+	Ratio float64 `json:"ratio"`
+}
+
+type StatsJson struct {
+	Total        int64                   `json:"total"`
+	Attributes   map[string][]Count      `json:"attributes"`
+	Sources      []SourceCount           `json:"sources"`
+	SourceTypes  []ValueCount            `json:"source_types"`
+	Measurements map[string]Distribution `json:"measurements"`
+	// 軸の名前 → 属性 → 属性の値ごとの平均
+	RadarAverages map[string]map[string][]Average `json:"radar_averages"`
+	Tags          []TagFrequency                  `json:"tags"`
+}
@@ -0,0 +1,174 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/api/entry_filter"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+const (
+	// MaxBins はbinsに指定できる最大の区間の数
+	MaxBins = 100
+	// CacheTTL は集計結果をキャッシュする時間
+	// 登録や更新の直後はこの時間だけ古い結果を返すことがある
+	CacheTTL = time.Minute
+	// maxCacheEntries はキャッシュする絞り込みの条件の数
+	maxCacheEntries = 100
+)
+
+// cacheEntry はキャッシュした集計結果のjson
+type cacheEntry struct {
+	body    []byte
+	etag    string
+	expires time.Time
+}
+
+type ReadHandler struct {
+	svc *service.IndexService
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+	now   func() time.Time
+}
+
+func NewReadHandler(svc *service.IndexService) *ReadHandler {
+	return &ReadHandler{
+		svc:   svc,
+		cache: map[string]cacheEntry{},
+		now:   time.Now,
+	}
+}
+
+// ServeHTTP は絞り込んだentryの統計を返す
+//
+//	GET /api/stats?source_type=anime&tag=1&bust[gte]=80&bins=10
+//
+// 絞り込みの条件は/api/facetsと同じ
+// 結果は条件ごとにCacheTTLの間キャッシュし、ETagが一致する場合は304を返す
+func (h *ReadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// GET以外は受け付けない
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	query := r.URL.Query()
+	// 同じ条件でパラメータの順序だけが異なる場合も同じキャッシュを使う
+	key := query.Encode()
+	entry, ok := h.cached(key)
+	if !ok {
+		result, err := h.stats(r.Context(), query)
+		var verr validation.Errors
+		switch {
+		case errors.As(err, &verr):
+			problem.Validation(w, r, verr)
+			return
+		case err != nil:
+			problem.Database(w, r, err)
+			return
+		}
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(result); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		entry = h.store(key, buf.Bytes())
+	}
+
+	w.Header().Set("ETag", entry.etag)
+	// BasicAuthの内側のため、共有のキャッシュには保存させない
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(CacheTTL.Seconds())))
+	if r.Header.Get("If-None-Match") == entry.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(entry.body); err != nil {
+		log.Printf("write error: %v", err)
+	}
+}
+
+// stats は条件を検証し、絞り込んだentryを集計する
+// 条件の形式が正しくない場合はvalidation.Errorsを返す
+func (h *ReadHandler) stats(ctx context.Context, query url.Values) (*StatsJson, error) {
+	bins := DefaultBins
+	if v := query.Get("bins"); v != "" {
+		var err error
+		bins, err = strconv.Atoi(v)
+		if err != nil || bins < 1 || bins > MaxBins {
+			return nil, validation.Errors{"bins": fmt.Errorf("must be between 1 and %d", MaxBins)}
+		}
+	}
+	axes, err := entryfilter.LoadAxes(ctx, h.svc.DB)
+	if err != nil {
+		return nil, err
+	}
+	conditions, err := entryfilter.Parse(query, entryfilter.RangeColumns(axes))
+	if err != nil {
+		return nil, err
+	}
+	where, args := entryfilter.Where(conditions, "")
+	return Aggregate(ctx, h.svc.DB, where, args, bins)
+}
+
+// cached は期限内のキャッシュを返す
+func (h *ReadHandler) cached(key string) (cacheEntry, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entry, ok := h.cache[key]
+	if !ok || !h.now().Before(entry.expires) {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// store は集計結果をキャッシュする
+// キャッシュが一杯の場合は期限切れのものを削除し、それでも一杯の場合は全て削除する
+func (h *ReadHandler) store(key string, body []byte) cacheEntry {
+	sum := sha256.Sum256(body)
+	entry := cacheEntry{
+		body:    body,
+		etag:    `"` + hex.EncodeToString(sum[:16]) + `"`,
+		expires: h.now().Add(CacheTTL),
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.cache) >= maxCacheEntries {
+		now := h.now()
+		for k, e := range h.cache {
+			if !now.Before(e.expires) {
+				delete(h.cache, k)
+			}
+		}
+		if len(h.cache) >= maxCacheEntries {
+			h.cache = map[string]cacheEntry{}
+		}
+	}
+	h.cache[key] = entry
+	return entry
+}
+
+// Routes は/api/statsのルーティングの定義を返す
+func Routes(svc *service.IndexService) router.Resource {
+	return router.Resource{
+		Name: "stats",
+		Read: NewReadHandler(svc),
+	}
+}
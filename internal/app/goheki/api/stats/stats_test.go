@@ -0,0 +1,185 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadStatsHandler(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	assert.NoError(t, err)
+	// データベースに接続
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
+	assert.NoError(t, err)
+	defer cleanup()
+	// トランザクションの開始
+	tx, err := indexDB.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+
+	// トランザクションのロールバック
+	defer tx.RollbackCtx(ctx)
+
+	fixedTime := time.Date(2023, time.December, 27, 10, 55, 22, 0, time.UTC)
+	// データベースの準備
+	f := &fixtures.Fixture{DBv1: tx}
+	f.Build(t,
+		fixtures.NewHairColorType(ctx, func(s *fixtures.HairColorType) {
+			s.Color = "銀"
+		}),
+		fixtures.NewHekiRadarAxis(ctx, func(s *fixtures.HekiRadarAxis) {
+			s.Name = "ai"
+		}),
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "閃乱カグラ"
+			s.Url = "https://example.com/image1.png"
+			s.Type = "anime"
+		}).Connect(
+			fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+				s.Name = "雪泉"
+				s.Image = "https://example.com/image1.png"
+				s.Content = "かわいい"
+				s.CreatedAt = fixedTime
+			}).Connect(
+				fixtures.NewHairColor(ctx, func(s *fixtures.HairColor) {
+					s.ColorID = f.HairColorTypes[0].ID
+				}),
+				fixtures.NewBWH(ctx, func(s *fixtures.BWH) {
+					s.Bust = 86
+					s.Waist = 55
+					s.Hip = 84
+				}),
+				fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+					s.AxisID = f.HekiRadarAxes[0].ID
+					s.Score = 100
+				}),
+			),
+		),
+		fixtures.NewSource(ctx, func(s *fixtures.Source) {
+			s.Name = "アイドルマスター"
+			s.Url = "https://example.com/image2.png"
+			s.Type = "game"
+		}).Connect(fixtures.NewEntry(ctx, func(s *fixtures.Entry) {
+			s.Name = "四条貴音"
+			s.Image = "https://example.com/image2.png"
+			s.Content = "お姫ちん"
+			s.CreatedAt = fixedTime
+		}).Connect(
+			fixtures.NewHairColor(ctx, func(s *fixtures.HairColor) {
+				s.ColorID = f.HairColorTypes[0].ID
+			}),
+			fixtures.NewBWH(ctx, func(s *fixtures.BWH) {
+				s.Bust = 90
+				s.Waist = 62
+				s.Hip = 92
+			}),
+			fixtures.NewHekiRadarScore(ctx, func(s *fixtures.HekiRadarScore) {
+				s.AxisID = f.HekiRadarAxes[0].ID
+				s.Score = 80
+			}),
+		)),
+	)
+
+	var indexService = service.NewIndexService(
+		tx,
+		cookie.Store,
+		env,
+	)
+	handler := NewReadHandler(indexService)
+
+	t.Run("全てのentryの統計を取得", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, w.Header().Get("ETag"))
+		assert.Equal(t, "private, max-age=60", w.Header().Get("Cache-Control"))
+		var actual StatsJson
+		err := json.NewDecoder(w.Body).Decode(&actual)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), actual.Total)
+		assert.Equal(t, []Count{
+			{ID: f.HairColorTypes[0].ID, Name: "銀", Count: 2},
+		}, actual.Attributes["haircolor"])
+		assert.Equal(t, []ValueCount{
+			{Value: "anime", Count: 1},
+			{Value: "game", Count: 1},
+		}, actual.SourceTypes)
+		assert.Equal(t, int64(2), actual.Measurements["bust"].Count)
+		assert.Equal(t, 88.0, actual.Measurements["bust"].Percentiles["p50"])
+		assert.Equal(t, []Average{
+			{ID: f.HairColorTypes[0].ID, Name: "銀", Count: 2, Average: 90},
+		}, actual.RadarAverages["ai"]["haircolor"])
+	})
+
+	t.Run("絞り込んだentryの統計を取得", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/stats?source_type=game&ai[gte]=50", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var actual StatsJson
+		err := json.NewDecoder(w.Body).Decode(&actual)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), actual.Total)
+		if assert.Len(t, actual.Sources, 1) {
+			assert.Equal(t, "アイドルマスター", actual.Sources[0].Name)
+		}
+	})
+
+	t.Run("キャッシュした結果を返す", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/stats?source_type=anime", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		etag := w.Header().Get("ETag")
+
+		// 期限内は作品の種類の変更を反映しない
+		_, err := tx.ExecContext(ctx, "UPDATE source SET type = 'game' WHERE id = $1", f.Sources[0].ID)
+		assert.NoError(t, err)
+		req = httptest.NewRequest(http.MethodGet, "/api/stats?source_type=anime", nil)
+		req.Header.Set("If-None-Match", etag)
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Equal(t, etag, w.Header().Get("ETag"))
+
+		// 期限が切れた場合は集計し直す
+		handler.now = func() time.Time { return time.Now().Add(CacheTTL) }
+		req = httptest.NewRequest(http.MethodGet, "/api/stats?source_type=anime", nil)
+		req.Header.Set("If-None-Match", etag)
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEqual(t, etag, w.Header().Get("ETag"))
+		var actual StatsJson
+		err = json.NewDecoder(w.Body).Decode(&actual)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), actual.Total)
+	})
+
+	t.Run("条件の形式が正しくない", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/stats?haircolor=aaa", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+		req = httptest.NewRequest(http.MethodGet, "/api/stats?bins=0", nil)
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+}
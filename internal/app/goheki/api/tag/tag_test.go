@@ -14,7 +14,6 @@ import (
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
 	"github.com/maguro-alternative/goheki/internal/app/goheki/service/cookie"
 
-	"github.com/maguro-alternative/goheki/pkg/db"
 
 	"github.com/maguro-alternative/goheki/internal/app/goheki/model/fixtures"
 
@@ -27,7 +26,7 @@ func TestCreateTagHandler(t *testing.T) {
 		env, err := envconfig.NewEnv()
 		assert.NoError(t, err)
 		// データベースに接続
-		indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+		indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 		assert.NoError(t, err)
 		defer cleanup()
 		// トランザクションの開始
@@ -66,6 +65,11 @@ func TestCreateTagHandler(t *testing.T) {
 		err = json.NewDecoder(w.Body).Decode(&tags)
 		assert.NoError(t, err)
 
+		// 採番されたidが返る
+		for i := range tags.Tags {
+			assert.NotZero(t, tags.Tags[i].ID)
+			tag.Tags[i].ID = tags.Tags[i].ID
+		}
 		assert.Equal(t, tag, tags)
 
 		var actuals []Tag
@@ -81,7 +85,7 @@ func TestCreateTagHandler(t *testing.T) {
 		env, err := envconfig.NewEnv()
 		assert.NoError(t, err)
 		// データベースに接続
-		indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+		indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 		assert.NoError(t, err)
 		defer cleanup()
 		// トランザクションの開始
@@ -121,7 +125,7 @@ func TestReadTagHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -303,7 +307,7 @@ func TestReadTagHandler(t *testing.T) {
 
 		// tx.RollbackCtx(ctx)
 
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 	})
 
 	t.Run("tag2件取得(内1件形式が正しくない)", func(t *testing.T) {
@@ -322,7 +326,7 @@ func TestReadTagHandler(t *testing.T) {
 
 		// tx.RollbackCtx(ctx)
 
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 	})
 }
 
@@ -331,7 +335,7 @@ func TestUpdateTagHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -405,9 +409,11 @@ func TestUpdateTagHandler(t *testing.T) {
 	t.Run("tag更新", func(t *testing.T) {
 		updateTag := TagsJson{[]Tag{
 			{
+				ID:   f.Tags[0].ID,
 				Name: "テストタグ3",
 			},
 			{
+				ID:   f.Tags[1].ID,
 				Name: "テストタグ4",
 			},
 		}}
@@ -448,7 +454,7 @@ func TestDeleteTagHandler(t *testing.T) {
 	env, err := envconfig.NewEnv()
 	assert.NoError(t, err)
 	// データベースに接続
-	indexDB, cleanup, err := db.NewDBV1(ctx, "postgres", env.DatabaseURL)
+	indexDB, cleanup, err := fixtures.NewDB(ctx, env)
 	assert.NoError(t, err)
 	defer cleanup()
 	// トランザクションの開始
@@ -0,0 +1,113 @@
+package fulltext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"ユキミ":       "ゆきみ",
+		"ﾕｷﾐ":       "ゆきみ",
+		"ｶﾞｯｺｳ":     "がっこう",
+		"ＡＢＣ１２３":    "abc123",
+		"  お姫　ちん  ": "お姫 ちん",
+		"ヽヾ":        "ゝゞ",
+	}
+	for input, expected := range cases {
+		assert.Equal(t, expected, Normalize(input), input)
+	}
+}
+
+func testIndex() *Index {
+	return NewIndex([]Document{
+		{ID: 1, Fields: []Field{
+			{Name: "name", Text: "四条貴音", Weight: 4},
+			{Name: "content", Text: "お姫ちん。ラーメンが好き", Weight: 1},
+		}},
+		{ID: 2, Fields: []Field{
+			{Name: "name", Text: "雪泉", Weight: 4},
+			{Name: "content", Text: "かわいい", Weight: 1},
+		}},
+		{ID: 3, Fields: []Field{
+			{Name: "name", Text: "ラーメン屋の娘", Weight: 4},
+			{Name: "content", Text: "<b>ﾗｰﾒﾝ</b>の湯切りが上手", Weight: 1},
+		}},
+	})
+}
+
+func TestIndexSearch(t *testing.T) {
+	idx := testIndex()
+
+	t.Run("名前に一致したものを上位にする", func(t *testing.T) {
+		hits, total := idx.Search("らーめん", 10)
+		assert.Equal(t, 2, total)
+		if assert.Len(t, hits, 2) {
+			assert.Equal(t, int64(3), hits[0].ID)
+			assert.Equal(t, int64(1), hits[1].ID)
+			assert.Greater(t, hits[0].Score, hits[1].Score)
+		}
+	})
+
+	t.Run("全ての検索語を含むものだけを返す", func(t *testing.T) {
+		hits, total := idx.Search("ラーメン 湯切り", 10)
+		assert.Equal(t, 1, total)
+		if assert.Len(t, hits, 1) {
+			assert.Equal(t, int64(3), hits[0].ID)
+		}
+	})
+
+	t.Run("1文字で検索できる", func(t *testing.T) {
+		hits, _ := idx.Search("雪", 10)
+		if assert.Len(t, hits, 1) {
+			assert.Equal(t, int64(2), hits[0].ID)
+		}
+	})
+
+	t.Run("bigramが揃っていても部分一致しないものは返さない", func(t *testing.T) {
+		// 「かわ」「わい」は含むが「かわわい」は含まない
+		hits, total := idx.Search("かわわい", 10)
+		assert.Equal(t, 0, total)
+		assert.Empty(t, hits)
+	})
+
+	t.Run("limitで件数を切り捨てる", func(t *testing.T) {
+		hits, total := idx.Search("ラーメン", 1)
+		assert.Equal(t, 2, total)
+		assert.Len(t, hits, 1)
+	})
+
+	t.Run("空のクエリ", func(t *testing.T) {
+		hits, total := idx.Search("　", 10)
+		assert.Equal(t, 0, total)
+		assert.Empty(t, hits)
+	})
+}
+
+func TestIndexSnippet(t *testing.T) {
+	idx := testIndex()
+
+	t.Run("元の表記のまま一致した部分を囲む", func(t *testing.T) {
+		hits, _ := idx.Search("ラーメン", 10)
+		assert.Equal(t, []Snippet{
+			{Field: "name", Text: "<mark>ラーメン</mark>屋の娘"},
+			{Field: "content", Text: "&lt;b&gt;<mark>ﾗｰﾒﾝ</mark>&lt;/b&gt;の湯切りが上手"},
+		}, hits[0].Snippets)
+	})
+
+	t.Run("長い項目は一致した位置の周辺を切り出す", func(t *testing.T) {
+		long := ""
+		for i := 0; i < 10; i++ {
+			long += "あいうえおかきくけこ"
+		}
+		idx := NewIndex([]Document{{ID: 1, Fields: []Field{
+			{Name: "content", Text: long + "雪泉" + long, Weight: 1},
+		}}})
+		hits, _ := idx.Search("雪泉", 10)
+		if assert.Len(t, hits, 1) {
+			text := hits[0].Snippets[0].Text
+			assert.Equal(t, "…"+long[len(long)-len("あ")*snippetContext:]+"<mark>雪泉</mark>"+long[:len("あ")*(SnippetLength-snippetContext-2)]+"…", text)
+		}
+	})
+}
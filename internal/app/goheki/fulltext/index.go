@@ -0,0 +1,201 @@
+package fulltext
+
+import (
+	"math"
+	"sort"
+)
+
+// Field は検索対象の項目
+// Weightが大きい項目に一致したものほど上位になる
+type Field struct {
+	Name   string
+	Text   string
+	Weight float64
+}
+
+// Document は検索対象の1件
+type Document struct {
+	ID     int64
+	Fields []Field
+}
+
+// Hit は検索結果の1件
+type Hit struct {
+	ID       int64     `json:"id"`
+	Score    float64   `json:"score"`
+	Snippets []Snippet `json:"snippets"`
+}
+
+type document struct {
+	id     int64
+	fields []field
+}
+
+type field struct {
+	name   string
+	weight float64
+	text   text
+}
+
+// Index はbigramの転置索引
+// PostgreSQLの拡張機能 (pg_trgm, pg_bigm) を使わずに日本語を部分一致で検索する
+type Index struct {
+	docs     []document
+	postings map[string][]int
+}
+
+// NewIndex はdocsの索引を作成する
+func NewIndex(docs []Document) *Index {
+	idx := &Index{
+		docs:     make([]document, len(docs)),
+		postings: map[string][]int{},
+	}
+	for i, d := range docs {
+		doc := document{id: d.ID, fields: make([]field, len(d.Fields))}
+		seen := map[string]bool{}
+		for j, f := range d.Fields {
+			doc.fields[j] = field{name: f.Name, weight: f.Weight, text: fold(f.Text)}
+			for _, g := range indexGrams(doc.fields[j].text.runes) {
+				if seen[g] {
+					continue
+				}
+				seen[g] = true
+				idx.postings[g] = append(idx.postings[g], i)
+			}
+		}
+		idx.docs[i] = doc
+	}
+	return idx
+}
+
+// Search はqueryの全ての検索語を含むものをスコアの高い順に返す
+// 2つ目の戻り値は一致した件数 (limitで切り捨てる前の件数)
+//
+// bigramで候補を絞り込んだ後、正規化した文字列の部分一致で確認する
+// スコアは項目の重み × (1 + log(出現回数)) × idf の検索語ごとの合計
+func (idx *Index) Search(query string, limit int) ([]Hit, int) {
+	ts := terms(query)
+	if len(ts) == 0 {
+		return []Hit{}, 0
+	}
+	candidates := idx.candidates(ts)
+
+	// 検索語ごとに一致した件数からidfを求める
+	matches := make(map[int][][]match, len(candidates))
+	df := make([]int, len(ts))
+	for _, i := range candidates {
+		ms := make([][]match, len(ts))
+		ok := true
+		for j, term := range ts {
+			ms[j] = idx.docs[i].find(term)
+			if len(ms[j]) == 0 {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		matches[i] = ms
+		for j := range ts {
+			df[j]++
+		}
+	}
+
+	hits := make([]Hit, 0, len(matches))
+	for i, ms := range matches {
+		doc := idx.docs[i]
+		var score float64
+		for j, termMatches := range ms {
+			idf := math.Log(1 + float64(len(idx.docs))/float64(df[j]))
+			counts := map[int]int{}
+			for _, m := range termMatches {
+				counts[m.field]++
+			}
+			for f, n := range counts {
+				score += doc.fields[f].weight * (1 + math.Log(float64(n))) * idf
+			}
+		}
+		hits = append(hits, Hit{
+			ID:       doc.id,
+			Score:    math.Round(score*1000) / 1000,
+			Snippets: doc.snippets(ms),
+		})
+	}
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		return hits[a].ID < hits[b].ID
+	})
+	total := len(hits)
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, total
+}
+
+// candidates は全ての検索語のbigramを含む文書の番号を返す
+func (idx *Index) candidates(ts [][]rune) []int {
+	var result []int
+	first := true
+	for _, term := range ts {
+		for _, g := range grams(term) {
+			postings := idx.postings[g]
+			if first {
+				result = append([]int(nil), postings...)
+				first = false
+				continue
+			}
+			result = intersect(result, postings)
+		}
+	}
+	return result
+}
+
+// intersect は昇順に並んだ2つの番号の一覧の共通部分を返す
+func intersect(a, b []int) []int {
+	result := a[:0]
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// match は項目内で検索語に一致した位置 (正規化後の文字単位)
+type match struct {
+	field      int
+	start, end int
+}
+
+// find は文書内でtermに一致した位置を全て返す
+func (d document) find(term []rune) []match {
+	var result []match
+	for f, fl := range d.fields {
+		runes := fl.text.runes
+		for i := 0; i+len(term) <= len(runes); i++ {
+			if equalRunes(runes[i:i+len(term)], term) {
+				result = append(result, match{field: f, start: i, end: i + len(term)})
+			}
+		}
+	}
+	return result
+}
+
+func equalRunes(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package fulltext
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// text は正規化した文字列と、各文字の元の文字列での位置
+// ハイライトの際に元の表記のまま切り出すために使う
+type text struct {
+	src    string
+	runes  []rune
+	starts []int // runes[i]の元の文字列での開始バイト位置
+	ends   []int // runes[i]の元の文字列での終了バイト位置
+}
+
+// Normalize は検索用に文字列を正規化する
+//
+//   - 全角英数字・半角カナはNFKCで半角英数字・全角カナにそろえる
+//   - 英字は小文字にそろえる
+//   - カタカナはひらがなにそろえる
+//   - 連続する空白は1つの半角空白にまとめる
+func Normalize(s string) string {
+	return string(fold(s).runes)
+}
+
+func fold(s string) text {
+	t := text{src: s}
+	var it norm.Iter
+	it.InitString(norm.NFKC, s)
+	for !it.Done() {
+		start := it.Pos()
+		seg := it.Next()
+		end := it.Pos()
+		// 合成や分解で文字数が変わるため、正規化の単位ごとに元の位置を対応付ける
+		for _, r := range string(seg) {
+			r = foldRune(r)
+			if unicode.IsSpace(r) {
+				if len(t.runes) == 0 || t.runes[len(t.runes)-1] == ' ' {
+					continue
+				}
+				r = ' '
+			}
+			t.runes = append(t.runes, r)
+			t.starts = append(t.starts, start)
+			t.ends = append(t.ends, end)
+		}
+	}
+	if n := len(t.runes); n > 0 && t.runes[n-1] == ' ' {
+		t.runes = t.runes[:n-1]
+		t.starts = t.starts[:n-1]
+		t.ends = t.ends[:n-1]
+	}
+	return t
+}
+
+func foldRune(r rune) rune {
+	r = unicode.ToLower(r)
+	switch {
+	// ァ-ヶ → ぁ-ゖ
+	case r >= 'ァ' && r <= 'ヶ':
+		return r - 0x60
+	// ヽヾ → ゝゞ
+	case r == 'ヽ' || r == 'ヾ':
+		return r - 0x60
+	}
+	return r
+}
+
+// terms はクエリを空白で区切った検索語を返す
+// 同じ検索語は1つにまとめる
+func terms(query string) [][]rune {
+	var result [][]rune
+	seen := map[string]bool{}
+	for _, term := range strings.Fields(Normalize(query)) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		result = append(result, []rune(term))
+	}
+	return result
+}
+
+// grams は検索語をbigramに分割する
+// 1文字の検索語はそのまま1文字で引く
+func grams(term []rune) []string {
+	if len(term) == 1 {
+		return []string{string(term)}
+	}
+	result := make([]string, 0, len(term)-1)
+	for i := 0; i+1 < len(term); i++ {
+		result = append(result, string(term[i:i+2]))
+	}
+	return result
+}
+
+// indexGrams は索引に登録する1文字とbigramを返す
+// 空白をまたぐbigramは登録しない
+func indexGrams(runes []rune) []string {
+	var result []string
+	for i, r := range runes {
+		if r == ' ' {
+			continue
+		}
+		result = append(result, string(r))
+		if i+1 < len(runes) && runes[i+1] != ' ' {
+			result = append(result, string(runes[i:i+2]))
+		}
+	}
+	return result
+}
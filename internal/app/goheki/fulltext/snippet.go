@@ -0,0 +1,95 @@
+package fulltext
+
+import (
+	"html"
+	"strings"
+)
+
+const (
+	// SnippetLength はスニペットの最大文字数 (正規化後の文字単位)
+	SnippetLength = 80
+	// snippetContext は最初に一致した位置より前に含める文字数
+	snippetContext = 20
+)
+
+// Snippet は一致した項目の抜粋
+// Textは一致した部分を<mark>で囲んだHTMLで、それ以外はエスケープ済み
+type Snippet struct {
+	Field string `json:"field"`
+	Text  string `json:"text"`
+}
+
+// snippets は一致した項目ごとの抜粋を項目の順に返す
+func (d document) snippets(ms [][]match) []Snippet {
+	byField := make([][]match, len(d.fields))
+	for _, termMatches := range ms {
+		for _, m := range termMatches {
+			byField[m.field] = append(byField[m.field], m)
+		}
+	}
+	var result []Snippet
+	for f, fieldMatches := range byField {
+		if len(fieldMatches) == 0 {
+			continue
+		}
+		result = append(result, Snippet{
+			Field: d.fields[f].name,
+			Text:  d.fields[f].text.highlight(fieldMatches),
+		})
+	}
+	return result
+}
+
+// highlight は最初に一致した位置の周辺を切り出し、一致した部分を<mark>で囲む
+// 切り出した文字列は正規化前の元の表記で返す
+func (t text) highlight(ms []match) string {
+	first := len(t.runes)
+	marked := make([]bool, len(t.runes))
+	for _, m := range ms {
+		if m.start < first {
+			first = m.start
+		}
+		for i := m.start; i < m.end; i++ {
+			marked[i] = true
+		}
+	}
+	from := first - snippetContext
+	if from < 0 {
+		from = 0
+	}
+	to := from + SnippetLength
+	if to > len(t.runes) {
+		to = len(t.runes)
+		if from = to - SnippetLength; from < 0 {
+			from = 0
+		}
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	inMark := false
+	for i := from; i < to; i++ {
+		// NFKCで1文字が複数の文字になった場合は元の文字を1回だけ書き出す
+		if i > from && t.starts[i] == t.starts[i-1] {
+			continue
+		}
+		if marked[i] != inMark {
+			if marked[i] {
+				b.WriteString("<mark>")
+			} else {
+				b.WriteString("</mark>")
+			}
+			inMark = marked[i]
+		}
+		b.WriteString(html.EscapeString(t.src[t.starts[i]:t.ends[i]]))
+	}
+	if inMark {
+		b.WriteString("</mark>")
+	}
+	if to < len(t.runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
@@ -0,0 +1,118 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+)
+
+// orientationTag はExifの画像の向きのタグ
+const orientationTag = 0x0112
+
+// jpegOrientation はJPEGのExifから画像の向き (1〜8) を返す
+// Exifがない場合や読めない場合は1 (そのまま) を返す
+func jpegOrientation(data []byte) int {
+	// SOIの後のセグメントを順に読む
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return 1
+	}
+	p := 2
+	for p+4 <= len(data) {
+		if data[p] != 0xff {
+			return 1
+		}
+		marker := data[p+1]
+		// SOSの後は画像データなのでExifはない
+		if marker == 0xda {
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[p+2:]))
+		if size < 2 || p+2+size > len(data) {
+			return 1
+		}
+		segment := data[p+4 : p+2+size]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		p += 2 + size
+	}
+	return 1
+}
+
+// tiffOrientation はExifのTIFF構造の最初のIFDから画像の向きを返す
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	n := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < n; i++ {
+		entry := ifd + 2 + 12*i
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) != orientationTag {
+			continue
+		}
+		// SHORT型の値はエントリの値の位置にそのまま入っている
+		v := int(order.Uint16(tiff[entry+8:]))
+		if v < 1 || v > 8 {
+			return 1
+		}
+		return v
+	}
+	return 1
+}
+
+// orient はExifの向きに従って画像を回転、反転する
+// Exifを取り除いても正しい向きで表示されるよう、画素に反映する
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	src := image.NewNRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(src, src.Bounds(), img, img.Bounds().Min, draw.Src)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	// 5〜8は90度単位で回転するため幅と高さが入れ替わる
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // 左右反転
+				dx, dy = w-1-x, y
+			case 3: // 180度回転
+				dx, dy = w-1-x, h-1-y
+			case 4: // 上下反転
+				dx, dy = x, h-1-y
+			case 5: // 左上と右下を結ぶ線で反転
+				dx, dy = y, x
+			case 6: // 時計回りに90度回転
+				dx, dy = h-1-y, x
+			case 7: // 右上と左下を結ぶ線で反転
+				dx, dy = h-1-y, w-1-x
+			case 8: // 反時計回りに90度回転
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,258 @@
+// Package media はアップロードされた画像を検証、変換して保存する
+// 保存する前に大きさを揃え、サムネイルとWebPを作成し、Exifなどのメタデータを取り除く
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+
+	// 読み込める画像の形式
+	_ "image/gif"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// DefaultMaxBytes はアップロードできる画像の既定の最大サイズ
+	DefaultMaxBytes = 10 << 20
+	// MaxPixels は読み込む画像の最大の画素数
+	// 小さいファイルで巨大な画像を展開させる攻撃を防ぐ
+	MaxPixels = 50_000_000
+	// jpegQuality はJPEGで保存する場合の品質
+	jpegQuality = 85
+)
+
+var (
+	// ErrTooLarge は画像のファイルサイズまたは画素数が大きすぎる場合のエラー
+	ErrTooLarge = errors.New("image is too large")
+	// ErrUnsupported は画像でない、または対応していない形式の場合のエラー
+	ErrUnsupported = errors.New("unsupported image format: jpeg, png, gif or webp is required")
+)
+
+// contentTypes は受け付ける画像の形式
+var contentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Size は保存する画像の大きさ
+type Size struct {
+	Name string
+	// 幅と高さの最大 (px)、小さい画像は拡大しない
+	MaxSize int
+}
+
+var (
+	// Large は表示用の画像
+	Large = Size{Name: "large", MaxSize: 2048}
+	// Thumbnail は一覧などで使うサムネイル
+	Thumbnail = Size{Name: "thumb", MaxSize: 320}
+)
+
+// Config は画像の保存先の設定
+type Config struct {
+	// 画像を保存するディレクトリ
+	Dir string
+	// 画像を配信するURL、/で始まる場合はこのサーバーで配信する
+	BaseURL string
+	// アップロードできる最大のファイルサイズ (byte)
+	MaxBytes int64
+}
+
+// NewConfig は環境変数から画像の保存先の設定を生成する
+// 指定されていない値は既定値を使う
+func NewConfig(env *envconfig.Env) (*Config, error) {
+	cfg := &Config{
+		Dir:      "uploads",
+		BaseURL:  "/images/",
+		MaxBytes: DefaultMaxBytes,
+	}
+	if env.ImageDir != "" {
+		cfg.Dir = env.ImageDir
+	}
+	if env.ImageBaseURL != "" {
+		cfg.BaseURL = env.ImageBaseURL
+	}
+	if v := env.ImageMaxBytes; v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid IMAGE_MAX_BYTES: %q", v)
+		}
+		cfg.MaxBytes = n
+	}
+	return cfg, nil
+}
+
+// Image は保存した画像のURL
+type Image struct {
+	URL              string `json:"url"`
+	Width            int    `json:"width"`
+	Height           int    `json:"height"`
+	ThumbnailURL     string `json:"thumbnail_url"`
+	WebPURL          string `json:"webp_url"`
+	ThumbnailWebPURL string `json:"thumbnail_webp_url"`
+
+	// keys は保存先のキー (Removeで削除する)
+	keys []string
+}
+
+// Uploader は画像を検証、変換してStorageに保存する
+type Uploader struct {
+	storage  Storage
+	maxBytes int64
+}
+
+func NewUploader(storage Storage, maxBytes int64) *Uploader {
+	return &Uploader{
+		storage:  storage,
+		maxBytes: maxBytes,
+	}
+}
+
+// MaxBytes はアップロードできる最大のファイルサイズを返す
+func (u *Uploader) MaxBytes() int64 {
+	return u.maxBytes
+}
+
+// Upload はrの画像を検証し、大きさごとに元の形式とWebPで保存する
+// 保存先のキーは画像の内容のハッシュから決めるため、同じ画像は同じURLになる
+func (u *Uploader) Upload(ctx context.Context, r io.Reader) (*Image, error) {
+	data, err := io.ReadAll(io.LimitReader(r, u.maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > u.maxBytes {
+		return nil, ErrTooLarge
+	}
+	img, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	prefix := hex.EncodeToString(sum[:])
+
+	// 透過のある画像はPNG、それ以外はJPEGで保存する
+	ext, contentType := "jpg", "image/jpeg"
+	if !opaque(img) {
+		ext, contentType = "png", "image/png"
+	}
+
+	res := &Image{}
+	for _, size := range []Size{Large, Thumbnail} {
+		resized := resize(img, size.MaxSize)
+		key := fmt.Sprintf("%s/%s.%s", prefix, size.Name, ext)
+		if err := u.put(ctx, key, contentType, func(w io.Writer) error {
+			if ext == "png" {
+				return png.Encode(w, resized)
+			}
+			return jpeg.Encode(w, resized, &jpeg.Options{Quality: jpegQuality})
+		}); err != nil {
+			return nil, err
+		}
+		res.keys = append(res.keys, key)
+		webpKey := fmt.Sprintf("%s/%s.webp", prefix, size.Name)
+		if err := u.put(ctx, webpKey, "image/webp", func(w io.Writer) error {
+			return EncodeWebP(w, resized)
+		}); err != nil {
+			return nil, err
+		}
+		res.keys = append(res.keys, webpKey)
+		if size == Large {
+			res.URL = u.storage.URL(key)
+			res.WebPURL = u.storage.URL(webpKey)
+			res.Width, res.Height = resized.Bounds().Dx(), resized.Bounds().Dy()
+		} else {
+			res.ThumbnailURL = u.storage.URL(key)
+			res.ThumbnailWebPURL = u.storage.URL(webpKey)
+		}
+	}
+	return res, nil
+}
+
+// Remove はUploadで保存した画像を全ての大きさと形式について削除する
+// 同じ内容の画像は同じキーに保存されるため、他で参照していないことを呼び出し元で確認する
+func (u *Uploader) Remove(ctx context.Context, img *Image) error {
+	var errs []error
+	for _, key := range img.keys {
+		if err := u.storage.Delete(ctx, key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// put はencodeで書き込んだ内容をkeyに保存する
+func (u *Uploader) put(ctx context.Context, key, contentType string, encode func(w io.Writer) error) error {
+	var buf bytes.Buffer
+	if err := encode(&buf); err != nil {
+		return err
+	}
+	return u.storage.Put(ctx, key, &buf, contentType)
+}
+
+// decode は画像の形式と画素数を検証してから読み込む
+// JPEGはExifの向きを画素に反映する
+func decode(data []byte) (image.Image, error) {
+	if !contentTypes[http.DetectContentType(data)] {
+		return nil, ErrUnsupported
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width < 1 || cfg.Height < 1 {
+		return nil, ErrUnsupported
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, ErrTooLarge
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+	return img, nil
+}
+
+// resize は幅と高さがmaxSize以下になるよう縦横比を保って縮小する
+// 縮小しない場合も、メタデータを持たない新しい画像として返す
+func resize(img image.Image, maxSize int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > maxSize || h > maxSize {
+		if w >= h {
+			w, h = maxSize, maxInt(1, h*maxSize/w)
+		} else {
+			w, h = maxInt(1, w*maxSize/h), maxSize
+		}
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	if w == b.Dx() && h == b.Dy() {
+		draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+		return dst
+	}
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// opaque は画像に透過がないかを返す
+func opaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newJPEG はw×hのJPEGを返す
+// orientationが1以外の場合はExifの向きを付ける
+func newJPEG(t *testing.T, w, h int, orientation uint16) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, img, nil))
+	if orientation == 1 {
+		return buf.Bytes()
+	}
+	// IFDに向きのエントリを1つだけ持つExif
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], orientationTag)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	tiff = append(tiff, entry...)
+	tiff = append(tiff, 0, 0, 0, 0)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), app1...), data[2:]...)
+}
+
+func TestUploader(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	u := NewUploader(NewLocalStorage(dir, "/images"), 1<<20)
+
+	t.Run("JPEGを縮小してWebPと合わせて保存する", func(t *testing.T) {
+		data := newJPEG(t, 3000, 1500, 1)
+		img, err := u.Upload(ctx, bytes.NewReader(data))
+		assert.NoError(t, err)
+		assert.Equal(t, 2048, img.Width)
+		assert.Equal(t, 1024, img.Height)
+		assert.True(t, strings.HasPrefix(img.URL, "/images/"))
+		assert.True(t, strings.HasSuffix(img.URL, "/large.jpg"))
+		assert.True(t, strings.HasSuffix(img.ThumbnailURL, "/thumb.jpg"))
+		assert.True(t, strings.HasSuffix(img.WebPURL, "/large.webp"))
+		assert.True(t, strings.HasSuffix(img.ThumbnailWebPURL, "/thumb.webp"))
+
+		f, err := os.Open(filepath.Join(dir, strings.TrimPrefix(img.ThumbnailURL, "/images/")))
+		assert.NoError(t, err)
+		defer f.Close()
+		cfg, format, err := image.DecodeConfig(f)
+		assert.NoError(t, err)
+		assert.Equal(t, "jpeg", format)
+		assert.Equal(t, 320, cfg.Width)
+		assert.Equal(t, 160, cfg.Height)
+	})
+
+	t.Run("Exifの向きを反映して取り除く", func(t *testing.T) {
+		// 時計回りに90度回転して表示する画像
+		data := newJPEG(t, 40, 20, 6)
+		img, err := u.Upload(ctx, bytes.NewReader(data))
+		assert.NoError(t, err)
+		assert.Equal(t, 20, img.Width)
+		assert.Equal(t, 40, img.Height)
+
+		stored, err := os.ReadFile(filepath.Join(dir, strings.TrimPrefix(img.URL, "/images/")))
+		assert.NoError(t, err)
+		assert.False(t, bytes.Contains(stored, []byte("Exif")))
+		assert.Equal(t, 1, jpegOrientation(stored))
+	})
+
+	t.Run("透過のある画像はPNGで保存する", func(t *testing.T) {
+		src := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+		var buf bytes.Buffer
+		assert.NoError(t, png.Encode(&buf, src))
+		img, err := u.Upload(ctx, &buf)
+		assert.NoError(t, err)
+		assert.True(t, strings.HasSuffix(img.URL, "/large.png"))
+		assert.Equal(t, 10, img.Width)
+	})
+
+	t.Run("保存した画像を削除する", func(t *testing.T) {
+		img, err := u.Upload(ctx, bytes.NewReader(newJPEG(t, 30, 30, 1)))
+		assert.NoError(t, err)
+		assert.NoError(t, u.Remove(ctx, img))
+		for _, url := range []string{img.URL, img.ThumbnailURL, img.WebPURL, img.ThumbnailWebPURL} {
+			_, err := os.Stat(filepath.Join(dir, strings.TrimPrefix(url, "/images/")))
+			assert.ErrorIs(t, err, os.ErrNotExist, url)
+		}
+	})
+
+	t.Run("画像でない", func(t *testing.T) {
+		_, err := u.Upload(ctx, strings.NewReader("<html>not an image</html>"))
+		assert.ErrorIs(t, err, ErrUnsupported)
+	})
+
+	t.Run("壊れた画像", func(t *testing.T) {
+		data := newJPEG(t, 10, 10, 1)
+		_, err := u.Upload(ctx, bytes.NewReader(data[:len(data)/2]))
+		assert.ErrorIs(t, err, ErrUnsupported)
+	})
+
+	t.Run("ファイルサイズが大きすぎる", func(t *testing.T) {
+		small := NewUploader(NewLocalStorage(dir, "/images"), 100)
+		_, err := small.Upload(ctx, bytes.NewReader(newJPEG(t, 100, 100, 1)))
+		assert.ErrorIs(t, err, ErrTooLarge)
+	})
+}
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	s := NewLocalStorage(t.TempDir(), "/images/")
+	assert.NoError(t, s.Put(ctx, "a/b.txt", strings.NewReader("hello"), "text/plain"))
+	assert.Equal(t, "/images/a/b.txt", s.URL("a/b.txt"))
+
+	t.Run("ディレクトリの外には保存しない", func(t *testing.T) {
+		for _, key := range []string{"../a", "/a", "a/../../b", ""} {
+			assert.ErrorIs(t, s.Put(ctx, key, strings.NewReader(""), ""), ErrInvalidKey, key)
+		}
+	})
+
+	t.Run("配信", func(t *testing.T) {
+		h := http.StripPrefix("/images/", s)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/images/a/b.txt", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "hello", w.Body.String())
+
+		// ディレクトリの一覧は返さない
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/images/a/", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("削除", func(t *testing.T) {
+		assert.NoError(t, s.Delete(ctx, "a/b.txt"))
+		// 存在しない場合もエラーにしない
+		assert.NoError(t, s.Delete(ctx, "a/b.txt"))
+	})
+}
@@ -0,0 +1,122 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidKey は保存先のキーが正しくない場合のエラー
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Storage は画像の保存先
+// ローカルのファイルシステムの他、S3互換のストレージなどを実装できる
+type Storage interface {
+	// Put はkeyにrの内容を保存する、同じkeyがある場合は上書きする
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Delete はkeyを削除する、keyがない場合もエラーにしない
+	Delete(ctx context.Context, key string) error
+	// URL はkeyを配信するURLを返す
+	URL(key string) string
+}
+
+// LocalStorage はローカルのディレクトリに保存するStorage
+// ServeHTTPで保存した画像を配信する
+type LocalStorage struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalStorage はdirに保存し、baseURL (例: /images/) で配信するLocalStorageを返す
+func NewLocalStorage(dir, baseURL string) *LocalStorage {
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	return &LocalStorage{
+		dir:     dir,
+		baseURL: baseURL,
+	}
+}
+
+// path はkeyを保存するファイルのパスを返す
+// ディレクトリの外を指すkeyは受け付けない
+func (s *LocalStorage) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") || path.Clean(key) != key || strings.HasPrefix(key, "..") {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	// 書き込み途中のファイルを配信しないよう、一時ファイルに書き込んでから置き換える
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return s.baseURL + key
+}
+
+// ServeHTTP は保存した画像を配信する
+// http.StripPrefixでbaseURLを取り除いてから渡す
+//
+//	GET /images/{key}
+func (s *LocalStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodHead)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	name, err := s.path(strings.TrimPrefix(r.URL.Path, "/"))
+	if err != nil || strings.HasPrefix(path.Base(r.URL.Path), ".") {
+		http.NotFound(w, r)
+		return
+	}
+	info, err := os.Stat(name)
+	// ディレクトリの一覧は返さない
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	// キーは画像の内容から決めるため、同じURLの内容は変わらない
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeFile(w, r, name)
+}
@@ -0,0 +1,603 @@
+package media
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"sort"
+)
+
+// WebPの可逆圧縮 (VP8L) のエンコーダ
+// 仕様は RFC 9649 を参照
+// 減色や色キャッシュは使わず、緑の減算と予測の変換、同じ色の連続のLZ77だけで圧縮する
+
+const (
+	// webpMaxSize はWebPで表現できる最大の幅と高さ
+	webpMaxSize = 1 << 14
+	// predictorBits は予測モードを切り替えるタイルの大きさ (2^bits px)
+	predictorBits = 4
+	// maxRunLength はLZ77で1回にコピーできる最大の長さ
+	maxRunLength = 4096
+	// minRunLength はLZ77を使う最小の長さ、これより短い場合はそのまま書き込む
+	minRunLength = 3
+	// leftDistanceCode は左隣の画素を表す距離のコード
+	leftDistanceCode = 2
+)
+
+const (
+	numLiteralCodes  = 256
+	numLengthCodes   = 24
+	numDistanceCodes = 40
+	numCodeLengths   = 19
+	// maxCodeLength はハフマン符号の最大の長さ
+	maxCodeLength = 15
+	// maxCodeLengthCodeLength は符号長を符号化するハフマン符号の最大の長さ
+	maxCodeLengthCodeLength = 7
+)
+
+// codeLengthCodeOrder は符号長の符号長を書き込む順番
+var codeLengthCodeOrder = [numCodeLengths]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+var errWebPTooLarge = errors.New("image is too large for webp")
+
+// EncodeWebP はimgを可逆圧縮のWebPとしてwに書き込む
+// メタデータは書き込まない
+func EncodeWebP(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width < 1 || height < 1 || width > webpMaxSize || height > webpMaxSize {
+		return errWebPTooLarge
+	}
+	nrgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(nrgba, nrgba.Bounds(), img, b.Min, draw.Src)
+	pix := nrgba.Pix
+	opaque := nrgba.Opaque()
+
+	bw := &bitWriter{}
+	// ヘッダー
+	bw.write(0x2f, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	if opaque {
+		bw.write(0, 1)
+	} else {
+		bw.write(1, 1)
+	}
+	bw.write(0, 3)
+
+	// 緑の減算: 赤と青から緑を引く
+	bw.write(1, 1)
+	bw.write(2, 2)
+	subtractGreen(pix)
+
+	// 予測: タイルごとに最も残差が小さくなる予測モードを使う
+	bw.write(1, 1)
+	bw.write(0, 2)
+	bw.write(predictorBits-2, 3)
+	modes, residuals := predict(pix, width, height)
+	tilesW, tilesH := tiles(width), tiles(height)
+	modeImage := make([]byte, 4*tilesW*tilesH)
+	for i, m := range modes {
+		modeImage[4*i+1] = m
+		modeImage[4*i+3] = 0xff
+	}
+	encodeImage(bw, modeImage, false)
+
+	// 変換の終わり
+	bw.write(0, 1)
+	encodeImage(bw, residuals, true)
+
+	data := bw.bytes()
+	return writeRIFF(w, data)
+}
+
+// writeRIFF はVP8LのデータをWebPのコンテナに入れて書き込む
+func writeRIFF(w io.Writer, data []byte) error {
+	padded := len(data) + len(data)&1
+	bw := bufio.NewWriter(w)
+	header := make([]byte, 20)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(4+8+padded))
+	copy(header[8:12], "WEBP")
+	copy(header[12:16], "VP8L")
+	binary.LittleEndian.PutUint32(header[16:20], uint32(len(data)))
+	if _, err := bw.Write(header); err != nil {
+		return err
+	}
+	if _, err := bw.Write(data); err != nil {
+		return err
+	}
+	if len(data)&1 == 1 {
+		if err := bw.WriteByte(0); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// tiles はsize pxを覆うタイルの数を返す
+func tiles(size int) int {
+	return (size + 1<<predictorBits - 1) >> predictorBits
+}
+
+// subtractGreen は赤と青から緑を引く
+func subtractGreen(pix []byte) {
+	for p := 0; p < len(pix); p += 4 {
+		pix[p+0] -= pix[p+1]
+		pix[p+2] -= pix[p+1]
+	}
+}
+
+// predict はタイルごとの予測モードと、予測との差 (残差) を返す
+// 1行目は左、1列目は上、左上の画素は黒から予測するのは仕様で決まっている
+func predict(pix []byte, width, height int) ([]byte, []byte) {
+	tilesW, tilesH := tiles(width), tiles(height)
+	modes := make([]byte, tilesW*tilesH)
+	residuals := make([]byte, len(pix))
+	stride := 4 * width
+	var pred [4]byte
+	for ty := 0; ty < tilesH; ty++ {
+		for tx := 0; tx < tilesW; tx++ {
+			x0, y0 := tx<<predictorBits, ty<<predictorBits
+			x1, y1 := minInt(x0+1<<predictorBits, width), minInt(y0+1<<predictorBits, height)
+			best, bestCost := byte(0), -1
+			for mode := byte(0); mode < 14; mode++ {
+				cost := 0
+				for y := y0; y < y1; y++ {
+					for x := x0; x < x1; x++ {
+						p := y*stride + 4*x
+						predictPixel(&pred, pix, p, stride, x, y, mode)
+						for c := 0; c < 4; c++ {
+							d := int(int8(pix[p+c] - pred[c]))
+							if d < 0 {
+								d = -d
+							}
+							cost += d
+						}
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+			modes[ty*tilesW+tx] = best
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					p := y*stride + 4*x
+					predictPixel(&pred, pix, p, stride, x, y, best)
+					for c := 0; c < 4; c++ {
+						residuals[p+c] = pix[p+c] - pred[c]
+					}
+				}
+			}
+		}
+	}
+	return modes, residuals
+}
+
+// predictPixel はpの画素をmodeで予測した値をpredに書き込む
+// 右上 (TR) は右端の場合、仕様どおり同じ行の左端の画素を参照する
+func predictPixel(pred *[4]byte, pix []byte, p, stride, x, y int, mode byte) {
+	switch {
+	case x == 0 && y == 0:
+		mode = 0
+	case y == 0:
+		mode = 1
+	case x == 0:
+		mode = 2
+	}
+	l, t := p-4, p-stride
+	tl, tr := t-4, t+4
+	for c := 0; c < 4; c++ {
+		var v byte
+		switch mode {
+		case 0:
+			if c == 3 {
+				v = 0xff
+			}
+		case 1:
+			v = pix[l+c]
+		case 2:
+			v = pix[t+c]
+		case 3:
+			v = pix[tr+c]
+		case 4:
+			v = pix[tl+c]
+		case 5:
+			v = avg2(avg2(pix[l+c], pix[tr+c]), pix[t+c])
+		case 6:
+			v = avg2(pix[l+c], pix[tl+c])
+		case 7:
+			v = avg2(pix[l+c], pix[t+c])
+		case 8:
+			v = avg2(pix[tl+c], pix[t+c])
+		case 9:
+			v = avg2(pix[t+c], pix[tr+c])
+		case 10:
+			v = avg2(avg2(pix[l+c], pix[tl+c]), avg2(pix[t+c], pix[tr+c]))
+		case 12:
+			v = clamp(int(pix[l+c]) + int(pix[t+c]) - int(pix[tl+c]))
+		case 13:
+			a := avg2(pix[l+c], pix[t+c])
+			v = clamp(int(a) + (int(a)-int(pix[tl+c]))/2)
+		}
+		pred[c] = v
+	}
+	// Selectは4つのチャンネルをまとめて比べる
+	if mode == 11 {
+		lCost, tCost := 0, 0
+		for c := 0; c < 4; c++ {
+			lCost += abs(int(pix[tl+c]) - int(pix[t+c]))
+			tCost += abs(int(pix[tl+c]) - int(pix[l+c]))
+		}
+		src := t
+		if lCost < tCost {
+			src = l
+		}
+		copy(pred[:], pix[src:src+4])
+	}
+}
+
+func avg2(a, b byte) byte {
+	return byte((int(a) + int(b)) / 2)
+}
+
+func clamp(v int) byte {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return byte(v)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// token は画素1つまたはLZ77のコピー1回分
+type token struct {
+	// lengthが0の場合はargbの画素、それ以外は左隣の画素をlength回コピーする
+	length int
+	pix    [4]byte
+}
+
+// encodeImage はハフマン符号で画素を書き込む
+// topLevelの場合はメインの画像として、メタ情報のハフマン符号を使わないことを書き込む
+func encodeImage(bw *bitWriter, pix []byte, topLevel bool) {
+	// 色キャッシュは使わない
+	bw.write(0, 1)
+	if topLevel {
+		bw.write(0, 1)
+	}
+
+	var tokens []token
+	for p := 0; p < len(pix); {
+		// 左隣と同じ画素が続く場合はコピーする
+		n := 0
+		if p > 0 {
+			for q := p; q < len(pix) && n < maxRunLength && equalPixel(pix, q, p-4); q += 4 {
+				n++
+			}
+		}
+		if n >= minRunLength {
+			tokens = append(tokens, token{length: n})
+			p += 4 * n
+			continue
+		}
+		var t token
+		copy(t.pix[:], pix[p:p+4])
+		tokens = append(tokens, t)
+		p += 4
+	}
+
+	green := make([]int, numLiteralCodes+numLengthCodes)
+	red := make([]int, numLiteralCodes)
+	blue := make([]int, numLiteralCodes)
+	alpha := make([]int, numLiteralCodes)
+	distance := make([]int, numDistanceCodes)
+	for _, t := range tokens {
+		if t.length > 0 {
+			code, _, _ := prefixEncode(t.length)
+			green[numLiteralCodes+code]++
+			dcode, _, _ := prefixEncode(leftDistanceCode)
+			distance[dcode]++
+			continue
+		}
+		red[t.pix[0]]++
+		green[t.pix[1]]++
+		blue[t.pix[2]]++
+		alpha[t.pix[3]]++
+	}
+	codes := [5]*huffmanCode{
+		newHuffmanCode(green, maxCodeLength),
+		newHuffmanCode(red, maxCodeLength),
+		newHuffmanCode(blue, maxCodeLength),
+		newHuffmanCode(alpha, maxCodeLength),
+		newHuffmanCode(distance, maxCodeLength),
+	}
+	for _, c := range codes {
+		c.writeLengths(bw)
+	}
+	for _, t := range tokens {
+		if t.length > 0 {
+			code, bits, extra := prefixEncode(t.length)
+			codes[0].writeSymbol(bw, numLiteralCodes+code)
+			bw.write(extra, bits)
+			dcode, dbits, dextra := prefixEncode(leftDistanceCode)
+			codes[4].writeSymbol(bw, dcode)
+			bw.write(dextra, dbits)
+			continue
+		}
+		codes[0].writeSymbol(bw, int(t.pix[1]))
+		codes[1].writeSymbol(bw, int(t.pix[0]))
+		codes[2].writeSymbol(bw, int(t.pix[2]))
+		codes[3].writeSymbol(bw, int(t.pix[3]))
+	}
+}
+
+func equalPixel(pix []byte, p, q int) bool {
+	return pix[p] == pix[q] && pix[p+1] == pix[q+1] && pix[p+2] == pix[q+2] && pix[p+3] == pix[q+3]
+}
+
+// prefixEncode はLZ77の長さと距離をプレフィックスのコードと追加のビットに分ける
+func prefixEncode(v int) (code int, bits uint, extra uint32) {
+	d := v - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+	hb := 0
+	for 1<<(hb+1) <= d {
+		hb++
+	}
+	second := (d >> (hb - 1)) & 1
+	bits = uint(hb - 1)
+	return 2*hb + second, bits, uint32(d & (1<<bits - 1))
+}
+
+// huffmanCode は正規ハフマン符号
+type huffmanCode struct {
+	lengths []uint8
+	codes   []uint32
+	// 使う記号が1つの場合は0ビットで表す
+	single bool
+}
+
+// newHuffmanCode は出現回数から最大maxBitsビットのハフマン符号を作る
+func newHuffmanCode(counts []int, maxBits int) *huffmanCode {
+	lengths := huffmanLengths(counts, maxBits)
+	used := 0
+	for _, l := range lengths {
+		if l > 0 {
+			used++
+		}
+	}
+	h := &huffmanCode{lengths: lengths, single: used == 1}
+	h.codes = canonicalCodes(lengths)
+	return h
+}
+
+// huffmanLengths は各記号の符号長を返す
+// 最大の長さを超える場合は少ない出現回数を底上げして作り直す
+func huffmanLengths(counts []int, maxBits int) []uint8 {
+	type node struct {
+		count       int
+		left, right int
+		symbol      int
+	}
+	lengths := make([]uint8, len(counts))
+	var leaves []int
+	for s, c := range counts {
+		if c > 0 {
+			leaves = append(leaves, s)
+		}
+	}
+	switch len(leaves) {
+	case 0:
+		// 使わない記号だけの場合も、デコーダーのために記号を1つ定義する
+		lengths[0] = 1
+		return lengths
+	case 1:
+		lengths[leaves[0]] = 1
+		return lengths
+	}
+
+	floor := 1
+	for {
+		nodes := make([]node, 0, 2*len(leaves))
+		for _, s := range leaves {
+			nodes = append(nodes, node{count: maxInt(counts[s], floor), left: -1, right: -1, symbol: s})
+		}
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].count < nodes[j].count })
+		// 葉と内部節点の2つのキューから小さい順に取り出す
+		leafQ, innerQ := 0, len(nodes)
+		take := func() int {
+			if leafQ < len(leaves) && (innerQ >= len(nodes) || nodes[leafQ].count <= nodes[innerQ].count) {
+				leafQ++
+				return leafQ - 1
+			}
+			innerQ++
+			return innerQ - 1
+		}
+		for i := 0; i < len(leaves)-1; i++ {
+			a, b := take(), take()
+			nodes = append(nodes, node{count: nodes[a].count + nodes[b].count, left: a, right: b, symbol: -1})
+		}
+		// 根から深さを数える
+		depth := make([]int, len(nodes))
+		tooLong := false
+		for i := len(nodes) - 1; i >= 0; i-- {
+			n := nodes[i]
+			if n.symbol >= 0 {
+				if depth[i] > maxBits {
+					tooLong = true
+				}
+				lengths[n.symbol] = uint8(depth[i])
+				continue
+			}
+			depth[n.left] = depth[i] + 1
+			depth[n.right] = depth[i] + 1
+		}
+		if !tooLong {
+			return lengths
+		}
+		floor *= 2
+	}
+}
+
+// canonicalCodes は符号長から正規ハフマン符号を作る
+func canonicalCodes(lengths []uint8) []uint32 {
+	var histogram [maxCodeLength + 1]uint32
+	for _, l := range lengths {
+		histogram[l]++
+	}
+	histogram[0] = 0
+	var next [maxCodeLength + 1]uint32
+	code := uint32(0)
+	for l := 1; l <= maxCodeLength; l++ {
+		code = (code + histogram[l-1]) << 1
+		next[l] = code
+	}
+	codes := make([]uint32, len(lengths))
+	for s, l := range lengths {
+		if l > 0 {
+			codes[s] = next[l]
+			next[l]++
+		}
+	}
+	return codes
+}
+
+// writeSymbol は記号を書き込む
+// 符号は上位ビットから読まれるため、ビットを反転して書き込む
+func (h *huffmanCode) writeSymbol(bw *bitWriter, symbol int) {
+	if h.single {
+		return
+	}
+	l := uint(h.lengths[symbol])
+	code := h.codes[symbol]
+	var rev uint32
+	for i := uint(0); i < l; i++ {
+		rev = rev<<1 | (code>>i)&1
+	}
+	bw.write(rev, l)
+}
+
+// writeLengths は符号長を、符号長のハフマン符号で圧縮して書き込む
+func (h *huffmanCode) writeLengths(bw *bitWriter) {
+	type lengthToken struct {
+		symbol int
+		extra  uint32
+		bits   uint
+	}
+	var tokens []lengthToken
+	for i := 0; i < len(h.lengths); {
+		l := h.lengths[i]
+		run := 1
+		for i+run < len(h.lengths) && h.lengths[i+run] == l {
+			run++
+		}
+		i += run
+		if l == 0 {
+			for run > 0 {
+				switch {
+				case run >= 11:
+					n := minInt(run, 138)
+					tokens = append(tokens, lengthToken{18, uint32(n - 11), 7})
+					run -= n
+				case run >= 3:
+					tokens = append(tokens, lengthToken{17, uint32(run - 3), 3})
+					run = 0
+				default:
+					tokens = append(tokens, lengthToken{symbol: 0})
+					run--
+				}
+			}
+			continue
+		}
+		// 最初の1つを書き込み、残りは直前の長さの繰り返しとする
+		tokens = append(tokens, lengthToken{symbol: int(l)})
+		run--
+		for run > 0 {
+			if run >= 3 {
+				n := minInt(run, 6)
+				tokens = append(tokens, lengthToken{16, uint32(n - 3), 2})
+				run -= n
+				continue
+			}
+			tokens = append(tokens, lengthToken{symbol: int(l)})
+			run--
+		}
+	}
+
+	counts := make([]int, numCodeLengths)
+	for _, t := range tokens {
+		counts[t.symbol]++
+	}
+	lengthCode := newHuffmanCode(counts, maxCodeLengthCodeLength)
+	n := 4
+	for i := numCodeLengths - 1; i >= 4; i-- {
+		if lengthCode.lengths[codeLengthCodeOrder[i]] > 0 {
+			n = i + 1
+			break
+		}
+	}
+	// 通常の符号 (2つ以下の記号の簡易な符号は使わない)
+	bw.write(0, 1)
+	bw.write(uint32(n-4), 4)
+	for i := 0; i < n; i++ {
+		bw.write(uint32(lengthCode.lengths[codeLengthCodeOrder[i]]), 3)
+	}
+	// 全ての記号の符号長を書き込む
+	bw.write(0, 1)
+	for _, t := range tokens {
+		lengthCode.writeSymbol(bw, t.symbol)
+		bw.write(t.extra, t.bits)
+	}
+}
+
+// bitWriter は下位ビットから順にビット列を書き込む
+type bitWriter struct {
+	buf   []byte
+	bits  uint64
+	nBits uint
+}
+
+func (b *bitWriter) write(v uint32, n uint) {
+	b.bits |= uint64(v) << b.nBits
+	b.nBits += n
+	for b.nBits >= 8 {
+		b.buf = append(b.buf, byte(b.bits))
+		b.bits >>= 8
+		b.nBits -= 8
+	}
+}
+
+func (b *bitWriter) bytes() []byte {
+	if b.nBits > 0 {
+		b.buf = append(b.buf, byte(b.bits))
+		b.bits, b.nBits = 0, 0
+	}
+	return b.buf
+}
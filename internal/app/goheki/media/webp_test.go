@@ -0,0 +1,48 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeWebP(t *testing.T) {
+	patterns := map[string]func(r *rand.Rand, x, y int) color.NRGBA{
+		"ノイズ": func(r *rand.Rand, x, y int) color.NRGBA {
+			return color.NRGBA{uint8(r.Intn(256)), uint8(r.Intn(256)), uint8(r.Intn(256)), uint8(r.Intn(256))}
+		},
+		"グラデーション": func(r *rand.Rand, x, y int) color.NRGBA {
+			return color.NRGBA{uint8(x * 3), uint8(y * 2), uint8(x + y), 0xff}
+		},
+		"同じ色が続く": func(r *rand.Rand, x, y int) color.NRGBA {
+			return color.NRGBA{uint8(x / 20 * 40), 10, 200, 0xff}
+		},
+	}
+	for name, pattern := range patterns {
+		for _, size := range []image.Point{{1, 1}, {2, 3}, {17, 5}, {300, 200}} {
+			t.Run(fmt.Sprintf("%s %dx%d", name, size.X, size.Y), func(t *testing.T) {
+				r := rand.New(rand.NewSource(1))
+				img := image.NewNRGBA(image.Rect(0, 0, size.X, size.Y))
+				for y := 0; y < size.Y; y++ {
+					for x := 0; x < size.X; x++ {
+						img.SetNRGBA(x, y, pattern(r, x, y))
+					}
+				}
+				var buf bytes.Buffer
+				assert.NoError(t, EncodeWebP(&buf, img))
+
+				// 可逆圧縮のため元の画素と一致する
+				decoded, err := webp.Decode(&buf)
+				assert.NoError(t, err)
+				assert.Equal(t, img.Pix, decoded.(*image.NRGBA).Pix)
+			})
+		}
+	}
+}
@@ -2,8 +2,11 @@ package middleware
 
 import (
 	"crypto/subtle"
+	"errors"
 	"net/http"
 	"os"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
 )
 
 func checkAuth(r *http.Request) bool {
@@ -16,7 +19,7 @@ func BasicAuth(h http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		if !checkAuth(r) {
 			w.Header().Add("WWW-Authenticate", `Basic realm="my private area"`)
-			http.Error(w, "Not authorized", http.StatusUnauthorized)
+			problem.Unauthorized(w, r, errors.New("not authorized"))
 			return
 		}
 		h.ServeHTTP(w, r)
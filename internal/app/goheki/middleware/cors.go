@@ -15,9 +15,10 @@ func CORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// クロスオリジン用にセット
 		w.Header().Set("Access-Control-Allow-Origin", env.FrontUrl)
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, "+RequestIDHeader)
+		w.Header().Set("Access-Control-Expose-Headers", RequestIDHeader)
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
-		w.Header().Set("Access-Control-Allow-Methods", "GET,PUT,POST,DELETE,UPDATE,OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
 		w.Header().Set("Content-Type", "application/json")
 
 		// preflight用に200でいったん返す
@@ -0,0 +1,41 @@
+/*
+0001_init.up.sqlで作成したテーブルを削除する
+
+外部キーで参照されるテーブルは参照するテーブルの後に削除する
+旧形式から移行したheki_radar_chart_backupは削除しない
+entryを削除できるよう、heki_radar_chart_backupの外部キーの制約だけを削除する
+*/
+DO $$
+DECLARE
+    fk record;
+BEGIN
+    IF to_regclass('heki_radar_chart_backup') IS NULL THEN
+        RETURN;
+    END IF;
+    FOR fk IN
+        SELECT conname FROM pg_constraint
+        WHERE conrelid = 'heki_radar_chart_backup'::regclass AND contype = 'f'
+    LOOP
+        EXECUTE format('ALTER TABLE heki_radar_chart_backup DROP CONSTRAINT %I', fk.conname);
+    END LOOP;
+END
+$$;
+DROP TABLE IF EXISTS entry_image;
+DROP TABLE IF EXISTS eyecolor;
+DROP TABLE IF EXISTS eyecolor_type;
+DROP TABLE IF EXISTS link;
+DROP TABLE IF EXISTS personality;
+DROP TABLE IF EXISTS personality_type;
+DROP TABLE IF EXISTS hairstyle;
+DROP TABLE IF EXISTS hairstyle_type;
+DROP TABLE IF EXISTS haircolor;
+DROP TABLE IF EXISTS haircolor_type;
+DROP TABLE IF EXISTS hairlength;
+DROP TABLE IF EXISTS hairlength_type;
+DROP TABLE IF EXISTS bwh;
+DROP TABLE IF EXISTS heki_radar_score;
+DROP TABLE IF EXISTS heki_radar_axis;
+DROP TABLE IF EXISTS entry_tag;
+DROP TABLE IF EXISTS tag;
+DROP TABLE IF EXISTS entry;
+DROP TABLE IF EXISTS source;
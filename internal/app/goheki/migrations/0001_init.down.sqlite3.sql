@@ -0,0 +1,25 @@
+/*
+0001_init.up.sqlで作成したテーブルを削除する
+
+外部キーで参照されるテーブルは参照するテーブルの後に削除する
+SQLiteでは旧形式から移行しないため、heki_radar_chart_backupはない
+*/
+DROP TABLE IF EXISTS entry_image;
+DROP TABLE IF EXISTS eyecolor;
+DROP TABLE IF EXISTS eyecolor_type;
+DROP TABLE IF EXISTS link;
+DROP TABLE IF EXISTS personality;
+DROP TABLE IF EXISTS personality_type;
+DROP TABLE IF EXISTS hairstyle;
+DROP TABLE IF EXISTS hairstyle_type;
+DROP TABLE IF EXISTS haircolor;
+DROP TABLE IF EXISTS haircolor_type;
+DROP TABLE IF EXISTS hairlength;
+DROP TABLE IF EXISTS hairlength_type;
+DROP TABLE IF EXISTS bwh;
+DROP TABLE IF EXISTS heki_radar_score;
+DROP TABLE IF EXISTS heki_radar_axis;
+DROP TABLE IF EXISTS entry_tag;
+DROP TABLE IF EXISTS tag;
+DROP TABLE IF EXISTS entry;
+DROP TABLE IF EXISTS source;
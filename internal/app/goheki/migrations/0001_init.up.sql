@@ -0,0 +1,217 @@
+/*
+出典
+
+type 2次元 3次元
+*/
+CREATE TABLE IF NOT EXISTS source (
+    id SERIAL NOT NULL PRIMARY KEY,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL,
+    type TEXT NOT NULL
+);
+/*
+人物の内容
+*/
+CREATE TABLE IF NOT EXISTS entry (
+    id SERIAL NOT NULL PRIMARY KEY,
+    source_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    image TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (source_id) REFERENCES source (id)
+);
+/*
+タグの内容
+*/
+CREATE TABLE IF NOT EXISTS tag (
+    id SERIAL NOT NULL PRIMARY KEY,
+    name TEXT NOT NULL
+);
+/*
+タグを付ける
+*/
+CREATE TABLE IF NOT EXISTS entry_tag (
+    id SERIAL NOT NULL PRIMARY KEY,
+    entry_id INTEGER NOT NULL,
+    tag_id INTEGER NOT NULL,
+    FOREIGN KEY (entry_id) REFERENCES entry (id),
+    FOREIGN KEY (tag_id) REFERENCES tag (id)
+);
+/*
+レーダーチャートの軸
+
+min以上max以下の値を付けられる
+display_orderの昇順に表示する
+*/
+CREATE TABLE IF NOT EXISTS heki_radar_axis (
+    id SERIAL NOT NULL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    min INTEGER NOT NULL DEFAULT 0,
+    max INTEGER NOT NULL DEFAULT 100,
+    display_order INTEGER NOT NULL DEFAULT 0,
+    CHECK (min < max)
+);
+/*
+軸ごとの値
+*/
+CREATE TABLE IF NOT EXISTS heki_radar_score (
+    entry_id INTEGER NOT NULL,
+    axis_id INTEGER NOT NULL,
+    score INTEGER NOT NULL,
+    PRIMARY KEY (entry_id, axis_id),
+    FOREIGN KEY (entry_id) REFERENCES entry (id),
+    FOREIGN KEY (axis_id) REFERENCES heki_radar_axis (id) ON DELETE CASCADE
+);
+/*
+旧形式の好感(ai, nu)を軸と値に移行する
+
+heki_radar_chartが残っている場合のみ実行し、移行後はheki_radar_chart_backupに名前を変える
+既存の値が範囲外にならないよう、軸の範囲は既存の値を含むように広げる
+*/
+DO $$
+BEGIN
+    IF to_regclass('heki_radar_chart') IS NULL THEN
+        RETURN;
+    END IF;
+    INSERT INTO heki_radar_axis (name, min, max, display_order)
+    SELECT 'ai', LEAST(0, COALESCE(MIN(ai), 0)), GREATEST(100, COALESCE(MAX(ai), 0)), 1 FROM heki_radar_chart
+    ON CONFLICT (name) DO NOTHING;
+    INSERT INTO heki_radar_axis (name, min, max, display_order)
+    SELECT 'nu', LEAST(0, COALESCE(MIN(nu), 0)), GREATEST(100, COALESCE(MAX(nu), 0)), 2 FROM heki_radar_chart
+    ON CONFLICT (name) DO NOTHING;
+    INSERT INTO heki_radar_score (entry_id, axis_id, score)
+    SELECT h.entry_id, a.id, h.ai FROM heki_radar_chart h JOIN heki_radar_axis a ON a.name = 'ai'
+    WHERE h.ai IS NOT NULL
+    ON CONFLICT DO NOTHING;
+    INSERT INTO heki_radar_score (entry_id, axis_id, score)
+    SELECT h.entry_id, a.id, h.nu FROM heki_radar_chart h JOIN heki_radar_axis a ON a.name = 'nu'
+    WHERE h.nu IS NOT NULL
+    ON CONFLICT DO NOTHING;
+    ALTER TABLE heki_radar_chart RENAME TO heki_radar_chart_backup;
+END
+$$;
+/*スリーサイズ 身長体重含む*/
+CREATE TABLE IF NOT EXISTS bwh (
+    entry_id INTEGER PRIMARY KEY,
+    bust INTEGER,
+    waist INTEGER,
+    hip INTEGER,
+    underbust INTEGER,
+    height INTEGER,
+    weight INTEGER,
+    FOREIGN KEY (entry_id) REFERENCES entry (id)
+);
+/*アンダーバストを追加する前に作成したテーブルに列を追加*/
+ALTER TABLE bwh ADD COLUMN IF NOT EXISTS underbust INTEGER;
+/*髪の長さの種類*/
+CREATE TABLE IF NOT EXISTS hairlength_type (
+    id SERIAL PRIMARY KEY,
+    length TEXT NOT NULL
+);
+/*髪の長さ*/
+CREATE TABLE IF NOT EXISTS hairlength (
+    entry_id INTEGER PRIMARY KEY,
+    hairlength_type_id INTEGER NOT NULL,
+    FOREIGN KEY (entry_id) REFERENCES entry (id),
+    FOREIGN KEY (hairlength_type_id) REFERENCES hairlength_type (id)
+);
+/*髪の色の種類*/
+CREATE TABLE IF NOT EXISTS haircolor_type (
+    id SERIAL PRIMARY KEY,
+    color TEXT NOT NULL
+);
+/*髪色*/
+CREATE TABLE IF NOT EXISTS haircolor (
+    entry_id INTEGER PRIMARY KEY,
+    color_id INTEGER NOT NULL,
+    FOREIGN KEY (entry_id) REFERENCES entry (id),
+    FOREIGN KEY (color_id) REFERENCES haircolor_type (id)
+);
+/*髪型の種類*/
+CREATE TABLE IF NOT EXISTS hairstyle_type (
+    id SERIAL PRIMARY KEY,
+    style TEXT NOT NULL
+);
+/*髪型*/
+CREATE TABLE IF NOT EXISTS hairstyle (
+    entry_id INTEGER PRIMARY KEY,
+    style_id INTEGER NOT NULL,
+    FOREIGN KEY (entry_id) REFERENCES entry (id),
+    FOREIGN KEY (style_id) REFERENCES hairstyle_type (id)
+);
+/*性格の種類*/
+CREATE TABLE IF NOT EXISTS personality_type (
+    id SERIAL PRIMARY KEY,
+    type TEXT NOT NULL
+);
+/*性格*/
+CREATE TABLE IF NOT EXISTS personality (
+    entry_id INTEGER PRIMARY KEY,
+    type_id INTEGER NOT NULL,
+    FOREIGN KEY (entry_id) REFERENCES entry (id),
+    FOREIGN KEY (type_id) REFERENCES personality_type (id)
+);
+/*urlリンク*/
+CREATE TABLE IF NOT EXISTS link (
+    id SERIAL NOT NULL PRIMARY KEY,
+    entry_id INTEGER NOT NULL,
+    type TEXT NOT NULL,
+    url TEXT NOT NULL,
+    nsfw BOOLEAN NOT NULL DEFAULT FALSE,
+    darkness BOOLEAN NOT NULL DEFAULT FALSE,
+    title TEXT,
+    description TEXT,
+    thumbnail TEXT,
+    site_name TEXT,
+    unfurled_url TEXT,
+    unfurled_at TIMESTAMP,
+    unfurl_error TEXT,
+    status_code INTEGER,
+    checked_at TIMESTAMP,
+    check_error TEXT,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (entry_id) REFERENCES entry (id)
+);
+/*リンク先の情報を追加する前に作成したテーブルに列を追加*/
+ALTER TABLE link ADD COLUMN IF NOT EXISTS title TEXT;
+ALTER TABLE link ADD COLUMN IF NOT EXISTS description TEXT;
+ALTER TABLE link ADD COLUMN IF NOT EXISTS thumbnail TEXT;
+ALTER TABLE link ADD COLUMN IF NOT EXISTS site_name TEXT;
+ALTER TABLE link ADD COLUMN IF NOT EXISTS unfurled_url TEXT;
+ALTER TABLE link ADD COLUMN IF NOT EXISTS unfurled_at TIMESTAMP;
+ALTER TABLE link ADD COLUMN IF NOT EXISTS unfurl_error TEXT;
+/*リンク切れの確認を追加する前に作成したテーブルに列を追加*/
+ALTER TABLE link ADD COLUMN IF NOT EXISTS status_code INTEGER;
+ALTER TABLE link ADD COLUMN IF NOT EXISTS checked_at TIMESTAMP;
+ALTER TABLE link ADD COLUMN IF NOT EXISTS check_error TEXT;
+ALTER TABLE link ADD COLUMN IF NOT EXISTS consecutive_failures INTEGER NOT NULL DEFAULT 0;
+CREATE TABLE IF NOT EXISTS eyecolor_type (
+    id SERIAL NOT NULL PRIMARY KEY,
+    color TEXT NOT NULL
+);
+/*目の色*/
+CREATE TABLE IF NOT EXISTS eyecolor (
+    entry_id INTEGER PRIMARY KEY,
+    color_id INTEGER NOT NULL,
+    FOREIGN KEY (entry_id) REFERENCES entry (id),
+    FOREIGN KEY (color_id) REFERENCES eyecolor_type (id)
+);
+/*entryの画像 (公式のイラスト、衣装違い、ファンアートなど)*/
+/*is_primaryの画像のurlをentry.imageにも保存する*/
+CREATE TABLE IF NOT EXISTS entry_image (
+    id SERIAL NOT NULL PRIMARY KEY,
+    entry_id INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    thumbnail_url TEXT,
+    webp_url TEXT,
+    thumbnail_webp_url TEXT,
+    width INTEGER,
+    height INTEGER,
+    caption TEXT NOT NULL DEFAULT '',
+    position INTEGER NOT NULL DEFAULT 0,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    nsfw BOOLEAN NOT NULL DEFAULT FALSE,
+    darkness BOOLEAN NOT NULL DEFAULT FALSE,
+    FOREIGN KEY (entry_id) REFERENCES entry (id)
+);
\ No newline at end of file
@@ -1,10 +1,16 @@
 /*
+SQLite用の0001_init.up.sql
+
+SERIALの代わりにINTEGER PRIMARY KEY AUTOINCREMENTを使う
+SQLiteのDBはマイグレーションを導入した後に作成するため、旧形式からの移行と列の追加は行わない
+*/
+/*
 出典
 
 type 2次元 3次元
 */
 CREATE TABLE IF NOT EXISTS source (
-    id SERIAL NOT NULL PRIMARY KEY,
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
     name TEXT NOT NULL,
     url TEXT NOT NULL,
     type TEXT NOT NULL
@@ -13,7 +19,7 @@ CREATE TABLE IF NOT EXISTS source (
 人物の内容
 */
 CREATE TABLE IF NOT EXISTS entry (
-    id SERIAL NOT NULL PRIMARY KEY,
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
     source_id INTEGER NOT NULL,
     name TEXT NOT NULL,
     image TEXT NOT NULL,
@@ -25,27 +31,43 @@ CREATE TABLE IF NOT EXISTS entry (
 タグの内容
 */
 CREATE TABLE IF NOT EXISTS tag (
-    id SERIAL NOT NULL PRIMARY KEY,
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
     name TEXT NOT NULL
 );
 /*
 タグを付ける
 */
 CREATE TABLE IF NOT EXISTS entry_tag (
-    id SERIAL NOT NULL PRIMARY KEY,
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
     entry_id INTEGER NOT NULL,
     tag_id INTEGER NOT NULL,
     FOREIGN KEY (entry_id) REFERENCES entry (id),
     FOREIGN KEY (tag_id) REFERENCES tag (id)
 );
 /*
-好感
+レーダーチャートの軸
+
+min以上max以下の値を付けられる
+display_orderの昇順に表示する
 */
-CREATE TABLE IF NOT EXISTS heki_radar_chart (
-    entry_id INTEGER PRIMARY KEY,
-    ai INTEGER DEFAULT 0,
-    nu INTEGER DEFAULT 0,
-    FOREIGN KEY (entry_id) REFERENCES entry (id)
+CREATE TABLE IF NOT EXISTS heki_radar_axis (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    min INTEGER NOT NULL DEFAULT 0,
+    max INTEGER NOT NULL DEFAULT 100,
+    display_order INTEGER NOT NULL DEFAULT 0,
+    CHECK (min < max)
+);
+/*
+軸ごとの値
+*/
+CREATE TABLE IF NOT EXISTS heki_radar_score (
+    entry_id INTEGER NOT NULL,
+    axis_id INTEGER NOT NULL,
+    score INTEGER NOT NULL,
+    PRIMARY KEY (entry_id, axis_id),
+    FOREIGN KEY (entry_id) REFERENCES entry (id),
+    FOREIGN KEY (axis_id) REFERENCES heki_radar_axis (id) ON DELETE CASCADE
 );
 /*スリーサイズ 身長体重含む*/
 CREATE TABLE IF NOT EXISTS bwh (
@@ -53,13 +75,14 @@ CREATE TABLE IF NOT EXISTS bwh (
     bust INTEGER,
     waist INTEGER,
     hip INTEGER,
+    underbust INTEGER,
     height INTEGER,
     weight INTEGER,
     FOREIGN KEY (entry_id) REFERENCES entry (id)
 );
 /*髪の長さの種類*/
 CREATE TABLE IF NOT EXISTS hairlength_type (
-    id SERIAL PRIMARY KEY,
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
     length TEXT NOT NULL
 );
 /*髪の長さ*/
@@ -71,7 +94,7 @@ CREATE TABLE IF NOT EXISTS hairlength (
 );
 /*髪の色の種類*/
 CREATE TABLE IF NOT EXISTS haircolor_type (
-    id SERIAL PRIMARY KEY,
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
     color TEXT NOT NULL
 );
 /*髪色*/
@@ -83,7 +106,7 @@ CREATE TABLE IF NOT EXISTS haircolor (
 );
 /*髪型の種類*/
 CREATE TABLE IF NOT EXISTS hairstyle_type (
-    id SERIAL PRIMARY KEY,
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
     style TEXT NOT NULL
 );
 /*髪型*/
@@ -95,7 +118,7 @@ CREATE TABLE IF NOT EXISTS hairstyle (
 );
 /*性格の種類*/
 CREATE TABLE IF NOT EXISTS personality_type (
-    id SERIAL PRIMARY KEY,
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
     type TEXT NOT NULL
 );
 /*性格*/
@@ -107,16 +130,27 @@ CREATE TABLE IF NOT EXISTS personality (
 );
 /*urlリンク*/
 CREATE TABLE IF NOT EXISTS link (
-    id SERIAL NOT NULL PRIMARY KEY,
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
     entry_id INTEGER NOT NULL,
     type TEXT NOT NULL,
     url TEXT NOT NULL,
     nsfw BOOLEAN NOT NULL DEFAULT FALSE,
     darkness BOOLEAN NOT NULL DEFAULT FALSE,
+    title TEXT,
+    description TEXT,
+    thumbnail TEXT,
+    site_name TEXT,
+    unfurled_url TEXT,
+    unfurled_at TIMESTAMP,
+    unfurl_error TEXT,
+    status_code INTEGER,
+    checked_at TIMESTAMP,
+    check_error TEXT,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
     FOREIGN KEY (entry_id) REFERENCES entry (id)
 );
 CREATE TABLE IF NOT EXISTS eyecolor_type (
-    id SERIAL NOT NULL PRIMARY KEY,
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
     color TEXT NOT NULL
 );
 /*目の色*/
@@ -125,4 +159,22 @@ CREATE TABLE IF NOT EXISTS eyecolor (
     color_id INTEGER NOT NULL,
     FOREIGN KEY (entry_id) REFERENCES entry (id),
     FOREIGN KEY (color_id) REFERENCES eyecolor_type (id)
+);
+/*entryの画像 (公式のイラスト、衣装違い、ファンアートなど)*/
+/*is_primaryの画像のurlをentry.imageにも保存する*/
+CREATE TABLE IF NOT EXISTS entry_image (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    entry_id INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    thumbnail_url TEXT,
+    webp_url TEXT,
+    thumbnail_webp_url TEXT,
+    width INTEGER,
+    height INTEGER,
+    caption TEXT NOT NULL DEFAULT '',
+    position INTEGER NOT NULL DEFAULT 0,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    nsfw BOOLEAN NOT NULL DEFAULT FALSE,
+    darkness BOOLEAN NOT NULL DEFAULT FALSE,
+    FOREIGN KEY (entry_id) REFERENCES entry (id)
 );
\ No newline at end of file
@@ -0,0 +1,15 @@
+// Package migrations はDBのスキーマのマイグレーションを埋め込む
+//
+// ファイル名は{連番}_{名前}.up.sqlと{連番}_{名前}.down.sqlとし、連番の昇順に適用する
+// 適用済みのファイルは変更せず、スキーマを変更する場合は新しい連番のファイルを追加する
+// PostgresとSQLiteでSQLが異なる場合は{連番}_{名前}.up.sqlite3.sqlのようにDBの種類ごとのファイルを追加する
+//
+// 0001_initはマイグレーションを導入する前のschema.sqlで、既存のテーブルがあっても適用できる
+package migrations
+
+import "embed"
+
+// FS はマイグレーションのSQLファイル
+//
+//go:embed *.sql
+var FS embed.FS
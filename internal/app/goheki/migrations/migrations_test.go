@@ -0,0 +1,93 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/pkg/db"
+	"github.com/maguro-alternative/goheki/pkg/migrate"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFS(t *testing.T) {
+	for _, dialect := range []db.Dialect{db.Postgres, db.SQLite} {
+		migrations, err := migrate.Load(FS, dialect)
+		require.NoError(t, err)
+		require.NotEmpty(t, migrations)
+		for i, m := range migrations {
+			// 連番は1から欠番なく付ける
+			assert.Equal(t, int64(i+1), m.Version, "%s: %s", dialect, m.Name)
+			assert.NotEmpty(t, m.Down, "%s: %d_%s has no down.sql", dialect, m.Version, m.Name)
+		}
+	}
+}
+
+func TestSQLite(t *testing.T) {
+	ctx := context.Background()
+	dbV1, cleanup, err := db.NewDBV1(ctx, "sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer cleanup()
+	migrator, err := migrate.New(dbV1, FS)
+	require.NoError(t, err)
+
+	// 全て適用し、全て取り消した後にもう一度適用できる
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	reverted, err := migrator.Down(ctx, len(applied))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(applied))
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	var id int64
+	err = dbV1.GetContext(ctx, &id, "INSERT INTO source (name, url, type) VALUES ('a', 'https://example.com', 'anime') RETURNING id")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+}
+
+func TestPostgres(t *testing.T) {
+	ctx := context.Background()
+	env, err := envconfig.NewEnv()
+	require.NoError(t, err)
+	dialect, err := db.ParseDialect(env.DatabaseType)
+	require.NoError(t, err)
+	if dialect != db.Postgres {
+		t.Skip("DATABASE_TYPEがpostgresの場合のみ実行する")
+	}
+	dbV1, cleanup, err := db.NewDBV1(ctx, env.DatabaseType, env.DatabaseURL)
+	require.NoError(t, err)
+	defer cleanup()
+	// 既存のテーブルに影響しないよう、ロールバックするスキーマの中で適用する
+	tx, err := dbV1.BeginTxx(ctx, nil)
+	require.NoError(t, err)
+	defer tx.RollbackCtx(ctx)
+	_, err = tx.ExecContext(ctx, "CREATE SCHEMA goheki_migrations_test; SET LOCAL search_path TO goheki_migrations_test")
+	require.NoError(t, err)
+	migrator, err := migrate.New(tx, FS)
+	require.NoError(t, err)
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	// 旧形式から移行した後のheki_radar_chart_backupはentryを参照している
+	_, err = tx.ExecContext(ctx, `CREATE TABLE heki_radar_chart_backup (
+		entry_id INTEGER PRIMARY KEY REFERENCES entry (id),
+		ai INTEGER,
+		nu INTEGER
+	)`)
+	require.NoError(t, err)
+
+	// 全て取り消した後にもう一度適用でき、heki_radar_chart_backupは残る
+	reverted, err := migrator.Down(ctx, len(applied))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(applied))
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	var backup *string
+	err = tx.GetContext(ctx, &backup, "SELECT to_regclass('heki_radar_chart_backup')::text")
+	require.NoError(t, err)
+	assert.NotNil(t, backup)
+}
@@ -6,21 +6,23 @@ import (
 )
 
 type BWH struct {
-	EntryID int64  `db:"entry_id"`
-	Bust    int64  `db:"bust"`
-	Waist   int64  `db:"waist"`
-	Hip     int64  `db:"hip"`
-	Height  *int64 `db:"height"`
-	Weight  *int64 `db:"weight"`
+	EntryID   int64  `db:"entry_id"`
+	Bust      int64  `db:"bust"`
+	Waist     int64  `db:"waist"`
+	Hip       int64  `db:"hip"`
+	Underbust *int64 `db:"underbust"`
+	Height    *int64 `db:"height"`
+	Weight    *int64 `db:"weight"`
 }
 
 func NewBWH(ctx context.Context, setter ...func(b *BWH)) *ModelConnector {
 	bwh := &BWH{
-		Bust:   1,
-		Waist:  1,
-		Hip:    1,
-		Height: nil,
-		Weight: nil,
+		Bust:      1,
+		Waist:     1,
+		Hip:       1,
+		Underbust: nil,
+		Height:    nil,
+		Weight:    nil,
 	}
 
 	//setter(bwh)
@@ -45,7 +47,7 @@ func NewBWH(ctx context.Context, setter ...func(b *BWH)) *ModelConnector {
 			}
 		},
 		insertTable: func(t *testing.T, f *Fixture) {
-			_, err := f.DBv1.NamedExecContext(ctx, "INSERT INTO bwh (entry_id, bust, waist, hip, height, weight) VALUES (:entry_id, :bust, :waist, :hip, :height, :weight)", bwh)
+			_, err := f.DBv1.NamedExecContext(ctx, "INSERT INTO bwh (entry_id, bust, waist, hip, underbust, height, weight) VALUES (:entry_id, :bust, :waist, :hip, :underbust, :height, :weight)", bwh)
 			if err != nil {
 				t.Fatalf("insert error: %v", err)
 			}
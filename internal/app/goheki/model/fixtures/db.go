@@ -0,0 +1,31 @@
+package fixtures
+
+import (
+	"context"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/migrations"
+	"github.com/maguro-alternative/goheki/pkg/db"
+	"github.com/maguro-alternative/goheki/pkg/migrate"
+)
+
+// NewDB はテストで使うDBに接続し、適用していないマイグレーションを適用する
+// DATABASE_TYPE=sqlite3、DATABASE_URL=:memory:の場合はPostgresを使わずに空のDBを作成する
+func NewDB(ctx context.Context, env *envconfig.Env) (*db.DB, func(), error) {
+	indexDB, cleanup, err := db.NewDBV1(ctx, env.DatabaseType, env.DatabaseURL)
+	if err != nil {
+		if cleanup == nil {
+			cleanup = func() {}
+		}
+		return nil, cleanup, err
+	}
+	migrator, err := migrate.New(indexDB, migrations.FS)
+	if err == nil {
+		_, err = migrator.Up(ctx)
+	}
+	if err != nil {
+		cleanup()
+		return nil, func() {}, err
+	}
+	return indexDB, cleanup, nil
+}
@@ -52,6 +52,9 @@ func NewEntry(ctx context.Context, setter ...func(e *Entry)) *ModelConnector {
 			case *Link:
 				link := connectingModel.(*Link)
 				link.EntryID = entry.ID
+			case *EntryImage:
+				image := connectingModel.(*EntryImage)
+				image.EntryID = entry.ID
 			case *HairColor:
 				hairColor := connectingModel.(*HairColor)
 				hairColor.EntryID = entry.ID
@@ -61,9 +64,9 @@ func NewEntry(ctx context.Context, setter ...func(e *Entry)) *ModelConnector {
 			case *HairStyle:
 				hairStyle := connectingModel.(*HairStyle)
 				hairStyle.EntryID = entry.ID
-			case *HekiRadarChart:
-				hekiRadarChart := connectingModel.(*HekiRadarChart)
-				hekiRadarChart.EntryID = entry.ID
+			case *HekiRadarScore:
+				hekiRadarScore := connectingModel.(*HekiRadarScore)
+				hekiRadarScore.EntryID = entry.ID
 			case *EyeColor:
 				eyeColor := connectingModel.(*EyeColor)
 				eyeColor.EntryID = entry.ID
@@ -74,19 +77,19 @@ func NewEntry(ctx context.Context, setter ...func(e *Entry)) *ModelConnector {
 		insertTable: func(t *testing.T, f *Fixture) {
 			result := f.DBv1.QueryRowxContext(
 				ctx,
-				`INSERT INTO entry (
+				f.DBv1.Rebind(`INSERT INTO entry (
 					source_id,
 					name,
 					image,
 					content,
 					created_at
 				) VALUES (
-					$1,
-					$2,
-					$3,
-					$4,
-					$5
-				) RETURNING id`,
+					?,
+					?,
+					?,
+					?,
+					?
+				) RETURNING id`),
 				entry.SourceID,
 				entry.Name,
 				entry.Image,
@@ -0,0 +1,86 @@
+package fixtures
+
+import (
+	"context"
+	"testing"
+)
+
+type EntryImage struct {
+	ID           int64   `db:"id"`
+	EntryID      int64   `db:"entry_id"`
+	URL          string  `db:"url"`
+	ThumbnailURL *string `db:"thumbnail_url"`
+	Caption      string  `db:"caption"`
+	Position     int64   `db:"position"`
+	Primary      bool    `db:"is_primary"`
+	Nsfw         bool    `db:"nsfw"`
+	Darkness     bool    `db:"darkness"`
+}
+
+func NewEntryImage(ctx context.Context, setter ...func(i *EntryImage)) *ModelConnector {
+	image := &EntryImage{
+		URL:      "https://example.com/image.png",
+		Caption:  "",
+		Position: 0,
+		Primary:  false,
+		Nsfw:     false,
+		Darkness: false,
+	}
+
+	return &ModelConnector{
+		Model: image,
+		setter: func() {
+			for _, s := range setter {
+				s(image)
+			}
+		},
+		addToFixture: func(t *testing.T, f *Fixture) {
+			f.EntryImages = append(f.EntryImages, image)
+		},
+		connect: func(t *testing.T, f *Fixture, connectingModel interface{}) {
+			switch connectingModel.(type) {
+			case *Entry:
+				entry := connectingModel.(*Entry)
+				image.EntryID = entry.ID
+			default:
+				t.Fatalf("%T cannot be connected to %T", connectingModel, image)
+			}
+		},
+		insertTable: func(t *testing.T, f *Fixture) {
+			// 連番されるIDをセットする
+			result := f.DBv1.QueryRowxContext(
+				ctx,
+				f.DBv1.Rebind(`INSERT INTO entry_image (
+					entry_id,
+					url,
+					thumbnail_url,
+					caption,
+					position,
+					is_primary,
+					nsfw,
+					darkness
+				) VALUES (
+					?,
+					?,
+					?,
+					?,
+					?,
+					?,
+					?,
+					?
+				) RETURNING id`),
+				image.EntryID,
+				image.URL,
+				image.ThumbnailURL,
+				image.Caption,
+				image.Position,
+				image.Primary,
+				image.Nsfw,
+				image.Darkness,
+			).Scan(&image.ID)
+			if result != nil {
+				t.Fatalf("insert error: %v", result)
+			}
+		},
+	}
+}
@@ -41,13 +41,13 @@ func NewEntryTag(ctx context.Context, setter ...func(e *EntryTag)) *ModelConnect
 		insertTable: func(t *testing.T, f *Fixture) {
 			result := f.DBv1.QueryRowxContext(
 				ctx,
-				`INSERT INTO entry_tag (
+				f.DBv1.Rebind(`INSERT INTO entry_tag (
 					entry_id,
 					tag_id
 				) VALUES (
-					$1,
-					$2
-				) RETURNING id`,
+					?,
+					?
+				) RETURNING id`),
 				entryTag.EntryID,
 				entryTag.TagID,
 			).Scan(&entryTag.ID)
@@ -39,11 +39,11 @@ func NewEyeColorType(ctx context.Context, setter ...func(e *EyeColorType)) *Mode
 		insertTable: func(t *testing.T, f *Fixture) {
 			r := f.DBv1.QueryRowxContext(
 				ctx,
-				`INSERT INTO eyecolor_type (
+				f.DBv1.Rebind(`INSERT INTO eyecolor_type (
 					color
 				) VALUES (
-					$1
-				) RETURNING id`,
+					?
+				) RETURNING id`),
 				eyeColorType.Color,
 			).Scan(&eyeColorType.ID)
 			if r != nil {
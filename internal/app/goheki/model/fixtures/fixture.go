@@ -14,7 +14,8 @@ type Fixture struct {
 	Tags             []*Tag
 	Sources          []*Source
 	BWHs             []*BWH
-	HekiRadarCharts  []*HekiRadarChart
+	HekiRadarAxes    []*HekiRadarAxis
+	HekiRadarScores  []*HekiRadarScore
 	HairLengths      []*HairLength
 	HairLengthTypes  []*HairLengthType
 	HairColors       []*HairColor
@@ -24,6 +25,7 @@ type Fixture struct {
 	Personalities    []*Personality
 	PersonalityTypes []*PersonalityType
 	Links            []*Link
+	EntryImages      []*EntryImage
 
 	DBv1 db.Driver
 }
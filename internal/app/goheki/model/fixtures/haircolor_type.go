@@ -39,11 +39,11 @@ func NewHairColorType(ctx context.Context, setter ...func(h *HairColorType)) *Mo
 		insertTable: func(t *testing.T, f *Fixture) {
 			r := f.DBv1.QueryRowxContext(
 				ctx,
-				`INSERT INTO haircolor_type (
+				f.DBv1.Rebind(`INSERT INTO haircolor_type (
 					color
 				) VALUES (
-					$1
-				) RETURNING id`,
+					?
+				) RETURNING id`),
 				hairColorType.Color,
 			).Scan(&hairColorType.ID)
 			if r != nil {
@@ -34,11 +34,11 @@ func NewHairLengthType(ctx context.Context, setter ...func(h *HairLengthType)) *
 		insertTable: func(t *testing.T, f *Fixture) {
 			r := f.DBv1.QueryRowxContext(
 				ctx,
-				`INSERT INTO hairlength_type (
+				f.DBv1.Rebind(`INSERT INTO hairlength_type (
 					length
 				) VALUES (
-					$1
-				) RETURNING id`,
+					?
+				) RETURNING id`),
 				heirLengthType.Length,
 			).Scan(&heirLengthType.ID)
 			if r != nil {
@@ -36,7 +36,7 @@ func NewHairStyleType(ctx context.Context, setter ...func(h *HairStyleType)) *Mo
 		insertTable: func(t *testing.T, f *Fixture) {
 			r := f.DBv1.QueryRowxContext(
 				ctx,
-				`INSERT INTO hairstyle_type (style) VALUES ($1) RETURNING id`,
+				f.DBv1.Rebind(`INSERT INTO hairstyle_type (style) VALUES (?) RETURNING id`),
 				hairStyleType.Style,
 			).Scan(&hairStyleType.ID)
 			if r != nil {
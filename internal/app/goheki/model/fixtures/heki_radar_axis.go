@@ -0,0 +1,67 @@
+package fixtures
+
+import (
+	"context"
+	"testing"
+)
+
+type HekiRadarAxis struct {
+	ID           int64  `db:"id"`
+	Name         string `db:"name"`
+	Min          int64  `db:"min"`
+	Max          int64  `db:"max"`
+	DisplayOrder int64  `db:"display_order"`
+}
+
+func NewHekiRadarAxis(ctx context.Context, setter ...func(h *HekiRadarAxis)) *ModelConnector {
+	hekiRadarAxis := &HekiRadarAxis{
+		Name: "ai",
+		Min:  0,
+		Max:  100,
+	}
+
+	return &ModelConnector{
+		Model: hekiRadarAxis,
+		setter: func() {
+			for _, s := range setter {
+				s(hekiRadarAxis)
+			}
+		},
+		addToFixture: func(t *testing.T, f *Fixture) {
+			f.HekiRadarAxes = append(f.HekiRadarAxes, hekiRadarAxis)
+		},
+		connect: func(t *testing.T, f *Fixture, connectingModel interface{}) {
+			switch connectingModel.(type) {
+			case *HekiRadarScore:
+				hekiRadarScore := connectingModel.(*HekiRadarScore)
+				hekiRadarScore.AxisID = hekiRadarAxis.ID
+			default:
+				t.Fatalf("%T cannot be connected to %T", connectingModel, hekiRadarAxis)
+			}
+		},
+		insertTable: func(t *testing.T, f *Fixture) {
+			// 連番されるIDをセットする
+			r := f.DBv1.QueryRowxContext(
+				ctx,
+				f.DBv1.Rebind(`INSERT INTO heki_radar_axis (
+					name,
+					min,
+					max,
+					display_order
+				) VALUES (
+					?,
+					?,
+					?,
+					?
+				) RETURNING id`),
+				hekiRadarAxis.Name,
+				hekiRadarAxis.Min,
+				hekiRadarAxis.Max,
+				hekiRadarAxis.DisplayOrder,
+			).Scan(&hekiRadarAxis.ID)
+			if r != nil {
+				t.Fatalf("insert error: %v", r)
+			}
+		},
+	}
+}
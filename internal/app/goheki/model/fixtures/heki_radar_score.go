@@ -0,0 +1,62 @@
+package fixtures
+
+import (
+	"context"
+	"testing"
+)
+
+type HekiRadarScore struct {
+	EntryID int64 `db:"entry_id"`
+	AxisID  int64 `db:"axis_id"`
+	Score   int64 `db:"score"`
+}
+
+func NewHekiRadarScore(ctx context.Context, setter ...func(h *HekiRadarScore)) *ModelConnector {
+	hekiRadarScore := &HekiRadarScore{
+		Score: 1,
+	}
+
+	return &ModelConnector{
+		Model: hekiRadarScore,
+		setter: func() {
+			for _, s := range setter {
+				s(hekiRadarScore)
+			}
+		},
+		addToFixture: func(t *testing.T, f *Fixture) {
+			f.HekiRadarScores = append(f.HekiRadarScores, hekiRadarScore)
+		},
+		connect: func(t *testing.T, f *Fixture, connectingModel interface{}) {
+			switch connectingModel.(type) {
+			case *Entry:
+				entry := connectingModel.(*Entry)
+				hekiRadarScore.EntryID = entry.ID
+			case *HekiRadarAxis:
+				hekiRadarAxis := connectingModel.(*HekiRadarAxis)
+				hekiRadarScore.AxisID = hekiRadarAxis.ID
+			default:
+				t.Fatalf("%T cannot be connected to %T", connectingModel, hekiRadarScore)
+			}
+		},
+		insertTable: func(t *testing.T, f *Fixture) {
+			_, err := f.DBv1.ExecContext(
+				ctx,
+				f.DBv1.Rebind(`INSERT INTO heki_radar_score (
+					entry_id,
+					axis_id,
+					score
+				) VALUES (
+					?,
+					?,
+					?
+				)`),
+				hekiRadarScore.EntryID,
+				hekiRadarScore.AxisID,
+				hekiRadarScore.Score,
+			)
+			if err != nil {
+				t.Fatalf("insert error: %v", err)
+			}
+		},
+	}
+}
@@ -3,6 +3,7 @@ package fixtures
 import (
 	"context"
 	"testing"
+	"time"
 )
 
 type Link struct {
@@ -12,6 +13,17 @@ type Link struct {
 	URL      string `db:"url"`
 	Nsfw     bool   `db:"nsfw"`
 	Darkness bool   `db:"darkness"`
+	// リンク先から取得した情報
+	Title       *string    `db:"title"`
+	Description *string    `db:"description"`
+	Thumbnail   *string    `db:"thumbnail"`
+	SiteName    *string    `db:"site_name"`
+	UnfurledURL *string    `db:"unfurled_url"`
+	UnfurledAt  *time.Time `db:"unfurled_at"`
+	// リンク切れの確認の結果
+	StatusCode          *int64     `db:"status_code"`
+	CheckedAt           *time.Time `db:"checked_at"`
+	ConsecutiveFailures int64      `db:"consecutive_failures"`
 }
 
 func NewLink(ctx context.Context, setter ...func(l *Link)) *ModelConnector {
@@ -47,24 +59,51 @@ func NewLink(ctx context.Context, setter ...func(l *Link)) *ModelConnector {
 			// 連番されるIDをセットする
 			result := f.DBv1.QueryRowxContext(
 				ctx,
-				`INSERT INTO link (
+				f.DBv1.Rebind(`INSERT INTO link (
 					entry_id,
 					type,
 					url,
 					nsfw,
-					darkness
+					darkness,
+					title,
+					description,
+					thumbnail,
+					site_name,
+					unfurled_url,
+					unfurled_at,
+					status_code,
+					checked_at,
+					consecutive_failures
 				) VALUES (
-					$1,
-					$2,
-					$3,
-					$4,
-					$5
-				) RETURNING id`,
+					?,
+					?,
+					?,
+					?,
+					?,
+					?,
+					?,
+					?,
+					?,
+					?,
+					?,
+					?,
+					?,
+					?
+				) RETURNING id`),
 				link.EntryID,
 				link.Type,
 				link.URL,
 				link.Nsfw,
 				link.Darkness,
+				link.Title,
+				link.Description,
+				link.Thumbnail,
+				link.SiteName,
+				link.UnfurledURL,
+				link.UnfurledAt,
+				link.StatusCode,
+				link.CheckedAt,
+				link.ConsecutiveFailures,
 			).Scan(&link.ID)
 			if result != nil {
 				t.Fatalf("insert error: %v", result)
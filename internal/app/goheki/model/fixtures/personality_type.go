@@ -37,11 +37,11 @@ func NewPersonalityType(ctx context.Context, setter ...func(p *PersonalityType))
 			// 連番されるIDをセットする
 			result := f.DBv1.QueryRowxContext(
 				ctx,
-				`INSERT INTO personality_type (
+				f.DBv1.Rebind(`INSERT INTO personality_type (
 					type
 				) VALUES (
-					$1
-				) RETURNING id`,
+					?
+				) RETURNING id`),
 				personalityType.Type,
 			).Scan(&personalityType.ID)
 			if result != nil {
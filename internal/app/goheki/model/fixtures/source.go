@@ -45,15 +45,15 @@ func NewSource(ctx context.Context, setter ...func(s *Source)) *ModelConnector {
 		insertTable: func(t *testing.T, f *Fixture) {
 			result := f.DBv1.QueryRowxContext(
 				ctx,
-				`INSERT INTO source (
+				f.DBv1.Rebind(`INSERT INTO source (
 					name,
 					url,
 					type
 				) VALUES (
-					$1,
-					$2,
-					$3
-				) RETURNING id`,
+					?,
+					?,
+					?
+				) RETURNING id`),
 				source.Name,
 				source.Url,
 				source.Type,
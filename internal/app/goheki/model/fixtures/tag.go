@@ -31,7 +31,7 @@ func NewTag(ctx context.Context, setter ...func(t *Tag)) *ModelConnector {
 			// 連番されるIDをセットする
 			result := f.DBv1.QueryRowxContext(
 				ctx,
-				"INSERT INTO tag (name) VALUES ($1) RETURNING id",
+				f.DBv1.Rebind("INSERT INTO tag (name) VALUES (?) RETURNING id"),
 				tag.Name,
 			).Scan(&tag.ID)
 			if result != nil {
@@ -0,0 +1,207 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// ContentType はRFC 7807のエラーレスポンスのContent-Type
+const ContentType = "application/problem+json"
+
+// requestIDHeader はmiddleware.RequestIDがレスポンスに設定するヘッダー名
+const requestIDHeader = "X-Request-ID"
+
+// エラーの種類を表すコード
+const (
+	CodeInvalidJSON      = "invalid_json"
+	CodeValidation       = "validation_failed"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeUnauthorized     = "unauthorized"
+	CodeDatabase         = "database_error"
+	CodeInternal         = "internal_error"
+	CodeUpstream         = "upstream_error"
+	CodeTooLarge         = "payload_too_large"
+	CodeConflict         = "conflict"
+)
+
+// DatabaseDetail はDBのエラーの代わりにレスポンスに含める説明
+const DatabaseDetail = "database error"
+
+// Problem はRFC 7807 (problem+json)のエラーレスポンス
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError はフィールドごとのバリデーションエラー
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Title + ": " + p.Detail
+}
+
+// New はステータスコードとエラーからProblemを生成する
+// errがvalidation.Errorsの場合はフィールドごとのエラーを含める
+func New(r *http.Request, status int, code string, err error) *Problem {
+	p := &Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: r.URL.Path,
+		Code:     code,
+	}
+	if err == nil {
+		return p
+	}
+	p.Detail = err.Error()
+	var verrs validation.Errors
+	if errors.As(err, &verrs) {
+		p.Errors = FieldErrors(verrs)
+	}
+	return p
+}
+
+// Write はエラーをログに出力し、problem+jsonとしてレスポンスに書き込む
+// 呼び出し元はWriteの後に必ずreturnすること
+func Write(w http.ResponseWriter, r *http.Request, status int, code string, err error) {
+	write(w, r, New(r, status, code, err), err)
+}
+
+// write はerrをログに出力し、pをレスポンスに書き込む
+func write(w http.ResponseWriter, r *http.Request, p *Problem, err error) {
+	// middleware.RequestIDを通っている場合はレスポンスヘッダーにIDが設定済み
+	p.RequestID = w.Header().Get(requestIDHeader)
+	log.Printf("[%s] %s %s: %d %s: %v", p.RequestID, r.Method, r.URL.Path, p.Status, p.Code, err)
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		log.Printf("[%s] json encode error: %v", p.RequestID, err)
+	}
+}
+
+// BadRequest はリクエストボディが読み込めない場合のエラーを書き込む
+func BadRequest(w http.ResponseWriter, r *http.Request, err error) {
+	Write(w, r, http.StatusBadRequest, CodeInvalidJSON, err)
+}
+
+// Validation はバリデーションエラーを書き込む
+func Validation(w http.ResponseWriter, r *http.Request, err error) {
+	Write(w, r, http.StatusUnprocessableEntity, CodeValidation, err)
+}
+
+// Unauthorized は認証に失敗した場合のエラーを書き込む
+func Unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	Write(w, r, http.StatusUnauthorized, CodeUnauthorized, err)
+}
+
+// NotFound はリソースが存在しない場合のエラーを書き込む
+func NotFound(w http.ResponseWriter, r *http.Request, err error) {
+	Write(w, r, http.StatusNotFound, CodeNotFound, err)
+}
+
+// MethodNotAllowed は許可されていないメソッドの場合のエラーを書き込む
+// Allowヘッダーは呼び出し元で設定する
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request, err error) {
+	Write(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, err)
+}
+
+// Database はSQLの実行に失敗した場合のエラーを書き込む
+// SQLやテーブルの構造を返さないよう、DBのエラーはリクエストIDとともにログにだけ出力する
+// errがvalidation.Errorsの場合は失敗したフィールドだけを返す
+func Database(w http.ResponseWriter, r *http.Request, err error) {
+	p := New(r, http.StatusInternalServerError, CodeDatabase, nil)
+	p.Detail = DatabaseDetail
+	var verrs validation.Errors
+	if errors.As(err, &verrs) {
+		p.Errors = DatabaseErrors(verrs)
+	}
+	write(w, r, p, err)
+}
+
+// DatabaseErrors はフィールドごとのDBのエラーを、メッセージを伏せて展開する
+func DatabaseErrors(errs validation.Errors) []FieldError {
+	fields := FieldErrors(errs)
+	for i := range fields {
+		fields[i].Message = DatabaseDetail
+	}
+	return fields
+}
+
+// Internal はサーバー内部のエラーを書き込む
+func Internal(w http.ResponseWriter, r *http.Request, err error) {
+	Write(w, r, http.StatusInternalServerError, CodeInternal, err)
+}
+
+// BadGateway は外部のサーバーへのリクエストに失敗した場合のエラーを書き込む
+func BadGateway(w http.ResponseWriter, r *http.Request, err error) {
+	Write(w, r, http.StatusBadGateway, CodeUpstream, err)
+}
+
+// TooLarge はリクエストボディが大きすぎる場合のエラーを書き込む
+func TooLarge(w http.ResponseWriter, r *http.Request, err error) {
+	Write(w, r, http.StatusRequestEntityTooLarge, CodeTooLarge, err)
+}
+
+// Conflict は既存のデータと矛盾する場合のエラーを書き込む
+func Conflict(w http.ResponseWriter, r *http.Request, err error) {
+	Write(w, r, http.StatusConflict, CodeConflict, err)
+}
+
+// FieldErrors はvalidation.Errorsをフィールドごとのエラーに展開する
+// 入れ子のフィールドは entries[0].name のように連結する
+func FieldErrors(errs validation.Errors) []FieldError {
+	var fields []FieldError
+	flatten("", errs, &fields)
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Field < fields[j].Field
+	})
+	return fields
+}
+
+func flatten(prefix string, errs validation.Errors, fields *[]FieldError) {
+	for key, err := range errs {
+		if err == nil {
+			continue
+		}
+		field := joinField(prefix, key)
+		var nested validation.Errors
+		if errors.As(err, &nested) {
+			flatten(field, nested, fields)
+			continue
+		}
+		*fields = append(*fields, FieldError{Field: field, Message: err.Error()})
+	}
+}
+
+// joinField はスライスの添字を[i]、構造体のフィールドを.nameとして連結する
+func joinField(prefix, key string) string {
+	if _, err := strconv.Atoi(key); err == nil {
+		return prefix + "[" + key + "]"
+	}
+	if prefix == "" || strings.HasPrefix(key, "[") {
+		return prefix + key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,83 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	t.Run("problem+jsonで返す", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		w.Header().Set("X-Request-ID", "abc")
+		r := httptest.NewRequest(http.MethodPost, "/api/entries", nil)
+
+		BadRequest(w, r, errors.New("unexpected EOF"))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+		var p Problem
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+		assert.Equal(t, Problem{
+			Type:      "about:blank",
+			Title:     "Bad Request",
+			Status:    http.StatusBadRequest,
+			Detail:    "unexpected EOF",
+			Instance:  "/api/entries",
+			Code:      CodeInvalidJSON,
+			RequestID: "abc",
+		}, p)
+	})
+
+	t.Run("バリデーションエラーはフィールドごとに返す", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/entries", nil)
+
+		Validation(w, r, validation.Errors{
+			"entries": validation.Errors{
+				"1": validation.Errors{
+					"name":      errors.New("cannot be blank"),
+					"source_id": errors.New("cannot be blank"),
+					"content":   nil,
+				},
+			},
+		})
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		var p Problem
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+		assert.Equal(t, CodeValidation, p.Code)
+		assert.Equal(t, []FieldError{
+			{Field: "entries[1].name", Message: "cannot be blank"},
+			{Field: "entries[1].source_id", Message: "cannot be blank"},
+		}, p.Errors)
+	})
+	t.Run("DBのエラーは返さない", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		w.Header().Set("X-Request-ID", "abc")
+		r := httptest.NewRequest(http.MethodPost, "/api/entries", nil)
+
+		Database(w, r, validation.Errors{
+			"entries[0]": errors.New(`pq: insert or update on table "entry" violates foreign key constraint "entry_source_id_fkey"`),
+		})
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		var p Problem
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+		assert.Equal(t, Problem{
+			Type:      "about:blank",
+			Title:     "Internal Server Error",
+			Status:    http.StatusInternalServerError,
+			Detail:    DatabaseDetail,
+			Instance:  "/api/entries",
+			Code:      CodeDatabase,
+			RequestID: "abc",
+			Errors:    []FieldError{{Field: "entries[0]", Message: DatabaseDetail}},
+		}, p)
+	})
+}
@@ -0,0 +1,146 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
+	"github.com/maguro-alternative/goheki/pkg/db"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// 1件ごとの処理結果
+const (
+	ItemOK     = "ok"
+	ItemFailed = "failed"
+)
+
+// ItemResult はpartialモードでの1件ごとの処理結果
+type ItemResult struct {
+	Index  int                  `json:"index"`
+	Status string               `json:"status"`
+	Code   string               `json:"code,omitempty"`
+	Errors []problem.FieldError `json:"errors,omitempty"`
+	// dbErr はログにだけ出力するDBのエラー
+	dbErr error
+}
+
+// isPartial は?mode=partialが指定されているかを返す
+// partialモードでは失敗した行だけを取り消し、残りの行は書き込む
+func isPartial(r *http.Request) bool {
+	return r.URL.Query().Get("mode") == "partial"
+}
+
+// writeFunc は1件を書き込む処理
+// DBが返した値はitemに書き戻す
+type writeFunc[T any] func(ctx context.Context, driver db.Driver, item *T) error
+
+// write はバリデーションを行いitemsを書き込んで、レスポンスを返す
+// 既定では全件を1つのトランザクションで書き込み、?mode=partialの場合は1件ごとに書き込む
+func (res *Resource[T]) write(w http.ResponseWriter, r *http.Request, driver db.Driver, exec writeFunc[T], items []T) {
+	if !isPartial(r) {
+		if err := res.validateList(items); err != nil {
+			problem.Validation(w, r, err)
+			return
+		}
+		if err := res.writeAll(r.Context(), driver, exec, items); err != nil {
+			problem.Database(w, r, err)
+			return
+		}
+		res.encodeList(w, items)
+		return
+	}
+	if err := validation.Validate(items, validation.Required); err != nil {
+		problem.Validation(w, r, validation.Errors{res.ListKey: err})
+		return
+	}
+	written, results, err := res.writeEach(r.Context(), driver, exec, items)
+	if err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+	for _, result := range results {
+		if result.dbErr != nil {
+			log.Printf("[%s] %s %s: %s: %v", w.Header().Get("X-Request-ID"), r.Method, r.URL.Path, res.itemField(result.Index), result.dbErr)
+		}
+	}
+	res.encodeResults(w, written, results)
+}
+
+// writeAll はitemsを1つのトランザクションで書き込む
+// 1件でも失敗した場合は全件を取り消し、失敗した行をvalidation.Errorsで返す
+func (res *Resource[T]) writeAll(ctx context.Context, driver db.Driver, exec writeFunc[T], items []T) error {
+	return db.WithTx(ctx, driver, func(tx db.Driver) error {
+		for i := range items {
+			if err := exec(ctx, tx, &items[i]); err != nil {
+				return validation.Errors{res.itemField(i): err}
+			}
+		}
+		return nil
+	})
+}
+
+// writeEach はitemsを1件ずつSAVEPOINTを作成して書き込む
+// バリデーションまたはSQLに失敗した行だけを取り消し、書き込んだ行と1件ごとの結果を返す
+func (res *Resource[T]) writeEach(ctx context.Context, driver db.Driver, exec writeFunc[T], items []T) ([]T, []ItemResult, error) {
+	written := make([]T, 0, len(items))
+	results := make([]ItemResult, len(items))
+	err := db.WithTx(ctx, driver, func(tx db.Driver) error {
+		for i := range items {
+			results[i] = ItemResult{Index: i, Status: ItemOK}
+			if err := res.validateItem(&items[i]); err != nil {
+				results[i].fail(problem.CodeValidation, validation.Errors{res.itemField(i): err})
+				continue
+			}
+			err := db.WithTx(ctx, tx, func(sp db.Driver) error {
+				return exec(ctx, sp, &items[i])
+			})
+			if err != nil {
+				results[i].failDatabase(validation.Errors{res.itemField(i): err}, err)
+				continue
+			}
+			written = append(written, items[i])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return written, results, nil
+}
+
+func (r *ItemResult) fail(code string, errs validation.Errors) {
+	r.Status = ItemFailed
+	r.Code = code
+	r.Errors = problem.FieldErrors(errs)
+}
+
+// failDatabase はDBのエラーを伏せて失敗した行を記録する
+// errはレスポンスに含めず、ログに出力する
+func (r *ItemResult) failDatabase(errs validation.Errors, err error) {
+	r.Status = ItemFailed
+	r.Code = problem.CodeDatabase
+	r.Errors = problem.DatabaseErrors(errs)
+	r.dbErr = err
+}
+
+// encodeResults はpartialモードのレスポンスを書き込む
+// 1件でも失敗した場合は207 Multi-Statusを返す
+func (res *Resource[T]) encodeResults(w http.ResponseWriter, items []T, results []ItemResult) {
+	for _, result := range results {
+		if result.Status != ItemOK {
+			w.WriteHeader(http.StatusMultiStatus)
+			break
+		}
+	}
+	err := json.NewEncoder(w).Encode(map[string]any{
+		res.ListKey: items,
+		"results":   results,
+	})
+	if err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package resource
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// filterOperators は絞り込みの演算子とSQLの対応
+//
+//	?nsfw=false            nsfw = false (複数指定した場合はIN)
+//	?bust[gte]=80          bust >= 80
+//	?type[in]=blog,twitter type IN ('blog', 'twitter')
+//	?name[like]=雪%         name LIKE '雪%'
+var filterOperators = map[string]string{
+	"eq":   "=",
+	"ne":   "<>",
+	"gt":   ">",
+	"gte":  ">=",
+	"lt":   "<",
+	"lte":  "<=",
+	"in":   "IN",
+	"like": "LIKE",
+}
+
+// reservedParams は絞り込み以外に使うクエリパラメータ
+var reservedParams = map[string]bool{
+	"limit":  true,
+	"after":  true,
+	"sort":   true,
+	"fields": true,
+	"mode":   true,
+}
+
+var filterParam = regexp.MustCompile(`^(\w+)\[(\w+)\]$`)
+
+// filter はカラム1つ分の絞り込みの条件
+// columnは計算するカラムの場合はSQLの式になる
+type filter struct {
+	column string
+	op     string
+	values []any
+}
+
+// where は?を置換文字とした条件と引数を返す
+func (f filter) where() (string, []any) {
+	if f.op == "in" || (f.op == "eq" && len(f.values) > 1) {
+		return f.column + " IN (?)", []any{f.values}
+	}
+	return fmt.Sprintf("%s %s ?", f.column, filterOperators[f.op]), f.values[:1]
+}
+
+// parseFilters はクエリパラメータから絞り込みの条件を読み込む
+// Keyの?id=はReadHandlerで扱うため含めない
+func (res *Resource[T]) parseFilters(query url.Values) ([]filter, error) {
+	// 条件の順序を固定するためパラメータ名で並べ替える
+	params := make([]string, 0, len(query))
+	for param := range query {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+
+	var filters []filter
+	errs := validation.Errors{}
+	for _, param := range params {
+		column, op := param, "eq"
+		if m := filterParam.FindStringSubmatch(param); m != nil {
+			column, op = m[1], m[2]
+		} else if reservedParams[param] || param == res.Key {
+			continue
+		}
+		if _, ok := filterOperators[op]; !ok {
+			errs[param] = fmt.Errorf("unknown operator %s", op)
+			continue
+		}
+		if !res.filterable(column) {
+			// []のない未知のパラメータは互換性のため無視する
+			if column != param {
+				errs[param] = fmt.Errorf("cannot filter by %s", column)
+			}
+			continue
+		}
+		raw := query[param]
+		if op == "in" {
+			raw = strings.Split(strings.Join(raw, ","), ",")
+		}
+		values, err := res.filterValues(column, op, raw)
+		if err != nil {
+			errs[param] = err
+			continue
+		}
+		filters = append(filters, filter{column: res.expr(column), op: op, values: values})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return filters, nil
+}
+
+// filterable はcolumnで絞り込めるかを返す
+func (res *Resource[T]) filterable(column string) bool {
+	if len(res.Filterable) == 0 {
+		return contains(res.rowColumns(), column) || contains(res.computedColumns(), column)
+	}
+	return contains(res.Filterable, column)
+}
+
+// filterValues はクエリパラメータの値をカラムに対応するフィールドの型に変換する
+func (res *Resource[T]) filterValues(column, op string, raw []string) ([]any, error) {
+	var zero T
+	field, ok := fieldByColumn(reflect.ValueOf(&zero).Elem(), column)
+	if !ok {
+		return nil, fmt.Errorf("cannot filter by %s", column)
+	}
+	t := field.Type()
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if op == "like" && t.Kind() != reflect.String {
+		return nil, fmt.Errorf("like is only available for text columns")
+	}
+	values := make([]any, 0, len(raw))
+	for _, s := range raw {
+		v, err := parseFilterValue(t, strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q", s)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func parseFilterValue(t reflect.Type, s string) (any, error) {
+	if t == timeType {
+		// 日付のみの指定も受け付ける
+		if v, err := time.Parse("2006-01-02", s); err == nil {
+			return v, nil
+		}
+		return time.Parse(time.RFC3339, s)
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(s, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(s, 64)
+	case reflect.Bool:
+		return strconv.ParseBool(s)
+	default:
+		return s, nil
+	}
+}
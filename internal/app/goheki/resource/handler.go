@@ -0,0 +1,329 @@
+package resource
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/router"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/pkg/db"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// IDs は削除するidの一覧
+type IDs struct {
+	IDs []int64 `json:"ids"`
+}
+
+func (i *IDs) Validate() error {
+	return validation.ValidateStruct(i,
+		validation.Field(&i.IDs, validation.Required),
+	)
+}
+
+type CreateHandler[T any] struct {
+	svc *service.IndexService
+	res *Resource[T]
+}
+
+func NewCreateHandler[T any](svc *service.IndexService, res *Resource[T]) *CreateHandler[T] {
+	return &CreateHandler[T]{
+		svc: svc,
+		res: res,
+	}
+}
+
+func (h *CreateHandler[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// POST以外は受け付けない
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	// json読み込み
+	items, _, err := h.res.decodeList(r)
+	if err != nil {
+		problem.BadRequest(w, r, err)
+		return
+	}
+	// バリデーションを行い、トランザクション内で書き込む
+	// レスポンスにはDBが採番したidや既定値を含めて返す
+	h.res.write(w, r, h.svc.DB, h.res.insert, items)
+}
+
+type ReadHandler[T any] struct {
+	svc *service.IndexService
+	res *Resource[T]
+}
+
+func NewReadHandler[T any](svc *service.IndexService, res *Resource[T]) *ReadHandler[T] {
+	return &ReadHandler[T]{
+		svc: svc,
+		res: res,
+	}
+}
+
+func (h *ReadHandler[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// GET以外は受け付けない
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	// ページング、並べ替え、返すフィールドの指定
+	p, err := h.res.parsePage(r.URL.Query())
+	if err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+	// カラムごとの絞り込みの指定
+	filters, err := h.res.parseFilters(r.URL.Query())
+	if err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+	var where []string
+	var args []any
+	// クエリパラメータにKeyがある場合はidで絞り込む
+	if queryIDs, ok := r.URL.Query()[h.res.Key]; ok {
+		ids, err := parseKeys(queryIDs)
+		if err != nil {
+			problem.Validation(w, r, validation.Errors{h.res.Key: err})
+			return
+		}
+		where = append(where, h.res.Key+" IN (?)")
+		args = append(args, ids)
+	}
+	for _, f := range filters {
+		cond, values := f.where()
+		where = append(where, cond)
+		args = append(args, values...)
+	}
+	// 除外する行がある場合は件数を数え、一覧から除く
+	var withheld *int64
+	if h.res.Withhold != nil {
+		cond, values, err := h.res.Withhold(r, h.svc)
+		var verrs validation.Errors
+		if errors.As(err, &verrs) {
+			problem.Validation(w, r, verrs)
+			return
+		} else if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		withheld = new(int64)
+		if cond != "" {
+			// 絞り込みに一致する行のうち、除外する行を数える
+			countWhere := append(append([]string{}, where...), "("+cond+")")
+			countArgs := append(append([]any{}, args...), values...)
+			countQuery, countArgs, err := h.res.countQuery(db.DialectOf(h.svc.DB), countWhere, countArgs)
+			if err != nil {
+				problem.Internal(w, r, err)
+				return
+			}
+			if err := h.svc.DB.GetContext(r.Context(), withheld, countQuery, countArgs...); err != nil {
+				problem.Database(w, r, err)
+				return
+			}
+			where = append(where, "NOT ("+cond+")")
+			args = append(args, values...)
+		}
+	}
+	query, args, err := h.res.listQuery(db.DialectOf(h.svc.DB), p, where, args)
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+	var items []T
+	if err := h.svc.DB.SelectContext(r.Context(), &items, query, args...); err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+	// limitより多く取得できた場合は次のページがある
+	var next string
+	if len(items) > p.limit {
+		items = items[:p.limit]
+		cursor, err := h.res.nextCursor(p, &items[len(items)-1])
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		next = nextURL(r, cursor)
+	}
+	h.res.hideKey(items)
+	h.res.encodePage(w, r, p, items, next, withheld)
+}
+
+type UpdateHandler[T any] struct {
+	svc *service.IndexService
+	res *Resource[T]
+}
+
+func NewUpdateHandler[T any](svc *service.IndexService, res *Resource[T]) *UpdateHandler[T] {
+	return &UpdateHandler[T]{
+		svc: svc,
+		res: res,
+	}
+}
+
+func (h *UpdateHandler[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// PUT、PATCH以外は受け付けない
+	if r.Method != http.MethodPut && r.Method != http.MethodPatch {
+		methodNotAllowed(w, r, http.MethodPatch+", "+http.MethodPut)
+		return
+	}
+	// json読み込み
+	items, raws, err := h.res.decodeList(r)
+	if err != nil {
+		problem.BadRequest(w, r, err)
+		return
+	}
+	// /api/{name}/{id}の場合はパスのidで1件を更新する
+	pathID := router.PathID(r)
+	if pathID != "" {
+		if len(items) != 1 {
+			problem.Validation(w, r, validation.Errors{
+				h.res.ListKey: fmt.Errorf("exactly one item is required for /%s", pathID),
+			})
+			return
+		}
+		id, _ := strconv.ParseInt(pathID, 10, 64)
+		if err := h.res.setKey(&items[0], id); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+	}
+	// PATCHは送られたフィールドだけを現在の値に上書きし、送られなかったカラムは変更しない
+	// パスのidで更新する場合とPATCHの場合は、該当する行がなければ404を返す
+	patch := r.Method == http.MethodPatch
+	if patch || pathID != "" {
+		for i := range items {
+			key, err := h.res.key(&items[i])
+			if err != nil {
+				problem.Internal(w, r, err)
+				return
+			}
+			current, err := h.res.load(r.Context(), h.svc.DB, key)
+			if err != nil {
+				problem.Database(w, r, err)
+				return
+			}
+			if current == nil {
+				problem.NotFound(w, r, fmt.Errorf("%s %d not found", h.res.Table, key))
+				return
+			}
+			if !patch {
+				continue
+			}
+			if err := json.Unmarshal(raws[i], current); err != nil {
+				problem.BadRequest(w, r, err)
+				return
+			}
+			// パスのidを本文のidより優先する
+			if err := h.res.setKey(current, key); err != nil {
+				problem.Internal(w, r, err)
+				return
+			}
+			items[i] = *current
+		}
+	}
+	// バリデーションを行い、トランザクション内で書き込む
+	h.res.write(w, r, h.svc.DB, h.res.update, items)
+}
+
+type DeleteHandler[T any] struct {
+	svc *service.IndexService
+	res *Resource[T]
+}
+
+func NewDeleteHandler[T any](svc *service.IndexService, res *Resource[T]) *DeleteHandler[T] {
+	return &DeleteHandler[T]{
+		svc: svc,
+		res: res,
+	}
+}
+
+func (h *DeleteHandler[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// DELETE以外は受け付けない
+	if r.Method != http.MethodDelete {
+		methodNotAllowed(w, r, http.MethodDelete)
+		return
+	}
+	var delIDs IDs
+	// json読み込み
+	if err := json.NewDecoder(r.Body).Decode(&delIDs); err != nil {
+		problem.BadRequest(w, r, err)
+		return
+	}
+	// jsonバリデーション
+	if err := delIDs.Validate(); err != nil {
+		problem.Validation(w, r, err)
+		return
+	}
+	// idの数だけ置換文字を作成
+	query, args, err := db.In(h.res.deleteQuery(), delIDs.IDs)
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+	// 関連するテーブルの同期と削除を1つのトランザクションで行う
+	err = db.WithTx(r.Context(), h.svc.DB, func(tx db.Driver) error {
+		if err := h.res.beforeDelete(r.Context(), tx, delIDs.IDs); err != nil {
+			return err
+		}
+		// DBの種類に合わせて置換文字を変える
+		_, err := tx.ExecContext(r.Context(), tx.Rebind(query), args...)
+		return err
+	})
+	if err != nil {
+		problem.Database(w, r, err)
+		return
+	}
+	// json返却
+	if err := json.NewEncoder(w).Encode(&delIDs); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
+
+// methodNotAllowed は許可されていないメソッドの場合に405とAllowヘッダーを返す
+func methodNotAllowed(w http.ResponseWriter, r *http.Request, allow string) {
+	w.Header().Set("Allow", allow)
+	problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+}
+
+// decodeList はリクエストボディの{ListKey: [...]}を読み込む
+// PATCHで送られたフィールドだけを上書きできるよう、1件ごとのjsonも返す
+func (res *Resource[T]) decodeList(r *http.Request) ([]T, []json.RawMessage, error) {
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, nil, err
+	}
+	var items []T
+	var raws []json.RawMessage
+	raw, ok := body[res.ListKey]
+	if !ok {
+		return items, raws, nil
+	}
+	if err := json.Unmarshal(raw, &raws); err != nil {
+		return nil, nil, err
+	}
+	items = make([]T, len(raws))
+	for i := range raws {
+		if err := json.Unmarshal(raws[i], &items[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+	return items, raws, nil
+}
+
+// encodeList はレスポンスボディに{ListKey: [...]}を書き込む
+func (res *Resource[T]) encodeList(w http.ResponseWriter, items []T) {
+	err := json.NewEncoder(w).Encode(map[string][]T{res.ListKey: items})
+	if err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
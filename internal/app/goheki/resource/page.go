@@ -0,0 +1,322 @@
+package resource
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
+	"github.com/maguro-alternative/goheki/pkg/db"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+const (
+	// DefaultLimit はlimitを省略した場合の1ページの件数
+	DefaultLimit = 100
+	// MaxLimit はlimitに指定できる最大の件数
+	MaxLimit = 1000
+)
+
+// page は一覧取得のページングと並べ替え、返すフィールドの指定
+//
+//	?limit=20              1ページの件数
+//	?after=<cursor>        前のページのレスポンスのnextに含まれるカーソル
+//	?sort=name, ?sort=-name 並べ替えるカラム (-は降順)、同じ値の場合はKeyの順
+//	?fields=name,image     返すフィールド (jsonのキーまたはカラム名)
+type page struct {
+	limit  int
+	sort   string
+	desc   bool
+	after  []any
+	fields []string
+}
+
+// parsePage はクエリパラメータからページングの指定を読み込む
+func (res *Resource[T]) parsePage(query url.Values) (*page, error) {
+	p := &page{
+		limit: DefaultLimit,
+		sort:  res.Key,
+	}
+	errs := validation.Errors{}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			errs["limit"] = fmt.Errorf("must be an integer between 1 and %d", MaxLimit)
+		}
+		p.limit = limit
+	}
+	if v := query.Get("sort"); v != "" {
+		p.desc = strings.HasPrefix(v, "-")
+		p.sort = strings.TrimPrefix(v, "-")
+		if !res.sortable(p.sort) {
+			errs["sort"] = fmt.Errorf("cannot sort by %s", p.sort)
+		}
+	}
+	if v := query.Get("after"); v != "" {
+		after, err := decodeCursor(v)
+		// NULLになりうるのは並べ替えるカラムだけで、Keyは必ず値がある
+		if err != nil || len(after) != len(p.cursorColumns(res.Key)) || after[len(after)-1] == nil {
+			errs["after"] = errors.New("invalid cursor")
+		}
+		p.after = after
+	}
+	if v := query.Get("fields"); v != "" {
+		names := jsonNames(reflect.TypeOf((*T)(nil)).Elem())
+		for _, field := range strings.Split(v, ",") {
+			name, ok := names[strings.TrimSpace(field)]
+			if !ok {
+				errs["fields"] = fmt.Errorf("unknown field %s", field)
+				break
+			}
+			p.fields = append(p.fields, name)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return p, nil
+}
+
+// sortable はcolumnで並べ替えられるかを返す
+func (res *Resource[T]) sortable(column string) bool {
+	if len(res.Sortable) == 0 {
+		return contains(res.rowColumns(), column) || contains(res.computedColumns(), column)
+	}
+	return contains(res.Sortable, column)
+}
+
+// cursorColumns はカーソルに含めるカラムを返す
+// 並べ替えるカラムが同じ値でも順序が決まるようにKeyを含める
+func (p *page) cursorColumns(key string) []string {
+	if p.sort == key {
+		return []string{key}
+	}
+	return []string{p.sort, key}
+}
+
+// listQuery は一覧取得のSELECT文と引数を返す
+// whereには?を置換文字とした条件を渡し、置換文字はdialectに合わせて変える
+func (res *Resource[T]) listQuery(dialect db.Dialect, p *page, where []string, args []any) (string, []any, error) {
+	columns := res.readColumns()
+	for _, column := range p.cursorColumns(res.Key) {
+		if !contains(columns, column) {
+			columns = append(columns, column)
+		}
+	}
+	cursor := p.cursorColumns(res.Key)
+	if p.after != nil {
+		cond, values := res.afterWhere(p)
+		where = append(where, cond)
+		args = append(args, values...)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", res.selectList(columns), res.Table)
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	order := make([]string, len(cursor))
+	for i, column := range cursor {
+		order[i] = res.expr(column)
+		if p.desc {
+			order[i] += " DESC"
+		}
+		// NULLの位置はDBによって異なるため、Postgresの既定に揃える
+		if res.nullable(column) {
+			if p.desc {
+				order[i] += " NULLS FIRST"
+			} else {
+				order[i] += " NULLS LAST"
+			}
+		}
+	}
+	// 次のページがあるかを判定するため1件多く取得する
+	query += fmt.Sprintf(" ORDER BY %s LIMIT %d", strings.Join(order, ", "), p.limit+1)
+
+	query, args, err := db.In(query, args...)
+	if err != nil {
+		return "", nil, err
+	}
+	return dialect.Rebind(query), args, nil
+}
+
+// afterWhere は前のページの続きから取得する条件と引数を返す
+// 並べ替えるカラムのNULLは昇順では最後、降順では最初に並ぶ
+func (res *Resource[T]) afterWhere(p *page) (string, []any) {
+	op := ">"
+	if p.desc {
+		op = "<"
+	}
+	key := res.expr(res.Key)
+	if p.sort == res.Key {
+		return fmt.Sprintf("%s %s ?", key, op), p.after
+	}
+	sort := res.expr(p.sort)
+	value, last := p.after[0], p.after[1]
+	switch {
+	case value == nil && p.desc:
+		// NULLの行の続きと、NULLでないすべての行
+		return fmt.Sprintf("((%s IS NULL AND %s < ?) OR %s IS NOT NULL)", sort, key, sort), []any{last}
+	case value == nil:
+		// NULLの行の続き
+		return fmt.Sprintf("(%s IS NULL AND %s > ?)", sort, key), []any{last}
+	case p.desc || !res.nullable(p.sort):
+		// (sort, key) < (?, ?) で前のページの続きから取得する
+		// 降順ではNULLの行は前のページまでに取得済み
+		return fmt.Sprintf("(%s, %s) %s (?, ?)", sort, key, op), []any{value, last}
+	default:
+		// NULLの行はNULLでない行の後に取得する
+		return fmt.Sprintf("((%s, %s) > (?, ?) OR %s IS NULL)", sort, key, sort), []any{value, last}
+	}
+}
+
+// nullable はcolumnがNULLになりうるかを返す
+// Tのフィールドがポインタの場合はNULLになりうるカラムとして扱う
+func (res *Resource[T]) nullable(column string) bool {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.Tag.Get("db") == column {
+			return f.Type.Kind() == reflect.Ptr
+		}
+	}
+	return false
+}
+
+// nextCursor は最後の行からカーソルを作成する
+// 並べ替えるカラムがNULLの場合はnullを含める
+func (res *Resource[T]) nextCursor(p *page, last *T) (string, error) {
+	v := reflect.ValueOf(last).Elem()
+	var values []any
+	for _, column := range p.cursorColumns(res.Key) {
+		field, ok := fieldByColumn(v, column)
+		if !ok {
+			return "", fmt.Errorf("%T has no field for column %s", *last, column)
+		}
+		values = append(values, field.Interface())
+	}
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeCursor(cursor string) ([]any, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(strings.NewReader(string(b)))
+	dec.UseNumber()
+	var values []any
+	if err := dec.Decode(&values); err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		switch v := value.(type) {
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				values[i] = n
+			} else if f, err := v.Float64(); err == nil {
+				values[i] = f
+			}
+		}
+	}
+	return values, nil
+}
+
+// nextURL はafterをカーソルに置き換えた次のページのURLを返す
+func nextURL(r *http.Request, cursor string) string {
+	u := *r.URL
+	query := u.Query()
+	query.Set("after", cursor)
+	u.RawQuery = query.Encode()
+	return u.RequestURI()
+}
+
+// encodePage はレスポンスボディに{ListKey: [...], "next": "..."}を書き込む
+// fieldsが指定されている場合は指定されたjsonのキーだけを返す
+// withheldがnilでない場合は除外した件数を"withheld"で返す
+func (res *Resource[T]) encodePage(w http.ResponseWriter, r *http.Request, p *page, items []T, next string, withheld *int64) {
+	body := map[string]any{res.ListKey: items}
+	if len(p.fields) > 0 {
+		shaped, err := selectFields(items, p.fields)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		body[res.ListKey] = shaped
+	}
+	if next != "" {
+		body["next"] = next
+	}
+	if withheld != nil {
+		body["withheld"] = *withheld
+	}
+	enc := json.NewEncoder(w)
+	// nextのURLの&をエスケープしない
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(body); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
+
+// selectFields は1件ずつjsonに変換し、fieldsのキーだけを残す
+func selectFields[T any](items []T, fields []string) ([]map[string]json.RawMessage, error) {
+	shaped := make([]map[string]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		b, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(b, &all); err != nil {
+			return nil, err
+		}
+		m := make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if v, ok := all[field]; ok {
+				m[field] = v
+			}
+		}
+		shaped = append(shaped, m)
+	}
+	return shaped, nil
+}
+
+// jsonNames はjsonのキーとカラム名からjsonのキーへの対応を返す
+func jsonNames(t reflect.Type) map[string]string {
+	names := map[string]string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tag, _, _ := strings.Cut(f.Tag.Get("json"), ","); tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		names[name] = name
+		if column := f.Tag.Get("db"); column != "" && column != "-" {
+			names[column] = name
+		}
+	}
+	return names
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,388 @@
+package resource
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/service"
+	"github.com/maguro-alternative/goheki/pkg/db"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/jmoiron/sqlx"
+)
+
+// Resource はテーブル1つ分のCRUDの定義
+// 定義からCreate、Read、Update、Deleteのハンドラを生成する
+type Resource[T any] struct {
+	// テーブル名
+	Table string
+	// 1件を特定するカラム名 (id または entry_id)
+	// Readではこの名前のクエリパラメータで絞り込む
+	Key string
+	// リクエストおよびレスポンスのjsonのキー (例: entries)
+	ListKey string
+	// INSERTおよびUPDATEで書き込むカラム
+	// Keyが連番の場合はKeyを含めない
+	Columns []string
+	// SELECTで読み込むカラム
+	// 空の場合はKeyとColumnsを読み込む
+	ReadColumns []string
+	// 一覧取得で並べ替えに使えるカラム
+	// 空の場合はKeyとColumnsで並べ替えられる
+	Sortable []string
+	// 一覧取得で絞り込みに使えるカラム
+	// 空の場合はKeyとColumnsで絞り込める
+	Filterable []string
+	// DBの既定値を使うカラム
+	// ゼロ値の場合はINSERTに含めず、DEFAULTの値を使う
+	Defaults []string
+	// 読み込み時にSQLの式で計算するカラム
+	// 書き込みには使わず、SELECTとRETURNINGで読み込む
+	// SortableとFilterableが空の場合は並べ替えと絞り込みにも使える
+	Computed []Computed
+	// 1件ごとのバリデーション
+	Validate func(*T) error
+	// 一覧取得でリクエストごとに除外する行の条件
+	// ?を置換文字とした条件と引数を返し、条件が空の場合は除外しない
+	// 指定した場合は除外した件数をレスポンスのwithheldで返す
+	Withhold func(r *http.Request, svc *service.IndexService) (string, []any, error)
+	// 1件をINSERTまたはUPDATEした後に同じトランザクション内で行う処理
+	// 関連するテーブルの同期などに使い、エラーの場合は書き込みを取り消す
+	// UPDATEで該当する行がない場合は呼ばない
+	AfterWrite func(ctx context.Context, driver db.Driver, item *T) error
+	// Keyがidsの行を削除する前に同じトランザクション内で行う処理
+	// 削除する行を参照している関連するテーブルの同期などに使い、エラーの場合は削除を取り消す
+	BeforeDelete func(ctx context.Context, driver db.Driver, ids []int64) error
+}
+
+// Computed はSQLの式で計算するカラム
+// Nameと同じdbタグのフィールドに読み込む
+type Computed struct {
+	Name string
+	Expr string
+}
+
+// readColumns はSELECTで読み込むカラムを返す
+func (res *Resource[T]) readColumns() []string {
+	if len(res.ReadColumns) > 0 {
+		return res.ReadColumns
+	}
+	return append(res.rowColumns(), res.computedColumns()...)
+}
+
+// computedColumns は計算するカラムの名前を返す
+func (res *Resource[T]) computedColumns() []string {
+	columns := make([]string, len(res.Computed))
+	for i, c := range res.Computed {
+		columns[i] = c.Name
+	}
+	return columns
+}
+
+// expr はWHEREやORDER BYでcolumnを参照する式を返す
+// 計算するカラムは別名を参照できないため式を返す
+func (res *Resource[T]) expr(column string) string {
+	for _, c := range res.Computed {
+		if c.Name == column {
+			return "(" + c.Expr + ")"
+		}
+	}
+	return column
+}
+
+// selectList はSELECTやRETURNINGで読み込むカラムの一覧を返す
+// 計算するカラムは式に別名を付ける
+func (res *Resource[T]) selectList(columns []string) string {
+	list := make([]string, len(columns))
+	for i, column := range columns {
+		if expr := res.expr(column); expr != column {
+			list[i] = expr + " AS " + column
+			continue
+		}
+		list[i] = column
+	}
+	return strings.Join(list, ", ")
+}
+
+// rowColumns はKeyとColumnsを合わせた1行分のカラムを返す
+func (res *Resource[T]) rowColumns() []string {
+	columns := make([]string, 0, len(res.Columns)+1)
+	if !res.hasKeyColumn() {
+		columns = append(columns, res.Key)
+	}
+	return append(columns, res.Columns...)
+}
+
+// hasKeyColumn はColumnsにKeyが含まれているかを返す
+func (res *Resource[T]) hasKeyColumn() bool {
+	return contains(res.Columns, res.Key)
+}
+
+// insertColumns はitemをINSERTする際のカラムを返す
+// Defaultsのカラムはゼロ値の場合に除く
+func (res *Resource[T]) insertColumns(item *T) []string {
+	if len(res.Defaults) == 0 {
+		return res.Columns
+	}
+	v := reflect.ValueOf(item).Elem()
+	columns := make([]string, 0, len(res.Columns))
+	for _, column := range res.Columns {
+		if contains(res.Defaults, column) {
+			if field, ok := fieldByColumn(v, column); ok && field.IsZero() {
+				continue
+			}
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// insertQuery はcolumnsをINSERTし、書き込んだ行を返すINSERT文を返す
+// 計算するカラムも合わせて返す
+func (res *Resource[T]) insertQuery(columns []string) string {
+	returning := res.selectList(append(res.rowColumns(), res.computedColumns()...))
+	if len(columns) == 0 {
+		return fmt.Sprintf("INSERT INTO %s DEFAULT VALUES RETURNING %s", res.Table, returning)
+	}
+	params := make([]string, len(columns))
+	for i, column := range columns {
+		params[i] = ":" + column
+	}
+	return fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s) RETURNING %s",
+		res.Table,
+		strings.Join(columns, ", "),
+		strings.Join(params, ", "),
+		returning,
+	)
+}
+
+// insert は1件をINSERTし、DBが採番したidや既定値を含む行をitemに書き戻す
+func (res *Resource[T]) insert(ctx context.Context, driver db.Driver, item *T) error {
+	query, args, err := sqlx.Named(res.insertQuery(res.insertColumns(item)), item)
+	if err != nil {
+		return err
+	}
+	// DBの種類に合わせて置換文字を変える
+	if err := driver.GetContext(ctx, item, driver.Rebind(query), args...); err != nil {
+		return err
+	}
+	return res.afterWrite(ctx, driver, item)
+}
+
+// Insert はハンドラを通さずに1件をバリデーションしてINSERTする
+// insertと同じくDBが採番したidや既定値をitemに書き戻し、AfterWriteも実行する
+func (res *Resource[T]) Insert(ctx context.Context, driver db.Driver, item *T) error {
+	if err := res.validateItem(item); err != nil {
+		return err
+	}
+	return res.insert(ctx, driver, item)
+}
+
+// updateQuery はKeyで1件を更新するUPDATE文を返す
+// 計算するカラムがある場合は更新後の値を返す
+func (res *Resource[T]) updateQuery() string {
+	sets := make([]string, 0, len(res.Columns))
+	for _, column := range res.Columns {
+		if column == res.Key {
+			continue
+		}
+		sets = append(sets, fmt.Sprintf("%s = :%s", column, column))
+	}
+	query := fmt.Sprintf(
+		"UPDATE %s SET %s WHERE %s = :%s",
+		res.Table,
+		strings.Join(sets, ", "),
+		res.Key,
+		res.Key,
+	)
+	if len(res.Computed) > 0 {
+		query += " RETURNING " + res.selectList(res.computedColumns())
+	}
+	return query
+}
+
+// update はKeyで1件を更新する
+// 計算するカラムはitemに書き戻す
+func (res *Resource[T]) update(ctx context.Context, driver db.Driver, item *T) error {
+	if len(res.Computed) == 0 {
+		result, err := driver.NamedExecContext(ctx, res.updateQuery(), item)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+		return res.afterWrite(ctx, driver, item)
+	}
+	query, args, err := sqlx.Named(res.updateQuery(), item)
+	if err != nil {
+		return err
+	}
+	// 該当する行がない場合は計算するカラムを書き戻さない
+	err = driver.GetContext(ctx, item, driver.Rebind(query), args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return res.afterWrite(ctx, driver, item)
+}
+
+// load はKeyがkeyの1行を読み込む
+// 該当する行がない場合はnilを返す
+func (res *Resource[T]) load(ctx context.Context, driver db.Driver, key int64) (*T, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", res.selectList(res.rowColumns()), res.Table, res.Key)
+	var items []T
+	// DBの種類に合わせて置換文字を変える
+	if err := driver.SelectContext(ctx, &items, driver.Rebind(query), key); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return &items[0], nil
+}
+
+// Update はハンドラを通さずに1件をバリデーションしてKeyで更新する
+// updateと同じく該当する行がある場合はAfterWriteも実行する
+func (res *Resource[T]) Update(ctx context.Context, driver db.Driver, item *T) error {
+	if err := res.validateItem(item); err != nil {
+		return err
+	}
+	return res.update(ctx, driver, item)
+}
+
+// afterWrite はAfterWriteが指定されている場合に実行する
+func (res *Resource[T]) afterWrite(ctx context.Context, driver db.Driver, item *T) error {
+	if res.AfterWrite == nil {
+		return nil
+	}
+	return res.AfterWrite(ctx, driver, item)
+}
+
+// beforeDelete はBeforeDeleteが指定されている場合に実行する
+func (res *Resource[T]) beforeDelete(ctx context.Context, driver db.Driver, ids []int64) error {
+	if res.BeforeDelete == nil {
+		return nil
+	}
+	return res.BeforeDelete(ctx, driver, ids)
+}
+
+// hideKey はReadColumnsに含まれないKeyをゼロ値に戻す
+// Keyはページングのカーソルのためだけに読み込む
+func (res *Resource[T]) hideKey(items []T) {
+	if len(res.ReadColumns) == 0 || contains(res.ReadColumns, res.Key) {
+		return
+	}
+	for i := range items {
+		_ = res.setKey(&items[i], 0)
+	}
+}
+
+// countQuery はwhereに一致する行数を数えるSELECT文と引数を返す
+func (res *Resource[T]) countQuery(dialect db.Dialect, where []string, args []any) (string, []any, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", res.Table)
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query, args, err := db.In(query, args...)
+	if err != nil {
+		return "", nil, err
+	}
+	return dialect.Rebind(query), args, nil
+}
+
+// deleteQuery はKeyのIN句で削除するDELETE文を返す
+func (res *Resource[T]) deleteQuery() string {
+	return fmt.Sprintf("DELETE FROM %s WHERE %s IN (?)", res.Table, res.Key)
+}
+
+// validateList はリクエストの一覧と1件ごとのバリデーションを行う
+func (res *Resource[T]) validateList(items []T) error {
+	if err := validation.Validate(items, validation.Required); err != nil {
+		return validation.Errors{res.ListKey: err}
+	}
+	errs := validation.Errors{}
+	for i := range items {
+		if err := res.validateItem(&items[i]); err != nil {
+			errs[res.itemField(i)] = err
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateItem は1件のバリデーションを行う
+func (res *Resource[T]) validateItem(item *T) error {
+	if res.Validate == nil {
+		return nil
+	}
+	return res.Validate(item)
+}
+
+// itemField はエラーを返す際のi件目のフィールド名を返す (例: entries[3])
+func (res *Resource[T]) itemField(i int) string {
+	return fmt.Sprintf("%s[%d]", res.ListKey, i)
+}
+
+// setKey はKeyに対応するフィールドにidを設定する
+func (res *Resource[T]) setKey(item *T, id int64) error {
+	field, ok := fieldByColumn(reflect.ValueOf(item).Elem(), res.Key)
+	if !ok {
+		return fmt.Errorf("%T has no field for column %s", *item, res.Key)
+	}
+	switch field.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		field.SetInt(id)
+	default:
+		return fmt.Errorf("column %s of %T is not an integer", res.Key, *item)
+	}
+	return nil
+}
+
+// key はKeyに対応するフィールドの値を返す
+func (res *Resource[T]) key(item *T) (int64, error) {
+	field, ok := fieldByColumn(reflect.ValueOf(item).Elem(), res.Key)
+	if !ok {
+		return 0, fmt.Errorf("%T has no field for column %s", *item, res.Key)
+	}
+	switch field.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return field.Int(), nil
+	}
+	return 0, fmt.Errorf("column %s of %T is not an integer", res.Key, *item)
+}
+
+// parseKeys はクエリパラメータのKeyを整数に変換する
+// 整数でない値はDBに渡さず、バリデーションエラーとする
+func parseKeys(values []string) ([]int64, error) {
+	keys := make([]int64, len(values))
+	for i, v := range values {
+		key, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be an integer: %q", v)
+		}
+		keys[i] = key
+	}
+	return keys, nil
+}
+
+// fieldByColumn はdbタグがcolumnのフィールドを返す
+func fieldByColumn(v reflect.Value, column string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("db") == column {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
@@ -0,0 +1,277 @@
+package resource
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/maguro-alternative/goheki/pkg/db"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/stretchr/testify/assert"
+)
+
+type testEntry struct {
+	ID       int64  `db:"id"`
+	SourceID int64  `db:"source_id"`
+	Name     string `db:"name"`
+}
+
+type testLink struct {
+	ID        int64     `db:"id"`
+	EntryID   int64     `db:"entry_id"`
+	Type      string    `db:"type"`
+	Nsfw      bool      `db:"nsfw"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type testBWH struct {
+	EntryID int64 `db:"entry_id"`
+	Bust    int64 `db:"bust"`
+}
+
+type testComputedBWH struct {
+	EntryID int64    `db:"entry_id"`
+	Waist   int64    `db:"waist"`
+	Hip     int64    `db:"hip"`
+	Ratio   *float64 `db:"ratio"`
+}
+
+func TestResourceQuery(t *testing.T) {
+	entry := &Resource[testEntry]{
+		Table:   "entry",
+		Key:     "id",
+		ListKey: "entries",
+		Columns: []string{"source_id", "name"},
+	}
+	bwh := &Resource[testBWH]{
+		Table:   "bwh",
+		Key:     "entry_id",
+		ListKey: "bwhs",
+		Columns: []string{"entry_id", "bust"},
+	}
+
+	t.Run("連番のKey", func(t *testing.T) {
+		assert.Equal(t, "INSERT INTO entry (source_id, name) VALUES (:source_id, :name) RETURNING id, source_id, name", entry.insertQuery(entry.Columns))
+		assert.Equal(t, "UPDATE entry SET source_id = :source_id, name = :name WHERE id = :id", entry.updateQuery())
+		assert.Equal(t, "DELETE FROM entry WHERE id IN (?)", entry.deleteQuery())
+	})
+
+	t.Run("Columnsに含まれるKey", func(t *testing.T) {
+		assert.Equal(t, "INSERT INTO bwh (entry_id, bust) VALUES (:entry_id, :bust) RETURNING entry_id, bust", bwh.insertQuery(bwh.Columns))
+		assert.Equal(t, "UPDATE bwh SET bust = :bust WHERE entry_id = :entry_id", bwh.updateQuery())
+	})
+
+	t.Run("ゼロ値のDefaultsはINSERTしない", func(t *testing.T) {
+		defaults := &Resource[testEntry]{
+			Table:    "entry",
+			Key:      "id",
+			Columns:  []string{"source_id", "name"},
+			Defaults: []string{"name"},
+		}
+		assert.Equal(t, []string{"source_id"}, defaults.insertColumns(&testEntry{SourceID: 1}))
+		assert.Equal(t, []string{"source_id", "name"}, defaults.insertColumns(&testEntry{SourceID: 1, Name: "a"}))
+		assert.Equal(t, "INSERT INTO entry DEFAULT VALUES RETURNING id, source_id, name", defaults.insertQuery(nil))
+	})
+
+	t.Run("Keyの設定", func(t *testing.T) {
+		var e testEntry
+		assert.NoError(t, entry.setKey(&e, 3))
+		assert.Equal(t, int64(3), e.ID)
+	})
+
+	t.Run("空の一覧はバリデーションエラー", func(t *testing.T) {
+		assert.EqualError(t, entry.validateList(nil), "entries: cannot be blank.")
+	})
+
+	t.Run("失敗したすべての行を返す", func(t *testing.T) {
+		named := &Resource[testEntry]{
+			ListKey: "entries",
+			Validate: func(e *testEntry) error {
+				if e.Name == "" {
+					return errors.New("name is required")
+				}
+				return nil
+			},
+		}
+		err := named.validateList([]testEntry{{Name: ""}, {Name: "a"}, {Name: ""}})
+		assert.EqualError(t, err, "entries[0]: name is required; entries[2]: name is required.")
+	})
+}
+
+func TestResourcePage(t *testing.T) {
+	entry := &Resource[testEntry]{
+		Table:       "entry",
+		Key:         "id",
+		ListKey:     "entries",
+		Columns:     []string{"source_id", "name"},
+		ReadColumns: []string{"source_id", "name"},
+	}
+
+	t.Run("既定はKeyの昇順", func(t *testing.T) {
+		p, err := entry.parsePage(url.Values{})
+		assert.NoError(t, err)
+		query, args, err := entry.listQuery(db.Postgres, p, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "SELECT source_id, name, id FROM entry ORDER BY id LIMIT 101", query)
+		assert.Empty(t, args)
+	})
+
+	t.Run("カーソルの続きから並べ替えて取得", func(t *testing.T) {
+		cursor, err := entry.nextCursor(&page{sort: "name"}, &testEntry{ID: 7, Name: "雪泉"})
+		assert.NoError(t, err)
+		p, err := entry.parsePage(url.Values{
+			"sort":  {"-name"},
+			"limit": {"20"},
+			"after": {cursor},
+		})
+		assert.NoError(t, err)
+		query, args, err := entry.listQuery(db.Postgres, p, []string{"id IN (?)"}, []any{[]string{"1", "7"}})
+		assert.NoError(t, err)
+		assert.Equal(t, "SELECT source_id, name, id FROM entry WHERE id IN ($1, $2) AND (name, id) < ($3, $4) ORDER BY name DESC, id DESC LIMIT 21", query)
+		assert.Equal(t, []any{"1", "7", "雪泉", int64(7)}, args)
+	})
+
+	t.Run("不正な指定はバリデーションエラー", func(t *testing.T) {
+		_, err := entry.parsePage(url.Values{
+			"limit":  {"0"},
+			"sort":   {"content"},
+			"after":  {"!!"},
+			"fields": {"name,unknown"},
+		})
+		assert.Equal(t, []string{"after", "fields", "limit", "sort"}, errorKeys(err))
+	})
+
+	t.Run("返すフィールドはjsonのキーかカラム名で指定する", func(t *testing.T) {
+		p, err := entry.parsePage(url.Values{"fields": {"Name,source_id"}})
+		assert.NoError(t, err)
+		shaped, err := selectFields([]testEntry{{ID: 1, SourceID: 2, Name: "a"}}, p.fields)
+		assert.NoError(t, err)
+		assert.Len(t, shaped, 1)
+		assert.JSONEq(t, `"a"`, string(shaped[0]["Name"]))
+		assert.JSONEq(t, `2`, string(shaped[0]["SourceID"]))
+		assert.NotContains(t, shaped[0], "ID")
+	})
+}
+
+func errorKeys(err error) []string {
+	var keys []string
+	for key := range err.(validation.Errors) {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestResourceFilter(t *testing.T) {
+	link := &Resource[testLink]{
+		Table:   "link",
+		Key:     "id",
+		ListKey: "links",
+		Columns: []string{"entry_id", "type", "nsfw", "created_at"},
+	}
+
+	t.Run("演算子ごとの条件", func(t *testing.T) {
+		filters, err := link.parseFilters(url.Values{
+			"id":                {"3"},
+			"limit":             {"10"},
+			"entry_id":          {"1", "2"},
+			"nsfw":              {"false"},
+			"type[like]":        {"blog%"},
+			"created_at[gte]":   {"2023-12-01"},
+			"id[in]":            {"4,5"},
+			"unknown_parameter": {"x"},
+		})
+		assert.NoError(t, err)
+		var where []string
+		var args []any
+		for _, f := range filters {
+			cond, values := f.where()
+			where = append(where, cond)
+			args = append(args, values...)
+		}
+		assert.Equal(t, []string{
+			"created_at >= ?",
+			"entry_id IN (?)",
+			"id IN (?)",
+			"nsfw = ?",
+			"type LIKE ?",
+		}, where)
+		assert.Equal(t, []any{
+			time.Date(2023, time.December, 1, 0, 0, 0, 0, time.UTC),
+			[]any{int64(1), int64(2)},
+			[]any{int64(4), int64(5)},
+			false,
+			"blog%",
+		}, args)
+	})
+
+	t.Run("不正な指定はバリデーションエラー", func(t *testing.T) {
+		_, err := link.parseFilters(url.Values{
+			"entry_id[between]": {"1"},
+			"name[eq]":          {"a"},
+			"nsfw":              {"maybe"},
+			"entry_id[like]":    {"1%"},
+		})
+		assert.Equal(t, []string{"entry_id[between]", "entry_id[like]", "name[eq]", "nsfw"}, errorKeys(err))
+	})
+}
+
+func TestResourceComputed(t *testing.T) {
+	bwh := &Resource[testComputedBWH]{
+		Table:   "bwh",
+		Key:     "entry_id",
+		ListKey: "bwhs",
+		Columns: []string{"entry_id", "waist", "hip"},
+		Computed: []Computed{
+			{Name: "ratio", Expr: "waist * 1.0 / hip"},
+		},
+	}
+
+	t.Run("書き込んだ行と合わせて計算したカラムを返す", func(t *testing.T) {
+		assert.Equal(t, "INSERT INTO bwh (entry_id, waist, hip) VALUES (:entry_id, :waist, :hip) RETURNING entry_id, waist, hip, (waist * 1.0 / hip) AS ratio", bwh.insertQuery(bwh.Columns))
+		assert.Equal(t, "UPDATE bwh SET waist = :waist, hip = :hip WHERE entry_id = :entry_id RETURNING (waist * 1.0 / hip) AS ratio", bwh.updateQuery())
+	})
+
+	t.Run("計算したカラムで並べ替えて取得", func(t *testing.T) {
+		ratio := 0.7
+		cursor, err := bwh.nextCursor(&page{sort: "ratio"}, &testComputedBWH{EntryID: 2, Ratio: &ratio})
+		assert.NoError(t, err)
+		p, err := bwh.parsePage(url.Values{"sort": {"ratio"}, "after": {cursor}})
+		assert.NoError(t, err)
+		query, args, err := bwh.listQuery(db.Postgres, p, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "SELECT entry_id, waist, hip, (waist * 1.0 / hip) AS ratio FROM bwh WHERE (((waist * 1.0 / hip), entry_id) > ($1, $2) OR (waist * 1.0 / hip) IS NULL) ORDER BY (waist * 1.0 / hip) NULLS LAST, entry_id LIMIT 101", query)
+		assert.Equal(t, []any{0.7, int64(2)}, args)
+	})
+
+	t.Run("NULLの行の続きから取得", func(t *testing.T) {
+		cursor, err := bwh.nextCursor(&page{sort: "ratio"}, &testComputedBWH{EntryID: 2})
+		assert.NoError(t, err)
+		p, err := bwh.parsePage(url.Values{"sort": {"ratio"}, "after": {cursor}})
+		assert.NoError(t, err)
+		query, args, err := bwh.listQuery(db.Postgres, p, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "SELECT entry_id, waist, hip, (waist * 1.0 / hip) AS ratio FROM bwh WHERE ((waist * 1.0 / hip) IS NULL AND entry_id > $1) ORDER BY (waist * 1.0 / hip) NULLS LAST, entry_id LIMIT 101", query)
+		assert.Equal(t, []any{int64(2)}, args)
+
+		p, err = bwh.parsePage(url.Values{"sort": {"-ratio"}, "after": {cursor}})
+		assert.NoError(t, err)
+		query, args, err = bwh.listQuery(db.Postgres, p, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "SELECT entry_id, waist, hip, (waist * 1.0 / hip) AS ratio FROM bwh WHERE (((waist * 1.0 / hip) IS NULL AND entry_id < $1) OR (waist * 1.0 / hip) IS NOT NULL) ORDER BY (waist * 1.0 / hip) DESC NULLS FIRST, entry_id DESC LIMIT 101", query)
+		assert.Equal(t, []any{int64(2)}, args)
+	})
+
+	t.Run("計算したカラムで絞り込む", func(t *testing.T) {
+		filters, err := bwh.parseFilters(url.Values{"ratio[lt]": {"0.7"}})
+		assert.NoError(t, err)
+		if assert.Len(t, filters, 1) {
+			cond, values := filters[0].where()
+			assert.Equal(t, "(waist * 1.0 / hip) < ?", cond)
+			assert.Equal(t, []any{0.7}, values)
+		}
+	})
+}
@@ -0,0 +1,294 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/maguro-alternative/goheki/internal/app/goheki/problem"
+
+	"github.com/justinas/alice"
+)
+
+// apiPrefix はAPIのパスの接頭辞
+const apiPrefix = "/api/"
+
+// Resource はREST形式で公開するリソースの定義
+type Resource struct {
+	// コレクションのパス名 (例: entries → /api/entries)
+	Name string
+	// 旧形式のパス名 (例: entry → /api/entry/create)
+	// 空の場合は旧形式のパスを登録しない
+	LegacyName string
+	// 1件を指定するクエリパラメータ名 (id または entry_id)
+	// 空の場合は/api/{name}/{id}を登録しない (検索などコレクションのみのリソース)
+	Key string
+
+	Create http.Handler
+	Read   http.Handler
+	Update http.Handler
+	Delete http.Handler
+
+	// 1件に対する読み込み専用のサブリソース (例: profile → /api/entries/{id}/profile)
+	// ハンドラにはReadと同様にパスの{id}をクエリパラメータKeyとして渡す
+	Subresources map[string]http.Handler
+	// 1件に対する操作 (例: unfurl → POST /api/links/{id}/unfurl)
+	// ハンドラにはSubresourcesと同様にパスの{id}をクエリパラメータKeyとして渡す
+	Actions map[string]http.Handler
+}
+
+// Router はリソースをhttp.ServeMuxに登録する
+type Router struct {
+	mux    *http.ServeMux
+	chain  alice.Chain
+	routes []Route
+}
+
+// Route は登録したパスと受け付けるメソッド
+type Route struct {
+	Path       string
+	Methods    []string
+	Deprecated bool
+}
+
+func New(mux *http.ServeMux, chain alice.Chain) *Router {
+	return &Router{
+		mux:   mux,
+		chain: chain,
+	}
+}
+
+// Mount は複数のリソースのルーティングを登録する
+func (rt *Router) Mount(resources ...Resource) {
+	for _, res := range resources {
+		rt.Handle(res)
+	}
+}
+
+// Routes は登録したルーティングの一覧を返す
+func (rt *Router) Routes() []Route {
+	return rt.routes
+}
+
+type pathIDKey struct{}
+
+// PathID はパスに含まれる{id}を返す
+// /api/entries/{id} 以外のリクエストでは空文字を返す
+func PathID(r *http.Request) string {
+	id, _ := r.Context().Value(pathIDKey{}).(string)
+	return id
+}
+
+// Handle はリソースのルーティングを登録する
+//
+//	GET    /api/{name}      → Read (全件または?id=で絞り込み)
+//	POST   /api/{name}      → Create
+//	PUT    /api/{name}      → Update (一括)
+//	PATCH  /api/{name}      → Update (一括、送ったフィールドのみ)
+//	DELETE /api/{name}      → Delete (一括)
+//	GET    /api/{name}/{id} → Read
+//	PUT    /api/{name}/{id} → Update
+//	PATCH  /api/{name}/{id} → Update (送ったフィールドのみ)
+//	DELETE /api/{name}/{id} → Delete
+//	GET    /api/{name}/{id}/{sub} → Subresources[sub]
+//	POST   /api/{name}/{id}/{sub} → Actions[sub]
+//
+// LegacyNameが指定されている場合は旧形式のパスも非推奨の別名として登録する
+func (rt *Router) Handle(res Resource) {
+	collectionPath := apiPrefix + res.Name
+	collection := methods{
+		http.MethodGet:    res.Read,
+		http.MethodPost:   res.Create,
+		http.MethodPut:    res.Update,
+		http.MethodPatch:  res.Update,
+		http.MethodDelete: res.Delete,
+	}
+	rt.handle(collectionPath, rt.chain.Then(collection), collection, false)
+	if res.Key != "" {
+		rt.handleItem(collectionPath, res)
+	}
+
+	if res.LegacyName == "" {
+		return
+	}
+	legacyPath := apiPrefix + res.LegacyName
+	legacy := []struct {
+		suffix  string
+		methods methods
+	}{
+		{"/create", methods{http.MethodPost: res.Create}},
+		{"/read", methods{http.MethodGet: res.Read}},
+		{"/update", methods{http.MethodPut: res.Update}},
+		{"/delete", methods{http.MethodDelete: res.Delete}},
+	}
+	for _, l := range legacy {
+		// ハンドラがない場合は登録しない
+		if len(l.methods.list()) == 0 {
+			continue
+		}
+		rt.handle(legacyPath+l.suffix, rt.chain.Append(deprecated(collectionPath)).Then(l.methods), l.methods, true)
+	}
+}
+
+// handleItem は/api/{name}/{id}と/api/{name}/{id}/{sub}を登録する
+func (rt *Router) handleItem(collectionPath string, res Resource) {
+	item := methods{
+		http.MethodGet:    withQueryID(res.Key, res.Read),
+		http.MethodPut:    res.Update,
+		http.MethodPatch:  res.Update,
+		http.MethodDelete: withBodyID(res.Delete),
+	}
+	subresources := make(map[string]methods, len(res.Subresources)+len(res.Actions))
+	for sub, h := range res.Subresources {
+		subresources[sub] = methods{http.MethodGet: withQueryID(res.Key, h)}
+	}
+	// 同じ名前のサブリソースがある場合はGETとPOSTの両方を受け付ける
+	for sub, h := range res.Actions {
+		if _, ok := subresources[sub]; !ok {
+			subresources[sub] = methods{}
+		}
+		subresources[sub][http.MethodPost] = withQueryID(res.Key, h)
+	}
+	rt.handle(collectionPath+"/{id}", rt.chain.Then(&itemHandler{
+		prefix:       collectionPath + "/",
+		methods:      item,
+		subresources: subresources,
+	}), item, false)
+	for _, sub := range sortedKeys(subresources) {
+		rt.record(collectionPath+"/{id}/"+sub, subresources[sub], false)
+	}
+}
+
+// handle はhttp.ServeMuxに登録し、登録したルーティングを記録する
+// パスの{id}はhttp.ServeMuxの部分一致のパターンに置き換える
+func (rt *Router) handle(path string, h http.Handler, m methods, deprecated bool) {
+	rt.mux.Handle(strings.TrimSuffix(path, "{id}"), h)
+	rt.record(path, m, deprecated)
+}
+
+// record は登録したルーティングを記録する
+func (rt *Router) record(path string, m methods, deprecated bool) {
+	rt.routes = append(rt.routes, Route{
+		Path:       path,
+		Methods:    m.list(),
+		Deprecated: deprecated,
+	})
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// methods はHTTPメソッドごとのハンドラ
+// 登録されていないメソッドには405とAllowヘッダーを返す
+type methods map[string]http.Handler
+
+func (m methods) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h, ok := m[r.Method]
+	if !ok || h == nil {
+		w.Header().Set("Allow", m.allow())
+		problem.MethodNotAllowed(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	h.ServeHTTP(w, r)
+}
+
+// list はハンドラが登録されているメソッドを返す
+func (m methods) list() []string {
+	allowed := make([]string, 0, len(m))
+	for method, h := range m {
+		if h != nil {
+			allowed = append(allowed, method)
+		}
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+// allow はAllowヘッダーの値を返す
+func (m methods) allow() string {
+	return strings.Join(m.list(), ", ")
+}
+
+// itemHandler は/api/{name}/{id}と/api/{name}/{id}/{sub}を処理する
+type itemHandler struct {
+	prefix       string
+	methods      methods
+	subresources map[string]methods
+}
+
+func (h *itemHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, sub, hasSub := strings.Cut(strings.TrimPrefix(r.URL.Path, h.prefix), "/")
+	// idは数値のみ受け付ける
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		problem.NotFound(w, r, fmt.Errorf("invalid id %q", id))
+		return
+	}
+	next := h.methods
+	if hasSub {
+		m, ok := h.subresources[sub]
+		if !ok {
+			problem.NotFound(w, r, fmt.Errorf("unknown resource %q", sub))
+			return
+		}
+		next = m
+	}
+	ctx := context.WithValue(r.Context(), pathIDKey{}, id)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// withQueryID はパスの{id}をクエリパラメータkeyとしてハンドラに渡す
+func withQueryID(key string, next http.Handler) http.Handler {
+	if next == nil {
+		return nil
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r2 := r.Clone(r.Context())
+		query := r2.URL.Query()
+		query.Set(key, PathID(r))
+		r2.URL.RawQuery = query.Encode()
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// withBodyID はパスの{id}を{"ids":[id]}としてハンドラに渡す
+func withBodyID(next http.Handler) http.Handler {
+	if next == nil {
+		return nil
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// PathIDはitemHandlerで数値であることを確認済み
+		id, _ := strconv.ParseInt(PathID(r), 10, 64)
+		body, err := json.Marshal(map[string][]int64{"ids": {id}})
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		r2 := r.Clone(r.Context())
+		r2.Body = io.NopCloser(bytes.NewReader(body))
+		r2.ContentLength = int64(len(body))
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// deprecated は旧形式のパスに非推奨であることを示すヘッダーを付与する
+func deprecated(successor string) alice.Constructor {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
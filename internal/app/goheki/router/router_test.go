@@ -0,0 +1,170 @@
+package router
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/justinas/alice"
+	"github.com/stretchr/testify/assert"
+)
+
+// echoHandler はハンドラ名とクエリ、ボディを返す
+func echoHandler(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Handler", name)
+		w.Header().Set("X-Query", r.URL.RawQuery)
+		w.Header().Set("X-Path-ID", PathID(r))
+		_, _ = w.Write(body)
+	})
+}
+
+func newTestMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	New(mux, alice.New()).Handle(Resource{
+		Name:       "entries",
+		LegacyName: "entry",
+		Key:        "id",
+		Create:     echoHandler("create"),
+		Read:       echoHandler("read"),
+		Update:     echoHandler("update"),
+		Delete:     echoHandler("delete"),
+		Subresources: map[string]http.Handler{
+			"profile": echoHandler("profile"),
+		},
+		Actions: map[string]http.Handler{
+			"refresh": echoHandler("refresh"),
+		},
+	})
+	return mux
+}
+
+func TestRouter(t *testing.T) {
+	mux := newTestMux()
+
+	t.Run("コレクションへのルーティング", func(t *testing.T) {
+		cases := map[string]string{
+			http.MethodGet:    "read",
+			http.MethodPost:   "create",
+			http.MethodPut:    "update",
+			http.MethodPatch:  "update",
+			http.MethodDelete: "delete",
+		}
+		for method, expected := range cases {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(method, "/api/entries", nil))
+			assert.Equal(t, http.StatusOK, w.Code, method)
+			assert.Equal(t, expected, w.Header().Get("X-Handler"), method)
+		}
+	})
+
+	t.Run("1件取得はidをクエリパラメータで渡す", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/entries/3", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "read", w.Header().Get("X-Handler"))
+		assert.Equal(t, "id=3", w.Header().Get("X-Query"))
+		assert.Equal(t, "3", w.Header().Get("X-Path-ID"))
+	})
+
+	t.Run("1件削除はidをボディで渡す", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/entries/3", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "delete", w.Header().Get("X-Handler"))
+		assert.JSONEq(t, `{"ids":[3]}`, w.Body.String())
+	})
+
+	t.Run("サブリソースはidをクエリパラメータで渡す", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/entries/3/profile", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "profile", w.Header().Get("X-Handler"))
+		assert.Equal(t, "id=3", w.Header().Get("X-Query"))
+
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/entries/3/unknown", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/entries/3/profile", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+
+	t.Run("操作はPOSTでidをクエリパラメータで渡す", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/entries/3/refresh", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "refresh", w.Header().Get("X-Handler"))
+		assert.Equal(t, "id=3", w.Header().Get("X-Query"))
+
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/entries/3/refresh", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, http.MethodPost, w.Header().Get("Allow"))
+
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/entries/3/profile", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+
+	t.Run("数値でないidは404", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/entries/aaa", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	})
+
+	t.Run("許可されていないメソッドは405", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/entries/3", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, "DELETE, GET, PATCH, PUT", w.Header().Get("Allow"))
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	})
+
+	t.Run("旧形式のパス", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/entry/create", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "create", w.Header().Get("X-Handler"))
+		assert.Equal(t, "true", w.Header().Get("Deprecation"))
+		assert.Equal(t, `</api/entries>; rel="successor-version"`, w.Header().Get("Link"))
+	})
+
+	t.Run("旧形式のパスで許可されていないメソッドは405", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/entry/create", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, http.MethodPost, w.Header().Get("Allow"))
+	})
+}
+
+func TestRoutes(t *testing.T) {
+	rt := New(http.NewServeMux(), alice.New())
+	rt.Mount(
+		Resource{Name: "entries", LegacyName: "entry", Key: "id", Read: echoHandler("read"), Subresources: map[string]http.Handler{
+			"profile": echoHandler("profile"),
+			"radar":   echoHandler("radar"),
+		}, Actions: map[string]http.Handler{
+			"radar":   echoHandler("radar"),
+			"refresh": echoHandler("refresh"),
+		}},
+		Resource{Name: "eyecolors", Key: "entry_id", Read: echoHandler("read"), Create: echoHandler("create")},
+		Resource{Name: "search", Read: echoHandler("search")},
+	)
+
+	assert.Equal(t, []Route{
+		{Path: "/api/entries", Methods: []string{"GET"}},
+		{Path: "/api/entries/{id}", Methods: []string{"GET"}},
+		{Path: "/api/entries/{id}/profile", Methods: []string{"GET"}},
+		{Path: "/api/entries/{id}/radar", Methods: []string{"GET", "POST"}},
+		{Path: "/api/entries/{id}/refresh", Methods: []string{"POST"}},
+		{Path: "/api/entry/read", Methods: []string{"GET"}, Deprecated: true},
+		{Path: "/api/eyecolors", Methods: []string{"GET", "POST"}},
+		{Path: "/api/eyecolors/{id}", Methods: []string{"GET"}},
+		{Path: "/api/search", Methods: []string{"GET"}},
+	}, rt.Routes())
+}
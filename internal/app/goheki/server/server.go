@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+)
+
+// Config はサーバーの設定
+type Config struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// シャットダウン時に処理中のリクエストを待つ時間
+	ShutdownTimeout time.Duration
+}
+
+// NewConfig は環境変数からサーバーの設定を生成する
+// 指定されていない値は既定値を使う
+func NewConfig(env *envconfig.Env) (*Config, error) {
+	port := env.ServerPort
+	// ポート番号が指定されていない場合は8080で起動する
+	if port == "" {
+		port = "8080"
+	}
+	cfg := &Config{
+		Addr:              ":" + port,
+		ReadTimeout:       15 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		ShutdownTimeout:   20 * time.Second,
+	}
+	durations := []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"SERVER_READ_TIMEOUT", env.ServerReadTimeout, &cfg.ReadTimeout},
+		{"SERVER_WRITE_TIMEOUT", env.ServerWriteTimeout, &cfg.WriteTimeout},
+		{"SERVER_IDLE_TIMEOUT", env.ServerIdleTimeout, &cfg.IdleTimeout},
+		{"SERVER_SHUTDOWN_TIMEOUT", env.ServerShutdownTimeout, &cfg.ShutdownTimeout},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", d.name, err)
+		}
+		*d.dest = v
+	}
+	return cfg, nil
+}
+
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Server はhttp.Serverとバックグラウンドのワーカーの起動と停止を管理する
+//
+// シャットダウンは以下の順に行う
+//  1. 新しいリクエストの受付を止め、処理中のリクエストの完了を待つ
+//  2. ワーカーのcontextをキャンセルし、終了を待つ
+//  3. OnShutdownで登録した処理を登録と逆順に実行する (DBの切断など)
+type Server struct {
+	cfg   Config
+	http  *http.Server
+	hooks []hook
+
+	workerCtx    context.Context
+	cancelWorker context.CancelFunc
+	workers      sync.WaitGroup
+}
+
+func New(cfg Config, handler http.Handler) *Server {
+	workerCtx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		cfg: cfg,
+		http: &http.Server{
+			Addr:              cfg.Addr,
+			Handler:           handler,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+		},
+		workerCtx:    workerCtx,
+		cancelWorker: cancel,
+	}
+}
+
+// Go はバックグラウンドで動作するワーカーを起動する
+// ワーカーに渡すcontextはシャットダウン時にキャンセルされる
+func (s *Server) Go(name string, worker func(ctx context.Context) error) {
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		if err := worker(s.workerCtx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("worker %s stopped: %v", name, err)
+		}
+	}()
+}
+
+// OnShutdown はシャットダウン時に実行する処理を登録する
+// 登録した順と逆順に実行する
+func (s *Server) OnShutdown(name string, fn func(ctx context.Context) error) {
+	s.hooks = append(s.hooks, hook{name: name, fn: fn})
+}
+
+// Run はConfig.Addrで待ち受け、ctxがキャンセルされるとシャットダウンする
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return s.shutdown(err)
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve はlnで待ち受け、ctxがキャンセルされるとシャットダウンする
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.http.Serve(ln)
+	}()
+
+	var err error
+	select {
+	case <-ctx.Done():
+		log.Println("shutting down server...")
+	case err = <-serveErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+	}
+	return s.shutdown(err)
+}
+
+// shutdown はサーバー、ワーカー、登録した処理の順に停止する
+func (s *Server) shutdown(cause error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+
+	errs := []error{cause}
+	// 処理中のリクエストの完了を待つ
+	if err := s.http.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("http server shutdown: %w", err))
+	}
+
+	// ワーカーの終了を待つ
+	s.cancelWorker()
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("workers shutdown: %w", ctx.Err()))
+	}
+
+	for i := len(s.hooks) - 1; i >= 0; i-- {
+		if err := s.hooks[i].fn(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s shutdown: %w", s.hooks[i].name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewConfig(t *testing.T) {
+	t.Run("既定値", func(t *testing.T) {
+		cfg, err := NewConfig(&envconfig.Env{})
+		assert.NoError(t, err)
+		assert.Equal(t, ":8080", cfg.Addr)
+		assert.Equal(t, 20*time.Second, cfg.ShutdownTimeout)
+	})
+
+	t.Run("環境変数の指定", func(t *testing.T) {
+		cfg, err := NewConfig(&envconfig.Env{
+			ServerPort:            "3000",
+			ServerReadTimeout:     "3s",
+			ServerShutdownTimeout: "1m",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, ":3000", cfg.Addr)
+		assert.Equal(t, 3*time.Second, cfg.ReadTimeout)
+		assert.Equal(t, time.Minute, cfg.ShutdownTimeout)
+	})
+
+	t.Run("不正な値", func(t *testing.T) {
+		_, err := NewConfig(&envconfig.Env{ServerWriteTimeout: "aaa"})
+		assert.Error(t, err)
+	})
+}
+
+func TestServerShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = io.WriteString(w, "done")
+	})
+
+	srv := New(Config{ShutdownTimeout: 5 * time.Second}, handler)
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, name)
+	}
+	srv.Go("worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		record("worker")
+		return ctx.Err()
+	})
+	srv.OnShutdown("db", func(ctx context.Context) error {
+		record("db")
+		return nil
+	})
+	srv.OnShutdown("cache", func(ctx context.Context) error {
+		record("cache")
+		return nil
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(ctx, ln)
+	}()
+
+	// 処理中のリクエストがある状態でシャットダウンする
+	resErr := make(chan error, 1)
+	var body string
+	go func() {
+		res, err := http.Get("http://" + ln.Addr().String())
+		if err == nil {
+			b, _ := io.ReadAll(res.Body)
+			res.Body.Close()
+			body = string(b)
+		}
+		resErr <- err
+	}()
+	<-started
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	assert.NoError(t, <-resErr)
+	assert.Equal(t, "done", body)
+	assert.NoError(t, <-serveErr)
+	assert.Equal(t, []string{"worker", "cache", "db"}, order)
+}
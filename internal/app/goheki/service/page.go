@@ -2,6 +2,7 @@ package service
 
 import (
 	"github.com/maguro-alternative/goheki/configs/envconfig"
+	"github.com/maguro-alternative/goheki/internal/app/goheki/media"
 	"github.com/maguro-alternative/goheki/pkg/db"
 
 	//"github.com/jmoiron/sqlx"
@@ -12,6 +13,8 @@ type IndexService struct {
 	DB             db.Driver
 	CookieStore    *sessions.CookieStore
 	Env            *envconfig.Env
+	// アップロードされた画像の保存先 (設定されていない場合はアップロードできない)
+	Images         *media.Uploader
 }
 
 // NewTODOService returns new TODOService.
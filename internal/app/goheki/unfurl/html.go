@@ -0,0 +1,140 @@
+package unfurl
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+var (
+	// 属性の値に>を含むタグは想定しない
+	tagPattern   = regexp.MustCompile(`(?is)<(meta|link)\b([^>]*)>`)
+	attrPattern  = regexp.MustCompile(`(?s)([a-zA-Z_:.-]+)\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+	titlePattern = regexp.MustCompile(`(?is)<title\b[^>]*>(.*?)</title>`)
+	// scriptやコメントの中のタグは読まない
+	ignorePattern = regexp.MustCompile(`(?is)<!--.*?-->|<script\b.*?</script>|<style\b.*?</style>`)
+	headEnd       = regexp.MustCompile(`(?i)</head>|<body\b`)
+	// <meta charset="shift_jis"> と <meta http-equiv="Content-Type" content="text/html; charset=shift_jis">
+	charsetPattern = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?([\w.:-]+)`)
+)
+
+// page はHTMLのheadから読み込んだ値
+type page struct {
+	// property (OpenGraph) またはname (Twitter Cardなど) を小文字にしたキー → 最初のcontent
+	meta  map[string]string
+	title string
+	// oEmbed (json) のエンドポイント
+	oembed string
+}
+
+// decode はcharsetに従ってUTF-8に変換する
+// Content-Typeで指定がない場合はmetaタグの指定を使い、どちらもない場合はUTF-8とみなす
+func decode(body []byte, charset string) (string, error) {
+	if charset == "" {
+		if m := charsetPattern.FindSubmatch(body); m != nil {
+			charset = string(m[1])
+		}
+	}
+	if charset == "" {
+		return string(body), nil
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		// 不明な文字コードはUTF-8として読む
+		return string(body), nil
+	}
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+// parseHTML はheadのmeta、link、titleを読み込む
+// 正しくないHTMLでも読めるよう、HTMLとして解釈せずにタグを探す
+func parseHTML(text string) *page {
+	text = ignorePattern.ReplaceAllString(text, "")
+	if loc := headEnd.FindStringIndex(text); loc != nil {
+		text = text[:loc[0]]
+	}
+	p := &page{meta: map[string]string{}}
+	for _, tag := range tagPattern.FindAllStringSubmatch(text, -1) {
+		attrs := parseAttrs(tag[2])
+		switch strings.ToLower(tag[1]) {
+		case "meta":
+			key := attrs["property"]
+			if key == "" {
+				key = attrs["name"]
+			}
+			key = strings.ToLower(key)
+			if _, ok := p.meta[key]; key != "" && !ok {
+				p.meta[key] = attrs["content"]
+			}
+		case "link":
+			if p.oembed == "" && strings.EqualFold(attrs["type"], "application/json+oembed") {
+				p.oembed = attrs["href"]
+			}
+		}
+	}
+	if m := titlePattern.FindStringSubmatch(text); m != nil {
+		p.title = m[1]
+	}
+	return p
+}
+
+// parseAttrs は属性を小文字の名前 → 値にする
+func parseAttrs(s string) map[string]string {
+	attrs := map[string]string{}
+	for _, m := range attrPattern.FindAllStringSubmatch(s, -1) {
+		value := strings.Trim(m[2], `"'`)
+		attrs[strings.ToLower(m[1])] = html.UnescapeString(value)
+	}
+	return attrs
+}
+
+// metadata はOpenGraph、Twitter Card、その他のmetaタグの順に値を選ぶ
+// タイトルがない場合はtitleタグの値を使い、画像のURLはbaseからの絶対URLにする
+func (p *page) metadata(base *url.URL) *Metadata {
+	m := &Metadata{
+		Title:       clean(p.first("og:title", "twitter:title")),
+		Description: clean(p.first("og:description", "twitter:description", "description")),
+		Thumbnail:   resolve(base, p.first("og:image", "og:image:url", "og:image:secure_url", "twitter:image", "twitter:image:src")),
+		SiteName:    clean(p.first("og:site_name", "application-name")),
+	}
+	if m.Title == "" {
+		m.Title = clean(html.UnescapeString(p.title))
+	}
+	return m
+}
+
+// first はkeysのうち最初に値があるものを返す
+func (p *page) first(keys ...string) string {
+	for _, key := range keys {
+		if v := strings.TrimSpace(p.meta[key]); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// clean は連続する空白や改行を1つの空白にする
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// resolve はrefをbaseからの絶対URLにする
+// httpとhttps以外のURL (data:など) は使わない
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || checkScheme(u) != nil {
+		return ""
+	}
+	return u.String()
+}
@@ -0,0 +1,238 @@
+package unfurl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultTimeout は1回のリクエストにかける最大の時間
+	DefaultTimeout = 10 * time.Second
+	// DefaultMaxBodySize は読み込むレスポンスボディの最大の大きさ
+	// OpenGraphなどのメタデータはheadにあるため先頭だけを読めば十分
+	DefaultMaxBodySize = 1 << 20
+	// maxRedirects は追跡するリダイレクトの最大の回数
+	maxRedirects = 5
+	// userAgent はリンク先に送るUser-Agent
+	userAgent = "goheki-unfurl/1.0 (+https://github.com/maguro-alternative/goheki)"
+)
+
+// ErrForbiddenAddress は接続先がプライベートなアドレスの場合のエラー
+var ErrForbiddenAddress = errors.New("unfurl: forbidden address")
+
+// Metadata はリンク先のページの情報
+// 取得できなかった項目は空文字になる
+type Metadata struct {
+	Title       string
+	Description string
+	// サムネイル画像の絶対URL
+	Thumbnail string
+	SiteName  string
+}
+
+// Fetcher はURLからリンク先の情報を取得する
+// テストではhttptestのサーバーを向いたHTTPFetcherや、固定の値を返す実装に差し替える
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*Metadata, error)
+}
+
+// HTTPFetcher はOpenGraph、Twitter Card、oEmbedの順にリンク先の情報を取得する
+type HTTPFetcher struct {
+	Client *http.Client
+	// 0の場合はDefaultMaxBodySize
+	MaxBodySize int64
+}
+
+// NewHTTPFetcher は既定の設定のHTTPFetcherを返す
+// ユーザーが登録したURLに接続するため、ループバックやプライベートなアドレスへの接続は拒否する
+func NewHTTPFetcher() *HTTPFetcher {
+	return &HTTPFetcher{
+		Client:      NewClient(DefaultTimeout),
+		MaxBodySize: DefaultMaxBodySize,
+	}
+}
+
+// NewClient はプライベートなアドレスに接続しないhttp.Clientを返す
+// リダイレクト先も同様に検査する
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		// 名前解決後のアドレスで検査するため、DNSで内部のアドレスを返された場合も拒否できる
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// プロキシを経由すると接続先を検査できないため使わない
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return checkScheme(req.URL)
+		},
+	}
+}
+
+// isPublic はインターネット上のアドレスかを返す
+func isPublic(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast()
+}
+
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	return nil
+}
+
+// Fetch はrawURLのページを取得し、メタデータを返す
+// 画像のURLの場合は画像自体をサムネイルとし、HTML以外の場合は空のMetadataを返す
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) (*Metadata, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkScheme(u); err != nil {
+		return nil, err
+	}
+	body, contentType, final, err := f.get(ctx, u.String(), "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasPrefix(mediaType, "image/"):
+		return &Metadata{Thumbnail: final.String()}, nil
+	case mediaType != "text/html" && mediaType != "application/xhtml+xml":
+		return &Metadata{}, nil
+	}
+
+	text, err := decode(body, params["charset"])
+	if err != nil {
+		return nil, err
+	}
+	p := parseHTML(text)
+	meta := p.metadata(final)
+	// タイトルやサムネイルがない場合はoEmbedで補う
+	if p.oembed != "" && (meta.Title == "" || meta.Thumbnail == "" || meta.SiteName == "") {
+		if o, err := f.oembed(ctx, final, p.oembed); err == nil {
+			meta.merge(o)
+		}
+	}
+	return meta, nil
+}
+
+// get はGETでMaxBodySizeまでのボディと、Content-Type、リダイレクト後のURLを返す
+func (f *HTTPFetcher) get(ctx context.Context, rawURL, accept string) ([]byte, string, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", accept)
+	res, err := f.client().Do(req)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= http.StatusBadRequest {
+		return nil, "", nil, fmt.Errorf("GET %s: %s", rawURL, res.Status)
+	}
+	limit := f.MaxBodySize
+	if limit <= 0 {
+		limit = DefaultMaxBodySize
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, limit))
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return body, res.Header.Get("Content-Type"), res.Request.URL, nil
+}
+
+func (f *HTTPFetcher) client() *http.Client {
+	if f.Client != nil {
+		return f.Client
+	}
+	return http.DefaultClient
+}
+
+// oembedResponse はoEmbedのレスポンスのうち使う項目
+type oembedResponse struct {
+	Title        string `json:"title"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	ProviderName string `json:"provider_name"`
+}
+
+// oembed はページで指定されたoEmbedのエンドポイントから情報を取得する
+func (f *HTTPFetcher) oembed(ctx context.Context, base *url.URL, endpoint string) (*Metadata, error) {
+	u, err := base.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkScheme(u); err != nil {
+		return nil, err
+	}
+	body, _, final, err := f.get(ctx, u.String(), "application/json")
+	if err != nil {
+		return nil, err
+	}
+	var o oembedResponse
+	if err := json.Unmarshal(body, &o); err != nil {
+		return nil, err
+	}
+	return &Metadata{
+		Title:     clean(o.Title),
+		Thumbnail: resolve(final, o.ThumbnailURL),
+		SiteName:  clean(o.ProviderName),
+	}, nil
+}
+
+// merge は空の項目をoの値で補う
+func (m *Metadata) merge(o *Metadata) {
+	for _, f := range []struct {
+		dst *string
+		src string
+	}{
+		{&m.Title, o.Title},
+		{&m.Description, o.Description},
+		{&m.Thumbnail, o.Thumbnail},
+		{&m.SiteName, o.SiteName},
+	} {
+		if *f.dst == "" {
+			*f.dst = f.src
+		}
+	}
+}
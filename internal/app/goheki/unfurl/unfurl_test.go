@@ -0,0 +1,131 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/japanese"
+)
+
+// newTestServer はパスごとに固定のレスポンスを返すサーバーを起動する
+func newTestServer(t *testing.T, pages map[string]struct{ contentType, body string }) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", p.contentType)
+		_, _ = w.Write([]byte(p.body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHTTPFetcher(t *testing.T) {
+	ctx := context.Background()
+	sjis, err := japanese.ShiftJIS.NewEncoder().String(`<html><head>
+<meta http-equiv="Content-Type" content="text/html; charset=Shift_JIS">
+<title>四条貴音 - アイドル名鑑</title>
+</head><body></body></html>`)
+	assert.NoError(t, err)
+
+	srv := newTestServer(t, map[string]struct{ contentType, body string }{
+		"/og": {"text/html; charset=utf-8", `<!DOCTYPE html>
+<html><head>
+<title>タイトルタグ</title>
+<!-- <meta property="og:title" content="コメント"> -->
+<meta property="og:title" content="雪泉 &amp; 飛鳥">
+<meta property="og:description" content="  閃乱カグラの
+  キャラクター ">
+<meta property="og:image" content="/images/yumi.png">
+<meta property="og:site_name" content="閃乱カグラ公式">
+<meta name="twitter:title" content="ツイッターのタイトル">
+</head>
+<body><meta property="og:title" content="本文"></body></html>`},
+		"/twitter": {"text/html", `<html><head>
+<meta name="twitter:card" content="summary">
+<meta name="twitter:title" content='四条貴音'>
+<meta name="description" content="お姫ちん">
+<link rel="alternate" type="application/json+oembed" href="/oembed?url=twitter">
+</head></html>`},
+		"/oembed": {"application/json", `{"type":"rich","version":"1.0","title":"oEmbedのタイトル","thumbnail_url":"https://cdn.example.com/takane.jpg","provider_name":"Example"}`},
+		"/title": {"text/html", `<html><head><title>
+  タイトルタグ
+</title><script>document.write('<meta property="og:title" content="script">')</script></head></html>`},
+		"/sjis":      {"text/html", sjis},
+		"/image.png": {"image/png", "\x89PNG"},
+		"/data.json": {"application/json", `{}`},
+	})
+	f := &HTTPFetcher{Client: srv.Client()}
+
+	t.Run("OpenGraphを優先する", func(t *testing.T) {
+		meta, err := f.Fetch(ctx, srv.URL+"/og")
+		assert.NoError(t, err)
+		assert.Equal(t, &Metadata{
+			Title:       "雪泉 & 飛鳥",
+			Description: "閃乱カグラの キャラクター",
+			Thumbnail:   srv.URL + "/images/yumi.png",
+			SiteName:    "閃乱カグラ公式",
+		}, meta)
+	})
+
+	t.Run("Twitter CardとoEmbedで補う", func(t *testing.T) {
+		meta, err := f.Fetch(ctx, srv.URL+"/twitter")
+		assert.NoError(t, err)
+		assert.Equal(t, &Metadata{
+			Title:       "四条貴音",
+			Description: "お姫ちん",
+			Thumbnail:   "https://cdn.example.com/takane.jpg",
+			SiteName:    "Example",
+		}, meta)
+	})
+
+	t.Run("titleタグ", func(t *testing.T) {
+		meta, err := f.Fetch(ctx, srv.URL+"/title")
+		assert.NoError(t, err)
+		assert.Equal(t, &Metadata{Title: "タイトルタグ"}, meta)
+	})
+
+	t.Run("metaタグの文字コード", func(t *testing.T) {
+		meta, err := f.Fetch(ctx, srv.URL+"/sjis")
+		assert.NoError(t, err)
+		assert.Equal(t, "四条貴音 - アイドル名鑑", meta.Title)
+	})
+
+	t.Run("画像はそのままサムネイルにする", func(t *testing.T) {
+		meta, err := f.Fetch(ctx, srv.URL+"/image.png")
+		assert.NoError(t, err)
+		assert.Equal(t, &Metadata{Thumbnail: srv.URL + "/image.png"}, meta)
+	})
+
+	t.Run("HTML以外は空", func(t *testing.T) {
+		meta, err := f.Fetch(ctx, srv.URL+"/data.json")
+		assert.NoError(t, err)
+		assert.Equal(t, &Metadata{}, meta)
+	})
+
+	t.Run("エラーのステータスコード", func(t *testing.T) {
+		_, err := f.Fetch(ctx, srv.URL+"/notfound")
+		assert.ErrorContains(t, err, "404")
+	})
+
+	t.Run("httpとhttps以外", func(t *testing.T) {
+		_, err := f.Fetch(ctx, "file:///etc/passwd")
+		assert.ErrorContains(t, err, "unsupported scheme")
+	})
+}
+
+func TestNewHTTPFetcher(t *testing.T) {
+	srv := newTestServer(t, map[string]struct{ contentType, body string }{
+		"/": {"text/html", "<title>local</title>"},
+	})
+
+	// 既定のFetcherはループバックのアドレスに接続しない
+	_, err := NewHTTPFetcher().Fetch(context.Background(), srv.URL+"/")
+	assert.True(t, errors.Is(err, ErrForbiddenAddress), err)
+}
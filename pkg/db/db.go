@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
@@ -76,7 +77,7 @@ func NewDBHandler(db *sqlx.DB) *DBHandler {
 	TablesCheck := func(ctx context.Context, dest []PGTable) error {
 		// retryOperationはエラーが発生した場合にリトライする
 		operation := func() error {
-			err := db.SelectContext(ctx, dest, "select schemaname, tablename, tableowner from pg_tables;")
+			err := db.SelectContext(ctx, dest, DialectOf(db).tablesQuery())
 			return errors.WithStack(err)
 		}
 		return retryOperation(ctx,func() error { return operation() })
@@ -283,10 +284,21 @@ func newDB(ctx context.Context, driverName string, path string) (*DB, func(), er
 			データベースの接続を閉じる関数
 			error型の変数
 	*/
-	db, err := sql.Open(driverName, path)
+	dialect, err := ParseDialect(driverName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if dialect == SQLite {
+		path = sqliteDSN(path)
+	}
+	db, err := sql.Open(string(dialect), path)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "failed to sql.Open(): ")
 	}
+	if dialect == SQLite {
+		// SQLiteは書き込みを1つずつしか行えず、:memory:は接続ごとに別のDBになるため接続を1つにする
+		db.SetMaxOpenConns(1)
+	}
 
 	// pingが通らない場合はエラーを返して終了する
 	ctx, cancel := context.WithTimeout(ctx, time.Second * retryInterval)
@@ -294,15 +306,50 @@ func newDB(ctx context.Context, driverName string, path string) (*DB, func(), er
 	if err := db.PingContext(ctx); err != nil {
 		return nil, func() { _ = db.Close() }, errors.Wrap(err, "failed to (*sql.DB).PingContext(): ")
 	}
-	xDriver := sqlx.NewDb(db, driverName)
+	xDriver := sqlx.NewDb(db, string(dialect))
 
 	return &DB{driver: xDriver}, func() { _ = db.Close() }, nil
 }
 
+// sqliteDSN はSQLiteの接続先に外部キーの制約を有効にするパラメータを付ける
+// 外部キーの制約は接続ごとに有効にする必要があるため、接続し直した場合も有効になるようにDSNで指定する
+func sqliteDSN(path string) string {
+	if strings.Contains(path, "_foreign_keys=") || strings.Contains(path, "_fk=") {
+		return path
+	}
+	if strings.Contains(path, "?") {
+		return path + "&_foreign_keys=on"
+	}
+	return path + "?_foreign_keys=on"
+}
+
 type DB struct {
 	driver *sqlx.DB
 }
 
+func (db *DB) DriverName() string {
+	/*
+		接続しているDBのドライバ名を返す関数
+
+		戻り値
+			ドライバ名 (postgres, sqlite3)
+	*/
+	return db.driver.DriverName()
+}
+
+func (db *DB) Rebind(query string) string {
+	/*
+		?で書いたクエリの置換文字を接続しているDBに合わせて変える関数
+
+		引数
+			query: sql文
+
+		戻り値
+			sql文
+	*/
+	return db.driver.Rebind(query)
+}
+
 func (db *DB) PingDB(ctx context.Context) error {
 	if err := db.driver.PingContext(ctx); err != nil {
 		return err
@@ -313,7 +360,7 @@ func (db *DB) PingDB(ctx context.Context) error {
 func (db *DB) TablesCheck(ctx context.Context, dest []PGTable) error {
 	// retryOperationはエラーが発生した場合にリトライする
 	operation := func() error {
-		err := db.SelectContext(ctx, dest, "select schemaname, tablename, tableowner from pg_tables;")
+		err := db.SelectContext(ctx, dest, DialectOf(db).tablesQuery())
 		return errors.WithStack(err)
 	}
 	return retryOperation(ctx,func() error { return operation() })
@@ -509,6 +556,29 @@ type Tx struct {
 	driver *sqlx.Tx
 }
 
+func (tx *Tx) DriverName() string {
+	/*
+		接続しているDBのドライバ名を返す関数
+
+		戻り値
+			ドライバ名 (postgres, sqlite3)
+	*/
+	return tx.driver.DriverName()
+}
+
+func (tx *Tx) Rebind(query string) string {
+	/*
+		?で書いたクエリの置換文字を接続しているDBに合わせて変える関数
+
+		引数
+			query: sql文
+
+		戻り値
+			sql文
+	*/
+	return tx.driver.Rebind(query)
+}
+
 func (tx *Tx) PingDB(ctx context.Context) error {
 	/*
 		データベースの接続を確認する関数
@@ -538,7 +608,7 @@ func (tx *Tx) TablesCheck(ctx context.Context, dest []PGTable) error {
 	*/
 	// retryOperationはエラーが発生した場合にリトライする
 	operation := func() error {
-		err := tx.driver.SelectContext(ctx, dest, "select schemaname, tablename, tableowner from pg_tables;")
+		err := tx.driver.SelectContext(ctx, dest, DialectOf(tx).tablesQuery())
 		return errors.WithStack(err)
 	}
 	return retryOperation(ctx,func() error { return operation() })
@@ -756,6 +826,8 @@ type Driver interface {
 	SelectContext(ctx context.Context, dest any, query string, args ...any) error
 	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 	NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error)
+	DriverName() string
+	Rebind(query string) string
 }
 
 // 上記のインターフェースを満たす構造体
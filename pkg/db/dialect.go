@@ -0,0 +1,87 @@
+package db
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/jmoiron/sqlx"
+)
+
+// Dialect はDBの種類ごとに異なるSQLの書き方をまとめる
+// 値はdatabase/sqlに登録されたドライバ名
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite3"
+)
+
+func ParseDialect(name string) (Dialect, error) {
+	/*
+		DBの種類の名前からDialectを返す関数
+		"postgresql"や"sqlite"のような別名も受け付ける
+
+		引数
+			name: DBの種類 (空の場合はpostgres)
+
+		戻り値
+			Dialect型の変数
+			error型の変数
+	*/
+	switch name {
+	case "", "postgres", "postgresql":
+		return Postgres, nil
+	case "sqlite3", "sqlite":
+		return SQLite, nil
+	}
+	return "", errors.Newf("unsupported database type %q", name)
+}
+
+func DialectOf(driver Driver) Dialect {
+	/*
+		driverが接続しているDBのDialectを返す関数
+		不明なドライバの場合はPostgresとして扱う
+
+		引数
+			driver: *DB, *Tx, *sqlx.DB, *sqlx.Tx のいずれか
+
+		戻り値
+			Dialect型の変数
+	*/
+	d, err := ParseDialect(driver.DriverName())
+	if err != nil {
+		return Postgres
+	}
+	return d
+}
+
+func (d Dialect) BindType() int {
+	/*
+		置換文字の種類を返す関数
+		Postgresは$1, $2, ...、SQLiteは?
+
+		戻り値
+			sqlx.DOLLAR, sqlx.QUESTION のいずれか
+	*/
+	return sqlx.BindType(string(d))
+}
+
+func (d Dialect) Rebind(query string) string {
+	/*
+		?で書いたクエリの置換文字をDBの種類に合わせて変える関数
+
+		引数
+			query: sql文
+
+		戻り値
+			sql文
+	*/
+	return sqlx.Rebind(d.BindType(), query)
+}
+
+// tablesQuery はpg_tablesと同じ列名でテーブルの一覧を返すクエリ
+func (d Dialect) tablesQuery() string {
+	if d == SQLite {
+		return `SELECT 'main' AS schemaname, name AS tablename, '' AS tableowner
+			FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`
+	}
+	return "select schemaname, tablename, tableowner from pg_tables;"
+}
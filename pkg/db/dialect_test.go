@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDialect(t *testing.T) {
+	for name, want := range map[string]Dialect{
+		"":           Postgres,
+		"postgres":   Postgres,
+		"postgresql": Postgres,
+		"sqlite3":    SQLite,
+		"sqlite":     SQLite,
+	} {
+		got, err := ParseDialect(name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, want, got, name)
+	}
+
+	_, err := ParseDialect("mysql")
+	assert.EqualError(t, err, `unsupported database type "mysql"`)
+}
+
+func TestDialectRebind(t *testing.T) {
+	query := "SELECT id FROM tag WHERE name = ? AND id <> ?"
+	assert.Equal(t, "SELECT id FROM tag WHERE name = $1 AND id <> $2", Postgres.Rebind(query))
+	assert.Equal(t, query, SQLite.Rebind(query))
+}
+
+func TestSQLite(t *testing.T) {
+	ctx := context.Background()
+	dbV1, cleanup, err := NewDBV1(ctx, "sqlite", ":memory:")
+	require.NoError(t, err)
+	defer cleanup()
+
+	assert.Equal(t, SQLite, DialectOf(dbV1))
+	_, err = dbV1.ExecContext(ctx, `CREATE TABLE source (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL);
+		CREATE TABLE entry (id INTEGER PRIMARY KEY AUTOINCREMENT, source_id INTEGER NOT NULL REFERENCES source (id))`)
+	require.NoError(t, err)
+
+	t.Run("トランザクション内でも同じDBを使う", func(t *testing.T) {
+		tx, err := dbV1.BeginTxx(ctx, nil)
+		require.NoError(t, err)
+		defer tx.RollbackCtx(ctx)
+		assert.Equal(t, SQLite, DialectOf(tx))
+
+		var id int64
+		require.NoError(t, tx.GetContext(ctx, &id, tx.Rebind("INSERT INTO source (name) VALUES (?) RETURNING id"), "a"))
+		assert.Equal(t, int64(1), id)
+	})
+
+	t.Run("外部キーの制約を確認する", func(t *testing.T) {
+		_, err := dbV1.ExecContext(ctx, dbV1.Rebind("INSERT INTO entry (source_id) VALUES (?)"), 100)
+		assert.ErrorContains(t, err, "FOREIGN KEY constraint failed")
+	})
+
+	t.Run("テーブルの一覧", func(t *testing.T) {
+		tables := make([]PGTable, 0)
+		require.NoError(t, dbV1.driver.SelectContext(ctx, &tables, SQLite.tablesQuery()))
+		names := make([]string, len(tables))
+		for i, table := range tables {
+			names[i] = table.TableName
+		}
+		assert.ElementsMatch(t, []string{"source", "entry"}, names)
+	})
+}
+
+func TestSQLiteDSN(t *testing.T) {
+	for path, want := range map[string]string{
+		":memory:":                    ":memory:?_foreign_keys=on",
+		"goheki.db":                   "goheki.db?_foreign_keys=on",
+		"file:goheki.db?cache=shared": "file:goheki.db?cache=shared&_foreign_keys=on",
+		"goheki.db?_foreign_keys=off": "goheki.db?_foreign_keys=off",
+	} {
+		assert.Equal(t, want, sqliteDSN(path), path)
+	}
+}
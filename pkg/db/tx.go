@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jmoiron/sqlx"
+)
+
+// savepointSeq はSAVEPOINTの名前が重複しないようにする連番
+var savepointSeq atomic.Uint64
+
+func WithTx(ctx context.Context, driver Driver, fn func(tx Driver) error) error {
+	/*
+		fnを1つのトランザクション内で実行する関数
+		fnがエラーを返した場合はロールバックし、そうでなければコミットする
+		driverが既にトランザクションの場合はSAVEPOINTを使い、fnの中の変更だけを取り消す
+
+		引数
+			ctx: context.Context型の変数
+			driver: *DB, *Tx, *sqlx.DB, *sqlx.Tx のいずれか
+			fn: トランザクション内で行う処理
+
+		戻り値
+			fnのエラー、またはトランザクションのエラー
+	*/
+	switch d := driver.(type) {
+	case *DB:
+		tx, err := d.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		return finishTx(fn(tx), func() error { return tx.CommitCtx(ctx) }, func() error { return tx.RollbackCtx(ctx) })
+	case *sqlx.DB:
+		tx, err := d.BeginTxx(ctx, nil)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return finishTx(fn(tx), tx.Commit, tx.Rollback)
+	case *Tx, *sqlx.Tx:
+		return withSavepoint(ctx, driver, fn)
+	default:
+		return errors.Newf("transactions are not supported by %T", driver)
+	}
+}
+
+func withSavepoint(ctx context.Context, tx Driver, fn func(tx Driver) error) error {
+	/*
+		トランザクション内でSAVEPOINTを作成してfnを実行する関数
+		fnがエラーを返した場合はSAVEPOINTまで巻き戻す
+
+		引数
+			ctx: context.Context型の変数
+			tx: トランザクション
+			fn: SAVEPOINT内で行う処理
+
+		戻り値
+			fnのエラー、またはSAVEPOINTの操作のエラー
+	*/
+	name := fmt.Sprintf("goheki_sp_%d", savepointSeq.Add(1))
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	return finishTx(
+		fn(tx),
+		func() error {
+			_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+			return err
+		},
+		func() error {
+			_, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			return err
+		},
+	)
+}
+
+// finishTx はfnErrがなければcommitし、あればrollbackしてfnErrを返す
+func finishTx(fnErr error, commit, rollback func() error) error {
+	if fnErr != nil {
+		if err := rollback(); err != nil {
+			return errors.CombineErrors(fnErr, errors.Wrap(err, "rollback"))
+		}
+		return fnErr
+	}
+	return errors.Wrap(commit(), "commit")
+}
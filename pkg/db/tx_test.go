@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	dbV1, cleanup, err := NewDBV1(ctx, "sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer cleanup()
+	_, err = dbV1.ExecContext(ctx, "CREATE TABLE tag (name TEXT NOT NULL UNIQUE)")
+	require.NoError(t, err)
+
+	count := func(d Driver) int {
+		var n int
+		require.NoError(t, d.GetContext(ctx, &n, "SELECT COUNT(*) FROM tag"))
+		return n
+	}
+	insert := func(names ...string) func(tx Driver) error {
+		return func(tx Driver) error {
+			for _, name := range names {
+				if _, err := tx.ExecContext(ctx, "INSERT INTO tag (name) VALUES (?)", name); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	t.Run("成功した場合はコミットする", func(t *testing.T) {
+		assert.NoError(t, WithTx(ctx, dbV1, insert("a", "b")))
+		assert.Equal(t, 2, count(dbV1))
+	})
+
+	t.Run("失敗した場合は全件をロールバックする", func(t *testing.T) {
+		assert.Error(t, WithTx(ctx, dbV1, insert("c", "a")))
+		assert.Equal(t, 2, count(dbV1))
+	})
+
+	t.Run("トランザクション内ではSAVEPOINTまで巻き戻す", func(t *testing.T) {
+		tx, err := dbV1.BeginTxx(ctx, nil)
+		require.NoError(t, err)
+		defer tx.RollbackCtx(ctx)
+
+		assert.NoError(t, WithTx(ctx, tx, insert("c")))
+		sentinel := errors.New("failed")
+		err = WithTx(ctx, tx, func(sp Driver) error {
+			if err := insert("d")(sp); err != nil {
+				return err
+			}
+			return sentinel
+		})
+		assert.ErrorIs(t, err, sentinel)
+		// cは残りdは取り消される
+		assert.Equal(t, 3, count(tx))
+	})
+}
@@ -0,0 +1,302 @@
+// Package migrate は連番のSQLファイルでDBのスキーマを更新する
+// 適用したマイグレーションはschema_migrationsテーブルに記録する
+package migrate
+
+import (
+	"context"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/maguro-alternative/goheki/pkg/db"
+)
+
+// Table は適用したマイグレーションを記録するテーブル
+const Table = "schema_migrations"
+
+// advisoryLockID はマイグレーション中に取得するPostgresのアドバイザリロックのキー
+// "goheki"をASCIIの16進数で表した値
+const advisoryLockID int64 = 0x676f68656b69
+
+// fileName はマイグレーションのファイル名 ({連番}_{名前}.up.sql または .down.sql)
+// DBの種類ごとにSQLが異なる場合は{連番}_{名前}.up.sqlite3.sqlのようにDialectを付ける
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)(?:\.(\w+))?\.sql$`)
+
+// Migration は1つのマイグレーション
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	// 空の場合は取り消せない
+	Down string
+}
+
+// Status はマイグレーションの適用状況
+type Status struct {
+	Version int64
+	Name    string
+	// 適用していない場合はnil
+	AppliedAt *time.Time
+	// 適用済みだがファイルがない (新しいバージョンで適用した) 場合はtrue
+	Missing bool
+}
+
+// Load はfsysの直下にあるdialectのマイグレーションのファイルを読み込み、連番の昇順に返す
+// Dialectを付けたファイルがある場合は、Dialectを付けていないファイルより優先する
+func Load(fsys fs.FS, dialect db.Dialect) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		m := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil {
+			continue
+		}
+		// 他のDBの種類のファイルは読み込まない
+		specific := m[4] != ""
+		if specific && m[4] != string(dialect) {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s", entry.Name())
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != m[2] {
+			return nil, errors.Newf("%s: version %d is already used by %q", entry.Name(), version, migration.Name)
+		}
+		sql, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		target := &migration.Up
+		if m[3] == "down" {
+			target = &migration.Down
+		}
+		if specific || *target == "" {
+			*target = string(sql)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, errors.Newf("migration %d_%s has no up.sql", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrator はマイグレーションを適用し、取り消す
+// マイグレーションは1つずつトランザクション内で実行し、失敗した場合はそのマイグレーションだけを取り消す
+type Migrator struct {
+	Driver     db.Driver
+	Migrations []Migration
+	// 各トランザクションの最初に実行し、他のプロセスのマイグレーションが終わるまで待つ
+	// nilの場合はロックしない
+	Lock func(ctx context.Context, tx db.Driver) error
+}
+
+// New はfsysからdriverのDBの種類のマイグレーションを読み込み、Migratorを返す
+// Postgresの場合はアドバイザリロックを使い、SQLiteの場合はロックしない
+// (SQLiteは同時に1つのトランザクションしか書き込めず、後から書き込む方が失敗する)
+func New(driver db.Driver, fsys fs.FS) (*Migrator, error) {
+	dialect := db.DialectOf(driver)
+	migrations, err := Load(fsys, dialect)
+	if err != nil {
+		return nil, err
+	}
+	m := &Migrator{
+		Driver:     driver,
+		Migrations: migrations,
+	}
+	if dialect == db.Postgres {
+		m.Lock = AdvisoryLock
+	}
+	return m, nil
+}
+
+// AdvisoryLock はトランザクションが終わるまで有効なPostgresのアドバイザリロックを取得する
+// 複数のサーバーが同時に起動しても、マイグレーションは1つずつ適用される
+func AdvisoryLock(ctx context.Context, tx db.Driver) error {
+	_, err := tx.ExecContext(ctx, tx.Rebind("SELECT pg_advisory_xact_lock(?)"), advisoryLockID)
+	return err
+}
+
+// createTable はschema_migrationsテーブルがなければ作成する
+func (m *Migrator) createTable(ctx context.Context, tx db.Driver) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+Table+` (
+		version BIGINT NOT NULL PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+// begin はトランザクション内でロックを取得し、schema_migrationsテーブルを用意してからfnを実行する
+func (m *Migrator) begin(ctx context.Context, fn func(tx db.Driver) error) error {
+	return db.WithTx(ctx, m.Driver, func(tx db.Driver) error {
+		if m.Lock != nil {
+			if err := m.Lock(ctx, tx); err != nil {
+				return errors.Wrap(err, "lock")
+			}
+		}
+		if err := m.createTable(ctx, tx); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// appliedVersions は適用済みの連番を昇順に返す
+func appliedVersions(ctx context.Context, tx db.Driver) ([]int64, error) {
+	var versions []int64
+	if err := tx.SelectContext(ctx, &versions, "SELECT version FROM "+Table+" ORDER BY version"); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// Up は適用していないマイグレーションを全て適用し、適用したマイグレーションを返す
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	for _, migration := range m.Migrations {
+		var done bool
+		err := m.begin(ctx, func(tx db.Driver) error {
+			// ロックを待つ間に他のプロセスが適用した場合は何もしない
+			versions, err := appliedVersions(ctx, tx)
+			if err != nil {
+				return err
+			}
+			if containsVersion(versions, migration.Version) {
+				return nil
+			}
+			if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+				return errors.Wrapf(err, "migration %d_%s", migration.Version, migration.Name)
+			}
+			query := tx.Rebind("INSERT INTO " + Table + " (version, name) VALUES (?, ?)")
+			if _, err := tx.ExecContext(ctx, query, migration.Version, migration.Name); err != nil {
+				return err
+			}
+			done = true
+			return nil
+		})
+		if err != nil {
+			return applied, err
+		}
+		if done {
+			applied = append(applied, migration)
+		}
+	}
+	return applied, nil
+}
+
+// Down は最後に適用したマイグレーションからn個を取り消し、取り消したマイグレーションを返す
+// ファイルがない、またはdown.sqlがないマイグレーションに当たった場合はエラーを返す
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	var reverted []Migration
+	for i := 0; i < n; i++ {
+		var migration *Migration
+		err := m.begin(ctx, func(tx db.Driver) error {
+			versions, err := appliedVersions(ctx, tx)
+			if err != nil {
+				return err
+			}
+			if len(versions) == 0 {
+				return nil
+			}
+			version := versions[len(versions)-1]
+			migration = m.find(version)
+			if migration == nil {
+				return errors.Newf("migration %d is applied but its file is missing", version)
+			}
+			if migration.Down == "" {
+				return errors.Newf("migration %d_%s cannot be reverted", migration.Version, migration.Name)
+			}
+			if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+				return errors.Wrapf(err, "migration %d_%s", migration.Version, migration.Name)
+			}
+			query := tx.Rebind("DELETE FROM " + Table + " WHERE version = ?")
+			_, err = tx.ExecContext(ctx, query, version)
+			return err
+		})
+		if err != nil {
+			return reverted, err
+		}
+		if migration == nil {
+			break
+		}
+		reverted = append(reverted, *migration)
+	}
+	return reverted, nil
+}
+
+// Status は全てのマイグレーションの適用状況を連番の昇順に返す
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var rows []struct {
+		Version   int64     `db:"version"`
+		Name      string    `db:"name"`
+		AppliedAt time.Time `db:"applied_at"`
+	}
+	err := m.begin(ctx, func(tx db.Driver) error {
+		return tx.SelectContext(ctx, &rows, "SELECT version, name, applied_at FROM "+Table+" ORDER BY version")
+	})
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Status{}
+	statuses := make([]*Status, 0, len(m.Migrations)+len(rows))
+	for _, migration := range m.Migrations {
+		s := &Status{Version: migration.Version, Name: migration.Name}
+		byVersion[migration.Version] = s
+		statuses = append(statuses, s)
+	}
+	for _, row := range rows {
+		appliedAt := row.AppliedAt
+		s, ok := byVersion[row.Version]
+		if !ok {
+			s = &Status{Version: row.Version, Name: row.Name, Missing: true}
+			statuses = append(statuses, s)
+		}
+		s.AppliedAt = &appliedAt
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	res := make([]Status, len(statuses))
+	for i, s := range statuses {
+		res[i] = *s
+	}
+	return res, nil
+}
+
+// find は連番に対応するマイグレーションを返す
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.Migrations {
+		if m.Migrations[i].Version == version {
+			return &m.Migrations[i]
+		}
+	}
+	return nil
+}
+
+func containsVersion(versions []int64, version int64) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/maguro-alternative/goheki/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_init.up.sql":        {Data: []byte("CREATE TABLE tag (name TEXT NOT NULL);")},
+		"0001_init.down.sql":      {Data: []byte("DROP TABLE tag;")},
+		"0002_color.up.sql":       {Data: []byte("ALTER TABLE tag ADD COLUMN color TEXT;")},
+		"0002_color.down.sql":     {Data: []byte("ALTER TABLE tag DROP COLUMN color;")},
+		"0003_seed.up.sql":        {Data: []byte("INSERT INTO tag (name, color) VALUES ('a', 'red');")},
+		"README.md":               {Data: []byte("マイグレーション以外のファイルは無視する")},
+		"0004_irreversible.txt":   {Data: []byte("拡張子が異なるファイルは無視する")},
+		"templates/0005_x.up.sql": {Data: []byte("ディレクトリの中は読み込まない")},
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("連番の昇順に読み込む", func(t *testing.T) {
+		migrations, err := Load(testFS(), db.SQLite)
+		require.NoError(t, err)
+		assert.Equal(t, []Migration{
+			{Version: 1, Name: "init", Up: "CREATE TABLE tag (name TEXT NOT NULL);", Down: "DROP TABLE tag;"},
+			{Version: 2, Name: "color", Up: "ALTER TABLE tag ADD COLUMN color TEXT;", Down: "ALTER TABLE tag DROP COLUMN color;"},
+			{Version: 3, Name: "seed", Up: "INSERT INTO tag (name, color) VALUES ('a', 'red');"},
+		}, migrations)
+	})
+
+	t.Run("DBの種類ごとのファイルを優先する", func(t *testing.T) {
+		fsys := testFS()
+		fsys["0001_init.up.sqlite3.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE tag (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL);")}
+		fsys["0001_init.up.postgres.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE tag (id SERIAL PRIMARY KEY, name TEXT NOT NULL);")}
+
+		sqlite, err := Load(fsys, db.SQLite)
+		require.NoError(t, err)
+		assert.Len(t, sqlite, 3)
+		assert.Equal(t, "CREATE TABLE tag (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL);", sqlite[0].Up)
+		assert.Equal(t, "DROP TABLE tag;", sqlite[0].Down)
+
+		postgres, err := Load(fsys, db.Postgres)
+		require.NoError(t, err)
+		assert.Equal(t, "CREATE TABLE tag (id SERIAL PRIMARY KEY, name TEXT NOT NULL);", postgres[0].Up)
+	})
+
+	t.Run("連番の重複", func(t *testing.T) {
+		fsys := testFS()
+		fsys["0002_other.up.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+		_, err := Load(fsys, db.SQLite)
+		assert.ErrorContains(t, err, "is already used by")
+	})
+
+	t.Run("up.sqlがない", func(t *testing.T) {
+		fsys := testFS()
+		fsys["0009_only_down.down.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+		_, err := Load(fsys, db.SQLite)
+		assert.EqualError(t, err, "migration 9_only_down has no up.sql")
+	})
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	dbV1, cleanup, err := db.NewDBV1(ctx, "sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer cleanup()
+
+	t.Run("SQLiteではロックしない", func(t *testing.T) {
+		m, err := New(dbV1, testFS())
+		require.NoError(t, err)
+		assert.Nil(t, m.Lock)
+		assert.Len(t, m.Migrations, 3)
+	})
+
+	migrations, err := Load(testFS(), db.SQLite)
+	require.NoError(t, err)
+	locks := 0
+	m := &Migrator{
+		Driver:     dbV1,
+		Migrations: migrations,
+		// SQLiteはアドバイザリロックがないため、呼ばれた回数だけを数える
+		Lock: func(ctx context.Context, tx db.Driver) error {
+			locks++
+			return nil
+		},
+	}
+
+	versions := func(statuses []Status) []int64 {
+		var applied []int64
+		for _, s := range statuses {
+			if s.AppliedAt != nil {
+				applied = append(applied, s.Version)
+			}
+		}
+		return applied
+	}
+
+	t.Run("適用前の状況", func(t *testing.T) {
+		statuses, err := m.Status(ctx)
+		require.NoError(t, err)
+		assert.Len(t, statuses, 3)
+		assert.Empty(t, versions(statuses))
+	})
+
+	t.Run("全て適用する", func(t *testing.T) {
+		locks = 0
+		applied, err := m.Up(ctx)
+		require.NoError(t, err)
+		assert.Len(t, applied, 3)
+		// マイグレーションごとにロックする
+		assert.Equal(t, 3, locks)
+
+		var color string
+		require.NoError(t, dbV1.GetContext(ctx, &color, "SELECT color FROM tag WHERE name = 'a'"))
+		assert.Equal(t, "red", color)
+
+		statuses, err := m.Status(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2, 3}, versions(statuses))
+	})
+
+	t.Run("適用済みの場合は何もしない", func(t *testing.T) {
+		applied, err := m.Up(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, applied)
+	})
+
+	t.Run("down.sqlがない場合は取り消せない", func(t *testing.T) {
+		reverted, err := m.Down(ctx, 1)
+		assert.EqualError(t, err, "migration 3_seed cannot be reverted")
+		assert.Empty(t, reverted)
+	})
+
+	t.Run("最後のマイグレーションから取り消す", func(t *testing.T) {
+		m.Migrations[2].Down = "DELETE FROM tag WHERE name = 'a';"
+		reverted, err := m.Down(ctx, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{3, 2}, []int64{reverted[0].Version, reverted[1].Version})
+
+		statuses, err := m.Status(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1}, versions(statuses))
+	})
+
+	t.Run("失敗したマイグレーションは記録しない", func(t *testing.T) {
+		m.Migrations = append(m.Migrations, Migration{Version: 4, Name: "broken", Up: "ALTER TABLE missing ADD COLUMN x TEXT;"})
+		defer func() { m.Migrations = m.Migrations[:len(m.Migrations)-1] }()
+		applied, err := m.Up(ctx)
+		assert.ErrorContains(t, err, "migration 4_broken")
+		// 失敗する前のマイグレーションは適用したまま残す
+		assert.Len(t, applied, 2)
+
+		statuses, err := m.Status(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2, 3}, versions(statuses))
+	})
+
+	t.Run("ファイルがないマイグレーション", func(t *testing.T) {
+		_, err := dbV1.ExecContext(ctx, "INSERT INTO "+Table+" (version, name) VALUES (10, 'future')")
+		require.NoError(t, err)
+
+		statuses, err := m.Status(ctx)
+		require.NoError(t, err)
+		last := statuses[len(statuses)-1]
+		assert.Equal(t, int64(10), last.Version)
+		assert.True(t, last.Missing)
+
+		_, err = m.Down(ctx, 1)
+		assert.EqualError(t, err, "migration 10 is applied but its file is missing")
+	})
+}